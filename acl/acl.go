@@ -0,0 +1,241 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Package acl provides helpers to compile ordered lists of packet filtering rules
+(such as firewall rules or access control lists) into BDDs, and to answer
+queries about them; for instance to check whether a rule is shadowed by the
+rules before it.
+
+A packet is described by a Header, that is a list of fields (such as source and
+destination addresses, protocol and ports in a "5-tuple") where each field is an
+unsigned integer with a fixed bit width. Each bit of a field is encoded with one
+BDD variable, starting with the most significant bit, so that prefix matches
+only constrain the first variables of a field.
+*/
+package acl
+
+import (
+	"fmt"
+
+	"github.com/dalzilio/rudd"
+)
+
+// Field describes a field in a packet header, such as the source address of an
+// IPv4 packet (Width 32) or a destination port (Width 16).
+type Field struct {
+	Name  string
+	Width int
+}
+
+// Match is the condition on the value of a field in a rule. It matches all the
+// values in the interval [Lo..Hi]. A rule with a nil Match on a field accepts
+// any value.
+type Match struct {
+	Lo, Hi uint64
+}
+
+// Exact returns a Match accepting only value v.
+func Exact(v uint64) *Match {
+	return &Match{v, v}
+}
+
+// Range returns a Match accepting all the values in the interval [lo..hi].
+func Range(lo, hi uint64) *Match {
+	return &Match{lo, hi}
+}
+
+// Prefix returns a Match accepting all the values, in a field of the given
+// width, that share the first length bits with v; like with the notation
+// 10.0.0.0/8 for IPv4 addresses.
+func Prefix(v uint64, length, width int) *Match {
+	if length <= 0 {
+		return &Match{0, mask(width)}
+	}
+	if length > width {
+		length = width
+	}
+	low := mask(width - length)
+	v = v & mask(width) &^ low
+	return &Match{v, v | low}
+}
+
+func mask(width int) uint64 {
+	if width >= 64 {
+		return ^uint64(0)
+	}
+	return (uint64(1) << uint(width)) - 1
+}
+
+// Rule is a packet filtering rule. It matches packets where the value of field
+// k is accepted by Matches[k]. We use a first-match semantics, meaning that the
+// action of a packet is given by the first rule that matches it.
+type Rule struct {
+	Matches []*Match
+	Permit  bool
+}
+
+// Header is used to encode packets and rules as BDDs. It records the position
+// of the variables used for each field.
+type Header struct {
+	bdd    *rudd.BDD
+	fields []Field
+	first  []int // index of the variable for the most significant bit of each field
+}
+
+// NewHeader returns a Header for a packet with the given fields, where the
+// bits of the fields are encoded using variables in b, starting from variable
+// offset. We return an error if b does not have enough variables.
+func NewHeader(b *rudd.BDD, offset int, fields ...Field) (*Header, error) {
+	h := &Header{bdd: b, fields: fields, first: make([]int, len(fields))}
+	next := offset
+	for k, f := range fields {
+		if f.Width < 1 || f.Width > 64 {
+			return nil, fmt.Errorf("invalid width (%d) for field %s", f.Width, f.Name)
+		}
+		h.first[k] = next
+		next += f.Width
+	}
+	if offset < 0 || next > b.Varnum() {
+		return nil, fmt.Errorf("not enough variables in BDD (need %d, got %d)", next, b.Varnum())
+	}
+	return h, nil
+}
+
+// Vars returns the variables used to encode field k, starting with the most
+// significant bit.
+func (h *Header) Vars(k int) []int {
+	res := make([]int, h.fields[k].Width)
+	for i := range res {
+		res[i] = h.first[k] + i
+	}
+	return res
+}
+
+// bit returns the variable encoding bit i (0 is the least significant bit) of
+// field k.
+func (h *Header) bit(k, i int) int {
+	return h.first[k] + h.fields[k].Width - 1 - i
+}
+
+// Range returns the set of packets such that the value of field k is in the
+// interval [lo..hi], where values above the maximal value of the field are
+// clamped. We return nil if k is not the index of a field.
+func (h *Header) Range(k int, lo, hi uint64) rudd.Node {
+	if k < 0 || k >= len(h.fields) {
+		return nil
+	}
+	b := h.bdd
+	width := h.fields[k].Width
+	if hi > mask(width) {
+		hi = mask(width)
+	}
+	if lo > hi {
+		return b.False()
+	}
+	// we build the constraints (x >= lo) and (x <= hi) starting from the
+	// least significant bit
+	geq, leq := b.True(), b.True()
+	for i := 0; i < width; i++ {
+		x := b.Ithvar(h.bit(k, i))
+		if (lo>>uint(i))&1 == 1 {
+			geq = b.Ite(x, geq, b.False())
+		} else {
+			geq = b.Ite(x, b.True(), geq)
+		}
+		if (hi>>uint(i))&1 == 1 {
+			leq = b.Ite(x, leq, b.True())
+		} else {
+			leq = b.Ite(x, b.False(), leq)
+		}
+	}
+	return b.And(geq, leq)
+}
+
+// Match returns the set of packets matched by rule r, irrespective of its
+// action.
+func (h *Header) Match(r Rule) rudd.Node {
+	res := h.bdd.True()
+	for k, m := range r.Matches {
+		if m != nil && k < len(h.fields) {
+			res = h.bdd.And(res, h.Range(k, m.Lo, m.Hi))
+		}
+	}
+	return res
+}
+
+// Packet returns the singleton set containing the packet where field k has
+// value values[k].
+func (h *Header) Packet(values ...uint64) rudd.Node {
+	res := h.bdd.True()
+	for k, v := range values {
+		if k < len(h.fields) {
+			res = h.bdd.And(res, h.Range(k, v, v))
+		}
+	}
+	return res
+}
+
+// Compile returns the set of packets permitted by the ordered list of rules,
+// using a first-match semantics and a default action of deny.
+func (h *Header) Compile(rules []Rule) rudd.Node {
+	b := h.bdd
+	res := b.False()
+	for k := len(rules) - 1; k >= 0; k-- {
+		res = b.Ite(h.Match(rules[k]), b.From(rules[k].Permit), res)
+	}
+	return res
+}
+
+// Effective returns, for each rule, the set of packets for which it is the
+// first matching rule.
+func (h *Header) Effective(rules []Rule) []rudd.Node {
+	b := h.bdd
+	res := make([]rudd.Node, len(rules))
+	seen := b.False()
+	for k, r := range rules {
+		m := h.Match(r)
+		res[k] = b.Apply(m, seen, rudd.OPdiff)
+		seen = b.Or(seen, m)
+	}
+	return res
+}
+
+// Shadowed returns true if rule k never applies because all the packets it
+// matches are also matched by some rule before it.
+func (h *Header) Shadowed(rules []Rule, k int) bool {
+	b := h.bdd
+	seen := b.False()
+	for _, r := range rules[:k] {
+		seen = b.Or(seen, h.Match(r))
+	}
+	return b.Equal(b.Apply(h.Match(rules[k]), seen, rudd.OPdiff), b.False())
+}
+
+// Redundant returns true if removing rule k does not change the set of
+// permitted packets. A shadowed rule is always redundant, but a rule can also
+// be redundant when it has the same action than the rules that would apply in
+// its absence.
+func (h *Header) Redundant(rules []Rule, k int) bool {
+	others := make([]Rule, 0, len(rules)-1)
+	others = append(others, rules[:k]...)
+	others = append(others, rules[k+1:]...)
+	return h.bdd.Equal(h.Compile(rules), h.Compile(others))
+}
+
+// Coverage returns the set of packets matched by at least one rule; the other
+// packets are handled by the default action.
+func (h *Header) Coverage(rules []Rule) rudd.Node {
+	res := h.bdd.False()
+	for _, r := range rules {
+		res = h.bdd.Or(res, h.Match(r))
+	}
+	return res
+}
+
+// Difference returns the set of packets that are handled differently by the
+// two lists of rules.
+func (h *Header) Difference(rules1, rules2 []Rule) rudd.Node {
+	return h.bdd.Apply(h.Compile(rules1), h.Compile(rules2), rudd.OPxor)
+}
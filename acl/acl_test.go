@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package acl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dalzilio/rudd"
+)
+
+func TestRange(t *testing.T) {
+	bdd, _ := rudd.New(8)
+	h, err := NewHeader(bdd, 0, Field{"port", 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ lo, hi uint64 }{{0, 255}, {3, 3}, {17, 200}, {128, 255}, {5, 4}} {
+		expected := int64(0)
+		if tt.hi >= tt.lo {
+			expected = int64(tt.hi - tt.lo + 1)
+		}
+		if actual := bdd.Satcount(h.Range(0, tt.lo, tt.hi)); actual.Int64() != expected {
+			t.Errorf("Range(%d, %d): expected %d values, actual %s", tt.lo, tt.hi, expected, actual)
+		}
+	}
+	// values above 255 are clamped, not truncated
+	if actual := bdd.Satcount(h.Range(0, 250, 300)); actual.Int64() != 6 {
+		t.Errorf("Range(250, 300): expected 6 values, actual %s", actual)
+	}
+	if h.Range(1, 0, 1) != nil || h.Range(-1, 0, 1) != nil {
+		t.Errorf("Range should return nil with an invalid field")
+	}
+	if m := Prefix(0xC0, 2, 8); m.Lo != 0xC0 || m.Hi != 0xFF {
+		t.Errorf("Prefix(0xC0/2): expected [192..255], actual [%d..%d]", m.Lo, m.Hi)
+	}
+}
+
+func TestRules(t *testing.T) {
+	bdd, _ := rudd.New(24)
+	h, err := NewHeader(bdd, 0, Field{"src", 8}, Field{"dst", 8}, Field{"port", 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []Rule{
+		{Matches: []*Match{Prefix(10, 4, 8), nil, Exact(22)}, Permit: true},
+		{Matches: []*Match{nil, nil, Range(20, 25)}, Permit: false},
+		{Matches: []*Match{Exact(10), nil, Exact(22)}, Permit: false}, // shadowed by rule 0
+		{Matches: []*Match{nil, Exact(1), nil}, Permit: true},
+	}
+	policy := h.Compile(rules)
+	permitted := func(src, dst, port uint64) bool {
+		return !bdd.Equal(bdd.And(policy, h.Packet(src, dst, port)), bdd.False())
+	}
+	if !permitted(12, 7, 22) || permitted(10, 1, 21) || !permitted(200, 1, 80) || permitted(200, 2, 80) {
+		t.Errorf("wrong first-match semantics in Compile")
+	}
+	if h.Shadowed(rules, 1) || !h.Shadowed(rules, 2) {
+		t.Errorf("wrong result for Shadowed")
+	}
+	if !h.Redundant(rules, 2) || h.Redundant(rules, 3) {
+		t.Errorf("wrong result for Redundant")
+	}
+	eff := h.Effective(rules)
+	if !bdd.Equal(eff[2], bdd.False()) {
+		t.Errorf("effective set of a shadowed rule should be empty")
+	}
+	if !bdd.Equal(h.Difference(rules, append(rules[:2:2], rules[3])), bdd.False()) {
+		t.Errorf("removing a shadowed rule should not change the policy")
+	}
+}
+
+func TestPrint(t *testing.T) {
+	bdd, _ := rudd.New(16)
+	h, _ := NewHeader(bdd, 0, Field{"src", 8}, Field{"port", 8})
+	rules := []Rule{
+		{Matches: []*Match{Prefix(0x10, 6, 8), Exact(22)}, Permit: true},
+	}
+	var buf bytes.Buffer
+	if err := h.Print(&buf, h.Compile(rules)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "src∈[16..19], port=22\n"; buf.String() != expected {
+		t.Errorf("expected %q, actual %q", expected, buf.String())
+	}
+	buf.Reset()
+	h.Print(&buf, bdd.Or(h.Packet(5, 1), h.Packet(7, 1)))
+	if expected := "src∈{5,7}, port=1\n"; buf.String() != expected {
+		t.Errorf("expected %q, actual %q", expected, buf.String())
+	}
+	buf.Reset()
+	h.Dot(&buf, h.Packet(5, 1))
+	if !strings.Contains(buf.String(), `label="src[7]"`) || !strings.Contains(buf.String(), `label="port[0]"`) {
+		t.Errorf("Dot should label nodes with fields, actual %s", buf.String())
+	}
+}
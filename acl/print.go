@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package acl
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dalzilio/rudd"
+)
+
+// Print writes a description of the set of packets n to w, with one line for
+// each cube of n (see Allsat), where the constraints on each field are decoded
+// from the bits of the field; for instance "src∈[10..11], port=22". Fields
+// without constraints are omitted, and we print "any" for a cube without
+// constraints and "none" if n is empty.
+func (h *Header) Print(w io.Writer, n rudd.Node) error {
+	b := h.bdd
+	if b.Equal(n, b.False()) {
+		_, err := fmt.Fprintln(w, "none")
+		return err
+	}
+	return b.Allsat(func(cube []int) error {
+		var res []string
+		for k := range h.fields {
+			if s := h.describe(k, cube); s != "" {
+				res = append(res, s)
+			}
+		}
+		if len(res) == 0 {
+			res = []string{"any"}
+		}
+		_, err := fmt.Fprintln(w, strings.Join(res, ", "))
+		return err
+	}, n)
+}
+
+// describe returns the constraint on field k in a cube, as returned by Allsat,
+// or the empty string if there is none.
+func (h *Header) describe(k int, cube []int) string {
+	f := h.fields[k]
+	var fixed, value uint64
+	free := []int{}
+	for i := 0; i < f.Width; i++ {
+		switch cube[h.bit(k, i)] {
+		case 0:
+			fixed |= 1 << uint(i)
+		case 1:
+			fixed |= 1 << uint(i)
+			value |= 1 << uint(i)
+		default:
+			free = append(free, i)
+		}
+	}
+	switch {
+	case len(free) == f.Width:
+		return ""
+	case len(free) == 0:
+		return fmt.Sprintf("%s=%d", f.Name, value)
+	case free[len(free)-1] == len(free)-1:
+		// the free bits are the least significant ones, like with a prefix
+		return fmt.Sprintf("%s∈[%d..%d]", f.Name, value, value|mask(len(free)))
+	case len(free) <= 3:
+		values := []uint64{}
+		for s := 0; s < 1<<uint(len(free)); s++ {
+			v := value
+			for j, i := range free {
+				if s&(1<<uint(j)) != 0 {
+					v |= 1 << uint(i)
+				}
+			}
+			values = append(values, v)
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		s := make([]string, len(values))
+		for i, v := range values {
+			s[i] = fmt.Sprint(v)
+		}
+		return fmt.Sprintf("%s∈{%s}", f.Name, strings.Join(s, ","))
+	}
+	// otherwise we print the bits of the field, with x for don't care
+	bits := make([]byte, f.Width)
+	for i := 0; i < f.Width; i++ {
+		c := byte('x')
+		if fixed&(1<<uint(i)) != 0 {
+			c = '0' + byte((value>>uint(i))&1)
+		}
+		bits[f.Width-1-i] = c
+	}
+	return fmt.Sprintf("%s=0b%s", f.Name, bits)
+}
+
+// Dot writes a graph-like description of n to w using Graphviz's dot format,
+// like rudd.Dot, but where each node is labelled with the name of a field and
+// the position of a bit in this field (0 for the least significant bit),
+// instead of a variable index.
+func (h *Header) Dot(w io.Writer, n rudd.Node) error {
+	b := h.bdd
+	if b.Errored() {
+		return fmt.Errorf(b.Error())
+	}
+	fmt.Fprintln(w, "digraph G {")
+	fmt.Fprintln(w, "1 [shape=box, label=\"1\", style=filled, shape=box, height=0.3, width=0.3];")
+	err := b.Allnodes(func(id, level, low, high int) error {
+		if id > 1 {
+			fmt.Fprintf(w, "%d [label=%q];\n", id, h.varname(b.Level2Var(level)))
+			if low != 0 {
+				fmt.Fprintf(w, "%d -> %d [style=dotted];\n", id, low)
+			}
+			if high != 0 {
+				fmt.Fprintf(w, "%d -> %d [style=filled];\n", id, high)
+			}
+		}
+		return nil
+	}, n)
+	fmt.Fprintln(w, "}")
+	return err
+}
+
+// varname returns the name of variable v, as a bit of a field, or "x" followed
+// by v if it is not used in h.
+func (h *Header) varname(v int) string {
+	for k, f := range h.fields {
+		if v >= h.first[k] && v < h.first[k]+f.Width {
+			return fmt.Sprintf("%s[%d]", f.Name, h.first[k]+f.Width-1-v)
+		}
+	}
+	return fmt.Sprintf("x%d", v)
+}
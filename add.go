@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/big"
+
+	"github.com/dalzilio/rudd/internal/dd"
+)
+
+// Numeric is the constraint satisfied by the types of values for which we
+// provide predefined operations on a Diagram, such as Plus or Max. A Diagram
+// with numeric values is also called an Algebraic Decision Diagram (ADD).
+// Rational numbers of type *big.Rat cannot be used directly, since pointers are
+// compared by address; use type Rat instead, with operations PlusRat,
+// TimesRat, MinRat and MaxRat.
+type Numeric interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+// Plus returns the addition of values, as an operation of d, for use with
+// Apply and Abstract. For instance, Abstract(n, vars, Plus(d)) is the sum of
+// the values of n over all the assignments of vars.
+func Plus[T Numeric](d *Diagram[T]) DiagramOp[T] {
+	return d.named("plus", func(x, y T) T { return x + y })
+}
+
+// Times returns the multiplication of values, as an operation of d.
+func Times[T Numeric](d *Diagram[T]) DiagramOp[T] {
+	return d.named("times", func(x, y T) T { return x * y })
+}
+
+// Min returns the minimum of two values, as an operation of d.
+func Min[T Numeric](d *Diagram[T]) DiagramOp[T] {
+	return d.named("min", func(x, y T) T {
+		if y < x {
+			return y
+		}
+		return x
+	})
+}
+
+// Max returns the maximum of two values, as an operation of d.
+func Max[T Numeric](d *Diagram[T]) DiagramOp[T] {
+	return d.named("max", func(x, y T) T {
+		if y > x {
+			return y
+		}
+		return x
+	})
+}
+
+// Rat is an arbitrary-precision rational number that can be used as the value
+// of the leaves of a Diagram. Unlike *big.Rat, values of type Rat are compared
+// by value, since we store the normalized representation of the number. The
+// zero value is the number 0.
+type Rat struct {
+	s string // normalized representation, as given by big.Rat.RatString ("" for 0)
+}
+
+// NewRat returns the rational number a/b. It panics if b is 0.
+func NewRat(a, b int64) Rat {
+	return RatOf(big.NewRat(a, b))
+}
+
+// RatOf returns the rational number with value x.
+func RatOf(x *big.Rat) Rat {
+	if x.Sign() == 0 {
+		return Rat{}
+	}
+	return Rat{x.RatString()}
+}
+
+// Big returns the value of r as a new *big.Rat.
+func (r Rat) Big() *big.Rat {
+	if r.s == "" {
+		return new(big.Rat)
+	}
+	x, _ := new(big.Rat).SetString(r.s)
+	return x
+}
+
+// String returns the representation of r in the form "a/b", or "a" if r is an
+// integer.
+func (r Rat) String() string {
+	if r.s == "" {
+		return "0"
+	}
+	return r.s
+}
+
+// PlusRat returns the addition of rational values, as an operation of d, see
+// Plus.
+func PlusRat(d *Diagram[Rat]) DiagramOp[Rat] {
+	return d.named("plus", func(x, y Rat) Rat {
+		z := x.Big()
+		return RatOf(z.Add(z, y.Big()))
+	})
+}
+
+// TimesRat returns the multiplication of rational values, as an operation of
+// d.
+func TimesRat(d *Diagram[Rat]) DiagramOp[Rat] {
+	return d.named("times", func(x, y Rat) Rat {
+		z := x.Big()
+		return RatOf(z.Mul(z, y.Big()))
+	})
+}
+
+// MinRat returns the minimum of two rational values, as an operation of d.
+func MinRat(d *Diagram[Rat]) DiagramOp[Rat] {
+	return d.named("min", func(x, y Rat) Rat {
+		if y.Big().Cmp(x.Big()) < 0 {
+			return y
+		}
+		return x
+	})
+}
+
+// MaxRat returns the maximum of two rational values, as an operation of d.
+func MaxRat(d *Diagram[Rat]) DiagramOp[Rat] {
+	return d.named("max", func(x, y Rat) Rat {
+		if y.Big().Cmp(x.Big()) > 0 {
+			return y
+		}
+		return x
+	})
+}
+
+// named returns the operation registered with name, registering f under this
+// name the first time, so that all the calls share the same cache entries.
+func (d *Diagram[T]) named(name string, f func(x, y T) T) DiagramOp[T] {
+	if op, ok := d.predefined[name]; ok {
+		return op
+	}
+	if d.predefined == nil {
+		d.predefined = make(map[string]DiagramOp[T])
+	}
+	op := d.Operation(f)
+	d.predefined[name] = op
+	return op
+}
+
+// FromBDD returns the diagram mapping each assignment to value one, if it
+// satisfies n, and to value zero otherwise. The BDD must have the same number
+// of variables than d, that are matched by index; the order of variables in b
+// can be different from the identity.
+func (d *Diagram[T]) FromBDD(b *BDD, n Node, zero, one T) Node {
+	if b.checkptr(n) != nil {
+		return d.seterror("Wrong operand in call to FromBDD (%s)", b.Error())
+	}
+	if b.varnum != d.varnum {
+		return d.seterror("Different number of variables (%d and %d) in call to FromBDD", b.varnum, d.varnum)
+	}
+	d.refstack = d.refstack[:0]
+	leaves := [2]int{d.pushref(d.leaf(zero)), d.pushref(d.leaf(one))}
+	// we keep all the intermediate results in the refstack, since they are
+	// also stored in memo
+	memo := make(map[int]int)
+	var rec func(k int) int
+	rec = func(k int) int {
+		if k < 2 {
+			return leaves[k]
+		}
+		if res, ok := memo[k]; ok {
+			return res
+		}
+		low := rec(b.low(k))
+		high := rec(b.high(k))
+		res := d.pushref(d.branch(b.level2var[b.level(k)], low, high))
+		memo[k] = res
+		return res
+	}
+	return d.retnode(rec(*n))
+}
+
+// branch returns the diagram equal to high when variable x is true, and to low
+// otherwise, where x may occur in low and high.
+func (d *Diagram[T]) branch(x int32, low, high int) int {
+	if low < 0 || high < 0 {
+		return -1
+	}
+	level := d.level(low)
+	if l := d.level(high); l < level {
+		level = l
+	}
+	if level > x {
+		return d.makenode(x, low, high)
+	}
+	// we use negative ids in the cache, since Apply and Abstract use positive
+	// ones
+	c := -int(x) - 1
+	if e := d.cache.Table[dd.Triple(low, high, c, len(d.cache.Table))]; e.A == low && e.B == high && e.C == c {
+		return e.Res
+	}
+	ll, lh := d.cofactors(low, level)
+	hl, hh := d.cofactors(high, level)
+	var res int
+	if level == x {
+		res = d.makenode(x, ll, hh)
+	} else {
+		initial := len(d.refstack)
+		l := d.pushref(d.branch(x, ll, hl))
+		h := d.pushref(d.branch(x, lh, hh))
+		res = d.makenode(level, l, h)
+		d.refstack = d.refstack[:initial]
+	}
+	if res >= 0 {
+		d.cache.Table[dd.Triple(low, high, c, len(d.cache.Table))] = dd.Entry4{A: low, B: high, C: c, Res: res}
+	}
+	return res
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AdviceKind is the kind of change to the variable order proposed in a
+// VarAdvice.
+type AdviceKind int
+
+const (
+	// AdviceSwap is a suggestion to swap two variables at adjacent levels.
+	AdviceSwap AdviceKind = iota
+	// AdviceGroup is a suggestion to keep a block of variables together, at
+	// adjacent levels, when changing the order.
+	AdviceGroup
+)
+
+func (k AdviceKind) String() string {
+	switch k {
+	case AdviceSwap:
+		return "swap"
+	case AdviceGroup:
+		return "group"
+	}
+	return fmt.Sprintf("AdviceKind(%d)", int(k))
+}
+
+// VarAdvice is a suggestion of change in the variable order, as returned by
+// ReorderAdvice.
+type VarAdvice struct {
+	Kind AdviceKind // Swap two variables or group a block of variables
+	Vars []int      // Variables concerned, by increasing level
+	Gain int        // Nodes saved by a swap, or edges inside a group
+}
+
+func (a VarAdvice) String() string {
+	if a.Kind == AdviceSwap {
+		return fmt.Sprintf("swap %v (saves %d nodes)", a.Vars, a.Gain)
+	}
+	return fmt.Sprintf("group %v (%d edges)", a.Vars, a.Gain)
+}
+
+// ReorderAdvice returns suggestions for improving the variable order, computed
+// from the level profile of the graph shared by the roots in n, or of all the
+// active nodes if n is empty. We give two kinds of advice. For every pair of
+// variables at adjacent levels, we compute the exact number of nodes saved by
+// swapping them, without changing the BDD, and suggest the swaps with a
+// positive gain, starting with the best one. We also suggest to group the
+// blocks of variables at adjacent levels that are strongly coupled, meaning
+// that at least half of the edges leaving each level reach the next one; such
+// blocks, like the current and next state variables of a transition relation,
+// are best moved together. Since the BDD cannot be reordered in place, the
+// advice can be followed by building a new BDD with the proposed order. We
+// return nil, and set the error flag of b, if there is an error.
+func (b *BDD) ReorderAdvice(n ...Node) []VarAdvice {
+	for _, v := range n {
+		if b.checkptr(v) != nil {
+			b.seterror("Wrong operand in call to ReorderAdvice")
+			return nil
+		}
+	}
+	p := b.newlevelprofile(n...)
+	res := []VarAdvice{}
+	for l := 0; l+1 < int(b.varnum); l++ {
+		if gain := p.swapgain(l); gain > 0 {
+			res = append(res, VarAdvice{
+				Kind: AdviceSwap,
+				Vars: []int{int(b.level2var[l]), int(b.level2var[l+1])},
+				Gain: gain,
+			})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Gain > res[j].Gain })
+	for l := 0; l+1 < int(b.varnum); {
+		start, edges := l, 0
+		for ; l+1 < int(b.varnum) && len(p.nodes[l]) > 0 && p.down[l] >= len(p.nodes[l]); l++ {
+			edges += p.down[l]
+		}
+		if l > start {
+			vars := make([]int, 0, l-start+1)
+			for k := start; k <= l; k++ {
+				vars = append(vars, int(b.level2var[k]))
+			}
+			res = append(res, VarAdvice{Kind: AdviceGroup, Vars: vars, Gain: edges})
+		} else {
+			l++
+		}
+	}
+	return res
+}
+
+// levelprofile records the nodes of a graph by level, together with the
+// information needed to compute the effect of swapping two adjacent levels.
+type levelprofile struct {
+	b        *BDD
+	nodes    [][]int      // nodes at each level
+	down     []int        // number of edges from each level to the next one
+	external map[int]bool // nodes with no parent, or a parent two levels above or more
+}
+
+func (b *BDD) newlevelprofile(n ...Node) *levelprofile {
+	p := &levelprofile{
+		b:        b,
+		nodes:    make([][]int, b.varnum),
+		down:     make([]int, b.varnum),
+		external: make(map[int]bool),
+	}
+	parent := make(map[int]bool)
+	b.Allnodes(func(id, level, low, high int) error {
+		if id < 2 {
+			return nil
+		}
+		p.nodes[level] = append(p.nodes[level], id)
+		for _, c := range [2]int{low, high} {
+			if c < 2 {
+				continue
+			}
+			parent[c] = true
+			if int(b.level(c)) == level+1 {
+				p.down[level]++
+			} else {
+				p.external[c] = true
+			}
+		}
+		return nil
+	}, n...)
+	for _, nodes := range p.nodes {
+		for _, k := range nodes {
+			if !parent[k] {
+				p.external[k] = true
+			}
+		}
+	}
+	return p
+}
+
+// swapgain returns the number of nodes saved by swapping levels l and l+1,
+// which can be negative. Only the nodes at these two levels are changed by a
+// swap. We rebuild the nodes at level l (labelled by x) using the cofactors of
+// their children with respect to the variable at level l+1 (y), and keep the
+// nodes at level l+1 that are referenced from elsewhere. Nodes below level l+1
+// are referenced by their id, and the new nodes labelled by x by a negative
+// index.
+func (p *levelprofile) swapgain(l int) int {
+	b := p.b
+	xnodes := make(map[[2]int]int)
+	ynodes := make(map[[2]int]bool)
+	cofactor := func(n int) (int, int) {
+		if n >= 2 && int(b.level(n)) == l+1 {
+			return b.low(n), b.high(n)
+		}
+		return n, n
+	}
+	mkx := func(low, high int) int {
+		if low == high {
+			return low
+		}
+		k, ok := xnodes[[2]int{low, high}]
+		if !ok {
+			k = -len(xnodes) - 1
+			xnodes[[2]int{low, high}] = k
+		}
+		return k
+	}
+	for _, k := range p.nodes[l] {
+		f00, f01 := cofactor(b.low(k))
+		f10, f11 := cofactor(b.high(k))
+		g0, g1 := mkx(f00, f10), mkx(f01, f11)
+		if g0 != g1 {
+			ynodes[[2]int{g0, g1}] = true
+		}
+	}
+	for _, k := range p.nodes[l+1] {
+		if p.external[k] {
+			ynodes[[2]int{b.low(k), b.high(k)}] = true
+		}
+	}
+	return len(p.nodes[l]) + len(p.nodes[l+1]) - len(xnodes) - len(ynodes)
+}
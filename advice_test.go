@@ -0,0 +1,74 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestReorderAdvice(t *testing.T) {
+	bdd, _ := New(6, Nodesize(5000), Cachesize(1000))
+	// the gain of a swap should be the difference in size with the function
+	// obtained by exchanging the two variables
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		cubes := make([][]int, 5)
+		for k := range cubes {
+			cubes[k] = make([]int, 6)
+			for v := range cubes[k] {
+				cubes[k][v] = rng.Intn(3)
+			}
+		}
+		// build returns the disjunction of cubes where variable v is renamed
+		// perm[v]
+		build := func(perm []int) Node {
+			f := bdd.False()
+			for _, c := range cubes {
+				m := bdd.True()
+				for v, pol := range c {
+					switch pol {
+					case 0:
+						m = bdd.And(m, bdd.Ithvar(perm[v]))
+					case 1:
+						m = bdd.And(m, bdd.NIthvar(perm[v]))
+					}
+				}
+				f = bdd.Or(f, m)
+			}
+			return f
+		}
+		f := build([]int{0, 1, 2, 3, 4, 5})
+		p := bdd.newlevelprofile(f)
+		for l := 0; l < 5; l++ {
+			perm := []int{0, 1, 2, 3, 4, 5}
+			perm[l], perm[l+1] = l+1, l
+			expected := bdd.AnodeCount(f) - bdd.AnodeCount(build(perm))
+			if actual := p.swapgain(l); actual != expected {
+				t.Errorf("swapgain(%d) for %d: expected %d, actual %d", l, *f, expected, actual)
+			}
+		}
+	}
+	// (x0 & x2) | (x1 & x3) is smaller with the order x0 < x2 < x1 < x3
+	f := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(2)), bdd.And(bdd.Ithvar(1), bdd.Ithvar(3)))
+	advice := bdd.ReorderAdvice(f)
+	if len(advice) == 0 || advice[0].Kind != AdviceSwap || advice[0].Vars[0] != 1 || advice[0].Vars[1] != 2 || advice[0].Gain <= 0 {
+		t.Errorf("expected a first advice to swap [1 2], actual %v", advice)
+	}
+	// a relation between two interleaved copies of the variables is a group
+	g := bdd.And(bdd.Equiv(bdd.Ithvar(0), bdd.Ithvar(1)), bdd.Equiv(bdd.Ithvar(2), bdd.Ithvar(3)))
+	found := false
+	for _, a := range bdd.ReorderAdvice(g) {
+		if a.Kind == AdviceGroup && len(a.Vars) >= 2 && a.Vars[0] == 0 && a.Vars[1] == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected to group variables 0 and 1, actual %v", bdd.ReorderAdvice(g))
+	}
+	if bdd.ReorderAdvice(nil) != nil || !bdd.Errored() {
+		t.Error("expected an error with a nil node")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+// agestat records the generation, meaning the number of garbage collections
+// before its creation, of each node in the table.
+type agestat struct {
+	birth     []int32 // generation of each node, or -1 if the slot is free
+	collected []int   // number of nodes reclaimed for each age
+}
+
+// AgeStats gives histograms of the age of nodes, where the age of a node is the
+// number of garbage collections it has survived. A high number of nodes
+// collected at age 0 indicates churn, meaning nodes that are created and
+// discarded soon after, whereas nodes with a high age are part of stable
+// structures.
+type AgeStats struct {
+	Generation int   // Number of garbage collections so far
+	Collected  []int // Collected[a] is the number of nodes reclaimed at age a
+	Live       []int // Live[a] is the number of nodes of age a in the table
+}
+
+func (b *BDD) initages(c *configs) {
+	if !c.trackages {
+		return
+	}
+	b.ages = &agestat{}
+	// the nodes created in New, for the variables, are from generation 0
+	b.allnodes(func(id, level, low, high int) error {
+		b.ages.born(id, 0)
+		return nil
+	})
+	b.setaftergc(b.sweepages)
+}
+
+// born records that node n exists at generation gen, unless it was already
+// recorded.
+func (a *agestat) born(n, gen int) {
+	if n < 2 {
+		return
+	}
+	for len(a.birth) <= n {
+		a.birth = append(a.birth, -1)
+	}
+	if a.birth[n] < 0 {
+		a.birth[n] = int32(gen)
+	}
+}
+
+// sweepages is called at the end of each garbage collection to record the age
+// of the nodes that were reclaimed.
+func (b *BDD) sweepages() {
+	a := b.ages
+	gen := len(b.history)
+	for n, g := range a.birth {
+		if g < 0 || b.low(n) != -1 {
+			continue
+		}
+		age := gen - 1 - int(g)
+		for len(a.collected) <= age {
+			a.collected = append(a.collected, 0)
+		}
+		a.collected[age]++
+		a.birth[n] = -1
+	}
+}
+
+// Ages returns histograms of the age of the nodes reclaimed by the garbage
+// collector and of the nodes still in the table, or nil if the BDD was not
+// created with option TrackAges. Nodes in the table include nodes that are no
+// longer referenced but have not yet been reclaimed. The constants are not
+// counted.
+func (b *BDD) Ages() *AgeStats {
+	if b.ages == nil {
+		return nil
+	}
+	gen := len(b.history)
+	res := &AgeStats{
+		Generation: gen,
+		Collected:  append([]int{}, b.ages.collected...),
+		Live:       make([]int, gen+1),
+	}
+	for _, g := range b.ages.birth {
+		if g >= 0 {
+			res.Live[gen-int(g)]++
+		}
+	}
+	return res
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestAges(t *testing.T) {
+	bdd, _ := New(10, Nodesize(50), Cachesize(20))
+	if bdd.Ages() != nil {
+		t.Errorf("Ages should be nil without option TrackAges")
+	}
+	bdd, _ = New(10, Nodesize(50), Cachesize(20), TrackAges(true))
+	parity := func() Node {
+		n := bdd.Ithvar(0)
+		for k := 1; k < 10; k++ {
+			n = bdd.Apply(n, bdd.Ithvar(k), OPxor)
+		}
+		return n
+	}
+	stable := parity()
+	// we create garbage to trigger garbage collections
+	for k := 0; k < 200; k++ {
+		runtime.GC()
+		vars := []int{}
+		for v := 0; v < 10; v++ {
+			if k&(1<<v) != 0 {
+				vars = append(vars, v)
+			}
+		}
+		bdd.Apply(stable, bdd.Makeset(vars), OPor)
+	}
+	ages := bdd.Ages()
+	if ages == nil {
+		t.Fatal("Ages should not be nil with option TrackAges")
+	}
+	if ages.Generation == 0 || ages.Generation != len(bdd.history) {
+		t.Fatalf("expected %d garbage collections, actual %d", len(bdd.history), ages.Generation)
+	}
+	collected, live := 0, 0
+	for _, c := range ages.Collected {
+		collected += c
+	}
+	for _, c := range ages.Live {
+		live += c
+	}
+	if collected == 0 {
+		t.Errorf("expected some nodes to be collected")
+	}
+	if expected := bdd.Live() - 2; live != expected {
+		t.Errorf("expected %d nodes in Live, actual %d", expected, live)
+	}
+	// the variables are created before the first garbage collection
+	if ages.Live[ages.Generation] < bdd.Varnum() {
+		t.Errorf("expected at least %d nodes of age %d, actual %d", bdd.Varnum(), ages.Generation, ages.Live[ages.Generation])
+	}
+	runtime.KeepAlive(stable)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Package appexcheck finds the places, in Go code using package rudd, where the
+result of a conjunction is immediately passed to Exist. Such code builds the
+(often large) result of the conjunction before quantifying over it, whereas
+method AndExist, or AppEx with operator OPand, performs the two operations at
+once.
+
+We report two patterns: a call to Exist with a call to And, or to Apply with
+OPand, as first argument; and an assignment of the result of And (or Apply
+with OPand) to a variable that is only used in a call to Exist in the next
+statement. The check is purely syntactic, since we do not depend on type
+information, and it may report methods with the same name on other types.
+The package follows the conventions of the go/analysis framework, with a
+Diagnostic type, so that it can easily be wrapped in an analysis.Analyzer.
+*/
+package appexcheck
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// Diagnostic is a message associated with a position in the source code.
+type Diagnostic struct {
+	Pos     token.Pos // Position of the call to Exist
+	Message string
+}
+
+// Message is the message used in all the diagnostics.
+const Message = "Exist over the result of a conjunction; use AndExist (or AppEx with OPand) instead"
+
+// Check returns the diagnostics for the calls to Exist in f that could be
+// replaced by a call to AndExist, sorted by position.
+func Check(f *ast.File) []Diagnostic {
+	var res []Diagnostic
+	report := func(call *ast.CallExpr) {
+		res = append(res, Diagnostic{Pos: call.Pos(), Message: Message})
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if isexist(n) && isconjunction(n.Args[0]) {
+				report(n)
+			}
+		case *ast.BlockStmt:
+			checkblock(n.List, report)
+		case *ast.CaseClause:
+			checkblock(n.Body, report)
+		case *ast.CommClause:
+			checkblock(n.Body, report)
+		}
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i].Pos < res[j].Pos })
+	return res
+}
+
+// checkblock looks for a statement assigning a conjunction to a variable that
+// is only used as the first argument of Exist in the following statement.
+func checkblock(list []ast.Stmt, report func(*ast.CallExpr)) {
+	for k := 0; k+1 < len(list); k++ {
+		id := conjunctionvar(list[k])
+		if id == nil {
+			continue
+		}
+		var exist *ast.CallExpr
+		ast.Inspect(list[k+1], func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok && isexist(call) {
+				if arg, ok := call.Args[0].(*ast.Ident); ok && arg.Name == id.Name {
+					exist = call
+				}
+			}
+			return exist == nil
+		})
+		if exist == nil {
+			continue
+		}
+		uses := 0
+		for _, s := range list[k+1:] {
+			ast.Inspect(s, func(n ast.Node) bool {
+				if x, ok := n.(*ast.Ident); ok && x.Name == id.Name {
+					uses++
+				}
+				return true
+			})
+		}
+		if uses == 1 {
+			report(exist)
+		}
+	}
+}
+
+// conjunctionvar returns the variable assigned by s if s is an assignment of a
+// conjunction to a single variable, and nil otherwise.
+func conjunctionvar(s ast.Stmt) *ast.Ident {
+	assign, ok := s.(*ast.AssignStmt)
+	if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return nil
+	}
+	if assign.Tok != token.ASSIGN && assign.Tok != token.DEFINE {
+		return nil
+	}
+	id, ok := assign.Lhs[0].(*ast.Ident)
+	if !ok || id.Name == "_" || !isconjunction(assign.Rhs[0]) {
+		return nil
+	}
+	return id
+}
+
+// isexist returns true if call is a call to a method named Exist with two
+// arguments.
+func isexist(call *ast.CallExpr) bool {
+	return method(call) == "Exist" && len(call.Args) == 2
+}
+
+// isconjunction returns true if e is a call to a method And with two operands,
+// or to a method Apply with OPand as third argument.
+func isconjunction(e ast.Expr) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	switch method(call) {
+	case "And":
+		return len(call.Args) == 2 && call.Ellipsis == token.NoPos
+	case "Apply":
+		return len(call.Args) == 3 && name(call.Args[2]) == "OPand"
+	}
+	return false
+}
+
+// method returns the name of the method called in call, or the empty string
+// if call is not a method call.
+func method(call *ast.CallExpr) string {
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	return ""
+}
+
+// name returns the name of an identifier, possibly qualified by a package
+// name, such as rudd.OPand.
+func name(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package appexcheck
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+const src = `package p
+
+import "github.com/dalzilio/rudd"
+
+func f(b *rudd.BDD, x, y, vs rudd.Node) []rudd.Node {
+	r1 := b.Exist(b.And(x, y), vs)                // reported
+	r2 := b.Exist(b.Apply(x, y, rudd.OPand), vs)  // reported
+	r3 := b.Exist(b.Apply(x, y, rudd.OPor), vs)
+	t := b.And(x, y)
+	r4 := b.Exist(t, vs)                          // reported
+	u := b.And(x, y)
+	r5 := b.Exist(u, vs)
+	r6 := b.Or(u, r5)
+	r7 := b.AndExist(x, y, vs)
+	r8 := b.Exist(b.And(x, y, r1), vs)
+	return []rudd.Node{r1, r2, r3, r4, r6, r7, r8}
+}
+`
+
+func TestCheck(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []int{}
+	for _, d := range Check(f) {
+		lines = append(lines, fset.Position(d.Pos).Line)
+	}
+	if len(lines) != 3 || lines[0] != 6 || lines[1] != 7 || lines[2] != 10 {
+		t.Errorf("expected diagnostics at lines 6, 7 and 10, actual %v", lines)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "fmt"
+
+// Workload is a coarse description of the operations performed with a BDD,
+// used by NewAuto to choose the value of the configuration parameters.
+type Workload int
+
+const (
+	// Reachability is for fixpoint computations over a transition relation,
+	// such as state space exploration, that are dominated by relational
+	// products (AppEx) and produce a lot of intermediate nodes.
+	Reachability Workload = iota
+	// Combinational is for building the BDD of formulas or circuits, with a
+	// large number of calls to Apply and Ite, such as in the N-Queens problem.
+	Combinational
+	// Counting is for computing a few BDDs that are then queried many times,
+	// for instance with Satcount or Allsat.
+	Counting
+)
+
+var workloadnames = [3]string{
+	Reachability:  "Reachability",
+	Combinational: "Combinational",
+	Counting:      "Counting",
+}
+
+func (w Workload) String() string {
+	if w < 0 || int(w) >= len(workloadnames) {
+		return fmt.Sprintf("Workload(%d)", int(w))
+	}
+	return workloadnames[w]
+}
+
+// NewAuto returns a new BDD with varnum variables, like New, where the initial
+// size of the node table and of the caches, the cache ratio and the ratio of
+// free nodes are chosen from varnum and a workload hint. The values are based
+// on the benchmarks of the package (the Milner scheduler for Reachability and
+// the N-Queens problem for Combinational). Additional configuration options
+// can be given; they are applied after the ones chosen by NewAuto and can
+// therefore override them.
+func NewAuto(varnum int, hint Workload, options ...func(*configs)) (*BDD, error) {
+	auto, err := autoconfigs(varnum, hint)
+	if err != nil {
+		return nil, err
+	}
+	return New(varnum, append(auto, options...)...)
+}
+
+// autoconfigs returns the configuration options chosen by NewAuto.
+func autoconfigs(varnum int, hint Workload) ([]func(*configs), error) {
+	var pervar, cachediv, ratio, minfree int
+	switch hint {
+	case Reachability:
+		pervar, cachediv, ratio, minfree = 2048, 4, 25, 20
+	case Combinational:
+		pervar, cachediv, ratio, minfree = 256, 4, 30, 20
+	case Counting:
+		pervar, cachediv, ratio, minfree = 512, 8, 10, 25
+	default:
+		return nil, fmt.Errorf("unknown workload (%s)", hint)
+	}
+	nodesize := varnum * pervar
+	if nodesize < 10000 {
+		nodesize = 10000
+	}
+	if nodesize > 1<<22 {
+		nodesize = 1 << 22
+	}
+	return []func(*configs){
+		Nodesize(nodesize),
+		Cachesize(nodesize / cachediv),
+		Cacheratio(ratio),
+		Minfreenodes(minfree),
+	}, nil
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+func TestNewAuto(t *testing.T) {
+	for _, hint := range []Workload{Reachability, Combinational, Counting} {
+		for _, varnum := range []int{1, 64, 1000, 100000} {
+			auto, err := autoconfigs(varnum, hint)
+			if err != nil {
+				t.Fatalf("autoconfigs(%d, %s): unexpected error %s", varnum, hint, err)
+			}
+			if w := Validate(varnum, auto...); w != nil {
+				t.Errorf("autoconfigs(%d, %s) should give a valid configuration, warnings %v", varnum, hint, w)
+			}
+		}
+	}
+	bdd, err := NewAuto(16, Combinational, Cachesize(5000))
+	if err != nil {
+		t.Fatalf("NewAuto: unexpected error %s", err)
+	}
+	if bdd.Varnum() != 16 || bdd.Allocated() < 10000 {
+		t.Errorf("NewAuto(16, Combinational): unexpected configuration\n%s", bdd.Stats())
+	}
+	if _, err := NewAuto(16, Workload(7)); err == nil {
+		t.Error("NewAuto should fail with an unknown workload")
+	}
+}
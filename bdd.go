@@ -6,93 +6,11 @@ package rudd
 
 import (
 	"fmt"
+	"math/bits"
+	"runtime"
+	"time"
 )
 
-// // BDD is an interface implementing the basic operations over Binary Decision
-// // Diagrams.
-// type BDD interface {
-// 	// Error returns the error status of the BDD. We return an empty string if
-// 	// there are no errors. Functions that return a Node result will signal an
-// 	// error by returning nil.
-// 	Error() string
-
-// 	// Varnum returns the number of defined variables.
-// 	Varnum() int
-
-// 	// Ithvar returns a BDD representing the i'th variable on success. The
-// 	// requested variable must be in the range [0..Varnum).
-// 	Ithvar(i int) Node
-
-// 	// NIthvar returns a bdd representing the negation of the i'th variable on
-// 	// success. See *ithvar* for further info.
-// 	NIthvar(i int) Node
-
-// 	// Low returns the false branch of a BDD or nil if there is an error.
-// 	Low(n Node) Node
-
-// 	// High returns the true branch of a BDD.
-// 	High(n Node) Node
-
-// 	// Makeset returns a node corresponding to the conjunction (the cube) of all
-// 	// the variables in varset, in their positive form. It is such that
-// 	// scanset(Makeset(a)) == a. It returns nil if one of the variables is
-// 	// outside the scope of the BDD (see documentation for function *Ithvar*).
-// 	Makeset(varset []int) Node
-
-// 	// Scanset returns the set of variables found when following the high branch
-// 	// of node n. This is the dual of function Makeset. The result may be nil if
-// 	// there is an error and it is an empty slice if the set is empty.
-// 	Scanset(n Node) []int
-
-// 	// Not returns the negation (!n) of expression n.
-// 	Not(n Node) Node
-
-// 	// Apply performs all of the basic binary operations on BDD nodes, such as
-// 	// AND, OR etc.
-// 	Apply(left Node, right Node, op Operator) Node
-
-// 	// Ite, short for if-then-else operator, computes the BDD for the expression
-// 	// [(f &  g) | (!f & h)] more efficiently than doing the three operations
-// 	// separately.
-// 	Ite(f, g, h Node) Node
-
-// 	// Exist returns the existential quantification of n for the variables in
-// 	// varset, where varset is a node built with a method such as Makeset.
-// 	Exist(n, varset Node) Node
-
-// 	// AppEx applies the binary operator *op* on the two operands left and right
-// 	// then performs an existential quantification over the variables in varset,
-// 	// where varset is a node computed with an operation such as Makeset.
-// 	AppEx(left Node, right Node, op Operator, varset Node) Node
-
-// 	// Replace takes a renamer and computes the result of n after replacing old
-// 	// variables with new ones. See type Renamer.
-// 	Replace(n Node, r Replacer) Node
-
-// 	// Satcount computes the number of satisfying variable assignments for the
-// 	// function denoted by n. We return a result using arbitrary-precision
-// 	// arithmetic to avoid possible overflows. The result is zero (and we set
-// 	// the error flag of b) if there is an error.
-// 	Satcount(n Node) *big.Int
-
-// 	// Allsat Iterates through all legal variable assignments for n and calls
-// 	// the function f on each of them. We pass an int slice of length varnum to
-// 	// f where each entry is either 0 if the variable is false, 1 if it is true,
-// 	// and -1 if it is a don't care. We stop and return an error if f returns an
-// 	// error at some point.
-// 	Allsat(n Node, f func([]int) error) error
-
-// 	// Allnodes is similar to Allsat but iterates over all the nodes accessible
-// 	// from one of the parameters in n (or all the active nodes if n is absent).
-// 	// Function f takes the id, level, and id's of the low and high successors
-// 	// of each node. The two constant nodes (True and False) have always the id
-// 	// 1 and 0 respectively.
-// 	Allnodes(f func(id, level, low, high int) error, n ...Node) error
-
-// 	// Stats returns information about the BDD
-// 	Stats() string
-// }
-
 // // implementation is an unexported interface implemented by different BDD
 // // structures
 // type implementation interface {
@@ -149,43 +67,255 @@ var bddzero Node = inode(0)
 // unicity tables for example. We propose multiple implementations (two at the
 // moment) all based on approaches where we use integers as the key for Nodes.
 type BDD struct {
-	varnum   int32    // Number of BDD variables.
-	varset   [][2]int // Set of variables used for Ithvar and NIthvar: we have a pair for each variable for its positive and negative occurrence
-	refstack []int    // Internal node reference stack, used to avoid collecting nodes while they are being processed.
-	error             // Error status: we use nil Nodes to signal a problem and store the error in this field. This help chain operations together.
-	caches            // Set of caches used for the operations in the BDD
-	*tables           // Underlying struct that encapsulates the list of nodes
+	kernel[bool] // Node table, refstack and error status, shared with Diagram
+
+	varset    [][2]int // Set of variables used for Ithvar and NIthvar: we have a pair for each variable for its positive and negative occurrence
+	var2level []int32  // Position (level) of each variable in the current order
+	level2var []int32  // Variable found at each level; the inverse of var2level
+	caches             // Set of caches used for the operations in the BDD
+	journal   *journal // Journal of operations, when enabled with the Journal option
+	warnings  []string // Problems found in the configuration, see Validate
+	unrolled  copymap  // Copies of the state variables allocated by Unroll
+	ages      *agestat // Generation of each node, when enabled with TrackAges
+	config    *configs // Options given to New, see SaveManager
+	watch     *watcher // Sizes published for the goroutines started by WatchSize
+	sealed    *seal    // Set by SealForQueries, when b is read-only (nil otherwise)
+	reorders  int      // Number of calls to Reorder, used to update the levels in replacers
+	autoorder *trigger // State of the automatic reordering (nil if disabled), see Autoreorder
+	callbacks int      // Number of user callbacks being executed, only used in debug mode
+	traversal int      // Number of calls to Allsat in progress, during which we cannot reorder
+	domains   []domain // Finite domain blocks allocated with ExtDomain
+}
+
+// initorder sets the variable order to the identity, meaning that variable i
+// is at level i.
+func (b *BDD) initorder(varnum int) {
+	b.var2level = make([]int32, varnum)
+	b.level2var = make([]int32, varnum)
+	for k := 0; k < varnum; k++ {
+		b.var2level[k] = int32(k)
+		b.level2var[k] = int32(k)
+	}
+}
+
+// Var2Level returns the level of variable v in the current variable order, or
+// -1 if v is not in the range [0..Varnum). All the functions in the public API
+// (such as Ithvar, Makeset, Scanset, Allsat or NewReplacer) work with variable
+// indices, whereas kernel functions (such as Makenode) work with levels. The
+// two notions coincide as long as the order of variables has not been changed.
+func (b *BDD) Var2Level(v int) int {
+	if (v < 0) || (int32(v) >= b.varnum) {
+		return -1
+	}
+	return int(b.var2level[v])
+}
+
+// Level2Var returns the variable found at level l in the current variable
+// order, or -1 if l is not in the range [0..Varnum). This is the inverse of
+// Var2Level.
+func (b *BDD) Level2Var(l int) int {
+	if (l < 0) || (int32(l) >= b.varnum) {
+		return -1
+	}
+	return int(b.level2var[l])
 }
 
-// Varnum returns the number of defined variables.
-func (b *BDD) Varnum() int {
-	return int(b.varnum)
+// VarOrder returns the current order of the variables, as a slice where the
+// value at index l is the variable at level l. The result is a fresh copy that
+// can be stored, for instance to restore the order found by Reorder in a later
+// run with SetVarOrder.
+func (b *BDD) VarOrder() []int {
+	res := make([]int, b.varnum)
+	for l := range res {
+		res[l] = int(b.level2var[l])
+	}
+	return res
 }
 
 // Makenode is a kernel function of the BDD package. Use it at your own risk.
 // Makenode returns a node corresponding to the tuple (level, low, high) if it
 // exist or creates a new one in the BDD. You can create a node from the value
-// returned by Makenode using function Retnode.
+// returned by Makenode using function Retnode. We return -1 if there is no
+// room left in the node table, after calling the handler registered with
+// option Memoryhandler, or we panic if the policy is OOMPanic. We also return
+// -1 if b is sealed, see SealForQueries.
 func (b *BDD) Makenode(level int32, low, high int) int {
+	if b.sealed != nil {
+		b.sealerror("Makenode")
+		return -1
+	}
+	b.checkreentry("Makenode")
 	res, err := b.tables.makenode(level, low, high, b.refstack)
-	if err == nil {
-		return res
+	if err == errMemory && b.config.oomhandler != nil && b.oomhandler() {
+		// the failed call may have run a garbage collection that recycled
+		// nodes still referenced in the caches, even if the retry succeeds.
+		b.cacheupdate(err)
+		// the handler may have dropped references to Nodes, but the nodes
+		// are only released by their finalizers.
+		collectroots()
+		res, err = b.tables.makenode(level, low, high, b.refstack)
 	}
-	if err == errReset {
-		// FIXME: we do not need to invalidate the cache for a reset when we use
-		// the Hudd implementation because the hash does not change. On the
-		// other hand we can reuse the index of nodes that have been reclaimed
-		// during the GC, but that correspond to other values now.
-		b.cachereset()
-		return res
+	if err == errMemory && b.config.oompolicy == OOMPanic {
+		panic(fmt.Errorf("rudd: %w (%d nodes)", errMemory, b.size()))
 	}
-	if err == errResize {
-		b.cacheresize(b.size())
-		return res
+	if b.assertions && res >= 0 {
+		b.assertnode(res, level, low, high)
+	}
+	if b.ages != nil && res >= 0 {
+		b.ages.born(res, len(b.history))
+	}
+	if b.watch != nil {
+		b.watch.publish(b.used(), b.size())
+	}
+	// FIXME: we do not need to invalidate the cache for a reset when we use
+	// the Hudd implementation because the hash does not change. On the other
+	// hand we can reuse the index of nodes that have been reclaimed during the
+	// GC, but that correspond to other values now.
+	b.cacheupdate(err)
+	return res
+}
+
+// oomunwind is the value of the panic raised by makenode when there is no room
+// left in the node table, or by correctify when Replace fails. It is recovered
+// by catchoom.
+type oomunwind struct{}
+
+// makenode is the version of Makenode used in the recursive operations. When
+// there is no room left in the node table, we set the error flag of b and
+// unwind the recursion up to the call to catchoom in the public operation.
+func (b *BDD) makenode(level int32, low, high int) int {
+	res := b.Makenode(level, low, high)
+	if res < 0 {
+		b.seterror("%s", errMemory)
+		panic(oomunwind{})
 	}
 	return res
 }
 
+// catchoom must be deferred in the operations that call makenode; it stops
+// the unwinding of the recursion started by makenode, in which case the result
+// of the operation is nil. This is also where we start the reorderings
+// requested with option Autoreorder, once the result is safe.
+func (b *BDD) catchoom(result *Node) {
+	if r := recover(); r != nil {
+		if _, ok := r.(oomunwind); !ok {
+			panic(r)
+		}
+		*result = nil
+	}
+	if b.autoorder != nil && b.autoorder.pending && *result != nil {
+		b.autoreorder()
+	}
+}
+
+// collectroots runs the Go garbage collector and waits for the finalizers of
+// the Nodes that are no longer reachable, so that the nodes they reference can
+// be reclaimed by the next garbage collection of the node table. This is a
+// best effort, since the runtime gives no guarantee on when finalizers run.
+func collectroots() {
+	for i := 0; i < 2; i++ {
+		done := make(chan struct{})
+		sentinel := new([16]byte)
+		runtime.SetFinalizer(sentinel, func(*[16]byte) { close(done) })
+		sentinel = nil
+		runtime.GC()
+		select {
+		case <-done:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// FlushReleasedRefs runs the Go garbage collector and waits for the finalizers
+// of the Nodes that are no longer reachable, so that the nodes they reference
+// can be reclaimed by the next garbage collection of the node table. The
+// garbage collection of the node table never runs the Go garbage collector
+// itself, since it is blocking, which means that nodes referenced by dropped
+// Nodes often look live and lead to resizing the table when it is not needed.
+// It is a good idea to call FlushReleasedRefs after dropping a large result,
+// for instance between two phases of a computation. This is a best effort,
+// since the runtime gives no guarantee on when finalizers run. It must not be
+// called from a callback, such as the function passed to Allnodes.
+func (b *BDD) FlushReleasedRefs() {
+	b.checkreentry("FlushReleasedRefs")
+	collectroots()
+}
+
+// assertnode panics if n is not a valid result for a call to makenode with
+// arguments level, low and high. This is only used when the Assertions option
+// is set.
+func (b *BDD) assertnode(n int, level int32, low, high int) {
+	var err error
+	switch {
+	case low == high:
+		if n != low {
+			err = fmt.Errorf("expected %d since both successors are equal", low)
+		}
+	case (n < 2) || (n >= b.size()) || (b.low(n) == -1):
+		err = fmt.Errorf("not an allocated node")
+	case (b.level(n) != level) || (b.low(n) != low) || (b.high(n) != high):
+		err = fmt.Errorf("found node (%d, %d, %d) instead", b.level(n), b.low(n), b.high(n))
+	case (b.level(low) <= level) || (b.level(high) <= level):
+		err = fmt.Errorf("successors have levels %d and %d", b.level(low), b.level(high))
+	default:
+		if ids := b.lookup(level, low, high); len(ids) != 1 || ids[0] != n {
+			err = fmt.Errorf("unique table has nodes %v for this triplet", ids)
+		}
+	}
+	if err != nil {
+		panic(fmt.Sprintf("rudd: invalid result %d for makenode(%d, %d, %d) with %d nodes in the table; %s", n, level, low, high, b.size(), err))
+	}
+}
+
+// bulkmake inserts a sequence of nodes, listed children before parents, in one
+// go and returns ids extended with their ids; or nil if there is not enough
+// memory. See the documentation of tables.bulkmake for the format of nodes.
+// Nodes in ids are not protected, so a sequence of calls to bulkmake should be
+// preceded by a call to reserve for the total number of nodes, or the ids
+// should be pushed on the refstack.
+func (b *BDD) bulkmake(ids []int, nodes [][3]int) []int {
+	offset := len(ids)
+	res, err := b.tables.bulkmake(ids, nodes, b.refstack)
+	b.cacheupdate(err)
+	if b.assertions && res != nil {
+		for k, v := range nodes {
+			b.assertnode(res[offset+k], int32(v[0]), res[v[1]], res[v[2]])
+		}
+	}
+	if b.ages != nil && res != nil {
+		for _, v := range res[offset:] {
+			b.ages.born(v, len(b.history))
+		}
+	}
+	if b.watch != nil {
+		b.watch.publish(b.used(), b.size())
+	}
+	return res
+}
+
+// reserve makes room for n new nodes in the node table and returns false if
+// there is not enough memory.
+func (b *BDD) reserve(n int) bool {
+	err := b.tables.reserve(n, b.refstack)
+	b.cacheupdate(err)
+	return err != errMemory
+}
+
+// cacheupdate resets or resizes the caches after a (possible) garbage
+// collection in the node table.
+func (b *BDD) cacheupdate(err error) {
+	switch err {
+	case errReset, errMemory:
+		b.cachereset()
+	case errResize:
+		b.cacheresize(b.size())
+	}
+	if t := b.autoorder; t != nil && (err == errReset || err == errResize) {
+		t.gcs++
+		t.pending = b.used() > t.threshold || (t.period > 0 && t.gcs >= t.period)
+	}
+}
+
 // caches is a collection of caches used for operations
 type caches struct {
 	*applycache   // Cache for apply results
@@ -201,6 +331,7 @@ type caches struct {
 // reclaimed during a memory management operation. You should always start with
 // Initref in a function that creates new nodes.
 func (b *BDD) Initref() {
+	b.checkreentry("Initref")
 	b.refstack = b.refstack[:0]
 }
 
@@ -245,15 +376,8 @@ type gcpoint struct {
 // checkptr performs a sanity check prior to accessing a node and return eventual
 // error code.
 func (b *BDD) checkptr(n Node) error {
-	switch {
-	case n == nil:
-		b.seterror("Illegal acces to node (nil value)")
-		return b.error
-	case (*n < 0) || (*n >= b.size()):
-		b.seterror("Illegal acces to node %d", *n)
-		return b.error
-	case (*n >= 2) && (b.low(*n) == -1):
-		b.seterror("Illegal acces to node %d", *n)
+	if err := b.checknode(n); err != nil {
+		b.seterror("%s", err)
 		return b.error
 	}
 	return nil
@@ -262,7 +386,10 @@ func (b *BDD) checkptr(n Node) error {
 // Ithvar returns a BDD representing the i'th variable on success (the
 // expression xi), otherwise we set the error status in the BDD and returns the
 // nil node. The requested variable must be in the range [0..Varnum).
-func (b *BDD) Ithvar(i int) Node {
+func (b *BDD) Ithvar(i int) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "ithvar", i) }()
+	}
 	if (i < 0) || (int32(i) >= b.varnum) {
 		return b.seterror("Unknown variable used (%d) in call to ithvar", i)
 	}
@@ -273,7 +400,10 @@ func (b *BDD) Ithvar(i int) Node {
 // NIthvar returns a node representing the negation of the i'th variable on
 // success (the expression !xi), otherwise the nil node. See *ithvar* for
 // further info.
-func (b *BDD) NIthvar(i int) Node {
+func (b *BDD) NIthvar(i int) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "nithvar", i) }()
+	}
 	if (i < 0) || (int32(i) >= b.varnum) {
 		return b.seterror("Unknown variable used (%d) in call to nithvar", i)
 	}
@@ -281,15 +411,41 @@ func (b *BDD) NIthvar(i int) Node {
 	return inode(b.varset[i][1])
 }
 
-// Label returns the variable (index) corresponding to node n in the BDD. We set
-// the BDD to its error state and return -1 if we try to access a constant node.
+// Label returns the variable (index) corresponding to node n in the BDD. This
+// is the same as Var.
+//
+// Deprecated: use Var, or LevelOf for the position of the variable in the
+// current order.
 func (b *BDD) Label(n Node) int {
+	return b.Var(n)
+}
+
+// Var returns the variable (index) labelling node n, that does not depend on the
+// current variable order. We set the BDD to its error state and return -1 if n
+// is not valid or if it is a constant node.
+func (b *BDD) Var(n Node) int {
 	if b.checkptr(n) != nil {
-		b.seterror("Illegal access to node %d in call to Label", n)
+		b.seterror("Illegal access to node in call to Var")
 		return -1
 	}
 	if *n < 2 {
-		b.seterror("Try to access label of constant node")
+		b.seterror("Try to access the variable of constant node %d in call to Var", *n)
+		return -1
+	}
+	return int(b.level2var[b.level(*n)])
+}
+
+// LevelOf returns the level of node n, meaning the position of its variable in
+// the current order. We set the BDD to its error state and return -1 if n is
+// not valid or if it is a constant node. See also PeekLevel, that returns
+// Varnum for constants.
+func (b *BDD) LevelOf(n Node) int {
+	if b.checkptr(n) != nil {
+		b.seterror("Illegal access to node in call to LevelOf")
+		return -1
+	}
+	if *n < 2 {
+		b.seterror("Try to access the level of constant node %d in call to LevelOf", *n)
 		return -1
 	}
 	return int(b.level(*n))
@@ -313,6 +469,42 @@ func (b *BDD) High(n Node) Node {
 	return b.Retnode(b.high(*n))
 }
 
+// PeekLow returns the id of the false (or low) branch of n. Unlike Low, it does
+// not create a new external reference, and therefore the result is only valid
+// as long as n is not reclaimed. It is meant for tight, read-only traversals of
+// a BDD, when the root of the traversal is already referenced. Since a Node is
+// a pointer to an int, we can continue the traversal from an id k by calling
+// PeekLow(&k). We return -1 and set the error flag in the BDD if there is an
+// error.
+func (b *BDD) PeekLow(n Node) int {
+	if b.checkptr(n) != nil {
+		b.seterror("Illegal access to node in call to PeekLow")
+		return -1
+	}
+	return b.low(*n)
+}
+
+// PeekHigh returns the id of the true (or high) branch of n, without creating
+// a new external reference. See PeekLow for further info.
+func (b *BDD) PeekHigh(n Node) int {
+	if b.checkptr(n) != nil {
+		b.seterror("Illegal access to node in call to PeekHigh")
+		return -1
+	}
+	return b.high(*n)
+}
+
+// PeekLevel returns the level of node n, without creating a new external
+// reference. The level of the constant nodes, True and False, is Varnum. We
+// return -1 and set the error flag in the BDD if there is an error.
+func (b *BDD) PeekLevel(n Node) int {
+	if b.checkptr(n) != nil {
+		b.seterror("Illegal access to node in call to PeekLevel")
+		return -1
+	}
+	return int(b.level(*n))
+}
+
 // And returns the logical 'and' of a sequence of nodes or, equivalently,
 // computes the intersection of a sequence of Boolean vectors.
 func (b *BDD) And(n ...Node) Node {
@@ -358,8 +550,205 @@ func (b *BDD) Equal(n1, n2 Node) bool {
 	return *n1 == *n2
 }
 
+// Leq returns true if f implies g, meaning that f is less or equal than g in
+// the boolean order, like Cudd_bddLeq in CUDD. This is equivalent to testing
+// that Imp(f, g) is True, but we never create new nodes, so there is no
+// garbage collection or resize of the caches, which is useful in tight
+// fixpoint loops. We return false, and set the error flag of b, if there is
+// an error.
+func (b *BDD) Leq(f, g Node) bool {
+	if b.checkptr(f) != nil || b.checkptr(g) != nil {
+		b.seterror("Wrong operand in call to Leq")
+		return false
+	}
+	return b.leq(*f, *g, make(map[[2]int]bool))
+}
+
+func (b *BDD) leq(f, g int, cache map[[2]int]bool) bool {
+	switch {
+	case f == 0 || g == 1 || f == g:
+		return true
+	case f == 1 || g == 0:
+		return false
+	}
+	if res, ok := cache[[2]int{f, g}]; ok {
+		return res
+	}
+	lf, lg := b.level(f), b.level(g)
+	f0, f1, g0, g1 := f, f, g, g
+	if lf <= lg {
+		f0, f1 = b.low(f), b.high(f)
+	}
+	if lg <= lf {
+		g0, g1 = b.low(g), b.high(g)
+	}
+	res := b.leq(f0, g0, cache) && b.leq(f1, g1, cache)
+	cache[[2]int{f, g}] = res
+	return res
+}
+
+// Intersects returns true if the conjunction of f and g is satisfiable, meaning
+// that f & g is not False. Like Leq, we only traverse the two nodes and never
+// build their conjunction, which is useful for disjointness tests, for
+// instance in reachability checks. We return false, and set the error flag of
+// b, if there is an error.
+func (b *BDD) Intersects(f, g Node) bool {
+	if b.checkptr(f) != nil || b.checkptr(g) != nil {
+		b.seterror("Wrong operand in call to Intersects")
+		return false
+	}
+	return b.intersects(*f, *g, make(map[[2]int]bool))
+}
+
+func (b *BDD) intersects(f, g int, cache map[[2]int]bool) bool {
+	switch {
+	case f == 0 || g == 0:
+		return false
+	case f == 1 || g == 1 || f == g:
+		return true
+	}
+	if f > g {
+		f, g = g, f
+	}
+	if res, ok := cache[[2]int{f, g}]; ok {
+		return res
+	}
+	lf, lg := b.level(f), b.level(g)
+	f0, f1, g0, g1 := f, f, g, g
+	if lf <= lg {
+		f0, f1 = b.low(f), b.high(f)
+	}
+	if lg <= lf {
+		g0, g1 = b.low(g), b.high(g)
+	}
+	res := b.intersects(f0, g0, cache) || b.intersects(f1, g1, cache)
+	cache[[2]int{f, g}] = res
+	return res
+}
+
+// IteConstant tests whether Ite(f, g, h) is a constant, like
+// Cudd_bddIteConstant in CUDD, without creating new nodes. We return the value
+// of the constant (0 for False and 1 for True) and true when this is the case;
+// otherwise we return -1 and false. The traversal stops as soon as we find two
+// paths leading to different values, which makes this function a cheap
+// building block for implication and containment tests. We also return -1 and
+// false, and set the error flag of b, if there is an error.
+func (b *BDD) IteConstant(f, g, h Node) (int, bool) {
+	if b.checkptr(f) != nil || b.checkptr(g) != nil || b.checkptr(h) != nil {
+		b.seterror("Wrong operand in call to IteConstant")
+		return -1, false
+	}
+	res := b.iteconstant(*f, *g, *h, make(map[[3]int]int))
+	return res, res >= 0
+}
+
+// iteconstant returns 0 or 1 if ite(f, g, h) is a constant and -1 otherwise.
+func (b *BDD) iteconstant(f, g, h int, cache map[[3]int]int) int {
+	switch {
+	case f == 1:
+		h = g
+	case f == 0:
+		g = h
+	}
+	switch {
+	case g == h:
+		if g < 2 {
+			return g
+		}
+		return -1
+	case g < 2 && h < 2:
+		// f is not a constant and ite(f, g, h) is either f or its negation
+		return -1
+	}
+	if res, ok := cache[[3]int{f, g, h}]; ok {
+		return res
+	}
+	level := b.level(f)
+	if l := b.level(g); l < level {
+		level = l
+	}
+	if l := b.level(h); l < level {
+		level = l
+	}
+	cofactor := func(n int) (int, int) {
+		if b.level(n) == level {
+			return b.low(n), b.high(n)
+		}
+		return n, n
+	}
+	f0, f1 := cofactor(f)
+	g0, g1 := cofactor(g)
+	h0, h1 := cofactor(h)
+	res := b.iteconstant(f0, g0, h0, cache)
+	if res >= 0 && b.iteconstant(f1, g1, h1, cache) != res {
+		res = -1
+	}
+	cache[[3]int{f, g, h}] = res
+	return res
+}
+
+// EqualCanonical tests the semantic equality of two nodes, meaning that it
+// returns true if and only if n1 and n2 denote the same Boolean function, even
+// if the nodes have been built with different variable orders (for instance
+// if one of them was computed before the BDD was reordered). Unlike Equal, it
+// does not rely on the ids of nodes. We first compare a signature of the two
+// functions that does not depend on the variable order, obtained by evaluating
+// the (unique) multilinear extension of each function on a fixed random point,
+// and only compute their equivalence if the signatures are equal. We return
+// false, and set the error flag in the BDD, if one of the nodes is not valid.
+func (b *BDD) EqualCanonical(n1, n2 Node) bool {
+	if b.checkptr(n1) != nil || b.checkptr(n2) != nil {
+		b.seterror("Wrong operand in call to EqualCanonical")
+		return false
+	}
+	if *n1 == *n2 {
+		return true
+	}
+	if b.signature(*n1) != b.signature(*n2) {
+		return false
+	}
+	res := b.Equiv(n1, n2)
+	return res != nil && *res == 1
+}
+
+// _SIGPRIME is the (Mersenne) prime 2^61 - 1 used for computing signatures.
+const _SIGPRIME uint64 = (1 << 61) - 1
+
+// signature returns the value, modulo _SIGPRIME, of the multilinear extension
+// of the function denoted by n, where variable v is replaced by a pseudo-random
+// value that depends only on v. Two nodes denoting the same function have the
+// same signature, whatever the variable order; and two different functions
+// have the same signature with a probability less than Varnum / _SIGPRIME.
+func (b *BDD) signature(n int) uint64 {
+	mulmod := func(x, y uint64) uint64 {
+		hi, lo := bits.Mul64(x, y)
+		// hi * 2^64 + lo, reduced using 2^61 = 1 mod _SIGPRIME
+		r := (lo & _SIGPRIME) + (lo >> 61) + (hi << 3)
+		for r >= _SIGPRIME {
+			r -= _SIGPRIME
+		}
+		return r
+	}
+	sig := map[int]uint64{0: 0, 1: 1}
+	for _, v := range b.topo(n) {
+		// splitmix64 of the variable index
+		w := uint64(b.level2var[b.level(v)]) + 0x9e3779b97f4a7c15
+		w = (w ^ (w >> 30)) * 0xbf58476d1ce4e5b9
+		w = (w ^ (w >> 27)) * 0x94d049bb133111eb
+		w = (w ^ (w >> 31)) % _SIGPRIME
+		// (1 - w) * low + w * high = low + w * (high - low)
+		low, high := sig[b.low(v)], sig[b.high(v)]
+		sig[v] = (low + mulmod(w, (high+_SIGPRIME-low)%_SIGPRIME)) % _SIGPRIME
+	}
+	return sig[n]
+}
+
 // AndExist returns the "relational composition" of two nodes with respect to
-// varset, meaning the result of (∃ varset . n1 & n2).
+// varset, meaning the result of (∃ varset . n1 & n2). It is far more efficient
+// than calling Exist on the result of And, since the quantification is
+// performed during the conjunction and the (often large) intermediate result
+// is never built. Package appexcheck can be used to find the places where the
+// two operations could be fused.
 func (b *BDD) AndExist(n1, n2, varset Node) Node {
 	return b.AppEx(n1, n2, OPand, varset)
 }
@@ -387,7 +776,9 @@ func (b *BDD) From(v bool) Node {
 
 // Stats returns information about the BDD. It is possible to print more
 // information about the caches and memory footprint of the BDD by compiling
-// your executable with the build tag 'debug'.
+// your executable with the build tag 'debug'. Use StatsStruct to get the same
+// information in a structured form, or StatsFormat to get a table that can be
+// parsed by scripts.
 func (b *BDD) Stats() string {
 	res := "==============\n"
 	res += fmt.Sprintf("Varnum:     %d\n", b.varnum)
@@ -402,3 +793,19 @@ func (b *BDD) Stats() string {
 	}
 	return res
 }
+
+// Allocated returns the number of nodes allocated in the node table of b,
+// including the free ones. This is cheaper than parsing the result of Stats.
+func (b *BDD) Allocated() int {
+	return b.size()
+}
+
+// Live returns the number of nodes in the node table of b that are not free,
+// meaning the number of allocated nodes minus the number of free nodes. This
+// includes the constants and the nodes that are no longer referenced but have
+// not yet been reclaimed by a garbage collection. Applications can use it to
+// implement their own triggers, for instance to reorder variables when the
+// number of live nodes is too high.
+func (b *BDD) Live() int {
+	return b.used()
+}
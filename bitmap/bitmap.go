@@ -0,0 +1,217 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Package bitmap provides a compressed bitmap, that is a set of 32-bit unsigned
+integers, based on the data structure of Roaring bitmaps. It is used for
+exporting the satisfying assignments of a BDD, over at most 32 variables, to
+code that works with sets of integers, without depending on an external
+library.
+
+The set of integers is split into chunks of 2^16 values sharing the same 16 most
+significant bits. Each chunk with at least one value is stored in a container,
+that is either a sorted array of the 16 least significant bits of its values,
+when there are at most 4096 of them, or a bitset with one bit for each of the
+2^16 possible values.
+*/
+package bitmap
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// arraymax is the maximal number of values in an array container.
+const arraymax = 4096
+
+// Bitmap is a compressed set of uint32 values. The zero value is an empty set
+// ready to use.
+type Bitmap struct {
+	keys       []uint16     // 16 most significant bits of each chunk, sorted
+	containers []*container // values of each chunk
+}
+
+// container stores the 16 least significant bits of the values in a chunk,
+// using an array or a bitset (if bitset is not nil).
+type container struct {
+	card   int      // number of values in the container
+	array  []uint16 // sorted values, when bitset is nil
+	bitset []uint64 // 1024 words of 64 bits
+}
+
+// container returns the container for chunk key, creating it if needed.
+func (b *Bitmap) container(key uint16) *container {
+	k := sort.Search(len(b.keys), func(i int) bool { return b.keys[i] >= key })
+	if k < len(b.keys) && b.keys[k] == key {
+		return b.containers[k]
+	}
+	b.keys = append(b.keys, 0)
+	copy(b.keys[k+1:], b.keys[k:])
+	b.keys[k] = key
+	b.containers = append(b.containers, nil)
+	copy(b.containers[k+1:], b.containers[k:])
+	b.containers[k] = &container{}
+	return b.containers[k]
+}
+
+// find returns the container for chunk key, or nil if there are none.
+func (b *Bitmap) find(key uint16) *container {
+	k := sort.Search(len(b.keys), func(i int) bool { return b.keys[i] >= key })
+	if k < len(b.keys) && b.keys[k] == key {
+		return b.containers[k]
+	}
+	return nil
+}
+
+// Add adds x to the set.
+func (b *Bitmap) Add(x uint32) {
+	b.container(uint16(x >> 16)).add(uint16(x))
+}
+
+// AddRange adds all the values in the interval [lo, hi) to the set. Values
+// greater than the maximal uint32 are ignored.
+func (b *Bitmap) AddRange(lo, hi uint64) {
+	if hi > 1<<32 {
+		hi = 1 << 32
+	}
+	for lo < hi {
+		// the end of the chunk of lo
+		end := (lo | 0xFFFF) + 1
+		if end > hi {
+			end = hi
+		}
+		b.container(uint16(lo>>16)).addrange(uint32(lo&0xFFFF), uint32(end-lo))
+		lo = end
+	}
+}
+
+// Contains returns true if x is in the set.
+func (b *Bitmap) Contains(x uint32) bool {
+	c := b.find(uint16(x >> 16))
+	return c != nil && c.contains(uint16(x))
+}
+
+// Cardinality returns the number of values in the set.
+func (b *Bitmap) Cardinality() uint64 {
+	res := uint64(0)
+	for _, c := range b.containers {
+		res += uint64(c.card)
+	}
+	return res
+}
+
+// Iterate calls f on all the values in the set, in increasing order, until f
+// returns false.
+func (b *Bitmap) Iterate(f func(x uint32) bool) {
+	for k, c := range b.containers {
+		high := uint32(b.keys[k]) << 16
+		if c.bitset == nil {
+			for _, v := range c.array {
+				if !f(high | uint32(v)) {
+					return
+				}
+			}
+			continue
+		}
+		for w, word := range c.bitset {
+			for word != 0 {
+				t := bits.TrailingZeros64(word)
+				if !f(high | uint32(w*64+t)) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+// ToArray returns the values in the set, in increasing order.
+func (b *Bitmap) ToArray() []uint32 {
+	res := make([]uint32, 0, b.Cardinality())
+	b.Iterate(func(x uint32) bool {
+		res = append(res, x)
+		return true
+	})
+	return res
+}
+
+// Containers returns the number of array and bitset containers used to store
+// the set, which gives an idea of its memory footprint.
+func (b *Bitmap) Containers() (arrays, bitsets int) {
+	for _, c := range b.containers {
+		if c.bitset == nil {
+			arrays++
+		} else {
+			bitsets++
+		}
+	}
+	return arrays, bitsets
+}
+
+func (c *container) contains(x uint16) bool {
+	if c.bitset != nil {
+		return c.bitset[x>>6]&(1<<(x&63)) != 0
+	}
+	k := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= x })
+	return k < len(c.array) && c.array[k] == x
+}
+
+func (c *container) add(x uint16) {
+	if c.bitset != nil {
+		if c.bitset[x>>6]&(1<<(x&63)) == 0 {
+			c.bitset[x>>6] |= 1 << (x & 63)
+			c.card++
+		}
+		return
+	}
+	k := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= x })
+	if k < len(c.array) && c.array[k] == x {
+		return
+	}
+	if len(c.array) == arraymax {
+		c.tobitset()
+		c.add(x)
+		return
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[k+1:], c.array[k:])
+	c.array[k] = x
+	c.card++
+}
+
+// addrange adds the count values starting from lo, all in the same chunk.
+func (c *container) addrange(lo, count uint32) {
+	if c.bitset == nil && count <= arraymax-uint32(len(c.array)) {
+		for x := lo; x < lo+count; x++ {
+			c.add(uint16(x))
+		}
+		return
+	}
+	if c.bitset == nil {
+		c.tobitset()
+	}
+	for x := lo; x < lo+count; {
+		w, t := x>>6, x&63
+		n := 64 - t
+		if n > lo+count-x {
+			n = lo + count - x
+		}
+		mask := ^uint64(0)
+		if n < 64 {
+			mask = (1<<n - 1) << t
+		}
+		c.card += bits.OnesCount64(mask &^ c.bitset[w])
+		c.bitset[w] |= mask
+		x += n
+	}
+}
+
+// tobitset converts an array container into a bitset container.
+func (c *container) tobitset() {
+	c.bitset = make([]uint64, 1024)
+	for _, x := range c.array {
+		c.bitset[x>>6] |= 1 << (x & 63)
+	}
+	c.array = nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package bitmap
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBitmap(t *testing.T) {
+	var b Bitmap
+	set := make(map[uint32]bool)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		// we mostly add values in a few chunks, so that some of them become
+		// bitsets
+		x := uint32(rng.Intn(3))<<16 | uint32(rng.Intn(1<<16))
+		if i%10 == 0 {
+			x = rng.Uint32()
+		}
+		b.Add(x)
+		set[x] = true
+	}
+	b.AddRange(1<<16-10, 3<<16+10)
+	for x := uint32(1<<16 - 10); x < 3<<16+10; x++ {
+		set[x] = true
+	}
+	b.AddRange(1<<32-5, 1<<33)
+	for x := uint64(1<<32 - 5); x < 1<<32; x++ {
+		set[uint32(x)] = true
+	}
+	if b.Cardinality() != uint64(len(set)) {
+		t.Errorf("expected cardinality %d, actual %d", len(set), b.Cardinality())
+	}
+	expected := make([]uint32, 0, len(set))
+	for x := range set {
+		expected = append(expected, x)
+		if !b.Contains(x) {
+			t.Errorf("expected %d in the bitmap", x)
+		}
+	}
+	sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+	actual := b.ToArray()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d values, actual %d", len(expected), len(actual))
+	}
+	for k := range actual {
+		if actual[k] != expected[k] {
+			t.Fatalf("expected %d at position %d, actual %d", expected[k], k, actual[k])
+		}
+	}
+	if _, bitsets := b.Containers(); bitsets == 0 {
+		t.Error("expected some bitset containers")
+	}
+}
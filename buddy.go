@@ -2,6 +2,7 @@
 //
 // MIT License
 
+//go:build buddy
 // +build buddy
 
 package rudd
@@ -11,54 +12,38 @@ import (
 	"log"
 	"sync/atomic"
 	"unsafe"
+
+	"github.com/dalzilio/rudd/internal/dd"
 )
 
 // tables is used with the build tag buddy and corresponds to Binary Decision
 // Diagrams based on the data structures and algorithms found in the BuDDy
-// library.
+// library. The node table, the unique table and the garbage collector are
+// provided by package internal/dd.
 type tables struct {
-	nodes         []buddynode // List of all the BDD nodes. Constants are always kept at index 0 and 1
-	freenum       int         // Number of free nodes
-	freepos       int         // First free node
-	produced      int         // Total number of new nodes ever produced
+	core          dd.Table    // List of all the BDD nodes, with the unique table. Constants are always kept at index 0 and 1
 	nodefinalizer interface{} // Finalizer used to decrement the ref count of external references
-	uniqueAccess  int         // accesses to the unique node table
-	uniqueChain   int         // iterations through the cache chains in the unique node table
-	uniqueHit     int         // entries actually found in the the unique node table
-	uniqueMiss    int         // entries not found in the the unique node table
+	audit         *leakaudit  // Stack traces of the Nodes returned by Retnode (nil if disabled)
 	gcstat                    // Information about garbage collections
 	configs                   // Configurable parameters
 }
 
-type buddynode struct {
-	refcou int32 // Count the number of external references
-	level  int32 // Order of the variable in the BDD
-	low    int   // Reference to the false branch
-	high   int   // Reference to the true branch
-	hash   int   // Index where to (possibly) find node with this hash value
-	next   int   // Next index to check in case of a collision, 0 if last
-}
-
 func (b *tables) ismarked(n int) bool {
-	return (b.nodes[n].level & 0x200000) != 0
+	return b.core.IsMarked(n)
 }
 
 func (b *tables) marknode(n int) {
-	b.nodes[n].level = b.nodes[n].level | 0x200000
+	b.core.Mark(n)
 }
 
 func (b *tables) unmarknode(n int) {
-	b.nodes[n].level = b.nodes[n].level & 0x1FFFFF
-}
-
-// The hash function for nodes is #(level, low, high)
-
-func (b *tables) ptrhash(n int) int {
-	return _TRIPLE(int(b.nodes[n].level), b.nodes[n].low, b.nodes[n].high, len(b.nodes))
+	b.core.Unmark(n)
 }
 
-func (b *tables) nodehash(level int32, low, high int) int {
-	return _TRIPLE(int(level), low, high, len(b.nodes))
+// lookup returns the ids of the nodes associated with a triplet in the unique
+// table, used to check that there is at most one of them.
+func (b *tables) lookup(level int32, low, high int) []int {
+	return b.core.Lookup(level, low, high)
 }
 
 // New returns a new BDD based on the implementation selected with the build
@@ -83,105 +68,171 @@ func New(varnum int, options ...func(*configs)) (*BDD, error) {
 	for _, f := range options {
 		f(config)
 	}
-	b.varnum = int32(varnum)
 	if _LOGLEVEL > 0 {
-		log.Printf("set varnum to %d\n", b.varnum)
+		log.Printf("set varnum to %d\n", varnum)
 	}
 	b.varset = make([][2]int, varnum)
-	// We also initialize the refstack.
-	b.refstack = make([]int, 0, 2*varnum+4)
+	b.initorder(varnum)
+	// We also initialize the node table and the refstack.
+	b.initkernel(varnum, config)
 	b.Initref()
-	b.error = nil
-	impl := &tables{}
-	impl.minfreenodes = config.minfreenodes
-	impl.maxnodeincrease = config.maxnodeincrease
-	nodesize := primeGte(config.nodesize)
-	impl.nodes = make([]buddynode, nodesize)
-	for k := range impl.nodes {
-		impl.nodes[k] = buddynode{
-			refcou: 0,
-			level:  0,
-			low:    -1,
-			high:   0,
-			hash:   0,
-			next:   k + 1,
-		}
-	}
-	impl.nodes[nodesize-1].next = 0
-	impl.nodes[0].refcou = _MAXREFCOUNT
-	impl.nodes[1].refcou = _MAXREFCOUNT
-	impl.nodes[0].low = 0
-	impl.nodes[0].high = 0
-	impl.nodes[1].low = 1
-	impl.nodes[1].high = 1
-	impl.nodes[0].level = int32(config.varnum)
-	impl.nodes[1].level = int32(config.varnum)
-	impl.freepos = 2
-	impl.freenum = nodesize - 2
-	impl.gcstat.history = []gcpoint{}
-	impl.nodefinalizer = func(n *int) {
-		if _DEBUG {
-			atomic.AddUint64(&(impl.gcstat.calledfinalizers), 1)
-			if _LOGLEVEL > 2 {
-				log.Printf("dec refcou %d\n", *n)
-			}
-		}
-		impl.nodes[*n].refcou--
-	}
+	impl := b.tables
 	for k := 0; k < config.varnum; k++ {
 		v0, _ := impl.makenode(int32(k), 0, 1, nil)
 		if v0 < 0 {
 			b.seterror("cannot allocate new variable %d in setVarnum", k)
 			return nil, b.error
 		}
-		impl.nodes[v0].refcou = _MAXREFCOUNT
+		impl.core.Nodes[v0].Refcou = _MAXREFCOUNT
 		b.Pushref(v0)
 		v1, _ := impl.makenode(int32(k), 1, 0, nil)
 		if v1 < 0 {
 			b.seterror("cannot allocate new variable %d in setVarnum", k)
 			return nil, b.error
 		}
-		impl.nodes[v1].refcou = _MAXREFCOUNT
+		impl.core.Nodes[v1].Refcou = _MAXREFCOUNT
 		b.Popref(1)
 		b.varset[k] = [2]int{v0, v1}
 	}
-	b.tables = impl
 	b.cacheinit(config)
+	b.initjournal(config)
+	b.initwarnings(config)
+	b.config = config
+	if config.autoreorder != nil {
+		t := *config.autoreorder
+		t.period = config.reorderperiod
+		b.autoorder = &t
+	}
+	b.initages(config)
 	return b, nil
 }
 
+// newtables returns a node table configured with config, with the two
+// constants at level config.varnum.
+func newtables(config *configs) *tables {
+	impl := &tables{}
+	impl.assertions = config.assertions
+	impl.core.Minfreenodes = config.minfreenodes
+	impl.core.Maxnodesize = config.maxnodesize
+	impl.core.Maxnodeincrease = config.maxnodeincrease
+	impl.core.Hash = config.hashfunc
+	impl.core.Maxchain = config.maxchain
+	impl.core.StopTheWorld = config.resizemode == ResizeStopTheWorld
+	impl.core.BeforeGC = impl.recordgc
+	impl.core.Init(config.nodesize, int32(config.varnum))
+	impl.gcstat.history = []gcpoint{}
+	impl.audit = newleakaudit(config)
+	impl.nodefinalizer = func(n *int) {
+		if _DEBUG {
+			atomic.AddUint64(&(impl.gcstat.calledfinalizers), 1)
+			if _LOGLEVEL > 2 {
+				log.Printf("dec refcou %d\n", *n)
+			}
+		}
+		// pinned nodes, for instance in a sealed BDD, are never released
+		if impl.core.Nodes[*n].Refcou < _MAXREFCOUNT {
+			impl.core.Nodes[*n].Refcou--
+		}
+	}
+	return impl
+}
+
 func (b *tables) size() int {
-	return len(b.nodes)
+	return len(b.core.Nodes)
+}
+
+// used returns the number of nodes in use in the table, including the
+// constants.
+func (b *tables) used() int {
+	return len(b.core.Nodes) - b.core.Freenum
+}
+
+// setaftergc sets a function called at the end of each garbage collection.
+func (b *tables) setaftergc(f func()) {
+	b.core.AfterGC = f
+}
+
+// setconstlevel sets the level of the constants, that is always equal to the
+// number of variables.
+func (b *tables) setconstlevel(level int32) {
+	b.core.Own()
+	b.core.Nodes[0].Level = level
+	b.core.Nodes[1].Level = level
+}
+
+// pinnode sets the reference count of node n to the maximal value, so that it
+// is never reclaimed.
+func (b *tables) pinnode(n int) {
+	b.core.Own()
+	b.core.Nodes[n].Refcou = _MAXREFCOUNT
+}
+
+// sharetables makes b use the nodes and the unique table of src, that must not
+// change afterwards, until the first change to b; see dd.Table.Share.
+func (b *tables) sharetables(src *tables) {
+	b.core.Share(&src.core)
+}
+
+// shared reports whether b still shares its nodes with another table.
+func (b *tables) shared() bool {
+	return b.core.Shared()
+}
+
+// producednum returns the total number of nodes ever produced.
+func (b *tables) producednum() int {
+	return b.core.Produced
 }
 
 func (b *tables) level(n int) int32 {
-	return b.nodes[n].level
+	return b.core.Nodes[n].Level
 }
 
 func (b *tables) low(n int) int {
-	return b.nodes[n].low
+	return b.core.Nodes[n].Low
 }
 
 func (b *tables) high(n int) int {
-	return b.nodes[n].high
+	return b.core.Nodes[n].High
+}
+
+// refcount returns the number of external references to node n.
+func (b *tables) refcount(n int) int32 {
+	return b.core.Nodes[n].Refcou
+}
+
+// unhash removes node n from the unique table, before changing it in place.
+func (b *tables) unhash(n int) {
+	b.core.Unhash(n)
+}
+
+// rehash sets the level and successors of node n, removed with unhash, and
+// adds it back to the unique table.
+func (b *tables) rehash(n int, level int32, low, high int) {
+	b.core.Rehash(n, level, low, high)
+}
+
+// freenode reclaims node n, that must not be referenced anymore, without
+// waiting for the next garbage collection.
+func (b *tables) freenode(n int) {
+	b.core.Free(n)
 }
 
 func (b *tables) allnodesfrom(f func(id, level, low, high int) error, n []Node) error {
 	for _, v := range n {
 		b.markrec(*v)
 	}
-	// if err := f(0, int(b.nodes[0].level), 0, 0); err != nil {
+	// if err := f(0, int(b.core.Nodes[0].Level), 0, 0); err != nil {
 	// 	b.unmarkall()
 	// 	return err
 	// }
-	// if err := f(1, int(b.nodes[1].level), 1, 1); err != nil {
+	// if err := f(1, int(b.core.Nodes[1].Level), 1, 1); err != nil {
 	// 	b.unmarkall()
 	// 	return err
 	// }
-	for k := range b.nodes {
+	for k := range b.core.Nodes {
 		if b.ismarked(k) {
 			b.unmarknode(k)
-			if err := f(k, int(b.nodes[k].level), b.nodes[k].low, b.nodes[k].high); err != nil {
+			if err := f(k, int(b.core.Nodes[k].Level), b.core.Nodes[k].Low, b.core.Nodes[k].High); err != nil {
 				b.unmarkall()
 				return err
 			}
@@ -191,15 +242,15 @@ func (b *tables) allnodesfrom(f func(id, level, low, high int) error, n []Node)
 }
 
 func (b *tables) allnodes(f func(id, level, low, high int) error) error {
-	// if err := f(0, int(b.nodes[0].level), 0, 0); err != nil {
+	// if err := f(0, int(b.core.Nodes[0].Level), 0, 0); err != nil {
 	// 	return err
 	// }
-	// if err := f(1, int(b.nodes[1].level), 1, 1); err != nil {
+	// if err := f(1, int(b.core.Nodes[1].Level), 1, 1); err != nil {
 	// 	return err
 	// }
-	for k, v := range b.nodes {
-		if v.low != -1 {
-			if err := f(k, int(v.level), v.low, v.high); err != nil {
+	for k, v := range b.core.Nodes {
+		if v.Low != -1 {
+			if err := f(k, int(v.Level), v.Low, v.High); err != nil {
 				return err
 			}
 		}
@@ -210,11 +261,13 @@ func (b *tables) allnodes(f func(id, level, low, high int) error) error {
 // Stats returns information about the BDD
 func (b *tables) stats() string {
 	res := "Impl.:      BuDDy\n"
-	res += fmt.Sprintf("Allocated:  %d  (%s)\n", len(b.nodes), humanSize(len(b.nodes), unsafe.Sizeof(buddynode{})))
-	res += fmt.Sprintf("Produced:   %d\n", b.produced)
-	r := (float64(b.freenum) / float64(len(b.nodes))) * 100
-	res += fmt.Sprintf("Free:       %d  (%.3g %%)\n", b.freenum, r)
-	res += fmt.Sprintf("Used:       %d  (%.3g %%)\n", len(b.nodes)-b.freenum, (100.0 - r))
+	res += fmt.Sprintf("Allocated:  %d  (%s)\n", len(b.core.Nodes), humanSize(len(b.core.Nodes), unsafe.Sizeof(dd.Node{})))
+	res += fmt.Sprintf("Produced:   %d\n", b.core.Produced)
+	r := (float64(b.core.Freenum) / float64(len(b.core.Nodes))) * 100
+	res += fmt.Sprintf("Free:       %d  (%.3g %%)\n", b.core.Freenum, r)
+	res += fmt.Sprintf("Used:       %d  (%.3g %%)\n", len(b.core.Nodes)-b.core.Freenum, (100.0 - r))
+	res += fmt.Sprintf("Buckets:    %d\n", b.core.Buckets())
+	res += fmt.Sprintf("Max chain:  %d\n", b.core.UniqueMaxChain)
 	res += "==============\n"
 	res += fmt.Sprintf("# of GC:    %d\n", len(b.gcstat.history))
 	if _DEBUG {
@@ -227,11 +280,28 @@ func (b *tables) stats() string {
 		res += fmt.Sprintf("Ext. refs:  %d\n", allocated)
 		res += fmt.Sprintf("Reclaimed:  %d\n", reclaimed)
 		res += "==============\n"
-		res += fmt.Sprintf("Unique Access:  %d\n", b.uniqueAccess)
-		res += fmt.Sprintf("Unique Chain:   %d\n", b.uniqueChain)
-		res += fmt.Sprintf("Unique Hit:     %d (%.1f%% + %.1f%%)\n", b.uniqueHit, (float64(b.uniqueHit)*100)/float64(b.uniqueAccess),
-			(float64(b.uniqueAccess-b.uniqueMiss-b.uniqueHit)*100)/float64(b.uniqueAccess))
-		res += fmt.Sprintf("Unique Miss:    %d\n", b.uniqueMiss)
+		res += fmt.Sprintf("Unique Access:  %d\n", b.core.UniqueAccess)
+		res += fmt.Sprintf("Unique Chain:   %d\n", b.core.UniqueChain)
+		res += fmt.Sprintf("Unique Hit:     %d (%.1f%% + %.1f%%)\n", b.core.UniqueHit, (float64(b.core.UniqueHit)*100)/float64(b.core.UniqueAccess),
+			(float64(b.core.UniqueAccess-b.core.UniqueMiss-b.core.UniqueHit)*100)/float64(b.core.UniqueAccess))
+		res += fmt.Sprintf("Unique Miss:    %d\n", b.core.UniqueMiss)
 	}
 	return res
 }
+
+// tablestats returns the name of the implementation and the state of the node
+// table, see StatsStruct.
+func (b *tables) tablestats() (string, TableStats) {
+	return "BuDDy", TableStats{
+		Allocated:    len(b.core.Nodes),
+		Bytes:        len(b.core.Nodes) * int(unsafe.Sizeof(dd.Node{})),
+		Produced:     b.core.Produced,
+		Free:         b.core.Freenum,
+		Used:         len(b.core.Nodes) - b.core.Freenum,
+		Buckets:      b.core.Buckets(),
+		MaxChain:     b.core.UniqueMaxChain,
+		UniqueAccess: b.core.UniqueAccess,
+		UniqueHit:    b.core.UniqueHit,
+		UniqueMiss:   b.core.UniqueMiss,
+	}
+}
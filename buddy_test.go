@@ -0,0 +1,26 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+//go:build buddy
+// +build buddy
+
+package rudd
+
+import (
+	"testing"
+)
+
+// TestLinearHashing checks that, after many insertions and resizes, every
+// node in the table can be found in the chain of its bucket.
+func TestLinearHashing(t *testing.T) {
+	for _, mode := range []ResizeMode{ResizeIncremental, ResizeStopTheWorld} {
+		bdd, _ := milner(t, true, 8, Nodesize(100), Cachesize(25), Resizemode(mode))
+		if err := bdd.core.Check(); err != nil {
+			t.Fatal(err)
+		}
+		if mode == ResizeStopTheWorld && bdd.core.Buckets() != len(bdd.core.Nodes) {
+			t.Errorf("expected %d buckets after stop-the-world resize, actual %d", len(bdd.core.Nodes), bdd.core.Buckets())
+		}
+	}
+}
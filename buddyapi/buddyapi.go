@@ -0,0 +1,326 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Package buddyapi is a thin layer over package rudd that mimics the C API of the
+BuDDy library, in order to ease the mechanical porting of BuDDy-based programs
+to Go. Each function in the C API, such as bdd_ithvar or bdd_appex, is mapped to
+a function with the same name, without the "bdd_" prefix and starting with a
+capital letter, such as Ithvar or Appex. Likewise, operators such as bddop_and
+are mapped to constants OpAnd, OpXor, ...
+
+Like with BuDDy, there is a single, global, BDD manager, created by Init and
+Setvarnum, and released with Done. Hence this package is not safe for
+concurrent use and new programs should use package rudd directly. Functions
+Addref and Delref are provided for compatibility but they have no effect, since
+the references to nodes are managed by the Go garbage collector. Also, errors
+are not returned as negative integers, like in C, but are recorded in the
+manager and available using Errstring.
+
+A typical BuDDy program, such as
+
+	bdd_init(1000, 100);
+	bdd_setvarnum(4);
+	BDD x = bdd_addref(bdd_and(bdd_ithvar(0), bdd_nithvar(1)));
+	printf("%f\n", bdd_satcount(x));
+	bdd_done();
+
+becomes
+
+	buddyapi.Init(1000, 100)
+	buddyapi.Setvarnum(4)
+	x := buddyapi.Addref(buddyapi.And(buddyapi.Ithvar(0), buddyapi.Nithvar(1)))
+	fmt.Println(buddyapi.Satcount(x))
+	buddyapi.Done()
+*/
+package buddyapi
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dalzilio/rudd"
+)
+
+// BDD is the type of BDD nodes, like in BuDDy.
+type BDD = rudd.Node
+
+// Operators used with Apply and Appex, with the same names (in Go style) than
+// in BuDDy.
+const (
+	OpAnd    = rudd.OPand
+	OpXor    = rudd.OPxor
+	OpOr     = rudd.OPor
+	OpNand   = rudd.OPnand
+	OpNor    = rudd.OPnor
+	OpImp    = rudd.OPimp
+	OpBiimp  = rudd.OPbiimp
+	OpDiff   = rudd.OPdiff
+	OpLess   = rudd.OPless
+	OpInvimp = rudd.OPinvimp
+)
+
+// manager is the global state of the package.
+var manager struct {
+	bdd       *rudd.BDD
+	err       error // error raised outside of the BDD, for instance in Replace
+	nodesize  int
+	cachesize int
+	blocks    int
+}
+
+// Init initializes the package with an initial number of nodes and a cache
+// size, like bdd_init. The BDD itself is created when calling Setvarnum.
+func Init(nodesize, cachesize int) {
+	manager.bdd = nil
+	manager.err = nil
+	manager.nodesize = nodesize
+	manager.cachesize = cachesize
+	manager.blocks = 0
+}
+
+// Done releases the global BDD, like bdd_done.
+func Done() {
+	manager.bdd = nil
+	manager.err = nil
+}
+
+// Isrunning returns true if the global BDD has been created, like
+// bdd_isrunning.
+func Isrunning() bool {
+	return manager.bdd != nil
+}
+
+// Setvarnum creates the global BDD with num variables, like bdd_setvarnum.
+// Unlike with BuDDy, the number of variables cannot change afterwards, so we
+// return an error if Setvarnum is called twice with different values.
+func Setvarnum(num int) error {
+	if manager.bdd != nil {
+		if num == manager.bdd.Varnum() {
+			return nil
+		}
+		return fmt.Errorf("cannot change the number of variables (%d) after Setvarnum", manager.bdd.Varnum())
+	}
+	b, err := rudd.New(num, rudd.Nodesize(manager.nodesize), rudd.Cachesize(manager.cachesize))
+	if err != nil {
+		return err
+	}
+	manager.bdd = b
+	return nil
+}
+
+// Manager returns the underlying rudd BDD, for instance to use functions that
+// have no equivalent in BuDDy. It is nil before the call to Setvarnum.
+func Manager() *rudd.BDD {
+	return manager.bdd
+}
+
+// Varnum returns the number of variables, like bdd_varnum.
+func Varnum() int {
+	return manager.bdd.Varnum()
+}
+
+// Errstring returns the error recorded in the global BDD, if any, like
+// bdd_errstring; it returns the empty string if there is no error.
+func Errstring() string {
+	switch {
+	case manager.bdd == nil:
+		return "BDD package not initialized"
+	case manager.bdd.Errored():
+		return manager.bdd.Error()
+	case manager.err != nil:
+		return manager.err.Error()
+	}
+	return ""
+}
+
+// Addvarblock adds a block of variables, given as a cube, like bdd_addvarblock,
+// and returns its index. Variable blocks are only used for dynamic reordering
+// in BuDDy, which is not available in rudd, so the block is not used.
+func Addvarblock(b BDD, fixed bool) int {
+	if !manager.bdd.IsCube(b) {
+		return -1
+	}
+	manager.blocks++
+	return manager.blocks - 1
+}
+
+// Addref returns n, like bdd_addref. References are managed by the Go runtime.
+func Addref(n BDD) BDD { return n }
+
+// Delref returns n, like bdd_delref. References are managed by the Go runtime.
+func Delref(n BDD) BDD { return n }
+
+// True returns the constant true, like bdd_true.
+func True() BDD { return manager.bdd.True() }
+
+// False returns the constant false, like bdd_false.
+func False() BDD { return manager.bdd.False() }
+
+// Ithvar returns the BDD for variable i, like bdd_ithvar.
+func Ithvar(i int) BDD { return manager.bdd.Ithvar(i) }
+
+// Nithvar returns the BDD for the negation of variable i, like bdd_nithvar.
+func Nithvar(i int) BDD { return manager.bdd.NIthvar(i) }
+
+// Var returns the variable labelling the root of n, like bdd_var.
+func Var(n BDD) int { return manager.bdd.Var(n) }
+
+// Low returns the false branch of n, like bdd_low.
+func Low(n BDD) BDD { return manager.bdd.Low(n) }
+
+// High returns the true branch of n, like bdd_high.
+func High(n BDD) BDD { return manager.bdd.High(n) }
+
+// Not returns the negation of n, like bdd_not.
+func Not(n BDD) BDD { return manager.bdd.Not(n) }
+
+// Apply applies operator op to l and r, like bdd_apply.
+func Apply(l, r BDD, op rudd.Operator) BDD { return manager.bdd.Apply(l, r, op) }
+
+// And returns the conjunction of l and r, like bdd_and.
+func And(l, r BDD) BDD { return manager.bdd.Apply(l, r, OpAnd) }
+
+// Or returns the disjunction of l and r, like bdd_or.
+func Or(l, r BDD) BDD { return manager.bdd.Apply(l, r, OpOr) }
+
+// Xor returns the exclusive or of l and r, like bdd_xor.
+func Xor(l, r BDD) BDD { return manager.bdd.Apply(l, r, OpXor) }
+
+// Imp returns the implication l => r, like bdd_imp.
+func Imp(l, r BDD) BDD { return manager.bdd.Apply(l, r, OpImp) }
+
+// Biimp returns the equivalence l <=> r, like bdd_biimp.
+func Biimp(l, r BDD) BDD { return manager.bdd.Apply(l, r, OpBiimp) }
+
+// Ite returns the if-then-else of f, g and h, like bdd_ite.
+func Ite(f, g, h BDD) BDD { return manager.bdd.Ite(f, g, h) }
+
+// Simplify restricts f to the domain d, like bdd_simplify.
+func Simplify(f, d BDD) BDD { return manager.bdd.Simplify(f, d) }
+
+// Exist returns the existential quantification of n over the variables in the
+// cube varset, like bdd_exist.
+func Exist(n, varset BDD) BDD { return manager.bdd.Exist(n, varset) }
+
+// Forall returns the universal quantification of n over the variables in the
+// cube varset, like bdd_forall.
+func Forall(n, varset BDD) BDD {
+	b := manager.bdd
+	return b.Not(b.Exist(b.Not(n), varset))
+}
+
+// Appex applies op to l and r and then quantifies the variables in varset,
+// like bdd_appex.
+func Appex(l, r BDD, op rudd.Operator, varset BDD) BDD {
+	return manager.bdd.AppEx(l, r, op, varset)
+}
+
+// Makeset returns the cube of the variables in v, like bdd_makeset.
+func Makeset(v []int) BDD { return manager.bdd.Makeset(v) }
+
+// Scanset returns the variables in the cube n, like bdd_scanset.
+func Scanset(n BDD) []int { return manager.bdd.Scanset(n) }
+
+// Satcount returns the number of satisfying assignments of n, like
+// bdd_satcount. The result is a float64, like the double returned in C; use
+// Manager().Satcount for an exact result.
+func Satcount(n BDD) float64 {
+	res, _ := new(big.Float).SetInt(manager.bdd.Satcount(n)).Float64()
+	return res
+}
+
+// Fullsatone returns a complete assignment satisfying n, like bdd_fullsatone,
+// where don't cares are negative.
+func Fullsatone(n BDD) BDD { return manager.bdd.Fullsatone(n, 0) }
+
+// Satoneset returns a cube over the variables in varset compatible with n, like
+// bdd_satoneset, where don't cares have polarity pol (True or False).
+func Satoneset(n, varset, pol BDD) BDD { return manager.bdd.Satoneset(n, varset, pol) }
+
+// Support returns the cube of the variables occurring in n, like bdd_support.
+func Support(n BDD) BDD { return manager.bdd.Support(n) }
+
+// Varprofile returns the number of nodes labelled with each variable in n,
+// like bdd_varprofile.
+func Varprofile(n BDD) []int { return manager.bdd.Varprofile(n) }
+
+// Pathcount returns the number of paths from n to the True constant, like
+// bdd_pathcount. The result is a float64; use Manager().PathCount for an exact
+// result.
+func Pathcount(n BDD) float64 {
+	res, _ := new(big.Float).SetInt(manager.bdd.PathCount(n)).Float64()
+	return res
+}
+
+// Nodecount returns the number of nodes in n, not counting the constants, like
+// bdd_nodecount.
+func Nodecount(n BDD) int {
+	count := 0
+	manager.bdd.Allnodes(func(id, level, low, high int) error {
+		if id > 1 {
+			count++
+		}
+		return nil
+	}, n)
+	return count
+}
+
+// Pair is a list of substitutions of variables, like the bddPair type of
+// BuDDy, used with Replace.
+type Pair struct {
+	oldvars  []int
+	newvars  []int
+	replacer rudd.Replacer
+}
+
+// Newpair returns an empty list of substitutions, like bdd_newpair.
+func Newpair() *Pair {
+	return &Pair{}
+}
+
+// Setpair adds the substitution of oldvar by newvar to p, like bdd_setpair.
+// It replaces any previous substitution for oldvar.
+func Setpair(p *Pair, oldvar, newvar int) {
+	p.replacer = nil
+	for k, v := range p.oldvars {
+		if v == oldvar {
+			p.newvars[k] = newvar
+			return
+		}
+	}
+	p.oldvars = append(p.oldvars, oldvar)
+	p.newvars = append(p.newvars, newvar)
+}
+
+// Setpairs adds the substitutions of oldvars[k] by newvars[k] to p, like
+// bdd_setpairs.
+func Setpairs(p *Pair, oldvars, newvars []int) {
+	for k := range oldvars {
+		if k < len(newvars) {
+			Setpair(p, oldvars[k], newvars[k])
+		}
+	}
+}
+
+// Resetpair removes all the substitutions in p, like bdd_resetpair.
+func Resetpair(p *Pair) {
+	*p = Pair{}
+}
+
+// Replace substitutes the variables in n following p, like bdd_replace. We
+// return nil, and record an error, if p is not a valid substitution.
+func Replace(n BDD, p *Pair) BDD {
+	if p.replacer == nil {
+		r, err := manager.bdd.NewReplacer(p.oldvars, p.newvars)
+		if err != nil {
+			if manager.err == nil {
+				manager.err = err
+			}
+			return nil
+		}
+		p.replacer = r
+	}
+	return manager.bdd.Replace(n, p.replacer)
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package buddyapi
+
+import "testing"
+
+func TestBuddyAPI(t *testing.T) {
+	Init(1000, 100)
+	defer Done()
+	if err := Setvarnum(4); err != nil || !Isrunning() || Varnum() != 4 {
+		t.Fatalf("Setvarnum(4) failed; %v", err)
+	}
+	if err := Setvarnum(6); err == nil {
+		t.Errorf("Setvarnum should not change the number of variables")
+	}
+	x := Addref(And(Ithvar(0), Nithvar(1)))
+	if Satcount(x) != 4 || Nodecount(x) != 2 || Pathcount(x) != 1 || Var(x) != 0 {
+		t.Errorf("unexpected result for x0 & !x1: satcount %f, nodecount %d", Satcount(x), Nodecount(x))
+	}
+	if Addvarblock(Makeset([]int{0, 1}), false) != 0 || Addvarblock(x, true) != 1 {
+		t.Errorf("Addvarblock should number the blocks from 0")
+	}
+	p := Newpair()
+	Setpairs(p, []int{0, 1}, []int{2, 3})
+	y := Replace(x, p)
+	if !Manager().Equal(y, And(Ithvar(2), Nithvar(3))) {
+		t.Errorf("Replace should rename x0 and x1 into x2 and x3")
+	}
+	if !Manager().Equal(Appex(x, y, OpAnd, Makeset([]int{0, 1})), y) {
+		t.Errorf("Appex(x, y, and, {0, 1}) should be equal to y")
+	}
+	if !Manager().Equal(Forall(Or(Ithvar(0), Ithvar(2)), Makeset([]int{0})), Ithvar(2)) {
+		t.Errorf("Forall x0 . (x0 | x2) should be x2")
+	}
+	if Errstring() != "" {
+		t.Errorf("unexpected error: %s", Errstring())
+	}
+	Setpair(p, 2, 3)
+	if Replace(x, p) != nil || Errstring() == "" {
+		t.Errorf("Replace with a bad pair should fail")
+	}
+}
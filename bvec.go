@@ -0,0 +1,216 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+// Bvec is a vector of Boolean functions, used to encode symbolic integers, as
+// in the bvec module of BuDDy. Element k is the BDD for bit k, starting with
+// the least significant bit. Arithmetic on a Bvec is modulo 2^len, where len
+// is the number of bits, and the operations between two vectors require that
+// they have the same number of bits. Like with other operations, we return nil
+// and set the error condition in the BDD when there is an error.
+type Bvec []Node
+
+// checkbvec returns false, and sets the error condition in b, if one of the
+// vectors has a wrong node or if they have different number of bits.
+func (b *BDD) checkbvec(op string, x Bvec, y ...Bvec) bool {
+	for _, v := range y {
+		if len(v) != len(x) {
+			b.seterror("bit vectors of different sizes (%d and %d) in call to %s", len(x), len(v), op)
+			return false
+		}
+	}
+	for _, v := range append([]Bvec{x}, y...) {
+		for _, n := range v {
+			if b.checkptr(n) != nil {
+				b.seterror("wrong node in call to %s", op)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// BvecCon returns a vector of bitnum bits encoding the constant val (modulo
+// 2^bitnum).
+func (b *BDD) BvecCon(bitnum, val int) Bvec {
+	if bitnum < 0 {
+		b.seterror("bad number of bits (%d) in call to BvecCon", bitnum)
+		return nil
+	}
+	res := make(Bvec, bitnum)
+	for k := range res {
+		res[k] = b.From(val&1 == 1)
+		val >>= 1
+	}
+	return res
+}
+
+// BvecVar returns a vector of bitnum bits where bit k is variable offset + k *
+// step. For instance, BvecVar(4, 0, 2) is the vector of variables 0, 2, 4 and
+// 6, with variable 0 as the least significant bit.
+func (b *BDD) BvecVar(bitnum, offset, step int) Bvec {
+	if bitnum < 0 {
+		b.seterror("bad number of bits (%d) in call to BvecVar", bitnum)
+		return nil
+	}
+	res := make(Bvec, bitnum)
+	for k := range res {
+		if v := offset + k*step; v < 0 || v >= int(b.varnum) {
+			b.seterror("unknown variable (%d) in call to BvecVar", v)
+			return nil
+		}
+		res[k] = b.Ithvar(offset + k*step)
+	}
+	return res
+}
+
+// BvecFdd returns the vector encoding the values of the finite domain block
+// dom, allocated with ExtDomain.
+func (b *BDD) BvecFdd(dom int) Bvec {
+	vars := b.FddVars(dom)
+	if vars == nil {
+		return nil
+	}
+	res := make(Bvec, len(vars))
+	for k, v := range vars {
+		res[k] = b.Ithvar(v)
+	}
+	return res
+}
+
+// BvecAdd returns the vector for the sum x + y (modulo 2^len).
+func (b *BDD) BvecAdd(x, y Bvec) Bvec {
+	if !b.checkbvec("BvecAdd", x, y) {
+		return nil
+	}
+	res := make(Bvec, len(x))
+	carry := b.False()
+	for k := range x {
+		res[k] = b.Apply(b.Apply(x[k], y[k], OPxor), carry, OPxor)
+		// carry = (x[k] & y[k]) | (carry & (x[k] | y[k]))
+		carry = b.Or(b.And(x[k], y[k]), b.And(carry, b.Or(x[k], y[k])))
+	}
+	return res
+}
+
+// BvecSub returns the vector for the difference x - y (modulo 2^len).
+func (b *BDD) BvecSub(x, y Bvec) Bvec {
+	if !b.checkbvec("BvecSub", x, y) {
+		return nil
+	}
+	res := make(Bvec, len(x))
+	borrow := b.False()
+	for k := range x {
+		res[k] = b.Apply(b.Apply(x[k], y[k], OPxor), borrow, OPxor)
+		// borrow = (!x[k] & (y[k] | borrow)) | (x[k] & y[k] & borrow)
+		borrow = b.Or(b.Apply(x[k], b.Or(y[k], borrow), OPless), b.And(x[k], y[k], borrow))
+	}
+	return res
+}
+
+// BvecMulFixed returns the vector for the product x * c (modulo 2^len), where
+// c is a non-negative constant.
+func (b *BDD) BvecMulFixed(x Bvec, c int) Bvec {
+	if !b.checkbvec("BvecMulFixed", x) {
+		return nil
+	}
+	if c < 0 {
+		b.seterror("negative constant (%d) in call to BvecMulFixed", c)
+		return nil
+	}
+	res := b.BvecCon(len(x), 0)
+	for shifted := x; c != 0 && res != nil; c >>= 1 {
+		if c&1 == 1 {
+			res = b.BvecAdd(res, shifted)
+		}
+		shifted = b.BvecShl(shifted, 1, b.False())
+	}
+	return res
+}
+
+// BvecShl returns the vector x shifted left by pos bits, where the pos least
+// significant bits are filled with c. The result has the same number of bits
+// than x.
+func (b *BDD) BvecShl(x Bvec, pos int, c Node) Bvec {
+	if !b.checkbvec("BvecShl", x) {
+		return nil
+	}
+	if pos < 0 || b.checkptr(c) != nil {
+		b.seterror("bad shift (%d) in call to BvecShl", pos)
+		return nil
+	}
+	res := make(Bvec, len(x))
+	for k := range res {
+		if k < pos {
+			res[k] = c
+		} else {
+			res[k] = x[k-pos]
+		}
+	}
+	return res
+}
+
+// BvecShr returns the vector x shifted right by pos bits, where the pos most
+// significant bits are filled with c. The result has the same number of bits
+// than x.
+func (b *BDD) BvecShr(x Bvec, pos int, c Node) Bvec {
+	if !b.checkbvec("BvecShr", x) {
+		return nil
+	}
+	if pos < 0 || b.checkptr(c) != nil {
+		b.seterror("bad shift (%d) in call to BvecShr", pos)
+		return nil
+	}
+	res := make(Bvec, len(x))
+	for k := range res {
+		if k+pos < len(x) {
+			res[k] = x[k+pos]
+		} else {
+			res[k] = c
+		}
+	}
+	return res
+}
+
+// BvecLth returns the BDD for the assignments where x < y, with x and y
+// interpreted as unsigned integers.
+func (b *BDD) BvecLth(x, y Bvec) Node {
+	if !b.checkbvec("BvecLth", x, y) {
+		return nil
+	}
+	return b.compare(x, y, b.False())
+}
+
+// BvecLte returns the BDD for the assignments where x <= y, with x and y
+// interpreted as unsigned integers.
+func (b *BDD) BvecLte(x, y Bvec) Node {
+	if !b.checkbvec("BvecLte", x, y) {
+		return nil
+	}
+	return b.compare(x, y, b.True())
+}
+
+// compare returns the BDD for x < y, when eq is False, or x <= y, when eq is
+// True. We start from the least significant bit, where the result for the
+// lower bits is only relevant when the current bits are equal.
+func (b *BDD) compare(x, y Bvec, eq Node) Node {
+	res := eq
+	for k := range x {
+		res = b.Or(b.Apply(x[k], y[k], OPless), b.And(b.Equiv(x[k], y[k]), res))
+	}
+	return res
+}
+
+// BvecEqu returns the BDD for the assignments where x and y are equal.
+func (b *BDD) BvecEqu(x, y Bvec) Node {
+	if !b.checkbvec("BvecEqu", x, y) {
+		return nil
+	}
+	res := b.True()
+	for k := range x {
+		res = b.And(res, b.Equiv(x[k], y[k]))
+	}
+	return res
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+// TestBvec checks the arithmetic operations on all the values of two vectors
+// of 4 bits, with interleaved variables.
+func TestBvec(t *testing.T) {
+	const bits = 4
+	const mod = 1 << bits
+	bdd, _ := New(2*bits, Nodesize(10000))
+	x, y := bdd.BvecVar(bits, 0, 2), bdd.BvecVar(bits, 1, 2)
+	ops := map[string]struct {
+		vec      Bvec
+		expected func(vx, vy int) int
+	}{
+		"add":  {bdd.BvecAdd(x, y), func(vx, vy int) int { return (vx + vy) % mod }},
+		"sub":  {bdd.BvecSub(x, y), func(vx, vy int) int { return (vx - vy + mod) % mod }},
+		"mul":  {bdd.BvecMulFixed(x, 3), func(vx, vy int) int { return (vx * 3) % mod }},
+		"shl":  {bdd.BvecShl(x, 1, bdd.True()), func(vx, vy int) int { return (vx<<1 | 1) % mod }},
+		"shr":  {bdd.BvecShr(x, 2, bdd.False()), func(vx, vy int) int { return vx >> 2 }},
+		"addc": {bdd.BvecAdd(x, bdd.BvecCon(bits, 5)), func(vx, vy int) int { return (vx + 5) % mod }},
+	}
+	lth, lte, equ := bdd.BvecLth(x, y), bdd.BvecLte(x, y), bdd.BvecEqu(x, y)
+	if bdd.Errored() {
+		t.Fatal(bdd.Error())
+	}
+	bit := func(v, k int) bool { return v&(1<<k) != 0 }
+	for vx := 0; vx < mod; vx++ {
+		for vy := 0; vy < mod; vy++ {
+			assignment := make([]bool, 2*bits)
+			for k := 0; k < bits; k++ {
+				assignment[2*k], assignment[2*k+1] = bit(vx, k), bit(vy, k)
+			}
+			eval := func(n Node) bool {
+				res, err := bdd.Eval(n, assignment)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return res
+			}
+			for name, op := range ops {
+				actual := 0
+				for k, n := range op.vec {
+					if eval(n) {
+						actual |= 1 << k
+					}
+				}
+				if expected := op.expected(vx, vy); actual != expected {
+					t.Fatalf("%s(%d, %d): expected %d, actual %d", name, vx, vy, expected, actual)
+				}
+			}
+			if eval(lth) != (vx < vy) || eval(lte) != (vx <= vy) || eval(equ) != (vx == vy) {
+				t.Fatalf("wrong comparison between %d and %d", vx, vy)
+			}
+		}
+	}
+	if bdd.BvecAdd(x, bdd.BvecCon(bits+1, 0)) != nil || !bdd.Errored() {
+		t.Errorf("expected an error with vectors of different sizes")
+	}
+}
+
+func TestBvecFdd(t *testing.T) {
+	bdd, _ := New(1)
+	doms, _ := bdd.ExtDomain([]int{10, 10})
+	x, y := bdd.BvecFdd(doms[0]), bdd.BvecFdd(doms[1])
+	// y = x + 3, with x < 7
+	n := bdd.And(bdd.BvecEqu(y, bdd.BvecAdd(x, bdd.BvecCon(len(x), 3))), bdd.BvecLth(x, bdd.BvecCon(len(x), 7)))
+	for v := 0; v < 7; v++ {
+		if actual := bdd.FddScanVar(bdd.And(n, bdd.FddIthVar(doms[0], v)), doms[1]); actual != v+3 {
+			t.Errorf("expected %d, actual %d", v+3, actual)
+		}
+	}
+	if bdd.Errored() {
+		t.Fatal(bdd.Error())
+	}
+}
@@ -0,0 +1,544 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"runtime"
+
+	"github.com/dalzilio/rudd/internal/dd"
+)
+
+// CBDD is a Binary Decision Diagram with complemented edges, meaning that an
+// edge to a node can carry a negation mark, so that a function and its
+// negation share the same nodes. Hence Not takes constant time and never
+// creates nodes, and diagrams are often smaller than with BDD, sometimes by a
+// factor of two, for instance with parity functions. We only store nodes whose
+// high edge is not complemented, so that each function still has a unique
+// representation. There is a single terminal node, the constant True, and
+// False is its negation.
+//
+// Like with ZDD, a CBDD uses the same node table, garbage collector and
+// operation cache than a BDD built with build tag buddy, and the same
+// configuration options (such as Nodesize or Cachesize). The level of a
+// variable is always equal to its index; use ToCBDD and FromCBDD to convert
+// from and to a BDD. A CBDD is not safe for concurrent use.
+type CBDD struct {
+	varnum        int32
+	core          dd.Table  // Node table, with complemented edges; the terminal is at level varnum
+	cache         dd.Cache4 // Cache for all the operations, see cbddop
+	vars          []int     // Edge of each variable
+	existID       int       // Current cache id for Exist
+	refstack      []int
+	nodefinalizer interface{}
+	error         error
+}
+
+// The values of a Node of a CBDD are edges, see dd.Table.Makenode: the id of a
+// node shifted left by one, with bit 0 set when the edge is complemented.
+const (
+	cbddtrue  = 0 // edge to the terminal
+	cbddfalse = 1 // complemented edge to the terminal
+)
+
+// cbddop is the identifier of an operation in the cache of a CBDD.
+type cbddop int
+
+const (
+	cbddand cbddop = iota
+	cbddxor
+)
+
+// NewCBDD returns a new decision diagram with complemented edges and varnum
+// variables. The configuration options are the same than with New, but
+// options Journal and Assertions are ignored. We return a nil value if there
+// is an error.
+func NewCBDD(varnum int, options ...func(*configs)) (*CBDD, error) {
+	if (varnum < 1) || (varnum > int(_MAXVAR)) {
+		return nil, fmt.Errorf("bad number of variable (%d)", varnum)
+	}
+	config := makeconfigs(varnum)
+	for _, f := range options {
+		f(config)
+	}
+	c := &CBDD{
+		varnum:   int32(varnum),
+		vars:     make([]int, varnum),
+		refstack: make([]int, 0, 2*varnum+4),
+	}
+	c.core.Minfreenodes = config.minfreenodes
+	c.core.Maxnodeincrease = config.maxnodeincrease
+	c.core.Hash = config.hashfunc
+	c.core.Maxchain = config.maxchain
+	c.core.StopTheWorld = config.resizemode == ResizeStopTheWorld
+	c.core.Complemented = true
+	c.core.Init(config.nodesize, c.varnum)
+	c.nodefinalizer = func(n *int) {
+		c.core.Nodes[*n>>1].Refcou--
+	}
+	size := 10000
+	if config.cachesize != 0 {
+		size = config.cachesize
+	}
+	c.cache.Init(size, config.cacheratio)
+	for k := range c.vars {
+		v := c.makenode(int32(k), cbddfalse, cbddtrue)
+		if v < 0 {
+			return nil, fmt.Errorf("cannot allocate new variable %d in NewCBDD", k)
+		}
+		c.core.Nodes[v>>1].Refcou = _MAXREFCOUNT
+		c.vars[k] = v
+	}
+	return c, nil
+}
+
+// Varnum returns the number of defined variables.
+func (c *CBDD) Varnum() int {
+	return int(c.varnum)
+}
+
+// Error returns the error status of the diagram.
+func (c *CBDD) Error() string {
+	if c.error == nil {
+		return ""
+	}
+	return c.error.Error()
+}
+
+// Errored returns true if there was an error during a computation.
+func (c *CBDD) Errored() bool {
+	return c.error != nil
+}
+
+func (c *CBDD) seterror(format string, a ...interface{}) Node {
+	if c.error != nil {
+		format = format + "; " + c.Error()
+	}
+	c.error = fmt.Errorf(format, a...)
+	if _DEBUG {
+		log.Println(c.error)
+	}
+	return nil
+}
+
+func (c *CBDD) makenode(level int32, low, high int) int {
+	if low < 0 || high < 0 {
+		return -1
+	}
+	res, err := c.core.Makenode(level, low, high, c.refstack)
+	switch err {
+	case dd.ErrReset:
+		c.cache.Reset()
+	case dd.ErrResize:
+		c.cache.Resize(len(c.core.Nodes))
+	case dd.ErrMemory:
+		c.seterror("%s", err)
+	}
+	return res
+}
+
+func (c *CBDD) pushref(e int) int {
+	c.refstack = append(c.refstack, e)
+	return e
+}
+
+func (c *CBDD) retnode(e int) Node {
+	if e < 0 {
+		return nil
+	}
+	x := e
+	if c.core.Nodes[e>>1].Refcou < _MAXREFCOUNT {
+		c.core.Nodes[e>>1].Refcou++
+		runtime.SetFinalizer(&x, c.nodefinalizer)
+	}
+	return &x
+}
+
+func (c *CBDD) checkptr(n Node) error {
+	if n == nil || *n < 0 || *n>>1 == 1 || *n>>1 >= len(c.core.Nodes) || (*n>>1 > 1 && c.core.Nodes[*n>>1].Low == -1) {
+		c.seterror("Illegal acces to node")
+		return c.error
+	}
+	return nil
+}
+
+// cnot returns the negation of edge e, or -1 if e is an error value.
+func cnot(e int) int {
+	if e < 0 {
+		return -1
+	}
+	return e ^ 1
+}
+
+func (c *CBDD) level(e int) int32 {
+	return c.core.Nodes[e>>1].Level
+}
+
+// cofactors returns the low and high successors of e with respect to a
+// variable at the given level, that should be less or equal to the level of e.
+// The successors of a complemented edge are also complemented.
+func (c *CBDD) cofactors(e int, level int32) (int, int) {
+	node := &c.core.Nodes[e>>1]
+	if node.Level != level {
+		return e, e
+	}
+	comp := e & 1
+	return node.Low ^ comp, node.High ^ comp
+}
+
+// True returns the constant true.
+func (c *CBDD) True() Node {
+	return inode(cbddtrue)
+}
+
+// False returns the constant false, the negation of True.
+func (c *CBDD) False() Node {
+	return inode(cbddfalse)
+}
+
+// Ithvar returns the diagram of the i'th variable (the expression xi). The
+// variable must be in the range [0..Varnum).
+func (c *CBDD) Ithvar(i int) Node {
+	if (i < 0) || (int32(i) >= c.varnum) {
+		return c.seterror("Unknown variable used (%d) in call to Ithvar", i)
+	}
+	return inode(c.vars[i])
+}
+
+// NIthvar returns the diagram of the negation of the i'th variable.
+func (c *CBDD) NIthvar(i int) Node {
+	if (i < 0) || (int32(i) >= c.varnum) {
+		return c.seterror("Unknown variable used (%d) in call to NIthvar", i)
+	}
+	return inode(c.vars[i] ^ 1)
+}
+
+// Not returns the negation of n. This is done in constant time, by
+// complementing the edge, and never creates new nodes.
+func (c *CBDD) Not(n Node) Node {
+	if c.checkptr(n) != nil {
+		return c.seterror("Wrong operand in call to Not")
+	}
+	return c.retnode(*n ^ 1)
+}
+
+// Apply performs all of the basic binary operations on diagrams, such as
+// conjunction (OPand) or implication (OPimp), like BDD.Apply. Since negation
+// is free, all the operators are computed from the conjunction and the
+// exclusive or of the operands, or of their negations.
+func (c *CBDD) Apply(n1, n2 Node, op Operator) Node {
+	if c.checkptr(n1) != nil || c.checkptr(n2) != nil {
+		return c.seterror("Wrong operand in call to Apply %s", op)
+	}
+	p, q := *n1, *n2
+	c.refstack = c.refstack[:0]
+	c.pushref(p)
+	c.pushref(q)
+	var res int
+	switch op {
+	case OPand:
+		res = c.and(p, q)
+	case OPxor:
+		res = c.xor(p, q)
+	case OPor:
+		res = cnot(c.and(p^1, q^1))
+	case OPnand:
+		res = cnot(c.and(p, q))
+	case OPnor:
+		res = c.and(p^1, q^1)
+	case OPimp:
+		res = cnot(c.and(p, q^1))
+	case OPbiimp:
+		res = cnot(c.xor(p, q))
+	case OPdiff:
+		res = c.and(p, q^1)
+	case OPless:
+		res = c.and(p^1, q)
+	case OPinvimp:
+		res = cnot(c.and(p^1, q))
+	default:
+		return c.seterror("Unauthorized operation (%s) in call to Apply", op)
+	}
+	return c.retnode(res)
+}
+
+func (c *CBDD) and(p, q int) int {
+	if p < 0 || q < 0 {
+		return -1
+	}
+	switch {
+	case p == cbddtrue || p == q:
+		return q
+	case q == cbddtrue:
+		return p
+	case p == cbddfalse || q == cbddfalse || p == q^1:
+		return cbddfalse
+	}
+	if p > q {
+		p, q = q, p
+	}
+	if e := c.cache.Table[dd.Triple(p, q, int(cbddand), len(c.cache.Table))]; e.A == p && e.B == q && e.C == int(cbddand) {
+		return e.Res
+	}
+	level := c.level(p)
+	if l := c.level(q); l < level {
+		level = l
+	}
+	p0, p1 := c.cofactors(p, level)
+	q0, q1 := c.cofactors(q, level)
+	initial := len(c.refstack)
+	low := c.pushref(c.and(p0, q0))
+	high := c.pushref(c.and(p1, q1))
+	res := c.makenode(level, low, high)
+	c.refstack = c.refstack[:initial]
+	if res >= 0 {
+		// the cache may have been resized during the recursive calls
+		c.cache.Table[dd.Triple(p, q, int(cbddand), len(c.cache.Table))] = dd.Entry4{A: p, B: q, C: int(cbddand), Res: res}
+	}
+	return res
+}
+
+func (c *CBDD) xor(p, q int) int {
+	if p < 0 || q < 0 {
+		return -1
+	}
+	// we remove the complement marks from the operands, since the negation
+	// of an operand negates the result
+	comp := (p ^ q) & 1
+	p, q = p&^1, q&^1
+	switch {
+	case p == q:
+		return cbddfalse ^ comp
+	case p == cbddtrue:
+		return q ^ 1 ^ comp
+	case q == cbddtrue:
+		return p ^ 1 ^ comp
+	}
+	if p > q {
+		p, q = q, p
+	}
+	if e := c.cache.Table[dd.Triple(p, q, int(cbddxor), len(c.cache.Table))]; e.A == p && e.B == q && e.C == int(cbddxor) {
+		return e.Res ^ comp
+	}
+	level := c.level(p)
+	if l := c.level(q); l < level {
+		level = l
+	}
+	p0, p1 := c.cofactors(p, level)
+	q0, q1 := c.cofactors(q, level)
+	initial := len(c.refstack)
+	low := c.pushref(c.xor(p0, q0))
+	high := c.pushref(c.xor(p1, q1))
+	res := c.makenode(level, low, high)
+	c.refstack = c.refstack[:initial]
+	if res < 0 {
+		return -1
+	}
+	c.cache.Table[dd.Triple(p, q, int(cbddxor), len(c.cache.Table))] = dd.Entry4{A: p, B: q, C: int(cbddxor), Res: res}
+	return res ^ comp
+}
+
+// Ite, short for if-then-else operator, computes the diagram for the
+// expression [(f & g) | (!f & h)].
+func (c *CBDD) Ite(f, g, h Node) Node {
+	if c.checkptr(f) != nil || c.checkptr(g) != nil || c.checkptr(h) != nil {
+		return c.seterror("Wrong operand in call to Ite")
+	}
+	c.refstack = c.refstack[:0]
+	c.pushref(*f)
+	c.pushref(*g)
+	c.pushref(*h)
+	a := c.pushref(c.and(*f, *g))
+	b := c.pushref(c.and(*f^1, *h))
+	return c.retnode(cnot(c.and(cnot(a), cnot(b))))
+}
+
+// Exist returns the existential quantification of n for the variables in vars,
+// that must be in the range [0..Varnum).
+func (c *CBDD) Exist(n Node, vars []int) Node {
+	if c.checkptr(n) != nil {
+		return c.seterror("Wrong operand in call to Exist")
+	}
+	set := make([]bool, c.varnum)
+	for _, v := range vars {
+		if (v < 0) || (int32(v) >= c.varnum) {
+			return c.seterror("Unknown variable used (%d) in call to Exist", v)
+		}
+		set[v] = true
+	}
+	c.existID++
+	c.refstack = c.refstack[:0]
+	c.pushref(*n)
+	return c.retnode(c.exist(*n, set, int(cbddxor)+c.existID))
+}
+
+func (c *CBDD) exist(e int, set []bool, id int) int {
+	if e < 0 {
+		return -1
+	}
+	if e>>1 == 0 {
+		return e
+	}
+	h := dd.Pair(e, id, len(c.cache.Table))
+	if r := c.cache.Table[h]; r.A == e && r.B == -1 && r.C == id {
+		return r.Res
+	}
+	level := c.level(e)
+	e0, e1 := c.cofactors(e, level)
+	initial := len(c.refstack)
+	low := c.pushref(c.exist(e0, set, id))
+	high := c.pushref(c.exist(e1, set, id))
+	var res int
+	if set[level] {
+		res = cnot(c.and(cnot(low), cnot(high)))
+	} else {
+		res = c.makenode(level, low, high)
+	}
+	c.refstack = c.refstack[:initial]
+	if res >= 0 {
+		c.cache.Table[dd.Pair(e, id, len(c.cache.Table))] = dd.Entry4{A: e, B: -1, C: id, Res: res}
+	}
+	return res
+}
+
+// Satcount computes the number of satisfying variable assignments for the
+// function denoted by n.
+func (c *CBDD) Satcount(n Node) *big.Int {
+	if c.checkptr(n) != nil {
+		return big.NewInt(0)
+	}
+	// count returns the number of models of e over the variables at levels
+	// [level(e)..varnum).
+	memo := make(map[int]*big.Int)
+	var count func(e int) *big.Int
+	count = func(e int) *big.Int {
+		if e&1 != 0 {
+			res := new(big.Int).Lsh(big.NewInt(1), uint(c.varnum-c.level(e)))
+			return res.Sub(res, count(e^1))
+		}
+		if e == cbddtrue {
+			return big.NewInt(1)
+		}
+		if res, ok := memo[e]; ok {
+			return res
+		}
+		level := c.level(e)
+		node := c.core.Nodes[e>>1]
+		res := new(big.Int).Lsh(count(node.Low), uint(c.level(node.Low)-level-1))
+		res.Add(res, new(big.Int).Lsh(count(node.High), uint(c.level(node.High)-level-1)))
+		memo[e] = res
+		return res
+	}
+	return new(big.Int).Lsh(count(*n), uint(c.level(*n)))
+}
+
+// Eval returns the value of n for an assignment of the variables, given as a
+// slice of Varnum Booleans.
+func (c *CBDD) Eval(n Node, assignment []bool) bool {
+	if c.checkptr(n) != nil {
+		return false
+	}
+	if len(assignment) != int(c.varnum) {
+		c.seterror("Wrong size of assignment (%d) in call to Eval", len(assignment))
+		return false
+	}
+	e, comp := *n, 0
+	for e>>1 != 0 {
+		comp ^= e & 1
+		node := &c.core.Nodes[e>>1]
+		if assignment[node.Level] {
+			e = node.High
+		} else {
+			e = node.Low
+		}
+	}
+	return e^comp == cbddtrue
+}
+
+// NodeCount returns the number of nodes in n, not counting the terminal. A
+// function and its negation have the same number of nodes.
+func (c *CBDD) NodeCount(n Node) int {
+	if c.checkptr(n) != nil {
+		return 0
+	}
+	seen := make(map[int]bool)
+	var visit func(k int)
+	visit = func(k int) {
+		if k < 2 || seen[k] {
+			return
+		}
+		seen[k] = true
+		visit(c.core.Nodes[k].Low >> 1)
+		visit(c.core.Nodes[k].High >> 1)
+	}
+	visit(*n >> 1)
+	return len(seen)
+}
+
+// ToCBDD returns the diagram with complemented edges for the function denoted
+// by n. The two diagrams must have the same number of variables, that are
+// matched by index; the order of variables in b can be different from the
+// identity. We return nil, and set the error flag of c, if there is an error.
+func (b *BDD) ToCBDD(c *CBDD, n Node) Node {
+	if b.checkptr(n) != nil {
+		return c.seterror("Wrong operand in call to ToCBDD (%s)", b.Error())
+	}
+	if c.varnum != b.varnum {
+		return c.seterror("Different number of variables (%d and %d) in call to ToCBDD", b.varnum, c.varnum)
+	}
+	memo := make(map[int]Node)
+	var rec func(k int) Node
+	rec = func(k int) Node {
+		switch k {
+		case 0:
+			return c.False()
+		case 1:
+			return c.True()
+		}
+		if res, ok := memo[k]; ok {
+			return res
+		}
+		res := c.Ite(c.Ithvar(int(b.level2var[b.level(k)])), rec(b.high(k)), rec(b.low(k)))
+		memo[k] = res
+		return res
+	}
+	return rec(*n)
+}
+
+// FromCBDD returns the node of b for the function denoted by n, in a diagram
+// with complemented edges. The two diagrams must have the same number of
+// variables, that are matched by index. This is the inverse of ToCBDD. We
+// return nil, and set the error flag of b, if there is an error.
+func (b *BDD) FromCBDD(c *CBDD, n Node) Node {
+	if b.sealed != nil {
+		return b.sealerror("FromCBDD")
+	}
+	if c.checkptr(n) != nil {
+		return b.seterror("Wrong operand in call to FromCBDD (%s)", c.Error())
+	}
+	if c.varnum != b.varnum {
+		return b.seterror("Different number of variables (%d and %d) in call to FromCBDD", b.varnum, c.varnum)
+	}
+	memo := make(map[int]Node)
+	var rec func(e int) Node
+	rec = func(e int) Node {
+		if e&1 != 0 {
+			return b.Not(rec(e ^ 1))
+		}
+		if e == cbddtrue {
+			return b.True()
+		}
+		if res, ok := memo[e]; ok {
+			return res
+		}
+		node := c.core.Nodes[e>>1]
+		res := b.Ite(b.Ithvar(int(node.Level)), rec(node.High), rec(node.Low))
+		memo[e] = res
+		return res
+	}
+	return rec(*n)
+}
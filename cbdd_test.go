@@ -0,0 +1,137 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// cbddtable returns the values of n for all the assignments of the
+// variables, where assignment k gives to variable v the value of bit v of k.
+func cbddtable(c *CBDD, n Node) []bool {
+	res := make([]bool, 1<<c.Varnum())
+	assignment := make([]bool, c.Varnum())
+	for k := range res {
+		for v := range assignment {
+			assignment[v] = k&(1<<v) != 0
+		}
+		res[k] = c.Eval(n, assignment)
+	}
+	return res
+}
+
+var cbddops = []struct {
+	op Operator
+	f  func(a, b bool) bool
+}{
+	{OPand, func(a, b bool) bool { return a && b }},
+	{OPxor, func(a, b bool) bool { return a != b }},
+	{OPor, func(a, b bool) bool { return a || b }},
+	{OPnand, func(a, b bool) bool { return !(a && b) }},
+	{OPnor, func(a, b bool) bool { return !(a || b) }},
+	{OPimp, func(a, b bool) bool { return !a || b }},
+	{OPbiimp, func(a, b bool) bool { return a == b }},
+	{OPdiff, func(a, b bool) bool { return a && !b }},
+	{OPless, func(a, b bool) bool { return !a && b }},
+	{OPinvimp, func(a, b bool) bool { return a || !b }},
+}
+
+// TestCBDD builds random functions with all the operators, in a small table so
+// that we trigger garbage collections, and compares the results with their
+// truth tables and with BDD.
+func TestCBDD(t *testing.T) {
+	const varnum = 6
+	c, err := NewCBDD(varnum, Nodesize(20), Cachesize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdd, _ := New(varnum)
+	if tt := cbddtable(c, c.False()); tt[0] || tt[len(tt)-1] {
+		t.Errorf("False should be false")
+	}
+	r := rand.New(rand.NewSource(1))
+	funcs := []Node{c.True(), c.False()}
+	tables := [][]bool{cbddtable(c, c.True()), cbddtable(c, c.False())}
+	for v := 0; v < varnum; v++ {
+		funcs = append(funcs, c.Ithvar(v), c.NIthvar(v))
+		tables = append(tables, cbddtable(c, c.Ithvar(v)), cbddtable(c, c.NIthvar(v)))
+	}
+	for k := 0; k < 300; k++ {
+		i, j := r.Intn(len(funcs)), r.Intn(len(funcs))
+		op := cbddops[r.Intn(len(cbddops))]
+		n := c.Apply(funcs[i], funcs[j], op.op)
+		if n == nil {
+			t.Fatal(c.Error())
+		}
+		expected := make([]bool, len(tables[i]))
+		for x := range expected {
+			expected[x] = op.f(tables[i][x], tables[j][x])
+		}
+		tt := cbddtable(c, n)
+		for x := range tt {
+			if tt[x] != expected[x] {
+				t.Fatalf("wrong result for %s at assignment %d", op.op, x)
+			}
+		}
+		count := 0
+		for _, b := range expected {
+			if b {
+				count++
+			}
+		}
+		if actual := c.Satcount(n); actual.Int64() != int64(count) {
+			t.Errorf("Satcount, expected %d, actual %s", count, actual)
+		}
+		x := bdd.FromCBDD(c, n)
+		if bdd.Satcount(x).Int64() != int64(count) {
+			t.Errorf("FromCBDD, wrong number of models")
+		}
+		if back := bdd.ToCBDD(c, x); back == nil || *back != *n {
+			t.Errorf("ToCBDD should return the same edge, expected %d, actual %v", *n, back)
+		}
+		funcs = append(funcs, n)
+		tables = append(tables, tt)
+	}
+	if c.Errored() || bdd.Errored() {
+		t.Fatal(c.Error(), bdd.Error())
+	}
+}
+
+// TestCBDDNot checks that negation only complements edges, and that a parity
+// function needs half the nodes of a BDD.
+func TestCBDDNot(t *testing.T) {
+	const varnum = 10
+	c, _ := NewCBDD(varnum)
+	bdd, _ := New(varnum)
+	parity := c.False()
+	for v := 0; v < varnum; v++ {
+		parity = c.Apply(parity, c.Ithvar(v), OPxor)
+	}
+	produced := c.core.Produced
+	n := c.Not(parity)
+	if c.core.Produced != produced {
+		t.Errorf("Not should not create nodes")
+	}
+	if *c.Not(n) != *parity {
+		t.Errorf("Not(Not(x)) should be x")
+	}
+	if c.NodeCount(parity) != varnum || c.NodeCount(n) != varnum {
+		t.Errorf("expected %d nodes for parity, actual %d", varnum, c.NodeCount(parity))
+	}
+	if b := bdd.FromCBDD(c, parity); bdd.AnodeCount(b) != 2*varnum-1 {
+		t.Errorf("expected %d nodes in a BDD, actual %d", 2*varnum-1, bdd.AnodeCount(b))
+	}
+	if tt := cbddtable(c, c.Ite(c.Ithvar(0), c.Ithvar(1), c.NIthvar(2))); !tt[3] || tt[1] || !tt[0] || tt[4] {
+		t.Errorf("wrong result for Ite")
+	}
+	ex := c.Exist(c.Apply(c.Ithvar(0), c.Ithvar(1), OPand), []int{0})
+	if *ex != *c.Ithvar(1) {
+		t.Errorf("Exist, expected x1")
+	}
+	if *c.Exist(parity, []int{3}) != cbddtrue {
+		t.Errorf("Exist of parity should be True")
+	}
+}
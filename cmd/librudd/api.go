@@ -0,0 +1,297 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dalzilio/rudd"
+)
+
+// node is the value associated with the handle of a node. We keep the session
+// it belongs to, so that operations do not need to be given the manager.
+type node struct {
+	s *session
+	n rudd.Node
+}
+
+// handles maps the integer handles given to foreign code to Go values, either
+// a *session or a node. A handle keeps its value alive, and stays valid, until
+// it is released. The value 0 is never used and denotes an error.
+type handles struct {
+	sync.Mutex
+	next   uint64
+	values map[uint64]interface{}
+	last   string // last error not related to a manager
+}
+
+var registry = handles{values: make(map[uint64]interface{})}
+
+func (h *handles) add(v interface{}) uint64 {
+	h.Lock()
+	defer h.Unlock()
+	h.next++
+	h.values[h.next] = v
+	return h.next
+}
+
+func (h *handles) get(id uint64) interface{} {
+	h.Lock()
+	defer h.Unlock()
+	return h.values[id]
+}
+
+func (h *handles) seterror(format string, a ...interface{}) uint64 {
+	h.Lock()
+	defer h.Unlock()
+	h.last = fmt.Sprintf(format, a...)
+	return 0
+}
+
+// manager returns the session with handle m, or nil if the handle is invalid
+// or if the session has exhausted its time quota. The operation is accounted
+// for in the metrics of the session, see start.
+func manager(m uint64) *session {
+	s, ok := registry.get(m).(*session)
+	if !ok {
+		registry.seterror("invalid manager handle (%d)", m)
+		return nil
+	}
+	if err := s.start(); err != nil {
+		registry.seterror("%s", err)
+		return nil
+	}
+	return s
+}
+
+// operands returns the nodes with the given handles and their session, or nil
+// if one of the handles is invalid, if they belong to different managers, or
+// if the session has exhausted its time quota.
+func operands(ids ...uint64) (*session, []rudd.Node) {
+	var s *session
+	res := make([]rudd.Node, len(ids))
+	for k, id := range ids {
+		v, ok := registry.get(id).(node)
+		if !ok {
+			registry.seterror("invalid node handle (%d)", id)
+			return nil, nil
+		}
+		if s != nil && v.s != s {
+			registry.seterror("nodes from different managers (%d and %d)", ids[0], id)
+			return nil, nil
+		}
+		s, res[k] = v.s, v.n
+	}
+	if err := s.start(); err != nil {
+		registry.seterror("%s", err)
+		return nil, nil
+	}
+	return s, res
+}
+
+// result returns a new handle for n, or 0 if n is nil.
+func result(s *session, n rudd.Node) uint64 {
+	if n == nil {
+		return 0
+	}
+	return registry.add(node{s: s, n: n})
+}
+
+func newbdd(varnum, nodesize, cachesize int) uint64 {
+	return newsession(varnum, nodesize, cachesize, 0, 0)
+}
+
+func newsession(varnum, nodesize, cachesize, maxnodes int, maxtime time.Duration) uint64 {
+	if maxnodes < 0 || maxtime < 0 {
+		return registry.seterror("negative quota in call to rudd_new_session")
+	}
+	b, err := rudd.New(varnum, rudd.Nodesize(nodesize), rudd.Cachesize(cachesize), rudd.Maxnodesize(maxnodes))
+	if err != nil {
+		return registry.seterror("%s", err)
+	}
+	return registry.add(&session{bdd: b, maxnodes: maxnodes, maxtime: maxtime})
+}
+
+func release(id uint64) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.values, id)
+}
+
+// lasterror returns the error status of the manager of handle id, if any, or
+// the last error not related to a manager.
+func lasterror(id uint64) string {
+	switch v := registry.get(id).(type) {
+	case *session:
+		if v.bdd.Errored() {
+			return v.bdd.Error()
+		}
+	case node:
+		if v.s.bdd.Errored() {
+			return v.s.bdd.Error()
+		}
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	return registry.last
+}
+
+func constant(m uint64, value bool) uint64 {
+	s := manager(m)
+	if s == nil {
+		return 0
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	if value {
+		return result(s, b.True())
+	}
+	return result(s, b.False())
+}
+
+func ithvar(m uint64, v int, negated bool) uint64 {
+	s := manager(m)
+	if s == nil {
+		return 0
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	if negated {
+		return result(s, b.NIthvar(v))
+	}
+	return result(s, b.Ithvar(v))
+}
+
+func makeset(m uint64, vars []int) uint64 {
+	s := manager(m)
+	if s == nil {
+		return 0
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	return result(s, b.Makeset(vars))
+}
+
+func not(n uint64) uint64 {
+	s, args := operands(n)
+	if s == nil {
+		return 0
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	return result(s, b.Not(args[0]))
+}
+
+func apply(left, right uint64, op int) uint64 {
+	s, args := operands(left, right)
+	if s == nil {
+		return 0
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	return result(s, b.Apply(args[0], args[1], rudd.Operator(op)))
+}
+
+func ite(f, g, h uint64) uint64 {
+	s, args := operands(f, g, h)
+	if s == nil {
+		return 0
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	return result(s, b.Ite(args[0], args[1], args[2]))
+}
+
+func exist(n, varset uint64) uint64 {
+	s, args := operands(n, varset)
+	if s == nil {
+		return 0
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	return result(s, b.Exist(args[0], args[1]))
+}
+
+// equal returns 1 if the two nodes are equal, 0 if they are different, and -1
+// if there is an error.
+func equal(n1, n2 uint64) int {
+	s, args := operands(n1, n2)
+	if s == nil {
+		return -1
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	if b.Equal(args[0], args[1]) {
+		return 1
+	}
+	return 0
+}
+
+// satcount returns the number of satisfying assignments of n in decimal, or
+// the empty string if there is an error.
+func satcount(n uint64) string {
+	s, args := operands(n)
+	if s == nil {
+		return ""
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	return b.Satcount(args[0]).String()
+}
+
+func nodecount(n uint64) int {
+	s, args := operands(n)
+	if s == nil {
+		return -1
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	return b.AnodeCount(args[0])
+}
+
+// save writes n to file name, in the format of Save.
+func save(name string, n uint64) error {
+	s, args := operands(n)
+	if s == nil {
+		return fmt.Errorf("%s", lasterror(0))
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := b.Save(f, args[0]); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// load reads the first node saved in file name into the manager m.
+func load(m uint64, name string) uint64 {
+	s := manager(m)
+	if s == nil {
+		return 0
+	}
+	defer s.account(time.Now())
+	b := s.bdd
+	f, err := os.Open(name)
+	if err != nil {
+		return registry.seterror("%s", err)
+	}
+	defer f.Close()
+	roots, err := b.Load(f)
+	if err != nil {
+		return registry.seterror("%s", err)
+	}
+	if len(roots) == 0 {
+		return registry.seterror("no node in file %s", name)
+	}
+	return result(s, roots[0])
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dalzilio/rudd"
+)
+
+func TestHandles(t *testing.T) {
+	m := newbdd(4, 1000, 1000)
+	if m == 0 {
+		t.Fatal(lasterror(0))
+	}
+	defer release(m)
+	x := apply(ithvar(m, 0, false), ithvar(m, 1, true), int(rudd.OPand))
+	if s := satcount(x); s != "4" {
+		t.Errorf("expected 4 assignments, actual %s", s)
+	}
+	if n := nodecount(x); n != 2 {
+		t.Errorf("expected 2 nodes, actual %d", n)
+	}
+	y := exist(x, makeset(m, []int{1}))
+	if equal(y, ithvar(m, 0, false)) != 1 {
+		t.Errorf("expected Exist to return variable 0")
+	}
+	if equal(ite(x, constant(m, false), not(x)), not(x)) != 1 {
+		t.Errorf("expected Ite(x, false, !x) to be !x")
+	}
+	file := filepath.Join(t.TempDir(), "x.bdd")
+	if err := save(file, x); err != nil {
+		t.Fatal(err)
+	}
+	other := newbdd(4, 1000, 1000)
+	if equal(load(other, file), apply(ithvar(other, 0, false), ithvar(other, 1, true), int(rudd.OPand))) != 1 {
+		t.Errorf("expected the same node after save and load")
+	}
+	// errors
+	if apply(x, ithvar(other, 0, false), int(rudd.OPand)) != 0 || !strings.Contains(lasterror(0), "different managers") {
+		t.Errorf("expected an error with nodes from different managers")
+	}
+	release(x)
+	if not(x) != 0 || !strings.Contains(lasterror(0), "invalid node handle") {
+		t.Errorf("expected an error with a released handle")
+	}
+	if ithvar(m, 7, false) != 0 || lasterror(m) == "" {
+		t.Errorf("expected an error with an unknown variable")
+	}
+	if newbdd(0, 10, 10) != 0 || lasterror(0) == "" {
+		t.Errorf("expected an error with no variables")
+	}
+}
+
+func TestSession(t *testing.T) {
+	m := newsession(4, 1000, 1000, 0, time.Nanosecond)
+	if m == 0 {
+		t.Fatal(lasterror(0))
+	}
+	defer release(m)
+	x := ithvar(m, 0, false)
+	if x == 0 {
+		t.Fatal(lasterror(0))
+	}
+	time.Sleep(time.Millisecond)
+	if not(x) != 0 || !strings.Contains(lasterror(0), "time quota") {
+		t.Errorf("expected an error once the time quota is exceeded")
+	}
+	var st sessionstats
+	if err := json.Unmarshal([]byte(stats(m)), &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Calls != 1 || st.Rejected != 1 {
+		t.Errorf("expected 1 call and 1 rejected call, actual %+v", st)
+	}
+	// node quota
+	m = newsession(10, 22, 100, 30, 0)
+	defer release(m)
+	f := constant(m, false)
+	for v := 0; v < 10 && f != 0; v++ {
+		f = apply(f, apply(ithvar(m, v, false), ithvar(m, (v+3)%10, false), int(rudd.OPxor)), int(rudd.OPor))
+	}
+	if f != 0 || lasterror(m) == "" {
+		t.Errorf("expected an error once the node quota is exceeded")
+	}
+	if newsession(4, 1000, 1000, -1, 0) != 0 {
+		t.Errorf("expected an error with a negative quota")
+	}
+	// sealing
+	m = newsession(4, 1000, 1000, 0, 0)
+	defer release(m)
+	x = ithvar(m, 0, false)
+	if seal(m) != 0 {
+		t.Fatal(lasterror(0))
+	}
+	if satcount(x) != "8" {
+		t.Errorf("expected queries to work on a sealed session")
+	}
+	if not(x) != 0 {
+		t.Errorf("expected an error when building a node in a sealed session")
+	}
+	if err := json.Unmarshal([]byte(stats(m)), &st); err != nil || !st.Sealed {
+		t.Errorf("expected a sealed session, actual %+v (%v)", st, err)
+	}
+}
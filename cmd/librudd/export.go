@@ -0,0 +1,131 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"time"
+	"unsafe"
+)
+
+//export rudd_new
+func rudd_new(varnum, nodesize, cachesize C.int) C.uint64_t {
+	return C.uint64_t(newbdd(int(varnum), int(nodesize), int(cachesize)))
+}
+
+//export rudd_new_session
+func rudd_new_session(varnum, nodesize, cachesize, maxnodes C.int, maxtimems C.int64_t) C.uint64_t {
+	return C.uint64_t(newsession(int(varnum), int(nodesize), int(cachesize), int(maxnodes), time.Duration(maxtimems)*time.Millisecond))
+}
+
+//export rudd_seal
+func rudd_seal(m C.uint64_t) C.int {
+	return C.int(seal(uint64(m)))
+}
+
+//export rudd_session_stats
+func rudd_session_stats(m C.uint64_t) *C.char {
+	return C.CString(stats(uint64(m)))
+}
+
+//export rudd_release
+func rudd_release(h C.uint64_t) {
+	release(uint64(h))
+}
+
+//export rudd_error
+func rudd_error(h C.uint64_t) *C.char {
+	return C.CString(lasterror(uint64(h)))
+}
+
+//export rudd_free_string
+func rudd_free_string(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+//export rudd_true
+func rudd_true(m C.uint64_t) C.uint64_t {
+	return C.uint64_t(constant(uint64(m), true))
+}
+
+//export rudd_false
+func rudd_false(m C.uint64_t) C.uint64_t {
+	return C.uint64_t(constant(uint64(m), false))
+}
+
+//export rudd_ithvar
+func rudd_ithvar(m C.uint64_t, v C.int) C.uint64_t {
+	return C.uint64_t(ithvar(uint64(m), int(v), false))
+}
+
+//export rudd_nithvar
+func rudd_nithvar(m C.uint64_t, v C.int) C.uint64_t {
+	return C.uint64_t(ithvar(uint64(m), int(v), true))
+}
+
+//export rudd_makeset
+func rudd_makeset(m C.uint64_t, vars *C.int, n C.int) C.uint64_t {
+	varset := make([]int, int(n))
+	if n > 0 {
+		for k, v := range unsafe.Slice(vars, int(n)) {
+			varset[k] = int(v)
+		}
+	}
+	return C.uint64_t(makeset(uint64(m), varset))
+}
+
+//export rudd_not
+func rudd_not(n C.uint64_t) C.uint64_t {
+	return C.uint64_t(not(uint64(n)))
+}
+
+//export rudd_apply
+func rudd_apply(left, right C.uint64_t, op C.int) C.uint64_t {
+	return C.uint64_t(apply(uint64(left), uint64(right), int(op)))
+}
+
+//export rudd_ite
+func rudd_ite(f, g, h C.uint64_t) C.uint64_t {
+	return C.uint64_t(ite(uint64(f), uint64(g), uint64(h)))
+}
+
+//export rudd_exist
+func rudd_exist(n, varset C.uint64_t) C.uint64_t {
+	return C.uint64_t(exist(uint64(n), uint64(varset)))
+}
+
+//export rudd_equal
+func rudd_equal(n1, n2 C.uint64_t) C.int {
+	return C.int(equal(uint64(n1), uint64(n2)))
+}
+
+//export rudd_satcount
+func rudd_satcount(n C.uint64_t) *C.char {
+	return C.CString(satcount(uint64(n)))
+}
+
+//export rudd_nodecount
+func rudd_nodecount(n C.uint64_t) C.int {
+	return C.int(nodecount(uint64(n)))
+}
+
+//export rudd_save
+func rudd_save(name *C.char, n C.uint64_t) C.int {
+	if err := save(C.GoString(name), uint64(n)); err != nil {
+		registry.seterror("%s", err)
+		return -1
+	}
+	return 0
+}
+
+//export rudd_load
+func rudd_load(m C.uint64_t, name *C.char) C.uint64_t {
+	return C.uint64_t(load(uint64(m), C.GoString(name)))
+}
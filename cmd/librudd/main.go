@@ -0,0 +1,48 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Command librudd is a C-ABI layer over package rudd, meant to be built as a
+shared library, so that rudd can be used from C or from languages with a
+foreign function interface, such as Python or Rust:
+
+	go build -buildmode=c-shared -o librudd.so ./cmd/librudd
+
+This also generates a header file, librudd.h, with the declarations of the
+exported functions, all prefixed with rudd_. Managers and nodes are denoted by
+integer handles (of type uint64_t), that stay valid until they are released
+with rudd_release; a node handle keeps the node alive, and remembers the
+manager it belongs to. Functions return the handle 0, or a negative value, in
+case of error, and the reason is given by rudd_error. For instance
+
+	uint64_t m = rudd_new(4, 1000, 1000);
+	uint64_t x = rudd_apply(rudd_ithvar(m, 0), rudd_nithvar(m, 1), 0);
+	char *s = rudd_satcount(x);
+	printf("%s\n", s);
+	rudd_free_string(s);
+	rudd_release(x);
+	rudd_release(m);
+
+where 0 is the code of operator OPand in package rudd. The handles of the
+intermediate results, above, are never released, which leaks the
+corresponding nodes. Strings returned by the library must be freed with
+rudd_free_string. Nodes can be exchanged with other programs, in the format of
+rudd.Save, using rudd_save and rudd_load.
+
+Each manager is a session, with its own node table, caches and error flag, so
+that clients sharing the library are isolated from each other. A session
+created with rudd_new_session can also be given quotas: a maximal number of
+nodes, which bounds the memory used by the session, and a maximal time spent
+in operations, in milliseconds, after which every call is rejected with an
+error. The time quota is checked between calls, since an operation cannot be
+interrupted. A value of 0 means that there is no limit. Function
+rudd_session_stats returns the metrics of a session, such as the number of
+calls, of rejected calls, and of live nodes, as a JSON object, and rudd_seal
+makes a session read-only, so that it can be queried from several threads.
+
+The library requires cgo; without it, the command builds to an empty program.
+*/
+package main
+
+func main() {}
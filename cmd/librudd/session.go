@@ -0,0 +1,104 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dalzilio/rudd"
+)
+
+// session is the value associated with the handle of a manager. Each session
+// has its own BDD, so that the nodes, caches and errors of two sessions are
+// isolated from each other, together with its own quotas and metrics. The
+// memory used by a session is bounded by its node quota, since the node table
+// is the only structure of a BDD that grows without limit.
+type session struct {
+	sync.Mutex
+	bdd      *rudd.BDD
+	maxnodes int           // maximal number of nodes, 0 if there is no limit
+	maxtime  time.Duration // maximal time spent in operations, 0 if there is no limit
+	elapsed  time.Duration // time spent in operations
+	calls    int           // number of operations
+	rejected int           // number of operations rejected because of the time quota
+	sealed   bool          // set by rudd_seal
+}
+
+// sessionstats are the metrics of a session returned by rudd_session_stats,
+// in JSON.
+type sessionstats struct {
+	Calls     int   `json:"calls"`
+	Rejected  int   `json:"rejected"`
+	ElapsedMs int64 `json:"elapsed_ms"`
+	MaxtimeMs int64 `json:"maxtime_ms"`
+	Maxnodes  int   `json:"maxnodes"`
+	Live      int   `json:"live"`
+	Allocated int   `json:"allocated"`
+	Sealed    bool  `json:"sealed"`
+}
+
+// start returns an error if s has exhausted its time quota. The quota is only
+// checked before an operation, since operations cannot be interrupted, so a
+// session can go over its quota by the duration of one operation.
+func (s *session) start() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.maxtime > 0 && s.elapsed >= s.maxtime {
+		s.rejected++
+		return fmt.Errorf("time quota of the session exceeded (%s)", s.maxtime)
+	}
+	s.calls++
+	return nil
+}
+
+// account adds the time spent since start to the metrics of s.
+func (s *session) account(start time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.elapsed += time.Since(start)
+}
+
+// seal makes the BDD of session m read-only, with SealForQueries, so that it
+// can be queried from several threads at the same time.
+func seal(m uint64) int {
+	s := manager(m)
+	if s == nil {
+		return -1
+	}
+	defer s.account(time.Now())
+	s.Lock()
+	defer s.Unlock()
+	if !s.sealed {
+		s.bdd.SealForQueries()
+		s.sealed = true
+	}
+	return 0
+}
+
+// stats returns the metrics of session m, in JSON, or the empty string if
+// there is an error. This call is not counted in the metrics.
+func stats(m uint64) string {
+	s, ok := registry.get(m).(*session)
+	if !ok {
+		registry.seterror("invalid manager handle (%d)", m)
+		return ""
+	}
+	s.Lock()
+	res := sessionstats{
+		Calls:     s.calls,
+		Rejected:  s.rejected,
+		ElapsedMs: s.elapsed.Milliseconds(),
+		MaxtimeMs: s.maxtime.Milliseconds(),
+		Maxnodes:  s.maxnodes,
+		Sealed:    s.sealed,
+	}
+	s.Unlock()
+	res.Live, res.Allocated = s.bdd.Live(), s.bdd.Allocated()
+	buf, _ := json.Marshal(res)
+	return string(buf)
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dalzilio/rudd"
+)
+
+// cover is the definition of a signal in a BLIF file, given by a .names
+// directive: the signal is the disjunction of the cubes (rows), over the
+// inputs, if onset is true, and its negation otherwise.
+type cover struct {
+	inputs []string
+	rows   []string
+	onset  bool
+}
+
+// readblif reads a combinational circuit in BLIF format. We only support a
+// single model, with directives .model, .inputs, .outputs, .names and .end.
+func (in *interp) readblif(r io.Reader) error {
+	inputs, outputs := []string{}, []string{}
+	covers := map[string]*cover{}
+	var current *cover
+	scanner := bufio.NewScanner(r)
+	for count := 1; scanner.Scan(); count++ {
+		// errors report the first line of a directive continued with \
+		line := count
+		text := scanner.Text()
+		for strings.HasSuffix(text, "\\") && scanner.Scan() {
+			count++
+			text = strings.TrimSuffix(text, "\\") + " " + scanner.Text()
+		}
+		if k := strings.IndexByte(text, '#'); k >= 0 {
+			text = text[:k]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(fields[0], ".") {
+			if current == nil {
+				return fmt.Errorf("line %d: cube outside of a .names directive", line)
+			}
+			if err := current.add(fields); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+		current = nil
+		switch fields[0] {
+		case ".model":
+		case ".inputs":
+			inputs = append(inputs, fields[1:]...)
+		case ".outputs":
+			outputs = append(outputs, fields[1:]...)
+		case ".names":
+			if len(fields) < 2 {
+				return fmt.Errorf("line %d: .names without output", line)
+			}
+			out := fields[len(fields)-1]
+			if _, ok := covers[out]; ok {
+				return fmt.Errorf("line %d: signal %q defined twice", line, out)
+			}
+			current = &cover{inputs: fields[1 : len(fields)-1], onset: true}
+			covers[out] = current
+		case ".end":
+			return in.buildblif(inputs, outputs, covers)
+		default:
+			return fmt.Errorf("line %d: unsupported directive %s", line, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return in.buildblif(inputs, outputs, covers)
+}
+
+// add adds a row of the cover, given as a list of fields. A constant signal
+// has no inputs and its rows are made of a single output value.
+func (c *cover) add(fields []string) error {
+	plane, value := "", fields[len(fields)-1]
+	if len(c.inputs) > 0 {
+		if len(fields) != 2 {
+			return fmt.Errorf("malformed cube %q", strings.Join(fields, " "))
+		}
+		plane = fields[0]
+	} else if len(fields) != 1 {
+		return fmt.Errorf("malformed cube %q", strings.Join(fields, " "))
+	}
+	if len(plane) != len(c.inputs) || strings.Trim(plane, "01-") != "" {
+		return fmt.Errorf("malformed cube %q", plane)
+	}
+	if value != "0" && value != "1" {
+		return fmt.Errorf("malformed output value %q", value)
+	}
+	if len(c.rows) > 0 && c.onset != (value == "1") {
+		return fmt.Errorf("cover mixes on-set and off-set rows")
+	}
+	c.onset = value == "1"
+	c.rows = append(c.rows, plane)
+	return nil
+}
+
+// buildblif declares the inputs of a circuit as variables and defines a
+// function for each one of its outputs.
+func (in *interp) buildblif(inputs, outputs []string, covers map[string]*cover) error {
+	if err := in.declare(inputs...); err != nil {
+		return err
+	}
+	b := in.bdd
+	if b == nil {
+		return fmt.Errorf("no variables declared and no inputs in the circuit")
+	}
+	signals := map[string]rudd.Node{}
+	for _, name := range inputs {
+		signals[name] = b.Ithvar(in.vars[name])
+	}
+	// we resolve signals recursively, using a nil entry in signals to detect
+	// cycles
+	var resolve func(name string) (rudd.Node, error)
+	resolve = func(name string) (rudd.Node, error) {
+		if n, ok := signals[name]; ok {
+			if n == nil {
+				return nil, fmt.Errorf("combinational cycle on signal %q", name)
+			}
+			return n, nil
+		}
+		c, ok := covers[name]
+		if !ok {
+			return nil, fmt.Errorf("signal %q is not defined", name)
+		}
+		signals[name] = nil
+		args := make([]rudd.Node, len(c.inputs))
+		for k, input := range c.inputs {
+			n, err := resolve(input)
+			if err != nil {
+				return nil, err
+			}
+			args[k] = n
+		}
+		res := b.False()
+		for _, row := range c.rows {
+			cube := b.True()
+			for k, v := range row {
+				switch v {
+				case '0':
+					cube = b.And(cube, b.Not(args[k]))
+				case '1':
+					cube = b.And(cube, args[k])
+				}
+			}
+			res = b.Or(res, cube)
+		}
+		if !c.onset {
+			res = b.Not(res)
+		}
+		signals[name] = res
+		return res, nil
+	}
+	for _, name := range outputs {
+		n, err := resolve(name)
+		if err != nil {
+			return err
+		}
+		if b.Errored() {
+			return fmt.Errorf("%s", b.Error())
+		}
+		if err := in.define(name, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
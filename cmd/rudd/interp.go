@@ -0,0 +1,380 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dalzilio/rudd"
+	"github.com/dalzilio/rudd/internal/dimacs"
+)
+
+// interp is the interpreter of scripts. It holds a BDD, created when the first
+// variable is declared, together with the names of the variables and of the
+// functions defined by the script.
+type interp struct {
+	bdd     *rudd.BDD
+	vars    map[string]int       // index of each variable
+	names   []string             // name of each variable
+	funcs   map[string]rudd.Node // functions defined in the script
+	defined []string             // names of the functions, in order of definition
+	out     io.Writer
+}
+
+func newinterp(out io.Writer) *interp {
+	return &interp{
+		vars:  make(map[string]int),
+		funcs: make(map[string]rudd.Node),
+		out:   out,
+	}
+}
+
+// declare adds the variables in names, at the bottom of the variable order,
+// and ignores the ones that are already declared.
+func (in *interp) declare(names ...string) error {
+	fresh := []string{}
+	for _, name := range names {
+		if _, ok := in.funcs[name]; ok {
+			return fmt.Errorf("%q is already a function", name)
+		}
+		if _, ok := in.vars[name]; !ok && !contains(fresh, name) {
+			if name == "true" || name == "false" {
+				return fmt.Errorf("%q cannot be used as a variable", name)
+			}
+			fresh = append(fresh, name)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	if in.bdd == nil {
+		b, err := rudd.New(len(fresh))
+		if err != nil {
+			return err
+		}
+		in.bdd = b
+	} else if _, err := in.bdd.ExtVarnum(len(fresh)); err != nil {
+		return err
+	}
+	for _, name := range fresh {
+		in.vars[name] = len(in.names)
+		in.names = append(in.names, name)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// define associates the function n with name.
+func (in *interp) define(name string, n rudd.Node) error {
+	if _, ok := in.vars[name]; ok {
+		return fmt.Errorf("%q is already a variable", name)
+	}
+	if name == "true" || name == "false" {
+		return fmt.Errorf("%q cannot be redefined", name)
+	}
+	if _, ok := in.funcs[name]; !ok {
+		in.defined = append(in.defined, name)
+	}
+	in.funcs[name] = n
+	return nil
+}
+
+// lookup returns the node for a function, a variable or a constant.
+func (in *interp) lookup(name string) (rudd.Node, error) {
+	if in.bdd == nil {
+		return nil, fmt.Errorf("no variables declared (unknown name %q)", name)
+	}
+	switch name {
+	case "true":
+		return in.bdd.True(), nil
+	case "false":
+		return in.bdd.False(), nil
+	}
+	if n, ok := in.funcs[name]; ok {
+		return n, nil
+	}
+	if v, ok := in.vars[name]; ok {
+		return in.bdd.Ithvar(v), nil
+	}
+	return nil, fmt.Errorf("unknown name %q", name)
+}
+
+// run executes the script read from r, where each line is a command. We stop
+// at the first error, which mentions the line number.
+func (in *interp) run(r io.Reader, source string) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if err := in.exec(scanner.Text()); err != nil {
+			return fmt.Errorf("%s:%d: %w", source, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// exec executes a line of the script, where commands can be separated by
+// semicolons and comments start with #.
+func (in *interp) exec(line string) error {
+	if k := strings.IndexByte(line, '#'); k >= 0 {
+		line = line[:k]
+	}
+	for _, cmd := range strings.Split(line, ";") {
+		if err := in.command(strings.TrimSpace(cmd)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// command executes a single command.
+func (in *interp) command(cmd string) error {
+	if cmd == "" {
+		return nil
+	}
+	if k := strings.IndexByte(cmd, '='); k > 0 {
+		name := strings.TrimSpace(cmd[:k])
+		if strings.IndexFunc(name, func(r rune) bool { return !isident(r) }) < 0 {
+			n, err := in.eval(cmd[k+1:])
+			if err != nil {
+				return err
+			}
+			return in.define(name, n)
+		}
+	}
+	fields := strings.Fields(cmd)
+	op, arg := fields[0], strings.TrimSpace(strings.TrimPrefix(cmd, fields[0]))
+	switch op {
+	case "var", "vars":
+		return in.declare(fields[1:]...)
+	case "read":
+		if len(fields) < 2 || len(fields) > 3 {
+			return fmt.Errorf("usage: read file [name]")
+		}
+		return in.read(fields[1], fields[2:]...)
+	case "save":
+		if len(fields) < 3 {
+			return fmt.Errorf("usage: save file name ...")
+		}
+		return in.save(fields[1], fields[2:])
+	case "list":
+		for _, name := range in.defined {
+			fmt.Fprintf(in.out, "%s: %d nodes\n", name, in.bdd.AnodeCount(in.funcs[name]))
+		}
+		return nil
+	case "order":
+		if in.bdd == nil {
+			return fmt.Errorf("no variables declared")
+		}
+		order := []string{}
+		for _, v := range in.bdd.VarOrder() {
+			order = append(order, in.names[v])
+		}
+		fmt.Fprintln(in.out, strings.Join(order, " "))
+		return nil
+	case "reorder":
+		if in.bdd == nil {
+			return fmt.Errorf("no variables declared")
+		}
+		if len(fields) == 1 {
+			return in.bdd.Reorder(rudd.ReorderSift)
+		}
+		for _, method := range []rudd.ReorderMethod{rudd.ReorderSift, rudd.ReorderSymSift} {
+			if method.String() == fields[1] {
+				return in.bdd.Reorder(method)
+			}
+		}
+		return fmt.Errorf("unknown reordering method %q", fields[1])
+	case "satcount", "support", "nodes", "profile", "print", "dot":
+		n, err := in.eval(arg)
+		if err != nil {
+			return err
+		}
+		return in.output(op, n)
+	}
+	return fmt.Errorf("unknown command %q", op)
+}
+
+// output prints information about n, depending on op.
+func (in *interp) output(op string, n rudd.Node) error {
+	b := in.bdd
+	switch op {
+	case "satcount":
+		// we count the assignments of the declared variables
+		fmt.Fprintln(in.out, b.Satcount(n))
+	case "support":
+		names := []string{}
+		for _, v := range b.SupportSet(n) {
+			names = append(names, in.names[v])
+		}
+		fmt.Fprintln(in.out, strings.Join(names, " "))
+	case "nodes":
+		fmt.Fprintln(in.out, b.AnodeCount(n))
+	case "profile":
+		// we list variables by level, to show where the graph is the widest
+		profile := b.Varprofile(n)
+		for _, v := range b.VarOrder() {
+			if profile[v] > 0 {
+				fmt.Fprintf(in.out, "%s: %d\n", in.names[v], profile[v])
+			}
+		}
+	case "print":
+		return in.print(n)
+	case "dot":
+		return in.dot(n)
+	}
+	return nil
+}
+
+// print writes the cubes of n, one per line, where each cube is the list of
+// its literals; or true and false for the constants.
+func (in *interp) print(n rudd.Node) error {
+	b := in.bdd
+	if *n < 2 {
+		fmt.Fprintln(in.out, *n == 1)
+		return nil
+	}
+	return b.Allsat(func(cube []int) error {
+		lits := []string{}
+		for v, val := range cube {
+			switch val {
+			case 0:
+				lits = append(lits, "!"+in.names[v])
+			case 1:
+				lits = append(lits, in.names[v])
+			}
+		}
+		_, err := fmt.Fprintln(in.out, strings.Join(lits, " "))
+		return err
+	}, n)
+}
+
+// dot writes the graph of n in the dot format of Graphviz, where nodes are
+// labelled with the names of variables. Like with rudd.Dot, we do not draw the
+// edges to False.
+func (in *interp) dot(n rudd.Node) error {
+	b := in.bdd
+	lines := []string{}
+	err := b.Allnodes(func(id, level, low, high int) error {
+		if id < 2 {
+			return nil
+		}
+		lines = append(lines, fmt.Sprintf("%d [label=%q];", id, in.names[b.Level2Var(level)]))
+		if low != 0 {
+			lines = append(lines, fmt.Sprintf("%d -> %d [style=dotted];", id, low))
+		}
+		if high != 0 {
+			lines = append(lines, fmt.Sprintf("%d -> %d;", id, high))
+		}
+		return nil
+	}, n)
+	if err != nil {
+		return err
+	}
+	sort.Strings(lines)
+	fmt.Fprintln(in.out, "digraph G {")
+	fmt.Fprintln(in.out, "1 [shape=box, label=\"1\"];")
+	if *n == 0 {
+		fmt.Fprintln(in.out, "0 [shape=box, label=\"0\"];")
+	}
+	for _, line := range lines {
+		fmt.Fprintln(in.out, line)
+	}
+	fmt.Fprintln(in.out, "}")
+	return nil
+}
+
+// read defines new functions from file, depending on its extension. For a CNF
+// formula in DIMACS format (extension .cnf or .dimacs), we define a function
+// with the given name, or the base name of the file, and declare variables x1
+// to xN. For a combinational circuit in BLIF format (extension .blif), we
+// declare the inputs as variables and define a function for each output.
+func (in *interp) read(file string, name ...string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ext := filepath.Ext(file)
+	switch ext {
+	case ".cnf", ".dimacs":
+		fname := strings.TrimSuffix(filepath.Base(file), ext)
+		if len(name) > 0 {
+			fname = name[0]
+		}
+		return in.readcnf(f, fname)
+	case ".blif":
+		if len(name) > 0 {
+			return fmt.Errorf("the functions defined by a BLIF file use the names of the outputs")
+		}
+		return in.readblif(f)
+	}
+	return fmt.Errorf("unknown format for file %s", file)
+}
+
+// readcnf defines name as the conjunction of the clauses of a CNF formula.
+func (in *interp) readcnf(r io.Reader, name string) error {
+	varnum, clauses, err := dimacs.Parse(r)
+	if err != nil {
+		return err
+	}
+	vars := make([]string, varnum)
+	for k := range vars {
+		vars[k] = fmt.Sprintf("x%d", k+1)
+	}
+	if err := in.declare(vars...); err != nil {
+		return err
+	}
+	b := in.bdd
+	res := b.True()
+	for _, clause := range clauses {
+		c := b.False()
+		for _, lit := range clause {
+			if lit > 0 {
+				c = b.Or(c, b.Ithvar(in.vars[vars[lit-1]]))
+			} else {
+				c = b.Or(c, b.NIthvar(in.vars[vars[-lit-1]]))
+			}
+		}
+		res = b.And(res, c)
+	}
+	if b.Errored() {
+		return fmt.Errorf("%s", b.Error())
+	}
+	return in.define(name, res)
+}
+
+// save writes the functions in names to file, in the format of SaveManager.
+func (in *interp) save(file string, names []string) error {
+	named := make(map[string]rudd.Node, len(names))
+	for _, name := range names {
+		n, ok := in.funcs[name]
+		if !ok {
+			return fmt.Errorf("unknown function %q", name)
+		}
+		named[name] = n
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := in.bdd.SaveManager(f, named); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
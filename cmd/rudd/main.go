@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Command rudd reads Boolean functions, from expressions or from files, and
+performs operations on them given as a script. For instance
+
+	rudd -e 'var a b c; f = (a | b) & !c; g = exists(a, f); satcount g; print g'
+
+declares three variables, defines two functions, and prints the number of
+satisfying assignments of g (over the three variables) and the list of its
+cubes. Usage is
+
+	rudd [-i] [-e script] [file ...]
+
+Files with extension .cnf (or .dimacs) and .blif are read as inputs before
+executing the script: a CNF formula in DIMACS format defines a function named
+after the file, over variables x1 to xN; and a combinational circuit in BLIF
+format defines a function for each of its outputs, over its inputs. Other files
+are scripts, executed in order. Without script, we print a summary of the
+functions defined by the inputs; and without files, we read the script on the
+standard input. With option -i, we start an interactive session after reading
+the files and executing the scripts, where errors do not stop the interpreter
+and command help lists the available commands.
+
+Scripts have one command per line, or separated by semicolons, and comments
+start with #. The commands are:
+
+	var a b ...          declare variables, at the bottom of the order
+	f = expr             define function f
+	read file [f]        read a DIMACS or BLIF file
+	satcount expr        print the number of satisfying assignments
+	support expr         print the variables in the support
+	nodes expr           print the number of nodes
+	profile expr         print the number of nodes for each variable
+	print expr           print the cubes of a function
+	dot expr             print the BDD in the dot format of Graphviz
+	save file f ...      save functions in the format of SaveManager
+	list                 print the size of all the functions
+	order                print the current order of variables
+	reorder [method]     reorder variables (sift or symsift)
+
+Expressions use the constants true and false, variables, functions, negation
+(! or ~), and the binary operators &, ^, |, -> and <->, by decreasing order of
+precedence. We also support the following operations:
+
+	exists(a b ..., expr)    existential quantification
+	forall(a b ..., expr)    universal quantification
+	ite(f, g, h)             if-then-else
+	rename(expr, a:b ...)    rename variable a into b, ...
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	script := flag.String("e", "", "execute `script` after reading the input files")
+	interactive := flag.Bool("i", false, "start an interactive session")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: rudd [-i] [-e script] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("rudd: ")
+	if err := run(os.Stdin, os.Stdout, *script, *interactive, flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run executes the command with the given script and files, reading scripts
+// (or commands, in interactive mode) from stdin if needed.
+func run(stdin io.Reader, stdout io.Writer, script string, interactive bool, files []string) error {
+	in := newinterp(stdout)
+	scripts := []string{}
+	for _, file := range files {
+		switch filepath.Ext(file) {
+		case ".cnf", ".dimacs", ".blif":
+			if err := in.read(file); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		default:
+			scripts = append(scripts, file)
+		}
+	}
+	for _, file := range scripts {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		err = in.run(f, file)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if script != "" {
+		if err := in.run(strings.NewReader(script), "-e"); err != nil {
+			return err
+		}
+	}
+	if interactive {
+		return in.repl(stdin, stdout)
+	}
+	if script != "" || len(scripts) > 0 {
+		return nil
+	}
+	if len(files) > 0 {
+		return in.exec("list")
+	}
+	return in.run(stdin, "stdin")
+}
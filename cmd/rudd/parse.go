@@ -0,0 +1,293 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dalzilio/rudd"
+)
+
+// token is a lexical unit of an expression. The text of punctuation tokens,
+// such as "&" or "->", is also their kind; identifiers have kind "ident".
+type token struct {
+	kind string
+	text string
+}
+
+// symbols are the punctuation tokens, longest first.
+var symbols = []string{"<->", "->", "(", ")", ",", ":", "!", "~", "&", "|", "^"}
+
+// isident returns true if r can be part of an identifier. We accept primes
+// and brackets, as in x' or x[3].
+func isident(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.'[]", r)
+}
+
+// lex splits s into tokens.
+func lex(s string) ([]token, error) {
+	res := []token{}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if k := strings.IndexFunc(s, func(r rune) bool { return !isident(r) }); k != 0 {
+			if k < 0 {
+				k = len(s)
+			}
+			res = append(res, token{kind: "ident", text: s[:k]})
+			s = s[k:]
+			continue
+		}
+		found := false
+		for _, sym := range symbols {
+			if strings.HasPrefix(s, sym) {
+				res = append(res, token{kind: sym, text: sym})
+				s = s[len(sym):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unexpected character %q", s[0])
+		}
+	}
+	return res, nil
+}
+
+// parser evaluates an expression, using the variables and functions defined in
+// an interpreter. We use a recursive descent parser, where the binary
+// operators are, by increasing precedence: <->, -> (right associative), |, ^
+// and &; negation (! or ~) has the highest precedence.
+type parser struct {
+	in   *interp
+	toks []token
+	pos  int
+}
+
+// eval returns the node for expression s.
+func (in *interp) eval(s string) (rudd.Node, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("missing expression")
+	}
+	p := &parser{in: in, toks: toks}
+	n, err := p.equiv()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q after expression", p.toks[p.pos].text)
+	}
+	if in.bdd != nil && in.bdd.Errored() {
+		return nil, fmt.Errorf("%s", in.bdd.Error())
+	}
+	return n, nil
+}
+
+// next returns true, and consumes the next token, if it has the given kind.
+func (p *parser) next(kind string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind string) error {
+	if p.next(kind) {
+		return nil
+	}
+	if p.pos < len(p.toks) {
+		return fmt.Errorf("expected %q, found %q", kind, p.toks[p.pos].text)
+	}
+	return fmt.Errorf("expected %q at end of expression", kind)
+}
+
+// binary parses a sequence of operands, with operator sym between them, and
+// combines them with op, from left to right.
+func (p *parser) binary(operand func() (rudd.Node, error), sym string, op rudd.Operator) (rudd.Node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.next(sym) {
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = p.in.bdd.Apply(left, right, op)
+	}
+	return left, nil
+}
+
+func (p *parser) equiv() (rudd.Node, error) {
+	return p.binary(p.imp, "<->", rudd.OPbiimp)
+}
+
+func (p *parser) imp() (rudd.Node, error) {
+	left, err := p.or()
+	if err != nil || !p.next("->") {
+		return left, err
+	}
+	right, err := p.imp()
+	if err != nil {
+		return nil, err
+	}
+	return p.in.bdd.Imp(left, right), nil
+}
+
+func (p *parser) or() (rudd.Node, error) {
+	return p.binary(p.xor, "|", rudd.OPor)
+}
+
+func (p *parser) xor() (rudd.Node, error) {
+	return p.binary(p.and, "^", rudd.OPxor)
+}
+
+func (p *parser) and() (rudd.Node, error) {
+	return p.binary(p.unary, "&", rudd.OPand)
+}
+
+func (p *parser) unary() (rudd.Node, error) {
+	if p.next("!") || p.next("~") {
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return p.in.bdd.Not(n), nil
+	}
+	return p.atom()
+}
+
+func (p *parser) atom() (rudd.Node, error) {
+	if p.next("(") {
+		n, err := p.equiv()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	}
+	if p.pos >= len(p.toks) || p.toks[p.pos].kind != "ident" {
+		if p.pos < len(p.toks) {
+			return nil, fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+		}
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	name := p.toks[p.pos].text
+	p.pos++
+	if p.next("(") {
+		return p.call(name)
+	}
+	return p.in.lookup(name)
+}
+
+// call parses the arguments of a function and applies it. The functions are
+// exists(vars, e), forall(vars, e), ite(f, g, h) and rename(e, old:new ...).
+func (p *parser) call(name string) (rudd.Node, error) {
+	b := p.in.bdd
+	switch name {
+	case "exists", "forall":
+		vars, err := p.vars()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		n, err := p.equiv()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if name == "exists" {
+			return b.Exist(n, b.Makeset(vars)), nil
+		}
+		return b.Not(b.Exist(b.Not(n), b.Makeset(vars))), nil
+	case "ite":
+		args := make([]rudd.Node, 3)
+		for k := range args {
+			if k > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			n, err := p.equiv()
+			if err != nil {
+				return nil, err
+			}
+			args[k] = n
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return b.Ite(args[0], args[1], args[2]), nil
+	case "rename":
+		n, err := p.equiv()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		oldvars, newvars := []int{}, []int{}
+		for !p.next(")") {
+			pair := [2]int{}
+			for k := range pair {
+				if k == 1 {
+					if err := p.expect(":"); err != nil {
+						return nil, err
+					}
+				}
+				v, err := p.variable()
+				if err != nil {
+					return nil, err
+				}
+				pair[k] = v
+			}
+			oldvars, newvars = append(oldvars, pair[0]), append(newvars, pair[1])
+		}
+		r, err := b.NewReplacer(oldvars, newvars)
+		if err != nil {
+			return nil, err
+		}
+		return b.Replace(n, r), nil
+	}
+	return nil, fmt.Errorf("unknown function %q", name)
+}
+
+// vars parses a non-empty list of variables.
+func (p *parser) vars() ([]int, error) {
+	res := []int{}
+	for p.pos < len(p.toks) && p.toks[p.pos].kind == "ident" {
+		v, err := p.variable()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("expected a list of variables")
+	}
+	return res, nil
+}
+
+// variable parses the name of a variable and returns its index.
+func (p *parser) variable() (int, error) {
+	if p.pos >= len(p.toks) || p.toks[p.pos].kind != "ident" {
+		return 0, fmt.Errorf("expected a variable")
+	}
+	name := p.toks[p.pos].text
+	p.pos++
+	v, ok := p.in.vars[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown variable %q", name)
+	}
+	return v, nil
+}
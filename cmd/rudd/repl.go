@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// help is the text printed by command help in interactive mode.
+const help = `commands:
+  var a b ...          declare variables, at the bottom of the order
+  f = expr             define function f
+  read file [f]        read a DIMACS or BLIF file
+  satcount expr        print the number of satisfying assignments
+  support expr         print the variables in the support
+  nodes expr           print the number of nodes
+  profile expr         print the number of nodes for each variable
+  print expr           print the cubes of a function
+  dot expr             print the BDD in the dot format of Graphviz
+  save file f ...      save functions in the format of SaveManager
+  list                 print the size of all the functions
+  order                print the current order of variables
+  reorder [method]     reorder variables (sift or symsift)
+  help                 print this message
+  quit                 leave the interpreter
+operators, by decreasing order of precedence:
+  ! ~  &  ^  |  ->  <->
+  exists(a b ..., e)  forall(a b ..., e)  ite(f, g, h)  rename(e, a:b ...)
+`
+
+// repl runs the interpreter in interactive mode: we print a prompt before
+// reading each line and, unlike with scripts, we report errors and go on with
+// the next line. We stop at the end of the input or with command quit.
+func (in *interp) repl(r io.Reader, prompt io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(prompt, "rudd> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(prompt)
+			return scanner.Err()
+		}
+		switch strings.TrimSpace(scanner.Text()) {
+		case "quit", "exit":
+			return nil
+		case "help", "?":
+			fmt.Fprint(in.out, help)
+			continue
+		}
+		if err := in.exec(scanner.Text()); err != nil {
+			fmt.Fprintf(in.out, "error: %s\n", err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	for _, tt := range []struct {
+		script   string
+		expected string
+	}{
+		{"var a b c; f = (a | b) & !c; satcount f", "3"},
+		{"var a b c; satcount a -> b -> c", "7"},
+		{"var a b; satcount a <-> b; satcount a ^ b; satcount ~a & b", "2\n2\n1"},
+		{"var a b c; f = (a | b) & !c; g = exists(a, f); print g", "!c"},
+		{"var a b; print forall(a, a | b); print forall(a b, a | !a)", "b\ntrue"},
+		{"var a b c; support ite(a, b, c); support true", "a b c\n"},
+		{"var a b c; print rename(a & !b, a:c); nodes a & b", "!b c\n2"},
+		{"var a b c; profile (a | b) & c; order", "a: 1\nb: 1\nc: 1\na b c"},
+		{"var a b c d; f = (a & c) | (b & d); nodes f; reorder symsift; nodes f", "6\n4"},
+		{"var a b\n# comment\nf = a & b # other comment\n\nlist", "f: 2 nodes"},
+	} {
+		out := &bytes.Buffer{}
+		if err := run(nil, out, tt.script, false, nil); err != nil {
+			t.Errorf("error in %q: %s", tt.script, err)
+			continue
+		}
+		if actual := strings.TrimSpace(out.String()); actual != strings.TrimSpace(tt.expected) {
+			t.Errorf("expected %q for %q, actual %q", tt.expected, tt.script, actual)
+		}
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	for _, tt := range []struct {
+		script string
+		err    string
+	}{
+		{"satcount a", "no variables declared"},
+		{"var a; satcount b", "unknown name"},
+		{"var a\nsatcount a &", "-e:2: unexpected end"},
+		{"var a; satcount (a", `expected ")"`},
+		{"var a; f = a; var f", "already a function"},
+		{"var a; a = true", "already a variable"},
+		{"var a; print foo(a)", "unknown function"},
+		{"var a b; print rename(a, a:b a:b)", "duplicate variable"},
+		{"var a; frobnicate a", "unknown command"},
+		{"var a; reorder random", "unknown reordering method"},
+	} {
+		err := run(nil, &bytes.Buffer{}, tt.script, false, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("expected error %q for %q, actual %v", tt.err, tt.script, err)
+		}
+	}
+}
+
+func TestRepl(t *testing.T) {
+	stdin := strings.NewReader("var a b\nsatcount a &\nf = a | b\nhelp\nsatcount f\nquit\nsatcount a\n")
+	out := &bytes.Buffer{}
+	if err := run(stdin, out, "", true, nil); err != nil {
+		t.Fatal(err)
+	}
+	// errors do not stop the session, but quit does
+	for _, s := range []string{"error: unexpected end", "commands:", "rudd> 3\n"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %q in the output, actual %q", s, out)
+		}
+	}
+	if strings.Count(out.String(), "rudd> ") != 6 {
+		t.Errorf("expected 6 prompts, actual %q", out)
+	}
+}
+
+func TestDot(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := run(nil, out, "var a b; dot a & !b", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"digraph G {", `[label="a"]`, `[label="b"]`, "[style=dotted]"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %q in dot output, actual %s", s, out)
+		}
+	}
+}
+
+func TestInputs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	cnf := write("small.cnf", "c example\np cnf 3 2\n1 -2 0\n2 3 0\n")
+	blif := write("adder.blif", `.model adder
+.inputs a b \
+  cin
+.outputs s cout
+# sum and carry of a full adder
+.names a b t
+10 1
+01 1
+.names t cin s
+10 1
+01 1
+.names a b cin cout
+11- 1
+1-1 1
+-11 1
+.names a b nand
+11 0
+.end
+`)
+	script := write("script.txt", "satcount small\nsatcount s\nprint cout & !a\nsave "+filepath.Join(dir, "out.bdd")+" s cout\n")
+	out := &bytes.Buffer{}
+	if err := run(nil, out, "", false, []string{cnf, blif, script}); err != nil {
+		t.Fatal(err)
+	}
+	// the BDD has 6 variables, x1 to x3 and the inputs of the adder
+	expected := "32\n32\n!a b cin"
+	if actual := strings.TrimSpace(out.String()); actual != expected {
+		t.Errorf("expected %q, actual %q", expected, actual)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.bdd")); err != nil {
+		t.Error(err)
+	}
+	// without script, we print a summary of the inputs
+	out.Reset()
+	if err := run(nil, out, "", false, []string{cnf}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "small: ") {
+		t.Errorf("expected a summary, actual %q", out)
+	}
+	// errors in BLIF files
+	for _, tt := range []struct {
+		content string
+		err     string
+	}{
+		{".inputs a\n.outputs o\n.names a o\n1 1\n.latch a o\n", "unsupported directive"},
+		{".inputs a\n.outputs o\n.names a o\n11 1\n", "malformed cube"},
+		{".inputs a\n.outputs o\n.names a p o\n11 1\n.names o p\n1 1\n", "cycle"},
+		{".inputs a\n.outputs o\n", "not defined"},
+	} {
+		file := write("bad.blif", tt.content)
+		err := run(nil, &bytes.Buffer{}, "", false, []string{file})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("expected error %q for %q, actual %v", tt.err, tt.content, err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Command ruddbench runs a standard suite of BDD problems, the N-queens and
+Milner's cyclers examples of the BuDDy distribution, and the satisfiability of
+CNF files in DIMACS format, for all the combinations of configuration options
+given on the command line, and prints a comparison table. For instance
+
+	ruddbench -queens 8,10 -milner 20,50 -nodesize 10000,100000 -cacheratio 0,25 example.cnf
+
+runs 4 problems (and the CNF file) with 4 different configurations. Each run
+is repeated count times (option -count) and the table reports the fastest one,
+together with the number of nodes allocated and live at the end of the
+computation, the number of garbage collections, and the result of the problem
+(the number of solutions, or of reachable states), which must be the same in
+all the configurations.
+
+The implementation of the node table is selected when building the command,
+like for any program using package rudd, so that the backend column is the same
+for all the rows. Use option -benchstat to print the results in the format of
+the Go benchmarks, with one line for each run, and compare two backends with
+benchstat:
+
+	go run ./cmd/ruddbench -benchstat -count 10 > hudd.txt
+	go run -tags buddy ./cmd/ruddbench -benchstat -count 10 > buddy.txt
+	benchstat hudd.txt buddy.txt
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dalzilio/rudd"
+)
+
+// config is a combination of configuration options for New.
+type config struct {
+	nodesize   int
+	cachesize  int
+	cacheratio int
+}
+
+// new returns a BDD with varnum variables and the options in c.
+func (c config) new(varnum int) (*rudd.BDD, error) {
+	return rudd.New(varnum, rudd.Nodesize(c.nodesize), rudd.Cachesize(c.cachesize), rudd.Cacheratio(c.cacheratio))
+}
+
+func (c config) String() string {
+	return fmt.Sprintf("nodesize=%d/cachesize=%d/cacheratio=%d", c.nodesize, c.cachesize, c.cacheratio)
+}
+
+// result is the outcome of one run of a problem with a given configuration.
+type result struct {
+	problem   string
+	config    config
+	backend   string
+	elapsed   time.Duration
+	allocated int
+	live      int
+	gc        int
+	value     string
+}
+
+// intlist is a flag.Value for comma separated lists of integers.
+type intlist []int
+
+func (l *intlist) String() string {
+	res := make([]string, len(*l))
+	for k, v := range *l {
+		res[k] = strconv.Itoa(v)
+	}
+	return strings.Join(res, ",")
+}
+
+func (l *intlist) Set(s string) error {
+	*l = nil
+	for _, f := range strings.Split(s, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return err
+		}
+		*l = append(*l, v)
+	}
+	return nil
+}
+
+func main() {
+	queens := intlist{8}
+	milner := intlist{20}
+	nodesize := intlist{10000}
+	cachesize := intlist{10000}
+	cacheratio := intlist{0}
+	flag.Var(&queens, "queens", "comma separated `sizes` of the N-queens problems")
+	flag.Var(&milner, "milner", "comma separated `numbers` of cyclers in Milner's problems")
+	flag.Var(&nodesize, "nodesize", "comma separated initial `sizes` of the node table")
+	flag.Var(&cachesize, "cachesize", "comma separated initial `sizes` of the caches")
+	flag.Var(&cacheratio, "cacheratio", "comma separated cache `ratios` (in %)")
+	count := flag.Int("count", 1, "number of runs of each problem and configuration")
+	benchstat := flag.Bool("benchstat", false, "print the results in the format of Go benchmarks")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: ruddbench [options] [file.cnf ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("ruddbench: ")
+
+	problems := []problem{}
+	for _, n := range queens {
+		problems = append(problems, nqueens(n))
+	}
+	for _, n := range milner {
+		problems = append(problems, cyclers(n))
+	}
+	for _, name := range flag.Args() {
+		p, err := readcnf(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		problems = append(problems, p)
+	}
+	configs := []config{}
+	for _, ns := range nodesize {
+		for _, cs := range cachesize {
+			for _, cr := range cacheratio {
+				configs = append(configs, config{nodesize: ns, cachesize: cs, cacheratio: cr})
+			}
+		}
+	}
+
+	if *benchstat {
+		fmt.Printf("goos: %s\ngoarch: %s\npkg: github.com/dalzilio/rudd/cmd/ruddbench\n", runtime.GOOS, runtime.GOARCH)
+	}
+	results := []result{}
+	for _, p := range problems {
+		for _, c := range configs {
+			var best result
+			for k := 0; k < *count; k++ {
+				r, err := run(p, c)
+				if err != nil {
+					log.Fatalf("%s (%s): %s", p.name, c, err)
+				}
+				if *benchstat {
+					printbench(r)
+				}
+				if k == 0 || r.elapsed < best.elapsed {
+					best = r
+				}
+			}
+			results = append(results, best)
+		}
+	}
+	if !*benchstat {
+		printtable(results)
+	}
+	if err := check(results); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run solves problem p with configuration c.
+func run(p problem, c config) (result, error) {
+	runtime.GC()
+	start := time.Now()
+	bdd, value, err := p.solve(c.new)
+	elapsed := time.Since(start)
+	if err != nil {
+		return result{}, err
+	}
+	backend, gc := parsestats(bdd.Stats())
+	return result{
+		problem:   p.name,
+		config:    c,
+		backend:   backend,
+		elapsed:   elapsed,
+		allocated: bdd.Allocated(),
+		live:      bdd.Live(),
+		gc:        gc,
+		value:     value,
+	}, nil
+}
+
+// parsestats returns the name of the implementation and the number of garbage
+// collections found in the result of Stats.
+func parsestats(stats string) (string, int) {
+	backend, gc := "", 0
+	for _, line := range strings.Split(stats, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Impl.":
+			backend = strings.ToLower(strings.TrimSpace(value))
+		case "# of GC":
+			gc, _ = strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	return backend, gc
+}
+
+// check returns an error if a problem has different results depending on the
+// configuration.
+func check(results []result) error {
+	values := map[string]string{}
+	for _, r := range results {
+		if v, ok := values[r.problem]; ok && v != r.value {
+			return fmt.Errorf("different results for %s (%s and %s)", r.problem, v, r.value)
+		}
+		values[r.problem] = r.value
+	}
+	return nil
+}
+
+func printtable(results []result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "problem\tbackend\tnodesize\tcachesize\tcacheratio\ttime\tallocated\tlive\tgc\tresult\t")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%d\t%d\t%d\t%s\t\n",
+			r.problem, r.backend, r.config.nodesize, r.config.cachesize, r.config.cacheratio,
+			r.elapsed.Round(time.Microsecond), r.allocated, r.live, r.gc, r.value)
+	}
+	w.Flush()
+}
+
+// printbench prints r as the result of a Go benchmark with a single
+// iteration, with the nodes and garbage collections as extra metrics.
+func printbench(r result) {
+	name := strings.ToUpper(r.problem[:1]) + r.problem[1:]
+	fmt.Printf("Benchmark%s/backend=%s/%s-%d\t1\t%d ns/op\t%d allocated-nodes\t%d live-nodes\t%d gc/op\n",
+		strings.ReplaceAll(name, " ", "_"), r.backend, r.config, runtime.GOMAXPROCS(0),
+		r.elapsed.Nanoseconds(), r.allocated, r.live, r.gc)
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestProblems(t *testing.T) {
+	c := config{nodesize: 1000, cachesize: 1000, cacheratio: 25}
+	for _, tt := range []struct {
+		p        problem
+		expected string
+	}{
+		{nqueens(6), "4"},
+		{cyclers(3), fmt.Sprint(3 << 13)},
+	} {
+		r, err := run(tt.p, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.value != tt.expected {
+			t.Errorf("expected %s for %s, actual %s", tt.expected, tt.p.name, r.value)
+		}
+		if r.backend == "" || r.allocated == 0 {
+			t.Errorf("expected statistics for %s, actual %+v", tt.p.name, r)
+		}
+	}
+}
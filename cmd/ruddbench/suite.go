@@ -0,0 +1,177 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dalzilio/rudd"
+	"github.com/dalzilio/rudd/internal/dimacs"
+)
+
+// problem is a benchmark of the suite. Function solve builds the problem in a
+// BDD created with newbdd and returns this BDD, together with the result of
+// the problem.
+type problem struct {
+	name  string
+	solve func(newbdd func(varnum int) (*rudd.BDD, error)) (*rudd.BDD, string, error)
+}
+
+// nqueens is the N-queens problem, where we count the number of ways of
+// placing N queens on a NxN chess board such that no two queens attack each
+// other. We have one variable for each square of the board.
+func nqueens(n int) problem {
+	solve := func(newbdd func(int) (*rudd.BDD, error)) (*rudd.BDD, string, error) {
+		bdd, err := newbdd(n * n)
+		if err != nil {
+			return nil, "", err
+		}
+		x := func(i, j int) rudd.Node { return bdd.Ithvar(i*n + j) }
+		queen := bdd.True()
+		// there is a queen in each row
+		for i := 0; i < n; i++ {
+			e := bdd.False()
+			for j := 0; j < n; j++ {
+				e = bdd.Or(e, x(i, j))
+			}
+			queen = bdd.And(queen, e)
+		}
+		// a queen on square (i, j) forbids the squares in the same row, column
+		// and diagonals
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				forbid := bdd.True()
+				for k := 0; k < n; k++ {
+					if k != j {
+						forbid = bdd.And(forbid, bdd.Not(x(i, k)))
+					}
+					if k != i {
+						forbid = bdd.And(forbid, bdd.Not(x(k, j)))
+						if l := k - i + j; l >= 0 && l < n {
+							forbid = bdd.And(forbid, bdd.Not(x(k, l)))
+						}
+						if l := i + j - k; l >= 0 && l < n {
+							forbid = bdd.And(forbid, bdd.Not(x(k, l)))
+						}
+					}
+				}
+				queen = bdd.And(queen, bdd.Imp(x(i, j), forbid))
+			}
+		}
+		if bdd.Errored() {
+			return nil, "", fmt.Errorf("%s", bdd.Error())
+		}
+		return bdd, bdd.Satcount(queen).String(), nil
+	}
+	return problem{name: fmt.Sprintf("queens/N=%d", n), solve: solve}
+}
+
+// cyclers is the example of Milner's cyclers, where we compute the set of
+// reachable states of a system of n cyclers, with a monolithic transition
+// relation. We have six variables for each cycler, for the current and next
+// values of c, t and h.
+func cyclers(n int) problem {
+	solve := func(newbdd func(int) (*rudd.BDD, error)) (*rudd.BDD, string, error) {
+		bdd, err := newbdd(6 * n)
+		if err != nil {
+			return nil, "", err
+		}
+		c, cp := make([]rudd.Node, n), make([]rudd.Node, n)
+		t, tp := make([]rudd.Node, n), make([]rudd.Node, n)
+		h, hp := make([]rudd.Node, n), make([]rudd.Node, n)
+		for i := 0; i < n; i++ {
+			c[i], cp[i] = bdd.Ithvar(6*i), bdd.Ithvar(6*i+1)
+			t[i], tp[i] = bdd.Ithvar(6*i+2), bdd.Ithvar(6*i+3)
+			h[i], hp[i] = bdd.Ithvar(6*i+4), bdd.Ithvar(6*i+5)
+		}
+		nvar, pvar := make([]int, 3*n), make([]int, 3*n)
+		for i := range nvar {
+			nvar[i], pvar[i] = 2*i, 2*i+1
+		}
+		replacer, err := bdd.NewReplacer(pvar, nvar)
+		if err != nil {
+			return nil, "", err
+		}
+		// unchanged states that the variables in x, except the z-th one, keep
+		// their value
+		unchanged := func(x, y []rudd.Node, z int) rudd.Node {
+			res := bdd.True()
+			for i := 0; i < n; i++ {
+				if i != z {
+					res = bdd.And(res, bdd.Equiv(x[i], y[i]))
+				}
+			}
+			return res
+		}
+		I := bdd.And(c[0], bdd.Not(h[0]), bdd.Not(t[0]))
+		for i := 1; i < n; i++ {
+			I = bdd.And(I, bdd.Not(c[i]), bdd.Not(h[i]), bdd.Not(t[i]))
+		}
+		T := bdd.False()
+		for i := 0; i < n; i++ {
+			j := (i + 1) % n
+			P1 := bdd.And(c[i], bdd.Not(cp[i]), tp[i], bdd.Not(t[i]), hp[i], unchanged(c, cp, i), unchanged(t, tp, i), unchanged(h, hp, i))
+			P2 := bdd.And(h[i], bdd.Not(hp[i]), cp[j], unchanged(c, cp, j), unchanged(h, hp, i), unchanged(t, tp, n))
+			E := bdd.And(t[i], bdd.Not(tp[i]), unchanged(t, tp, i), unchanged(h, hp, n), unchanged(c, cp, n))
+			T = bdd.Or(T, P1, P2, E)
+		}
+		R := I
+		normvar := bdd.Makeset(nvar)
+		for {
+			prev := R
+			R = bdd.Or(bdd.Replace(bdd.AndExist(R, T, normvar), replacer), R)
+			if R == nil || *prev == *R {
+				break
+			}
+		}
+		if bdd.Errored() {
+			return nil, "", fmt.Errorf("%s", bdd.Error())
+		}
+		return bdd, bdd.Satcount(R).String(), nil
+	}
+	return problem{name: fmt.Sprintf("milner/N=%d", n), solve: solve}
+}
+
+// readcnf returns the problem of counting the models of the formula in file
+// name, in DIMACS CNF format. The clauses are conjoined in the order of the
+// file.
+func readcnf(name string) (problem, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return problem{}, err
+	}
+	defer file.Close()
+	varnum, clauses, err := dimacs.Parse(file)
+	if err != nil {
+		return problem{}, fmt.Errorf("%s: %w", name, err)
+	}
+	solve := func(newbdd func(int) (*rudd.BDD, error)) (*rudd.BDD, string, error) {
+		bdd, err := newbdd(varnum)
+		if err != nil {
+			return nil, "", err
+		}
+		res := bdd.True()
+		for _, clause := range clauses {
+			c := bdd.False()
+			for _, lit := range clause {
+				if lit > 0 {
+					c = bdd.Or(c, bdd.Ithvar(lit-1))
+				} else {
+					c = bdd.Or(c, bdd.NIthvar(-lit-1))
+				}
+			}
+			res = bdd.And(res, c)
+		}
+		if bdd.Errored() {
+			return nil, "", fmt.Errorf("%s", bdd.Error())
+		}
+		return bdd, bdd.Satcount(res).String(), nil
+	}
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	return problem{name: "cnf/" + strings.ReplaceAll(base, " ", "_"), solve: solve}, nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Codec is the interface of compression methods that can be used with
+// SaveWith. Each codec has an ID, stored in the header of the stream, that is
+// used by Load to find the method needed to read it back. IDs from 1 to 127
+// are reserved for the codecs defined in this package; the others can be used
+// for new codecs, declared with RegisterCodec.
+type Codec interface {
+	// ID returns the (non-zero) identifier of the codec.
+	ID() byte
+	// NewWriter returns a writer that compresses data to w. Closing the
+	// writer should flush all the data, but not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader that decompresses data from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Gzip is a codec using the gzip format (see package compress/gzip) with the
+// default compression level.
+var Gzip Codec = gzipCodec(gzip.DefaultCompression)
+
+// GzipLevel returns a codec using the gzip format with a given compression
+// level, from gzip.HuffmanOnly to gzip.BestCompression. Streams written with
+// this codec can be read back by Load whatever the level.
+func GzipLevel(level int) Codec {
+	return gzipCodec(level)
+}
+
+type gzipCodec int
+
+func (c gzipCodec) ID() byte {
+	return 1
+}
+
+func (c gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, int(c))
+}
+
+func (c gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+var codecs = struct {
+	sync.RWMutex
+	m map[byte]Codec
+}{m: map[byte]Codec{1: Gzip}}
+
+// RegisterCodec makes codec c available to Load. We return an error if the ID
+// of c is reserved or already used by another codec.
+func RegisterCodec(c Codec) error {
+	id := c.ID()
+	if id < 128 {
+		return fmt.Errorf("codec ID %d is reserved", id)
+	}
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, ok := codecs.m[id]; ok {
+		return fmt.Errorf("codec ID %d is already registered", id)
+	}
+	codecs.m[id] = c
+	return nil
+}
+
+func findCodec(id byte) Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.m[id]
+}
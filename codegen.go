@@ -0,0 +1,267 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"io"
+)
+
+// GenerateGo writes the source code of a Go function, named funcName in package
+// pkg, that evaluates the Boolean function denoted by n. The generated code is
+// self-contained and has no dependency on this library, which makes it
+// possible to embed the result of a computation (such as an access-control
+// policy) inside a program.
+//
+// If varnames is nil, the generated function has signature func(x []bool) bool
+// and variable i is x[i]. Otherwise varnames should give a valid and distinct
+// Go identifier for each of the Varnum variables of b, in which case the
+// generated function takes one bool parameter for each of them, in the order
+// of variable indices. We reject names that are not valid identifiers, as well
+// as the predeclared identifiers of Go (such as true, bool or len), since a
+// parameter named false, for instance, would change the meaning of the code.
+func (b *BDD) GenerateGo(w io.Writer, pkg, funcName string, n Node, varnames []string) error {
+	if b.checkptr(n) != nil {
+		return fmt.Errorf("wrong node in call to GenerateGo; %s", b.error)
+	}
+	if !token.IsIdentifier(pkg) || !goIdentifier(funcName) {
+		return fmt.Errorf("invalid package or function name in call to GenerateGo")
+	}
+	varname := func(v int) string { return fmt.Sprintf("x[%d]", v) }
+	params := "x []bool"
+	if varnames != nil {
+		if len(varnames) != int(b.varnum) {
+			return fmt.Errorf("wrong number of variable names (%d) in call to GenerateGo", len(varnames))
+		}
+		seen := make(map[string]bool)
+		for _, s := range varnames {
+			if !goIdentifier(s) || seen[s] || s == "n" {
+				return fmt.Errorf("invalid variable name %q in call to GenerateGo", s)
+			}
+			seen[s] = true
+		}
+		varname = func(v int) string { return varnames[v] }
+		params = ""
+		for k, s := range varnames {
+			if k > 0 {
+				params += ", "
+			}
+			params += s
+		}
+		params += " bool"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by rudd; DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	fmt.Fprintf(&buf, "// %s evaluates a Boolean function computed with a BDD.\n", funcName)
+	fmt.Fprintf(&buf, "func %s(%s) bool {\n", funcName, params)
+	if *n < 2 {
+		fmt.Fprintf(&buf, "return %v\n}\n", *n == 1)
+	} else {
+		f, _ := b.flatten(*n)
+		fmt.Fprintf(&buf, "n := %d\nfor {\nswitch n {\n", f.Roots[0])
+		for k := len(f.Nodes) - 1; k >= 0; k-- {
+			v := f.Nodes[k]
+			fmt.Fprintf(&buf, "case %d:\nif %s {\nn = %d\n} else {\nn = %d\n}\n", k+2, varname(v[0]), v[2], v[1])
+		}
+		fmt.Fprintf(&buf, "case 1:\nreturn true\ndefault:\nreturn false\n}\n}\n}\n")
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goIdentifier reports whether s is a valid Go identifier that does not shadow
+// one of the predeclared identifiers of the language.
+func goIdentifier(s string) bool {
+	return token.IsIdentifier(s) && types.Universe.Lookup(s) == nil
+}
+
+// GenerateC writes the source code of a C function, named funcName, that
+// evaluates the Boolean function denoted by n. The generated function has
+// signature int funcName(const unsigned char *x), where x is a bit-vector such
+// that variable i is true if and only if bit (i % 8) of byte x[i / 8] is set.
+// The result is 1 when the function is true, and 0 otherwise. The nodes of the
+// BDD are stored in a static table, using the same numbering as Flatten. We
+// return an error if funcName is not a valid C identifier.
+func (b *BDD) GenerateC(w io.Writer, funcName string, n Node) error {
+	if b.checkptr(n) != nil {
+		return fmt.Errorf("wrong node in call to GenerateC; %s", b.error)
+	}
+	if !cIdentifier(funcName) {
+		return fmt.Errorf("invalid function name in call to GenerateC")
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "/* Code generated by rudd; DO NOT EDIT. */\n\n")
+	if *n < 2 {
+		fmt.Fprintf(&buf, "int %s(const unsigned char *x)\n{\n\t(void)x;\n\treturn %d;\n}\n", funcName, *n)
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	f, _ := b.flatten(*n)
+	fmt.Fprintf(&buf, "/* triplets (variable, low, high) for nodes 2 to %d */\n", len(f.Nodes)+1)
+	fmt.Fprintf(&buf, "static const unsigned int %s_nodes[%d][3] = {\n", funcName, len(f.Nodes))
+	for _, v := range f.Nodes {
+		fmt.Fprintf(&buf, "\t{%d, %d, %d},\n", v[0], v[1], v[2])
+	}
+	fmt.Fprintf(&buf, "};\n\n")
+	fmt.Fprintf(&buf, "int %s(const unsigned char *x)\n{\n", funcName)
+	fmt.Fprintf(&buf, "\tunsigned int n = %d;\n", f.Roots[0])
+	fmt.Fprintf(&buf, "\twhile (n > 1) {\n")
+	fmt.Fprintf(&buf, "\t\tconst unsigned int *node = %s_nodes[n - 2];\n", funcName)
+	fmt.Fprintf(&buf, "\t\tn = ((x[node[0] >> 3] >> (node[0] & 7)) & 1) ? node[2] : node[1];\n")
+	fmt.Fprintf(&buf, "\t}\n\treturn (int)n;\n}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// GenerateWasm writes a (binary) WebAssembly module that evaluates the Boolean
+// function denoted by n. The module exports a memory, named "memory", and a
+// function, named funcName, of type [i32] -> [i32]. The function takes the
+// address, in memory, of a bit-vector with the same layout as the one used in
+// GenerateC and returns 1 when the function is true, and 0 otherwise. Like
+// with GenerateC, funcName must be a valid C identifier; it must also differ
+// from the names of the other exports. The nodes of the BDD are stored as a
+// table of 32-bits triplets at the start of the memory, so the input must be
+// written at an address greater or equal to the value of the exported
+// (immutable) global "input", which is also the start of a free area large
+// enough to hold the input.
+func (b *BDD) GenerateWasm(w io.Writer, funcName string, n Node) error {
+	if b.checkptr(n) != nil {
+		return fmt.Errorf("wrong node in call to GenerateWasm; %s", b.error)
+	}
+	if !cIdentifier(funcName) || funcName == "memory" || funcName == "input" {
+		return fmt.Errorf("invalid function name in call to GenerateWasm")
+	}
+	f, _ := b.flatten(*n)
+	root := *n
+	if root >= 2 {
+		root = f.Roots[0]
+	}
+	// the node table, in little endian
+	table := make([]byte, 0, 12*len(f.Nodes))
+	for _, v := range f.Nodes {
+		for _, x := range v {
+			table = append(table, byte(x), byte(x>>8), byte(x>>16), byte(x>>24))
+		}
+	}
+	input := (len(table) + 7) &^ 7
+	pages := (input + (f.Varnum+7)/8 + 0xFFFF) / 0x10000
+
+	// the code of the evaluation function; local 0 is the parameter, local 1
+	// the current node and local 2 the address of the current node in the
+	// table.
+	code := []byte{0x01, 0x02, 0x7f} // two locals of type i32
+	code = append(code, 0x41)
+	code = wasmSleb(code, int64(root))
+	code = append(code,
+		0x21, 0x01, // local.set 1
+		0x02, 0x40, // block
+		0x03, 0x40, // loop
+		0x20, 0x01, 0x41, 0x02, 0x49, 0x0d, 0x01, // br_if 1 (n < 2)
+		0x20, 0x01, 0x41, 0x02, 0x6b, 0x41, 0x0c, 0x6c, 0x21, 0x02, // a = (n - 2) * 12
+		0x20, 0x00, 0x20, 0x02, 0x28, 0x02, 0x00, 0x41, 0x03, 0x76, 0x6a, // x + (var >> 3)
+		0x2d, 0x00, 0x00, // i32.load8_u
+		0x20, 0x02, 0x28, 0x02, 0x00, 0x41, 0x07, 0x71, 0x76, 0x41, 0x01, 0x71, // bit
+		0x41, 0x02, 0x74, 0x20, 0x02, 0x6a, 0x28, 0x02, 0x04, 0x21, 0x01, // n = a[1 + bit]
+		0x0c, 0x00, // br 0
+		0x0b,       // end loop
+		0x0b,       // end block
+		0x20, 0x01, // local.get 1
+		0x0b, // end function
+	)
+
+	mod := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	// type section: (func (param i32) (result i32))
+	mod = wasmSection(mod, 1, []byte{0x01, 0x60, 0x01, 0x7f, 0x01, 0x7f})
+	// function section
+	mod = wasmSection(mod, 3, []byte{0x01, 0x00})
+	// memory section
+	mod = wasmSection(mod, 5, wasmUleb([]byte{0x01, 0x00}, uint64(pages)))
+	// global section
+	global := []byte{0x01, 0x7f, 0x00, 0x41}
+	global = append(wasmSleb(global, int64(input)), 0x0b)
+	mod = wasmSection(mod, 6, global)
+	// export section
+	export := []byte{0x03}
+	export = append(wasmName(export, "memory"), 0x02, 0x00)
+	export = append(wasmName(export, funcName), 0x00, 0x00)
+	export = append(wasmName(export, "input"), 0x03, 0x00)
+	mod = wasmSection(mod, 7, export)
+	// code section
+	body := wasmUleb(nil, uint64(len(code)))
+	mod = wasmSection(mod, 10, append(append([]byte{0x01}, body...), code...))
+	// data section
+	data := []byte{0x01, 0x00, 0x41, 0x00, 0x0b}
+	data = append(wasmUleb(data, uint64(len(table))), table...)
+	mod = wasmSection(mod, 11, data)
+	_, err := w.Write(mod)
+	return err
+}
+
+// cKeywords are the keywords of C (up to C11), that cannot be used as
+// identifiers.
+var cKeywords = map[string]bool{
+	"auto": true, "break": true, "case": true, "char": true, "const": true,
+	"continue": true, "default": true, "do": true, "double": true, "else": true,
+	"enum": true, "extern": true, "float": true, "for": true, "goto": true,
+	"if": true, "inline": true, "int": true, "long": true, "register": true,
+	"restrict": true, "return": true, "short": true, "signed": true,
+	"sizeof": true, "static": true, "struct": true, "switch": true,
+	"typedef": true, "union": true, "unsigned": true, "void": true,
+	"volatile": true, "while": true,
+}
+
+// cIdentifier returns true if s is a valid identifier in C, made of ASCII
+// letters, digits and underscores, and not starting with a digit.
+func cIdentifier(s string) bool {
+	if s == "" || cKeywords[s] || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+func wasmUleb(buf []byte, v uint64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(buf, c)
+		}
+		buf = append(buf, c|0x80)
+	}
+}
+
+func wasmSleb(buf []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(buf, c)
+		}
+		buf = append(buf, c|0x80)
+	}
+}
+
+func wasmName(buf []byte, s string) []byte {
+	return append(wasmUleb(buf, uint64(len(s))), s...)
+}
+
+func wasmSection(buf []byte, id byte, content []byte) []byte {
+	buf = append(buf, id)
+	return append(wasmUleb(buf, uint64(len(content))), content...)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wasmSections splits a WebAssembly module into its sections, indexed by their
+// ids, and checks that the length of each section matches its content.
+func wasmSections(t *testing.T, mod []byte) map[byte][]byte {
+	if !bytes.HasPrefix(mod, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}) {
+		t.Fatalf("bad magic number or version, % x", mod[:8])
+	}
+	res := make(map[byte][]byte)
+	r := bytes.NewReader(mod[8:])
+	last := byte(0)
+	for r.Len() > 0 {
+		id, _ := r.ReadByte()
+		size, err := binary.ReadUvarint(r)
+		if err != nil || size > uint64(r.Len()) {
+			t.Fatalf("bad length for section %d", id)
+		}
+		if id <= last {
+			t.Fatalf("section %d after section %d", id, last)
+		}
+		last = id
+		res[id] = make([]byte, size)
+		r.Read(res[id])
+	}
+	return res
+}
+
+func TestGenerateWasm(t *testing.T) {
+	bdd, _ := New(3)
+	// n == (x0 & x1) | !x2
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(1)), bdd.NIthvar(2))
+	var buf bytes.Buffer
+	if err := bdd.GenerateWasm(&buf, "eval", n); err != nil {
+		t.Fatal(err)
+	}
+	sections := wasmSections(t, buf.Bytes())
+	for _, id := range []byte{1, 3, 5, 6, 7, 10, 11} {
+		if _, ok := sections[id]; !ok {
+			t.Errorf("missing section %d", id)
+		}
+	}
+	// exports: a count, then a name, a kind and an index for each export
+	export := bytes.NewReader(sections[7])
+	count, _ := binary.ReadUvarint(export)
+	names := []string{}
+	for k := uint64(0); k < count; k++ {
+		size, _ := binary.ReadUvarint(export)
+		name := make([]byte, size)
+		export.Read(name)
+		kind, _ := export.ReadByte()
+		export.ReadByte()
+		names = append(names, string(name)+"/"+string('0'+kind))
+	}
+	if actual := strings.Join(names, " "); actual != "memory/2 eval/0 input/3" || export.Len() != 0 {
+		t.Errorf("unexpected exports, %s", actual)
+	}
+	// the data section holds the three nodes of n, as 32-bits triplets
+	if data := sections[11]; len(data) != 6+3*12 {
+		t.Errorf("expected 3 nodes in the data section, found %d bytes", len(data))
+	}
+
+	for _, name := range []string{"", "1x", "a-b", "é", "int"} {
+		if err := bdd.GenerateWasm(&buf, name, n); err == nil {
+			t.Errorf("GenerateWasm should reject function name %q", name)
+		}
+		if err := bdd.GenerateC(&buf, name, n); err == nil {
+			t.Errorf("GenerateC should reject function name %q", name)
+		}
+	}
+	if err := bdd.GenerateWasm(&buf, "memory", n); err == nil {
+		t.Errorf("GenerateWasm should reject the name of another export")
+	}
+
+	// we run the module if node.js is available
+	nodejs, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node.js not found")
+	}
+	file := filepath.Join(t.TempDir(), "eval.wasm")
+	buf.Reset()
+	bdd.GenerateWasm(&buf, "eval", n)
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := `const i = new WebAssembly.Instance(new WebAssembly.Module(require("fs").readFileSync(process.argv[1])));
+const mem = new Uint8Array(i.exports.memory.buffer);
+const a = i.exports.input.value;
+let r = "";
+for (let x = 0; x < 8; x++) { mem[a] = x; r += i.exports.eval(a); }
+console.log(r);`
+	out, err := exec.Command(nodejs, "-e", script, file).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := strings.TrimSpace(string(out)); actual != "11110001" {
+		t.Errorf("expected results 11110001, actual %s", actual)
+	}
+}
+
+func TestGenerateGo(t *testing.T) {
+	bdd, _ := New(3)
+	// n == (x0 & x1) | !x2
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(1)), bdd.NIthvar(2))
+	var buf bytes.Buffer
+	for _, names := range [][]string{
+		{"true", "y", "z"}, {"x", "false", "z"}, {"x", "y", "bool"}, {"nil", "y", "z"},
+		{"iota", "y", "z"}, {"x", "len", "z"}, {"x", "y", "x"}, {"n", "y", "z"}, {"1x", "y", "z"},
+	} {
+		if err := bdd.GenerateGo(&buf, "main", "eval", n, names); err == nil {
+			t.Errorf("GenerateGo should reject variable names %v", names)
+		}
+	}
+	if err := bdd.GenerateGo(&buf, "main", "int", n, nil); err == nil {
+		t.Errorf("GenerateGo should reject function name int")
+	}
+
+	// we compile and run the code if the go command is available
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	buf.Reset()
+	if err := bdd.GenerateGo(&buf, "main", "eval", n, []string{"a", "b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	main := `package main
+
+import "fmt"
+
+func main() {
+	for x := 0; x < 8; x++ {
+		if eval(x&1 != 0, x&2 != 0, x&4 != 0) {
+			fmt.Print(1)
+		} else {
+			fmt.Print(0)
+		}
+	}
+}
+`
+	files := map[string]string{
+		"go.mod":  "module eval\n\ngo 1.21\n",
+		"eval.go": buf.String(),
+		"main.go": main,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gocmd, "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s\n%s", err, out)
+	}
+	if actual := strings.TrimSpace(string(out)); actual != "11110001" {
+		t.Errorf("expected results 11110001, actual %s", actual)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"math"
+)
+
+// Composer is the type of substitution objects used in a VecCompose operation,
+// that substitutes variables in a BDD with functions (BDDs). The only method
+// returning an object of this type is the NewComposer method. Like with a
+// Replacer, the result obtained when using a composer created from a BDD, in a
+// VecCompose operation over a different BDD is unspecified.
+type Composer interface {
+	Compose(int32) (int, bool)
+	Id() int
+}
+
+type composer struct {
+	id     int    // unique identifier used for caching intermediate results
+	vimage []Node // map variables to functions (nil if a variable is not substituted)
+	image  []int  // map levels to the node substituted for the variable at this level
+	last   int32  // last level in the Composer, to speed up computations
+	order  int    // value of b.reorders when the levels were computed
+}
+
+func (c *composer) String() string {
+	res := fmt.Sprintf("composer(last: %d)[", c.last)
+	first := true
+	for k, v := range c.vimage {
+		if v != nil {
+			if !first {
+				res += ", "
+			}
+			first = false
+			res += fmt.Sprintf("%d<-@%d", k, *v)
+		}
+	}
+	return res + "]"
+}
+
+// setlevels computes the mapping between levels and nodes used in the
+// VecCompose operation from the mapping in c.vimage, using the current
+// variable order of b. A variable that is not substituted is mapped to
+// itself.
+func (c *composer) setlevels(b *BDD) {
+	c.order = b.reorders
+	c.last = 0
+	for v, n := range c.vimage {
+		level := b.var2level[v]
+		if n == nil {
+			c.image[level] = b.varset[v][0]
+			continue
+		}
+		c.image[level] = *n
+		if level > c.last {
+			c.last = level
+		}
+	}
+}
+
+// Compose returns the node substituted for the variable at the given level, and
+// false if there are no substitutions for this level or below.
+func (c *composer) Compose(level int32) (int, bool) {
+	if level > c.last {
+		return -1, false
+	}
+	return c.image[level], true
+}
+
+func (c *composer) Id() int {
+	return c.id
+}
+
+// NewComposer returns a Composer that can be used for substituting variable
+// vars[k] with the function funcs[k] in the BDD b. We return an error if the
+// two slices do not have the same length, if we find the same variable twice
+// in vars, or if one of the functions is not a valid node. All values in vars
+// must be variable indices in the interval [0..Varnum).
+func (b *BDD) NewComposer(vars []int, funcs []Node) (Composer, error) {
+	res := &composer{}
+	if len(vars) != len(funcs) {
+		return nil, fmt.Errorf("unmatched length of slices")
+	}
+	if _REPLACEID == (math.MaxInt32 >> 2) {
+		return nil, fmt.Errorf("too many replacers created")
+	}
+	res.id = (_REPLACEID << 2) | cacheidVECCOMPOSE
+	_REPLACEID++
+	varnum := b.Varnum()
+	res.vimage = make([]Node, varnum)
+	res.image = make([]int, varnum)
+	for k, v := range vars {
+		if v < 0 || v >= varnum {
+			return nil, fmt.Errorf("invalid variable in vars (%d)", v)
+		}
+		if res.vimage[v] != nil {
+			return nil, fmt.Errorf("duplicate variable (%d) in vars", v)
+		}
+		if b.checkptr(funcs[k]) != nil {
+			return nil, fmt.Errorf("invalid node in funcs (variable %d)", v)
+		}
+		res.vimage[v] = funcs[k]
+	}
+	res.setlevels(b)
+	if b.journal != nil {
+		b.recordcomposer(res, vars, funcs)
+	}
+	return res, nil
+}
@@ -4,21 +4,40 @@
 
 package rudd
 
+import (
+	"io"
+	"log"
+)
+
 // configs is used to store the values of different parameters of the BDD
 type configs struct {
-	varnum          int // number of BDD variables
-	nodesize        int // initial number of nodes in the table
-	cachesize       int // initial cache size (general)
-	cacheratio      int // initial ratio (general, 0 if size constant) between cache size and node table
-	maxnodesize     int // Maximum total number of nodes (0 if no limit)
-	maxnodeincrease int // Maximum number of nodes that can be added to the table at each resize (0 if no limit)
-	minfreenodes    int // Minimum number of nodes that should be left after GC before triggering a resize
+	varnum          int         // number of BDD variables
+	nodesize        int         // initial number of nodes in the table
+	cachesize       int         // initial cache size (general)
+	cacheratio      int         // initial ratio (general, 0 if size constant) between cache size and node table
+	maxnodesize     int         // Maximum total number of nodes (0 if no limit)
+	maxnodeincrease int         // Maximum number of nodes that can be added to the table at each resize (0 if no limit)
+	minfreenodes    int         // Minimum number of nodes that should be left after GC before triggering a resize
+	hashfunc        NodeHash    // Hash function for the unique table (only with build tag buddy)
+	maxchain        int         // Maximal length of a chain in the unique table before triggering a GC (0 if no limit, only with build tag buddy)
+	resizemode      ResizeMode  // Strategy used to grow the unique table (only with build tag buddy)
+	journal         io.Writer   // Destination of the journal of operations (nil if disabled)
+	assertions      bool        // Validate each node returned by Makenode
+	strict          bool        // Panic on operations performed after an error
+	trackages       bool        // Record the generation in which each node is created
+	leakrate        int         // Sampling rate of the leak audit (0 if disabled)
+	logger          *log.Logger // Logger for the warnings found by Validate (nil if disabled)
+	oompolicy       OOMPolicy   // What to do when there is no room left in the node table
+	oomhandler      OOMHandler  // Called before applying oompolicy (nil if none)
+	autoreorder     *trigger    // Method and threshold for the automatic reordering (nil if disabled)
+	reorderperiod   int         // Number of garbage collections between two automatic reorderings (0 if no limit)
 }
 
 func makeconfigs(varnum int) *configs {
 	c := &configs{varnum: varnum}
 	c.minfreenodes = _MINFREENODES
 	c.maxnodeincrease = _DEFAULTMAXNODEINC
+	c.hashfunc = FibonacciHash
 	// we build enough nodes to include all the variables in varset
 	c.nodesize = 2*varnum + 2
 	return c
@@ -40,8 +59,9 @@ func Nodesize(size int) func(*configs) {
 // Maxnodesize is a configuration option (function). Used as a parameter in New
 // it sets a limit to the number of nodes in the BDD. An operation trying to
 // raise the number of nodes above this limit will generate an error and return
-// a nil Node. The default value (0) means that there is no limit. In which case
-// allocation can panic if we exhaust all the available memory.
+// a nil Node, unless another policy is selected with options Outofmemory and
+// Memoryhandler. The default value (0) means that there is no limit. In which
+// case allocation can panic if we exhaust all the available memory.
 func Maxnodesize(size int) func(*configs) {
 	return func(c *configs) {
 		c.maxnodesize = size
@@ -92,3 +112,200 @@ func Cacheratio(ratio int) func(*configs) {
 		c.cacheratio = ratio
 	}
 }
+
+// NodeHash is the type of hash functions used for finding nodes in the unique
+// table. It returns a 64 bits hash value for a triplet (level, low, high) that
+// is reduced, afterwards, to an index in the table.
+type NodeHash func(level int32, low, high int) uint64
+
+// PairHash is the hash function used in the BuDDy library. It is based on the
+// Cantor pairing function, that maps (bijectively) a pair of integers into a
+// unique integer.
+func PairHash(level int32, low, high int) uint64 {
+	pair := func(a, b uint64) uint64 {
+		return (((a + b) * (a + b + 1)) / 2) + a
+	}
+	return pair(uint64(high), pair(uint64(level), uint64(low)))
+}
+
+// FibonacciHash is a multiplicative hash function based on the golden ratio
+// (sometimes called Fibonacci hashing), that mixes the bits of the three
+// components of a node. This is the default hash function.
+func FibonacciHash(level int32, low, high int) uint64 {
+	h := uint64(uint32(level)) * 0x9E3779B97F4A7C15
+	h = (h ^ uint64(low)) * 0x9E3779B97F4A7C15
+	h = (h ^ uint64(high)) * 0x9E3779B97F4A7C15
+	return h ^ (h >> 29)
+}
+
+// Nodehash is a configuration option (function). Used as a parameter in New it
+// sets the hash function used in the unique table of nodes. This option is
+// only meaningful with the BuDDy-style implementation (build tag buddy), since
+// the default implementation relies on the runtime hashmap. The default value
+// is FibonacciHash.
+func Nodehash(h NodeHash) func(*configs) {
+	return func(c *configs) {
+		if h != nil {
+			c.hashfunc = h
+		}
+	}
+}
+
+// Maxchain is a configuration option (function). Used as a parameter in New it
+// sets a limit on the length of the collision chains in the unique table. When
+// the insertion of a new node follows a chain longer than this limit, we
+// trigger a garbage collection (and possibly a resize of the table) even if
+// there are free nodes left; we avoid triggering a new collection before a
+// quarter of the table has been used. This option is only meaningful with the
+// BuDDy-style implementation (build tag buddy). The default value (0) means
+// that there is no limit.
+func Maxchain(length int) func(*configs) {
+	return func(c *configs) {
+		c.maxchain = length
+	}
+}
+
+// ResizeMode is the type of strategies used for growing the unique table when
+// the node table is resized.
+type ResizeMode int
+
+const (
+	// ResizeIncremental means that the unique table grows incrementally, with
+	// the nodes in a bounded number of buckets rehashed during each subsequent
+	// call to Makenode. This spreads the cost of a resize over many
+	// operations.
+	ResizeIncremental ResizeMode = iota
+	// ResizeStopTheWorld means that the unique table is completely rebuilt
+	// each time the node table is resized, like in the BuDDy library.
+	ResizeStopTheWorld
+)
+
+// Resizemode is a configuration option (function). Used as a parameter in New
+// it selects the strategy used for growing the unique table when the node
+// table is resized. This option is only meaningful with the BuDDy-style
+// implementation (build tag buddy), since the default implementation relies
+// on the runtime hashmap, that already grows incrementally. The default value
+// is ResizeIncremental.
+func Resizemode(mode ResizeMode) func(*configs) {
+	return func(c *configs) {
+		c.resizemode = mode
+	}
+}
+
+// OOMPolicy is the type of strategies used when an operation cannot create a
+// new node, because the node table has reached the size set with Maxnodesize
+// (or because we cannot allocate more memory).
+type OOMPolicy int
+
+const (
+	// OOMError means that the operation sets the error flag of the BDD and
+	// returns a nil Node. This is the default policy.
+	OOMError OOMPolicy = iota
+	// OOMPanic means that the operation panics with an error wrapping
+	// ErrMemory.
+	OOMPanic
+)
+
+// Outofmemory is a configuration option (function). Used as a parameter in New
+// it selects the policy applied when there is no room left in the node table,
+// after a garbage collection and a possible resize of the table, and after a
+// call to the handler set with Memoryhandler. The default value is OOMError.
+func Outofmemory(policy OOMPolicy) func(*configs) {
+	return func(c *configs) {
+		c.oompolicy = policy
+	}
+}
+
+// OOMHandler is the type of functions called when there is no room left in the
+// node table. A handler can free some resources, for instance by dropping
+// references to Nodes (the roots of a computation), and returns true if the
+// allocation should be retried. A handler is called in the middle of an
+// operation and must not call any method of b, except read-only methods such
+// as Stats or Allocated.
+type OOMHandler func(b *BDD) bool
+
+// Memoryhandler is a configuration option (function). Used as a parameter in
+// New it registers a handler called each time an operation cannot create a new
+// node. When the handler returns true, we run the Go garbage collector, so that
+// the Nodes dropped by the handler are released, and we retry the allocation
+// once. When the handler returns false, or if the allocation fails again, we
+// apply the policy selected with option Outofmemory. This is useful to shed
+// load, for instance in a server, instead of failing the request.
+func Memoryhandler(h OOMHandler) func(*configs) {
+	return func(c *configs) {
+		c.oomhandler = h
+	}
+}
+
+// Assertions is a configuration option (function). Used as a parameter in New
+// it enables the validation of each node returned by Makenode, and of nodes
+// added with the bulk insertion path used by Load and Unflatten. We check that
+// the node has distinct successors, that the level of its successors is
+// strictly greater than its own, and that it is the only node in the unique
+// table with this level and successors. We panic, with a description of the
+// faulty node, if one of these conditions is violated. This is useful when
+// developing new kernel functions, but it slows down all the operations.
+func Assertions(enable bool) func(*configs) {
+	return func(c *configs) {
+		c.assertions = enable
+	}
+}
+
+// Strict is a configuration option (function). Used as a parameter in New it
+// changes the way errors are handled once the error flag of the BDD is set.
+// By default, errors are sticky: the first error is recorded and subsequent
+// operations quietly return nil Nodes, which often leads to an error such as
+// "Illegal access to node" far from the initial problem. In strict mode, we
+// record a stack trace when the first error occurs, and every subsequent error
+// panics with the original error and this stack trace.
+func Strict(enable bool) func(*configs) {
+	return func(c *configs) {
+		c.strict = enable
+	}
+}
+
+// TrackAges is a configuration option (function). Used as a parameter in New
+// it records the generation, meaning the number of garbage collections, at
+// which each node is created, in order to compute the histograms returned by
+// method Ages. This is useful to distinguish churn (nodes created and collected
+// soon after) from stable structures, but it slows down node creation and
+// garbage collection.
+func TrackAges(enable bool) func(*configs) {
+	return func(c *configs) {
+		c.trackages = enable
+	}
+}
+
+// Autoreorder is a configuration option (function). Used as a parameter in New
+// it enables the automatic reordering of variables, with the given method,
+// like bdd_autoreorder in BuDDy. We check the number of nodes in use after each
+// garbage collection (or resize) of the node table, and start a reordering
+// when it is above threshold. A threshold of 0 means that we reorder after
+// every garbage collection. After a reordering, the threshold is raised to
+// twice the number of nodes left in the table, so that we do not reorder
+// repeatedly a table that cannot be made smaller. Since it is not safe to move
+// nodes in the middle of a recursive operation, the reordering is delayed until
+// the end of the operation (such as Apply or Exist) that triggered it. The
+// default method is ReorderNone, meaning that the order only changes with
+// explicit calls to Reorder or SetVarOrder. See also option Reorderperiod.
+func Autoreorder(method ReorderMethod, threshold int) func(*configs) {
+	return func(c *configs) {
+		c.autoreorder = nil
+		if method != ReorderNone {
+			c.autoreorder = &trigger{method: method, threshold: threshold}
+		}
+	}
+}
+
+// Reorderperiod is a configuration option (function). Used as a parameter in
+// New, together with Autoreorder, it starts an automatic reordering after
+// every gcs garbage collections (or resizes) of the node table, even when the
+// number of nodes in use stays below the threshold of Autoreorder. The count
+// restarts after each automatic reordering. The default value (0) means that
+// we only reorder when the threshold is crossed. This option has no effect if
+// the automatic reordering is disabled.
+func Reorderperiod(gcs int) func(*configs) {
+	return func(c *configs) {
+		c.reorderperiod = gcs
+	}
+}
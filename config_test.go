@@ -0,0 +1,197 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"math/big"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHashPolicy(t *testing.T) {
+	N := 8
+	expected := big.NewInt(int64(N))
+	pow := big.NewInt(0)
+	pow.SetBit(pow, 4*N+1, 1)
+	expected.Mul(expected, pow)
+	for _, h := range []NodeHash{PairHash, FibonacciHash} {
+		bdd, R := milner(t, true, N, Nodesize(100), Cachesize(25), Nodehash(h), Maxchain(2))
+		if actual := bdd.Satcount(R); actual.Cmp(expected) != 0 {
+			t.Errorf("Error in Milner(%d), expected %s, actual %s", N, expected, actual)
+		}
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	bdd, R := milner(t, true, 6, Nodesize(100), Cachesize(25), Assertions(true))
+	var buf bytes.Buffer
+	bdd.Save(&buf, R)
+	if _, err := bdd.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// we try to build a node with a successor that is above it
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Makenode should panic with a badly ordered node")
+		}
+	}()
+	bdd.Makenode(3, 0, *bdd.Ithvar(1))
+}
+
+func TestValidate(t *testing.T) {
+	if w := Validate(10); w != nil {
+		t.Errorf("expected no warnings for the default configuration, actual %v", w)
+	}
+	tests := []struct {
+		options  []func(*configs)
+		expected string
+	}{
+		{[]func(*configs){Nodesize(1000000), Cachesize(1000), Cacheratio(10)}, "far smaller"},
+		{[]func(*configs){Nodesize(100000)}, "will not grow"},
+		{[]func(*configs){Minfreenodes(80)}, "resize"},
+		{[]func(*configs){Nodesize(1000), Maxnodesize(500)}, "maximal number of nodes"},
+	}
+	for _, tt := range tests {
+		w := Validate(10, tt.options...)
+		if len(w) != 1 || !strings.Contains(w[0], tt.expected) {
+			t.Errorf("expected a warning with %q, actual %v", tt.expected, w)
+		}
+	}
+	var buf bytes.Buffer
+	bdd, _ := New(10, Minfreenodes(60), Logger(log.New(&buf, "", 0)))
+	if len(bdd.Warnings()) != 1 || !strings.Contains(buf.String(), "rudd: ratio of free nodes") {
+		t.Errorf("New should log the warnings, actual %q", buf.String())
+	}
+}
+
+func TestStrict(t *testing.T) {
+	bdd, _ := New(4, Strict(true))
+	if bdd.Ithvar(4) != nil || !bdd.Errored() {
+		t.Fatal("Ithvar(4) should fail")
+	}
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok {
+			t.Fatalf("an operation after an error should panic in strict mode, recovered %v", r)
+		}
+		if msg := err.Error(); !strings.Contains(msg, "Unknown variable") || !strings.Contains(msg, "TestStrict") {
+			t.Errorf("panic should report the first error and its stack trace, actual %q", msg)
+		}
+	}()
+	bdd.Not(bdd.Ithvar(4))
+}
+
+// pairs returns the conjunction of (x_i <=> x_{i+n}) for i in [0..n), whose
+// size grows exponentially with n with the default variable order. We stop at
+// the first error.
+func pairs(bdd *BDD, n int) Node {
+	res := bdd.True()
+	for i := 0; i < n && res != nil; i++ {
+		if eq := bdd.Equiv(bdd.Ithvar(i), bdd.Ithvar(i+n)); eq != nil {
+			res = bdd.And(res, eq)
+		} else {
+			res = nil
+		}
+	}
+	return res
+}
+
+func TestOutofmemory(t *testing.T) {
+	bdd, _ := New(20, Nodesize(100), Maxnodesize(500))
+	if pairs(bdd, 10) != nil || !strings.Contains(bdd.Error(), ErrMemory.Error()) {
+		t.Errorf("expected an error with policy OOMError, actual %q", bdd.Error())
+	}
+	bdd, _ = New(20, Nodesize(100), Maxnodesize(500), Outofmemory(OOMPanic))
+	func() {
+		defer func() {
+			err, ok := recover().(error)
+			if !ok || !errors.Is(err, ErrMemory) {
+				t.Errorf("expected a panic with ErrMemory, recovered %v", err)
+			}
+		}()
+		pairs(bdd, 10)
+	}()
+	// the handler frees some nodes and asks for a retry the first time
+	var waste Node
+	calls := 0
+	handler := func(b *BDD) bool {
+		calls++
+		if waste == nil {
+			return false
+		}
+		waste = nil
+		return true
+	}
+	bdd, _ = New(20, Nodesize(100), Maxnodesize(425), Memoryhandler(handler))
+	waste = pairs(bdd, 5)
+	if waste == nil {
+		t.Fatal(bdd.Error())
+	}
+	res := pairs(bdd, 6)
+	if res == nil || calls == 0 {
+		t.Fatalf("expected a successful retry after a call to the handler, actual %d calls, %s", calls, bdd.Error())
+	}
+	// the result must still be correct after the retry
+	expected := big.NewInt(0).Lsh(big.NewInt(1), 14)
+	if actual := bdd.Satcount(res); actual.Cmp(expected) != 0 {
+		t.Errorf("wrong result after a retry, expected %s models, actual %s", expected, actual)
+	}
+	for i := 0; i < 6; i++ {
+		eq := bdd.Equiv(bdd.Ithvar(i), bdd.Ithvar(i+6))
+		if !bdd.Equal(bdd.Imp(res, eq), bdd.True()) {
+			t.Errorf("wrong result after a retry, expected x%d <=> x%d", i, i+6)
+		}
+	}
+	if waste != nil {
+		t.Error("expected a call to the handler")
+	}
+}
+
+func TestMemoryhandlerCache(t *testing.T) {
+	// the handler always asks for a retry, which succeeds since the garbage
+	// collection done before the first failure frees enough nodes; node ids
+	// recycled by this collection must not be returned by the caches.
+	calls := 0
+	handler := func(b *BDD) bool {
+		calls++
+		return true
+	}
+	bdd, _ := New(10, Nodesize(100), Maxnodesize(100), Minfreenodes(60), Memoryhandler(handler))
+	for i := 0; i < 10; i++ {
+		bdd.And(bdd.Ithvar(i), bdd.Ithvar((i+1)%10))
+	}
+	bdd.FlushReleasedRefs()
+	var roots []Node
+	for i := 2; calls == 0 && i < 10; i++ {
+		res := pairs(bdd, i/2)
+		if res == nil {
+			t.Fatal(bdd.Error())
+		}
+		roots = append(roots, bdd.Apply(res, bdd.Ithvar(i), OPxor))
+	}
+	if calls == 0 {
+		t.Fatal("expected a call to the handler")
+	}
+	for i := 0; i < 10; i++ {
+		res := bdd.And(bdd.Ithvar(i), bdd.Ithvar((i+1)%10))
+		if res == nil {
+			t.Fatal(bdd.Error())
+		}
+		lo, hi := i, (i+1)%10
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if bdd.Label(res) != lo || *bdd.Low(res) != *bdd.False() || *bdd.High(res) != *bdd.Ithvar(hi) {
+			t.Errorf("wrong result for x%d & x%d after a retry: node %d is (%d, %d, %d)",
+				i, (i+1)%10, *res, bdd.Label(res), *bdd.Low(res), *bdd.High(res))
+		}
+	}
+	runtime.KeepAlive(roots)
+}
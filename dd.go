@@ -0,0 +1,180 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "math/big"
+
+// DD is an interface implementing the basic operations over Decision Diagrams.
+// It is implemented by *BDD and can be used by client code to mock the engine,
+// or to wrap it (for instance with logging or metrics) without depending on a
+// concrete type.
+type DD interface {
+	// Error returns the error status of the BDD. We return an empty string if
+	// there are no errors. Functions that return a Node result will signal an
+	// error by returning nil.
+	Error() string
+
+	// Errored returns true if there was an error during a computation.
+	Errored() bool
+
+	// Varnum returns the number of defined variables.
+	Varnum() int
+
+	// Ithvar returns a BDD representing the i'th variable on success. The
+	// requested variable must be in the range [0..Varnum).
+	Ithvar(i int) Node
+
+	// NIthvar returns a bdd representing the negation of the i'th variable on
+	// success. See *ithvar* for further info.
+	NIthvar(i int) Node
+
+	// True returns the constant true BDD.
+	True() Node
+
+	// False returns the constant false BDD.
+	False() Node
+
+	// Low returns the false branch of a BDD or nil if there is an error.
+	Low(n Node) Node
+
+	// High returns the true branch of a BDD.
+	High(n Node) Node
+
+	// Var returns the index of the variable labelling node n.
+	Var(n Node) int
+
+	// Makeset returns a node corresponding to the conjunction (the cube) of all
+	// the variables in varset, in their positive form. It is such that
+	// scanset(Makeset(a)) == a. It returns nil if one of the variables is
+	// outside the scope of the BDD (see documentation for function *Ithvar*).
+	Makeset(varset []int) Node
+
+	// Scanset returns the set of variables occurring in the cube n. This is the
+	// dual of function Makeset.
+	Scanset(n Node) []int
+
+	// IsCube returns true if n is a conjunction of literals.
+	IsCube(n Node) bool
+
+	// Makecube returns the conjunction of the variables in varset, each one
+	// taken with the polarity given at the same position in polarity.
+	Makecube(varset []int, polarity []bool) Node
+
+	// Makelits returns a node corresponding to the conjunction of the literals
+	// in lits, where each literal is a variable with a polarity.
+	Makelits(lits []Literal) Node
+
+	// Scanlits returns the literals of the cube n. This is the dual of
+	// function Makelits.
+	Scanlits(n Node) []Literal
+
+	// Not returns the negation (!n) of expression n.
+	Not(n Node) Node
+
+	// Apply performs all of the basic binary operations on BDD nodes, such as
+	// AND, OR etc.
+	Apply(n1, n2 Node, op Operator) Node
+
+	// And returns the logical 'and' of a sequence of nodes.
+	And(n ...Node) Node
+
+	// Or returns the logical 'or' of a sequence of nodes.
+	Or(n ...Node) Node
+
+	// Imp returns the logical 'implication' between two nodes.
+	Imp(n1, n2 Node) Node
+
+	// Equiv returns the logical 'bi-implication' between two nodes.
+	Equiv(n1, n2 Node) Node
+
+	// Equal tests equivalence between nodes.
+	Equal(n1, n2 Node) bool
+
+	// Leq returns true if f implies g, without creating new nodes.
+	Leq(f, g Node) bool
+
+	// Intersects returns true if the conjunction of f and g is satisfiable,
+	// without creating new nodes.
+	Intersects(f, g Node) bool
+
+	// IteConstant tests whether Ite(f, g, h) is a constant, without creating
+	// new nodes, and returns its value (0 or 1) in this case.
+	IteConstant(f, g, h Node) (int, bool)
+
+	// Ite, short for if-then-else operator, computes the BDD for the expression
+	// [(f &  g) | (!f & h)] more efficiently than doing the three operations
+	// separately.
+	Ite(f, g, h Node) Node
+
+	// Simplify tries to simplify the BDD f by restricting it to the domain
+	// covered by d.
+	Simplify(f, d Node) Node
+
+	// Exist returns the existential quantification of n for the variables in
+	// varset, where varset is a node built with a method such as Makeset.
+	Exist(n, varset Node) Node
+
+	// AppEx applies the binary operator *op* on the two operands n1 and n2
+	// then performs an existential quantification over the variables in
+	// varset, where varset is a node computed with an operation such as
+	// Makeset.
+	AppEx(n1, n2 Node, op Operator, varset Node) Node
+
+	// Replace takes a renamer and computes the result of n after replacing old
+	// variables with new ones. See type Replacer.
+	Replace(n Node, r Replacer) Node
+
+	// Compose returns the result of substituting the function g for the
+	// variable of index v in f.
+	Compose(f Node, v int, g Node) Node
+
+	// VecCompose takes a Composer and computes the result of n after
+	// substituting variables with functions, simultaneously.
+	VecCompose(n Node, c Composer) Node
+
+	// Satcount computes the number of satisfying variable assignments for the
+	// function denoted by n. We return a result using arbitrary-precision
+	// arithmetic to avoid possible overflows. The result is zero (and we set
+	// the error flag of b) if there is an error.
+	Satcount(n Node) *big.Int
+
+	// Fullsatone returns a complete assignment satisfying n, where the
+	// don't care variables take the value polarity.
+	Fullsatone(n Node, polarity int) Node
+
+	// Satoneset returns a cube over exactly the variables in varset that is
+	// compatible with n, where don't cares take the polarity pol.
+	Satoneset(n, varset, pol Node) Node
+
+	// Eval returns the value of the function denoted by n for an assignment
+	// of the variables.
+	Eval(n Node, assignment []bool) (bool, error)
+
+	// PathCount returns the number of paths from n to the True constant.
+	PathCount(n Node) *big.Int
+
+	// AnodeCount returns the number of nodes in the graph shared by the roots
+	// in n, not counting the two constants.
+	AnodeCount(n ...Node) int
+
+	// Allsat Iterates through all legal variable assignments for n and calls
+	// the function f on each of them. We pass an int slice of length varnum to
+	// f where each entry is either 0 if the variable is false, 1 if it is true,
+	// and -1 if it is a don't care. We stop and return an error if f returns an
+	// error at some point.
+	Allsat(f func([]int) error, n Node) error
+
+	// Allnodes is similar to Allsat but iterates over all the nodes accessible
+	// from one of the parameters in n (or all the active nodes if n is absent).
+	// Function f takes the id, level, and id's of the low and high successors
+	// of each node. The two constant nodes (True and False) have always the id
+	// 1 and 0 respectively.
+	Allnodes(f func(id, level, low, high int) error, n ...Node) error
+
+	// Stats returns information about the BDD
+	Stats() string
+}
+
+var _ DD = (*BDD)(nil)
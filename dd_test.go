@@ -0,0 +1,34 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+// countingDD wraps a DD and counts the number of calls to And.
+type countingDD struct {
+	DD
+	ands int
+}
+
+func (c *countingDD) And(n ...Node) Node {
+	c.ands++
+	return c.DD.And(n...)
+}
+
+func TestDD(t *testing.T) {
+	bdd, _ := New(3, Nodesize(1000), Cachesize(1000))
+	var d DD = &countingDD{DD: bdd}
+	n := d.And(d.Ithvar(0), d.NIthvar(1))
+	n = d.And(n, d.Ithvar(2))
+	if d.(*countingDD).ands != 2 {
+		t.Errorf("expected 2 calls to And, actual %d", d.(*countingDD).ands)
+	}
+	if !d.Equal(n, d.Makecube([]int{0, 1, 2}, []bool{true, false, true})) {
+		t.Error("wrapped And: unexpected result")
+	}
+	if d.Satcount(n).Int64() != 1 {
+		t.Errorf("expected 1 satisfying assignment, actual %s", d.Satcount(n))
+	}
+}
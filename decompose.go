@@ -0,0 +1,142 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// DecompKind is the kind of decomposition found by Decompose.
+type DecompKind int
+
+const (
+	// DecompNone means that no decomposition was found.
+	DecompNone DecompKind = iota
+	// DecompAnd is for a function equal to the conjunction of its parts.
+	DecompAnd
+	// DecompOr is for a function equal to the disjunction of its parts.
+	DecompOr
+	// DecompXor is for a function equal to the exclusive or of its parts.
+	DecompXor
+)
+
+func (k DecompKind) String() string {
+	switch k {
+	case DecompNone:
+		return "none"
+	case DecompAnd:
+		return "and"
+	case DecompOr:
+		return "or"
+	case DecompXor:
+		return "xor"
+	}
+	return fmt.Sprintf("DecompKind(%d)", int(k))
+}
+
+// Decompose tries to find a disjoint-support decomposition of the function
+// denoted by n, meaning two functions g and h, depending on disjoint sets of
+// variables, such that n is equal to g & h, g | h, or g ^ h. When this is the
+// case, we return the kind of decomposition, the two parts [g, h], and true.
+// This is useful for splitting large functions into independent modules.
+//
+// We split the support of n into two sets A and B, where A initially contains
+// the top variable of n, and test whether n can be decomposed with g (resp. h)
+// depending only on A (resp. B), using quantifications and cofactors. If this
+// is not the case, we grow A, one variable at a time, by selecting the variable
+// that gives the smallest number of errors. This is a heuristic, so we may
+// miss some decompositions, but all the decompositions that we return are
+// correct. We return DecompNone and false if there is an error (in which case
+// we also set the error flag of b), or if n depends on less than two
+// variables.
+func (b *BDD) Decompose(n Node) (DecompKind, []Node, bool) {
+	support := b.SupportSet(n)
+	if len(support) < 2 {
+		return DecompNone, nil, false
+	}
+	ina := map[int]bool{support[0]: true}
+	kind, parts, errors := b.decomposewith(n, support, ina)
+	for errors != nil && errors.Sign() != 0 && len(ina) < len(support)-1 {
+		var best *big.Int
+		bestvar := -1
+		for _, v := range support {
+			if ina[v] {
+				continue
+			}
+			ina[v] = true
+			k, p, e := b.decomposewith(n, support, ina)
+			delete(ina, v)
+			if e == nil || e.Sign() == 0 {
+				return k, p, e != nil
+			}
+			if best == nil || e.Cmp(best) < 0 {
+				best, bestvar = e, v
+			}
+		}
+		ina[bestvar] = true
+		errors = best
+	}
+	if errors == nil || errors.Sign() != 0 {
+		return DecompNone, nil, false
+	}
+	return kind, parts, true
+}
+
+// decomposewith tests the three kinds of decompositions of n with a split of
+// the support between the variables in A (such that ina[v] is true) and the
+// other ones. We return the decomposition with the smallest number of errors,
+// meaning the number of assignments where the candidate differs from n. We
+// return a nil number of errors if there is an error.
+func (b *BDD) decomposewith(n Node, support []int, ina map[int]bool) (DecompKind, []Node, *big.Int) {
+	avars, bvars := []int{}, []int{}
+	for _, v := range support {
+		if ina[v] {
+			avars = append(avars, v)
+		} else {
+			bvars = append(bvars, v)
+		}
+	}
+	aset, bset := b.Makeset(avars), b.Makeset(bvars)
+	// And: g = ∃B.n and h = ∃A.n; Or is the dual with the negation of n
+	g, h := b.Exist(n, bset), b.Exist(n, aset)
+	notn := b.Not(n)
+	ng, nh := b.Exist(notn, bset), b.Exist(notn, aset)
+	// Xor: g = n[B:=0] and h = n[A:=0] ^ n[A:=0,B:=0]
+	acube := b.Makecube(avars, make([]bool, len(avars)))
+	bcube := b.Makecube(bvars, make([]bool, len(bvars)))
+	xg := b.Exist(b.And(n, bcube), bset)
+	xh := b.Exist(b.And(n, acube), aset)
+	if b.Errored() {
+		return DecompNone, nil, nil
+	}
+	if b.Exist(b.And(xg, acube), aset) == b.True() {
+		xh = b.Not(xh)
+	}
+	candidates := []struct {
+		kind  DecompKind
+		parts []Node
+		res   Node
+	}{
+		{DecompAnd, []Node{g, h}, b.And(g, h)},
+		{DecompOr, []Node{b.Not(ng), b.Not(nh)}, b.Not(b.And(ng, nh))},
+		{DecompXor, []Node{xg, xh}, b.Apply(xg, xh, OPxor)},
+	}
+	if b.Errored() {
+		return DecompNone, nil, nil
+	}
+	var best *big.Int
+	kind, parts := DecompNone, []Node{}
+	for _, c := range candidates {
+		errors := b.Satcount(b.Apply(n, c.res, OPxor))
+		if best == nil || errors.Cmp(best) < 0 {
+			best, kind, parts = errors, c.kind, c.parts
+		}
+	}
+	if b.Errored() {
+		return DecompNone, nil, nil
+	}
+	return kind, parts, best
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+func TestDecompose(t *testing.T) {
+	bdd, _ := New(5, Nodesize(1000), Cachesize(1000))
+	x := func(v int) Node { return bdd.Ithvar(v) }
+	xor := func(a, b Node) Node { return bdd.Apply(a, b, OPxor) }
+	tests := []struct {
+		name string
+		n    Node
+		kind DecompKind
+	}{
+		{"(x0 & x2) | (x1 & x3)", bdd.Or(bdd.And(x(0), x(2)), bdd.And(x(1), x(3))), DecompOr},
+		{"(x0 | x3) & (x1 ^ x2)", bdd.And(bdd.Or(x(0), x(3)), xor(x(1), x(2))), DecompAnd},
+		{"x0 ^ !x4 ^ (x2 & x3)", xor(xor(x(0), bdd.NIthvar(4)), bdd.And(x(2), x(3))), DecompXor},
+		{"maj(x0, x1, x2)", bdd.Or(bdd.And(x(0), x(1)), bdd.And(x(0), x(2)), bdd.And(x(1), x(2))), DecompNone},
+		{"x1", x(1), DecompNone},
+	}
+	for _, tt := range tests {
+		kind, parts, ok := bdd.Decompose(tt.n)
+		if kind != tt.kind || ok != (tt.kind != DecompNone) {
+			t.Errorf("Decompose(%s): expected %s, actual %s (%v)", tt.name, tt.kind, kind, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		var res Node
+		switch kind {
+		case DecompAnd:
+			res = bdd.And(parts...)
+		case DecompOr:
+			res = bdd.Or(parts...)
+		case DecompXor:
+			res = xor(parts[0], parts[1])
+		}
+		if !bdd.Equal(res, tt.n) {
+			t.Errorf("Decompose(%s): the parts do not compose into the function", tt.name)
+		}
+		for _, v := range bdd.SupportSet(parts[0]) {
+			for _, w := range bdd.SupportSet(parts[1]) {
+				if v == w {
+					t.Errorf("Decompose(%s): variable %d occurs in both parts", tt.name, v)
+				}
+			}
+		}
+	}
+	if bdd.Errored() {
+		t.Error(bdd.Error())
+	}
+	if _, _, ok := bdd.Decompose(nil); ok || !bdd.Errored() {
+		t.Error("expected an error with a nil node")
+	}
+}
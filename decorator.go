@@ -0,0 +1,245 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware is a function called around every operation of a DD built with
+// Decorate. Parameter op is the name of the operation, such as "Apply" or
+// "Exist", and next computes its result. A middleware returning a non-nil
+// error sets the error flag of the decorated DD, in which case the operation
+// returns nil.
+type Middleware func(op string, next func() Node) (Node, error)
+
+// decorated is the DD returned by Decorate. Methods that do not build a new
+// node, such as Satcount or Scanset, are delegated to the embedded DD.
+type decorated struct {
+	DD
+	around func(op string, next func() Node) Node
+	error  error
+}
+
+// Decorate returns a DD that behaves like d but where all the operations
+// returning a Node, like Apply or Exist, are wrapped by the middlewares in m.
+// The first middleware is the outermost one. Decorators can be stacked, since
+// the result of Decorate is also a DD.
+func Decorate(d DD, m ...Middleware) DD {
+	dec := &decorated{DD: d}
+	around := func(op string, next func() Node) Node { return next() }
+	for i := len(m) - 1; i >= 0; i-- {
+		inner, mw := around, m[i]
+		around = func(op string, next func() Node) Node {
+			res, err := mw(op, func() Node { return inner(op, next) })
+			if err != nil {
+				dec.seterror(op, err)
+				return nil
+			}
+			return res
+		}
+	}
+	dec.around = around
+	return dec
+}
+
+func (d *decorated) seterror(op string, err error) {
+	err = fmt.Errorf("%s in call to %s", err, op)
+	if d.error != nil {
+		err = fmt.Errorf("%s; %s", err, d.error)
+	}
+	d.error = err
+}
+
+// Error returns the error status of the decorated DD, followed by the one of
+// the underlying DD.
+func (d *decorated) Error() string {
+	switch {
+	case d.error == nil:
+		return d.DD.Error()
+	case d.DD.Errored():
+		return d.error.Error() + "; " + d.DD.Error()
+	default:
+		return d.error.Error()
+	}
+}
+
+// Errored returns true if there was an error in a middleware or during a
+// computation.
+func (d *decorated) Errored() bool {
+	return d.error != nil || d.DD.Errored()
+}
+
+func (d *decorated) Makeset(varset []int) Node {
+	return d.around("Makeset", func() Node { return d.DD.Makeset(varset) })
+}
+
+func (d *decorated) Makecube(varset []int, polarity []bool) Node {
+	return d.around("Makecube", func() Node { return d.DD.Makecube(varset, polarity) })
+}
+
+func (d *decorated) Makelits(lits []Literal) Node {
+	return d.around("Makelits", func() Node { return d.DD.Makelits(lits) })
+}
+
+func (d *decorated) Not(n Node) Node {
+	return d.around("Not", func() Node { return d.DD.Not(n) })
+}
+
+func (d *decorated) Apply(n1, n2 Node, op Operator) Node {
+	return d.around("Apply", func() Node { return d.DD.Apply(n1, n2, op) })
+}
+
+func (d *decorated) And(n ...Node) Node {
+	return d.around("And", func() Node { return d.DD.And(n...) })
+}
+
+func (d *decorated) Or(n ...Node) Node {
+	return d.around("Or", func() Node { return d.DD.Or(n...) })
+}
+
+func (d *decorated) Imp(n1, n2 Node) Node {
+	return d.around("Imp", func() Node { return d.DD.Imp(n1, n2) })
+}
+
+func (d *decorated) Equiv(n1, n2 Node) Node {
+	return d.around("Equiv", func() Node { return d.DD.Equiv(n1, n2) })
+}
+
+func (d *decorated) Ite(f, g, h Node) Node {
+	return d.around("Ite", func() Node { return d.DD.Ite(f, g, h) })
+}
+
+func (d *decorated) Simplify(f, dom Node) Node {
+	return d.around("Simplify", func() Node { return d.DD.Simplify(f, dom) })
+}
+
+func (d *decorated) Exist(n, varset Node) Node {
+	return d.around("Exist", func() Node { return d.DD.Exist(n, varset) })
+}
+
+func (d *decorated) AppEx(n1, n2 Node, op Operator, varset Node) Node {
+	return d.around("AppEx", func() Node { return d.DD.AppEx(n1, n2, op, varset) })
+}
+
+func (d *decorated) Replace(n Node, r Replacer) Node {
+	return d.around("Replace", func() Node { return d.DD.Replace(n, r) })
+}
+
+func (d *decorated) Compose(f Node, v int, g Node) Node {
+	return d.around("Compose", func() Node { return d.DD.Compose(f, v, g) })
+}
+
+func (d *decorated) VecCompose(n Node, c Composer) Node {
+	return d.around("VecCompose", func() Node { return d.DD.VecCompose(n, c) })
+}
+
+func (d *decorated) Fullsatone(n Node, polarity int) Node {
+	return d.around("Fullsatone", func() Node { return d.DD.Fullsatone(n, polarity) })
+}
+
+func (d *decorated) Satoneset(n, varset, pol Node) Node {
+	return d.around("Satoneset", func() Node { return d.DD.Satoneset(n, varset, pol) })
+}
+
+// Logging returns a middleware that prints the name and duration of every
+// operation on l, together with a mark when the operation fails.
+func Logging(l *log.Logger) Middleware {
+	return func(op string, next func() Node) (Node, error) {
+		start := time.Now()
+		res := next()
+		if res == nil {
+			l.Printf("%s: %s (error)", op, time.Since(start))
+		} else {
+			l.Printf("%s: %s", op, time.Since(start))
+		}
+		return res, nil
+	}
+}
+
+// OpMetrics records the number of calls to an operation, the number of calls
+// that returned an error, and the total time spent in the operation.
+type OpMetrics struct {
+	Calls    int           // Number of calls to the operation
+	Failures int           // Number of calls that returned nil
+	Duration time.Duration // Total time spent in the operation
+}
+
+// Metrics collects an OpMetrics for every operation of a decorated DD. The
+// zero value is ready to use and it is safe to read the metrics while they
+// are updated from another goroutine.
+type Metrics struct {
+	mu  sync.Mutex
+	ops map[string]OpMetrics
+}
+
+// Middleware returns a middleware that updates the metrics in m.
+func (m *Metrics) Middleware() Middleware {
+	return func(op string, next func() Node) (Node, error) {
+		start := time.Now()
+		res := next()
+		elapsed := time.Since(start)
+		m.mu.Lock()
+		if m.ops == nil {
+			m.ops = make(map[string]OpMetrics)
+		}
+		s := m.ops[op]
+		s.Calls++
+		s.Duration += elapsed
+		if res == nil {
+			s.Failures++
+		}
+		m.ops[op] = s
+		m.mu.Unlock()
+		return res, nil
+	}
+}
+
+// Get returns the metrics collected for operation op.
+func (m *Metrics) Get(op string) OpMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ops[op]
+}
+
+func (m *Metrics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.ops))
+	for op := range m.ops {
+		names = append(names, op)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, op := range names {
+		s := m.ops[op]
+		fmt.Fprintf(&sb, "%-10s %8d calls %6d failures %12s\n", op, s.Calls, s.Failures, s.Duration)
+	}
+	return sb.String()
+}
+
+// Budget returns a middleware that allows at most calls operations. All the
+// operations that go beyond the budget are not executed and set the error
+// flag of the decorated DD. The budget is shared by all the decorators using
+// the same middleware.
+func Budget(calls int) Middleware {
+	var mu sync.Mutex
+	left := calls
+	return func(op string, next func() Node) (Node, error) {
+		mu.Lock()
+		if left <= 0 {
+			mu.Unlock()
+			return nil, fmt.Errorf("operation budget (%d) exhausted", calls)
+		}
+		left--
+		mu.Unlock()
+		return next(), nil
+	}
+}
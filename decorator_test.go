@@ -0,0 +1,39 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestDecorate(t *testing.T) {
+	bdd, _ := New(3, Nodesize(1000), Cachesize(1000))
+	var sb strings.Builder
+	m := &Metrics{}
+	d := Decorate(bdd, Logging(log.New(&sb, "", 0)), m.Middleware(), Budget(4))
+	n := d.And(d.Ithvar(0), d.Ithvar(1))
+	n = d.Or(n, d.Ithvar(2))
+	d.Exist(n, d.Makeset([]int{2}))
+	if d.Errored() {
+		t.Fatalf("unexpected error: %s", d.Error())
+	}
+	if d.Not(n) != nil || !d.Errored() {
+		t.Error("expected an error when the budget is exhausted")
+	}
+	if bdd.Errored() {
+		t.Errorf("budget errors should not change the underlying BDD: %s", bdd.Error())
+	}
+	if got := m.Get("And").Calls; got != 1 {
+		t.Errorf("expected 1 call to And, actual %d", got)
+	}
+	if got := m.Get("Not"); got.Calls != 1 || got.Failures != 1 {
+		t.Errorf("expected 1 failed call to Not, actual %+v", got)
+	}
+	if lines := strings.Count(sb.String(), "\n"); lines != 5 {
+		t.Errorf("expected 5 lines of log, actual %d:\n%s", lines, sb.String())
+	}
+}
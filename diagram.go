@@ -0,0 +1,312 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+
+	"github.com/dalzilio/rudd/internal/dd"
+)
+
+// Diagram is a multi-terminal decision diagram (also called MTBDD or ADD),
+// meaning a decision diagram where leaves are labelled with values of type T
+// instead of the two constants False and True. This can be used to encode
+// functions from Boolean vectors to an arbitrary domain of values, such as
+// integers, probabilities, intervals or bit-sets. Like with BDD, nodes are
+// unique and there is at most one leaf for each value.
+//
+// The operations on values are given by the user, with Operation, and lifted
+// to diagrams with Apply and Abstract. A Diagram is built on the same kernel
+// than a BDD, meaning the same node table, garbage collector and error
+// handling, and accepts the same configuration options (such as Nodesize,
+// Cachesize or Strict); a BDD is the Diagram of Booleans whose two leaves are
+// the constants False and True. The level of a variable is always equal to its
+// index. As with BDD, a Diagram is not safe for concurrent use.
+type Diagram[T comparable] struct {
+	kernel[T]                          // Node table, with the leaves at level varnum
+	cache      dd.Cache4               // Cache for Apply and Abstract results
+	ops        int                     // Number of operations registered with Operation
+	abstractID int                     // Current cache id for Abstract
+	predefined map[string]DiagramOp[T] // Operations returned by Plus, Times, Min and Max
+}
+
+// DiagramOp is a binary operation on values of type T, registered with
+// Diagram.Operation, that can be used with Apply and Abstract.
+type DiagramOp[T comparable] struct {
+	d  *Diagram[T]
+	id int
+	f  func(x, y T) T
+}
+
+// NewDiagram returns a new multi-terminal diagram with varnum variables. The
+// configuration options are the same than with New, but options Journal and
+// Assertions are ignored. We return a nil value if there is an error.
+func NewDiagram[T comparable](varnum int, options ...func(*configs)) (*Diagram[T], error) {
+	if (varnum < 1) || (varnum > int(_MAXVAR)) {
+		return nil, fmt.Errorf("bad number of variable (%d)", varnum)
+	}
+	config := makeconfigs(varnum)
+	for _, f := range options {
+		f(config)
+	}
+	d := &Diagram[T]{}
+	d.initkernel(varnum, config)
+	d.setaftergc(d.sweepleaves)
+	size := 10000
+	if config.cachesize != 0 {
+		size = config.cachesize
+	}
+	d.cache.Init(size, config.cacheratio)
+	return d, nil
+}
+
+// Operation registers a binary operation on values, for use with Apply and
+// Abstract. The results of operations are cached, so f should be a pure
+// function.
+func (d *Diagram[T]) Operation(f func(x, y T) T) DiagramOp[T] {
+	d.ops++
+	return DiagramOp[T]{d: d, id: d.ops, f: f}
+}
+
+func (d *Diagram[T]) makenode(level int32, low, high int) int {
+	if low < 0 || high < 0 && high != dd.Leaf {
+		return -1
+	}
+	res, err := d.tables.makenode(level, low, high, d.refstack)
+	switch err {
+	case dd.ErrReset:
+		d.cache.Reset()
+	case dd.ErrResize:
+		d.cache.Resize(d.size())
+	case dd.ErrMemory:
+		d.seterror("%s", err)
+	}
+	return res
+}
+
+// leaf returns the id of the leaf for value v, creating it if needed.
+func (d *Diagram[T]) leaf(v T) int {
+	if id, ok := d.leaves[v]; ok {
+		return id
+	}
+	d.nextkey++
+	id := d.makenode(d.varnum, d.nextkey, dd.Leaf)
+	if id < 0 {
+		return -1
+	}
+	d.leaves[v] = id
+	d.values[id] = v
+	return id
+}
+
+func (d *Diagram[T]) checkptr(n Node) error {
+	if err := d.checknode(n); err != nil {
+		d.seterror("%s", err)
+		return d.error
+	}
+	return nil
+}
+
+// Leaf returns the leaf labelled with value v.
+func (d *Diagram[T]) Leaf(v T) Node {
+	d.refstack = d.refstack[:0]
+	return d.retnode(d.leaf(v))
+}
+
+// Var returns a diagram that maps every assignment to value low, when variable i
+// is false, or to value high otherwise. The variable must be in the range
+// [0..Varnum).
+func (d *Diagram[T]) Var(i int, low, high T) Node {
+	if (i < 0) || (int32(i) >= d.varnum) {
+		return d.seterror("Unknown variable used (%d) in call to Var", i)
+	}
+	d.refstack = d.refstack[:0]
+	l := d.pushref(d.leaf(low))
+	h := d.pushref(d.leaf(high))
+	return d.retnode(d.makenode(int32(i), l, h))
+}
+
+// Value returns the value of n and true if n is a leaf; otherwise the zero value
+// of T and false.
+func (d *Diagram[T]) Value(n Node) (T, bool) {
+	var zero T
+	if d.checkptr(n) != nil || !d.isleaf(*n) {
+		return zero, false
+	}
+	return d.values[*n], true
+}
+
+// Eval returns the value associated with an assignment of the variables, given
+// as a slice of Varnum Booleans.
+func (d *Diagram[T]) Eval(n Node, assignment []bool) T {
+	var zero T
+	if d.checkptr(n) != nil {
+		return zero
+	}
+	if len(assignment) != int(d.varnum) {
+		d.seterror("Wrong size of assignment (%d) in call to Eval", len(assignment))
+		return zero
+	}
+	k := *n
+	for !d.isleaf(k) {
+		if assignment[d.level(k)] {
+			k = d.high(k)
+		} else {
+			k = d.low(k)
+		}
+	}
+	return d.values[k]
+}
+
+// Leaves returns the values of the leaves reachable from n, in the order they
+// are found during a depth-first traversal, low branches first.
+func (d *Diagram[T]) Leaves(n Node) []T {
+	if d.checkptr(n) != nil {
+		return nil
+	}
+	res := []T{}
+	seen := make(map[int]bool)
+	var visit func(k int)
+	visit = func(k int) {
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		if d.isleaf(k) {
+			res = append(res, d.values[k])
+			return
+		}
+		visit(d.low(k))
+		visit(d.high(k))
+	}
+	visit(*n)
+	return res
+}
+
+// Apply returns the diagram mapping each assignment to op(x, y), where x and y
+// are the values of n1 and n2 for this assignment.
+func (d *Diagram[T]) Apply(n1, n2 Node, op DiagramOp[T]) Node {
+	if d.checkptr(n1) != nil || d.checkptr(n2) != nil {
+		return d.seterror("Wrong operand in call to Apply")
+	}
+	if op.d != d {
+		return d.seterror("Operation not registered with this diagram in call to Apply")
+	}
+	d.refstack = d.refstack[:0]
+	d.pushref(*n1)
+	d.pushref(*n2)
+	return d.retnode(d.apply(*n1, *n2, op))
+}
+
+func (d *Diagram[T]) apply(left, right int, op DiagramOp[T]) int {
+	if left < 0 || right < 0 {
+		return -1
+	}
+	if d.isleaf(left) && d.isleaf(right) {
+		return d.leaf(op.f(d.values[left], d.values[right]))
+	}
+	// we use even ids for Apply and odd ones for Abstract
+	c := 2 * op.id
+	h := dd.Triple(left, right, c, len(d.cache.Table))
+	if e := d.cache.Table[h]; e.A == left && e.B == right && e.C == c {
+		return e.Res
+	}
+	level := d.level(left)
+	if l := d.level(right); l < level {
+		level = l
+	}
+	ll, lh := d.cofactors(left, level)
+	rl, rh := d.cofactors(right, level)
+	initial := len(d.refstack)
+	low := d.pushref(d.apply(ll, rl, op))
+	high := d.pushref(d.apply(lh, rh, op))
+	res := d.makenode(level, low, high)
+	d.refstack = d.refstack[:initial]
+	if res >= 0 {
+		d.cache.Table[h] = dd.Entry4{A: left, B: right, C: c, Res: res}
+	}
+	return res
+}
+
+// cofactors returns the low and high successors of n with respect to a
+// variable at the given level, that should be less or equal to the level of n.
+func (d *Diagram[T]) cofactors(n int, level int32) (int, int) {
+	if d.level(n) != level {
+		return n, n
+	}
+	return d.low(n), d.high(n)
+}
+
+// Abstract returns the diagram obtained by removing the variables in vars from
+// n, where the two branches of each node labelled with one of these variables
+// are combined with op. For instance, using addition for op, we get the sum of
+// the values of n over all the possible values of variables vars. A variable
+// of vars that is skipped on a path, meaning that the two branches are equal,
+// is also abstracted, by combining the branch with itself; so that a sum
+// counts the two branches.
+func (d *Diagram[T]) Abstract(n Node, vars []int, op DiagramOp[T]) Node {
+	if d.checkptr(n) != nil {
+		return d.seterror("Wrong operand in call to Abstract")
+	}
+	if op.d != d {
+		return d.seterror("Operation not registered with this diagram in call to Abstract")
+	}
+	set := make([]bool, d.varnum)
+	for _, v := range vars {
+		if (v < 0) || (int32(v) >= d.varnum) {
+			return d.seterror("Unknown variable used (%d) in call to Abstract", v)
+		}
+		set[v] = true
+	}
+	d.abstractID++
+	d.refstack = d.refstack[:0]
+	d.pushref(*n)
+	res := d.pushref(d.abstract(*n, set, op, 2*d.abstractID+1))
+	return d.retnode(d.skipped(res, 0, d.level(*n), set, op))
+}
+
+// skipped returns the result of abstracting the variables of set at levels
+// [from..to) in n, where n does not depend on these variables.
+func (d *Diagram[T]) skipped(n int, from, to int32, set []bool, op DiagramOp[T]) int {
+	initial := len(d.refstack)
+	for level := to - 1; level >= from && n >= 0; level-- {
+		if set[level] {
+			n = d.pushref(d.apply(n, n, op))
+		}
+	}
+	d.refstack = d.refstack[:initial]
+	return n
+}
+
+func (d *Diagram[T]) abstract(n int, set []bool, op DiagramOp[T], c int) int {
+	if n < 0 {
+		return -1
+	}
+	if d.isleaf(n) {
+		return n
+	}
+	h := dd.Pair(n, c, len(d.cache.Table))
+	if e := d.cache.Table[h]; e.A == n && e.B == -1 && e.C == c {
+		return e.Res
+	}
+	level := d.level(n)
+	initial := len(d.refstack)
+	lo, hi := d.low(n), d.high(n)
+	low := d.pushref(d.abstract(lo, set, op, c))
+	low = d.pushref(d.skipped(low, level+1, d.level(lo), set, op))
+	high := d.pushref(d.abstract(hi, set, op, c))
+	high = d.pushref(d.skipped(high, level+1, d.level(hi), set, op))
+	var res int
+	if set[level] {
+		res = d.apply(low, high, op)
+	} else {
+		res = d.makenode(level, low, high)
+	}
+	d.refstack = d.refstack[:initial]
+	if res >= 0 {
+		d.cache.Table[h] = dd.Entry4{A: n, B: -1, C: c, Res: res}
+	}
+	return res
+}
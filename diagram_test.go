@@ -0,0 +1,164 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"testing"
+)
+
+func TestDiagram(t *testing.T) {
+	d, err := NewDiagram[int](4, Nodesize(10), Cachesize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plus := d.Operation(func(x, y int) int { return x + y })
+	// n is the value of the binary number x3 x2 x1 x0
+	n := d.Leaf(0)
+	for k := 0; k < 4; k++ {
+		n = d.Apply(n, d.Var(k, 0, 1<<k), plus)
+	}
+	assignment := make([]bool, 4)
+	for v := 0; v < 16; v++ {
+		for k := range assignment {
+			assignment[k] = v&(1<<k) != 0
+		}
+		if actual := d.Eval(n, assignment); actual != v {
+			t.Errorf("Eval(%v), expected %d, actual %d", assignment, v, actual)
+		}
+	}
+	if leaves := d.Leaves(n); len(leaves) != 16 {
+		t.Errorf("expected 16 leaves, actual %d", len(leaves))
+	}
+	// the sum over x0 and x1 is 4*(x3 x2) + (0 + 1 + 2 + 3)
+	s := d.Abstract(n, []int{0, 1}, plus)
+	for k := range assignment {
+		assignment[k] = k >= 2
+	}
+	if actual := d.Eval(s, assignment); actual != 4*12+6 {
+		t.Errorf("Abstract, expected %d, actual %d", 4*12+6, actual)
+	}
+	if v, ok := d.Value(d.Abstract(n, []int{0, 1, 2, 3}, plus)); !ok || v != 120 {
+		t.Errorf("Abstract over all variables, expected leaf 120, actual %d", v)
+	}
+	// leaves and nodes are unique
+	if m := d.Apply(d.Leaf(0), n, plus); *m != *n {
+		t.Errorf("Apply should return the same node for the same function")
+	}
+	if d.Errored() {
+		t.Error(d.Error())
+	}
+	other, _ := NewDiagram[int](4)
+	if other.Apply(other.Leaf(1), other.Leaf(2), plus) != nil || !other.Errored() {
+		t.Errorf("Apply should fail with an operation of another diagram")
+	}
+}
+
+// TestDiagramBool checks that a Diagram of Booleans computes the same functions
+// than a BDD, and that its leaves are the constants of a BDD.
+func TestDiagramBool(t *testing.T) {
+	d, _ := NewDiagram[bool](6, Nodesize(10))
+	b, _ := New(6)
+	if *d.Leaf(false) != *b.False() || *d.Leaf(true) != *b.True() {
+		t.Errorf("the leaves of a Diagram[bool] should be the constants, actual %d and %d", *d.Leaf(false), *d.Leaf(true))
+	}
+	and := d.Operation(func(x, y bool) bool { return x && y })
+	or := d.Operation(func(x, y bool) bool { return x || y })
+	n := d.Leaf(false)
+	m := b.False()
+	for k := 0; k < 6; k += 2 {
+		n = d.Apply(n, d.Apply(d.Var(k, false, true), d.Var(k+1, true, false), and), or)
+		m = b.Or(m, b.And(b.Ithvar(k), b.NIthvar(k+1)))
+	}
+	assignment := make([]bool, 6)
+	for v := 0; v < 64; v++ {
+		for k := range assignment {
+			assignment[k] = v&(1<<k) != 0
+		}
+		expected := *b.And(m, b.Makecube([]int{0, 1, 2, 3, 4, 5}, assignment)) != 0
+		if actual := d.Eval(n, assignment); actual != expected {
+			t.Errorf("Eval(%v), expected %v, actual %v", assignment, expected, actual)
+		}
+	}
+}
+
+func TestDiagramNumeric(t *testing.T) {
+	const varnum = 5
+	d, _ := NewDiagram[int64](varnum, Nodesize(10), Cachesize(10))
+	b, _ := New(varnum)
+	if err := b.SetVarOrder([]int{4, 2, 0, 3, 1}); err != nil {
+		t.Fatal(err)
+	}
+	f := b.Or(b.And(b.Ithvar(0), b.Ithvar(3)), b.And(b.NIthvar(1), b.Ithvar(4)), b.Ithvar(2))
+	n := d.FromBDD(b, f, 0, 1)
+	// the sum over all the variables is the number of models of f
+	if v, ok := d.Value(d.Abstract(n, []int{0, 1, 2, 3, 4}, Plus(d))); !ok || v != b.Satcount(f).Int64() {
+		t.Errorf("expected the sum to be %s, actual %d", b.Satcount(f), v)
+	}
+	// weight is the sum of 2^k for the variables k that are true
+	weight := d.Leaf(0)
+	for k := 0; k < varnum; k++ {
+		weight = d.Apply(weight, d.Var(k, 0, 1<<k), Plus(d))
+	}
+	cost := d.Apply(n, weight, Times(d))
+	assignment := make([]bool, varnum)
+	max := int64(0)
+	for v := 0; v < 1<<varnum; v++ {
+		for k := range assignment {
+			assignment[k] = v&(1<<k) != 0
+		}
+		expected := int64(0)
+		if *b.And(f, b.Makecube([]int{0, 1, 2, 3, 4}, assignment)) != 0 {
+			expected = int64(v)
+			max = int64(v)
+		}
+		if actual := d.Eval(cost, assignment); actual != expected {
+			t.Errorf("Eval(%v), expected %d, actual %d", assignment, expected, actual)
+		}
+	}
+	if v, _ := d.Value(d.Abstract(cost, []int{0, 1, 2, 3, 4}, Max(d))); v != max {
+		t.Errorf("expected maximal cost %d, actual %d", max, v)
+	}
+	if v, _ := d.Value(d.Abstract(d.Apply(weight, d.Leaf(3), Min(d)), []int{0, 1, 2, 3, 4}, Plus(d))); v != 0+1+2+3*29 {
+		t.Errorf("expected the sum of min(weight, 3) to be %d, actual %d", 0+1+2+3*29, v)
+	}
+	if Plus(d).id != Plus(d).id {
+		t.Errorf("expected Plus to return the same operation")
+	}
+	if d.Errored() {
+		t.Fatal(d.Error())
+	}
+}
+
+// TestDiagramRat checks the predefined operations on rational values, with the
+// probability that a sequence of independent events, each of probability 1/3,
+// gives the values of variables.
+func TestDiagramRat(t *testing.T) {
+	const varnum = 4
+	d, _ := NewDiagram[Rat](varnum, Nodesize(10), Cachesize(10))
+	p := d.Leaf(NewRat(1, 1))
+	for k := 0; k < varnum; k++ {
+		p = d.Apply(p, d.Var(k, NewRat(2, 3), NewRat(1, 3)), TimesRat(d))
+	}
+	if v, ok := d.Value(d.Abstract(p, []int{0, 1, 2, 3}, PlusRat(d))); !ok || v != NewRat(1, 1) {
+		t.Errorf("expected the sum of probabilities to be 1, actual %s", v)
+	}
+	// probability that x0 and x1 are true
+	s := d.Abstract(p, []int{2, 3}, PlusRat(d))
+	if v := d.Eval(s, []bool{true, true, false, false}); v != NewRat(1, 9) {
+		t.Errorf("expected probability 1/9, actual %s", v)
+	}
+	if v, _ := d.Value(d.Abstract(p, []int{0, 1, 2, 3}, MaxRat(d))); v != NewRat(16, 81) {
+		t.Errorf("expected maximal probability 16/81, actual %s", v)
+	}
+	if v, _ := d.Value(d.Abstract(p, []int{0, 1, 2, 3}, MinRat(d))); v != NewRat(1, 81) {
+		t.Errorf("expected minimal probability 1/81, actual %s", v)
+	}
+	if zero := d.Apply(p, d.Leaf(Rat{}), TimesRat(d)); *zero != *d.Leaf(NewRat(0, 5)) {
+		t.Errorf("the zero value of Rat should be the number 0")
+	}
+	if d.Errored() {
+		t.Error(d.Error())
+	}
+}
@@ -11,8 +11,14 @@ Basics
 
 Each BDD has a fixed number of variables, Varnum, declared when it is
 initialized (using the method New) and each variable is represented by an
-(integer) index in the interval [0..Varnum), called a level. Our library support
-the creation of multiple BDD with possibly different number of variables.
+(integer) index in the interval [0..Varnum). Our library support the creation
+of multiple BDD with possibly different number of variables.
+
+Variables are ordered and the position of a variable in this order is called
+its level. By default, variable i is at level i. All the functions in the public
+API (such as Ithvar, Makeset, Scanset, Allsat or NewReplacer) work with variable
+indices, whereas kernel functions, such as Makenode, work with levels. Use
+Var2Level and Level2Var to convert from one to the other.
 
 Most operations over BDD return a Node; that is a pointer to a "vertex" in the
 BDD that includes a variable level, and the address of the low and high branch
@@ -49,5 +55,34 @@ take care of BDD resizing and memory management directly in the library, but
 by the Go runtime. Unlike MuDDy, we do not provide an interface, but a genuine
 reimplementation of BDD in Go. As a consequence, we do not suffer from FFI
 overheads when calling from Go into C.
+
+Concurrency and callbacks
+
+A BDD is not safe for concurrent use: all the methods of a given BDD must be
+called from the same goroutine, or with an external synchronization, with
+the following exceptions. The finalizers of the Nodes, that run in their own
+goroutine, only touch the node table under a lock. After a call to
+SealForQueries, the queries (the operations that do not create nodes, such as
+Eval, Satcount or Allsat) can be called from any number of goroutines at the
+same time. The function passed to WatchSize runs in its own goroutine and only
+receives copies of the sizes of the node table; it must not call any method of
+the BDD. Different BDD can be used from different goroutines without
+synchronization.
+
+Some operations call user code (callbacks). The function passed to Allsat or
+AllsatUnder can call any method of the BDD, including the operations that
+create nodes, since the traversal only depends on nodes reachable from the
+root, that are protected, with the exception of Reorder and SetVarOrder. The function passed to Allnodes, the iterator passed
+to FromMinterIndices, and the handler registered with Memoryhandler are called
+in the middle of a computation and can only call queries; in particular, they
+must not create nodes, or call kernel functions such as Initref, Makenode or
+Reorder. Also, the function passed to Allnodes cannot call Allnodes, or the
+functions that use it, such as AnodeCount, since nodes are marked during the
+traversal. The library follows the same rule: the reorderings requested with
+option Autoreorder are delayed until the end of the operation that triggered
+them. When compiled with the build tag `debug`, the
+library checks these rules and panics with an error wrapping ErrReentry when
+an operation that can modify the node table is called from a callback that
+forbids it.
 */
 package rudd
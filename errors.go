@@ -4,13 +4,12 @@
 
 package rudd
 
-import (
-	"fmt"
-	"log"
-)
-
 // Error returns the error status of the BDD.
 func (b *BDD) Error() string {
+	if b.sealed != nil {
+		b.sealed.Lock()
+		defer b.sealed.Unlock()
+	}
 	if b.error == nil {
 		return ""
 	}
@@ -19,18 +18,17 @@ func (b *BDD) Error() string {
 
 // Errored returns true if there was an error during a computation.
 func (b *BDD) Errored() bool {
+	if b.sealed != nil {
+		b.sealed.Lock()
+		defer b.sealed.Unlock()
+	}
 	return b.error != nil
 }
 
 func (b *BDD) seterror(format string, a ...interface{}) Node {
-	if b.error != nil {
-		format = format + "; " + b.Error()
-		b.error = fmt.Errorf(format, a...)
-		return nil
-	}
-	b.error = fmt.Errorf(format, a...)
-	if _DEBUG {
-		log.Println(b.error)
+	if b.sealed != nil {
+		b.sealed.Lock()
+		defer b.sealed.Unlock()
 	}
-	return nil
+	return b.kernel.seterror(format, a...)
 }
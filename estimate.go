@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "math"
+
+// EstimateApplySize returns an estimation of the number of nodes, not counting
+// the constants, in the result of Apply(n1, n2, op), without computing it. This
+// can be used by schedulers to choose the order of operands, or to decide to
+// approximate, before committing to a computation that may blow up. We return
+// -1 and set the error flag in the BDD if there is an error.
+//
+// The estimation is based on the profiles of the operands: for each level l,
+// the number of nodes at level l and the number of distinct sub-BDDs that can
+// be reached from above level l (the width of the BDD at level l). A node of
+// the result at level l comes from a pair of sub-BDDs of the operands, where at
+// least one of them is at level l, so the product of the profiles gives an
+// upper bound for each level. Hence the result is always an upper bound, but it
+// can be very pessimistic, since it does not take into account the reduction
+// of nodes with equal successors or the simplifications due to op.
+func (b *BDD) EstimateApplySize(n1, n2 Node, op Operator) int {
+	if b.checkptr(n1) != nil || b.checkptr(n2) != nil {
+		b.seterror("Wrong operand in call to EstimateApplySize")
+		return -1
+	}
+	if op < 0 || op >= opnot {
+		b.seterror("Unauthorized operation (%d) in call to EstimateApplySize", op)
+		return -1
+	}
+	if *n1 < 2 && *n2 < 2 {
+		return 0
+	}
+	count1, width1 := b.profile(*n1)
+	count2, width2 := b.profile(*n2)
+	if *n1 < 2 || *n2 < 2 {
+		// the result is a sub-BDD of the other operand, or its negation
+		res := 0
+		for l := 0; l < int(b.varnum); l++ {
+			res += count1[l] + count2[l]
+		}
+		return res
+	}
+	res := 0.0
+	for l := 0; l < int(b.varnum); l++ {
+		res += float64(count1[l])*float64(width2[l]) + float64(width1[l])*float64(count2[l]) - float64(count1[l])*float64(count2[l])
+	}
+	if res > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(res)
+}
+
+// profile returns, for each level l in [0..Varnum], the number of nodes at level
+// l in the BDD rooted at n, and the number of nodes at a level greater or equal
+// to l that are the root or a successor of a node above l. Constants are at
+// level Varnum.
+func (b *BDD) profile(n int) (count []int, width []int) {
+	count = make([]int, b.varnum+1)
+	width = make([]int, b.varnum+1)
+	// parent is the smallest level of a predecessor of each node, or -1 for
+	// the root.
+	parent := map[int]int32{n: -1}
+	nodes := b.topo(n)
+	for k := len(nodes) - 1; k >= 0; k-- {
+		level := b.level(nodes[k])
+		for _, c := range []int{b.low(nodes[k]), b.high(nodes[k])} {
+			if p, ok := parent[c]; !ok || level < p {
+				parent[c] = level
+			}
+		}
+	}
+	diff := make([]int, b.varnum+2)
+	for v, p := range parent {
+		level := b.level(v)
+		if v >= 2 {
+			count[level]++
+		}
+		diff[p+1]++
+		diff[level+1]--
+	}
+	sum := 0
+	for l := range width {
+		sum += diff[l]
+		width[l] = sum
+	}
+	return count, width
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestEstimateApplySize checks that the estimation is an upper bound of the
+// actual size of the result on random BDDs.
+func TestEstimateApplySize(t *testing.T) {
+	bdd, _ := New(8)
+	rng := rand.New(rand.NewSource(42))
+	random := func() Node {
+		n := bdd.False()
+		for k := 0; k < 6; k++ {
+			c := bdd.True()
+			for v := 0; v < 8; v++ {
+				switch rng.Intn(3) {
+				case 0:
+					c = bdd.And(c, bdd.Ithvar(v))
+				case 1:
+					c = bdd.And(c, bdd.NIthvar(v))
+				}
+			}
+			n = bdd.Or(n, c)
+		}
+		return n
+	}
+	size := func(n Node) int {
+		return len(bdd.topo(*n))
+	}
+	for k := 0; k < 50; k++ {
+		n1, n2 := random(), random()
+		for _, op := range []Operator{OPand, OPor, OPxor, OPimp} {
+			estimate := bdd.EstimateApplySize(n1, n2, op)
+			if actual := size(bdd.Apply(n1, n2, op)); estimate < actual {
+				t.Fatalf("EstimateApplySize(%s) = %d, but the result has %d nodes", op, estimate, actual)
+			}
+		}
+	}
+	n := random()
+	if estimate := bdd.EstimateApplySize(n, bdd.True(), OPand); estimate != size(n) {
+		t.Errorf("expected estimation %d with a constant operand, actual %d", size(n), estimate)
+	}
+	if bdd.EstimateApplySize(n, n, opnot) != -1 {
+		t.Errorf("EstimateApplySize should fail with a unary operator")
+	}
+}
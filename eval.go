@@ -0,0 +1,146 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Eval returns the value of the function denoted by n for an assignment of the
+// variables, given as a slice of Varnum Booleans, where assignment[v] is the
+// value of variable v. We simply follow a path from n to a constant, so the
+// cost is at most one step per level and no node is created. We return an
+// error if n is not valid or if the assignment does not have the right size.
+func (b *BDD) Eval(n Node, assignment []bool) (bool, error) {
+	if err := b.checkptr(n); err != nil {
+		return false, fmt.Errorf("wrong node in call to Eval; %s", err)
+	}
+	if len(assignment) != int(b.varnum) {
+		return false, fmt.Errorf("wrong size of assignment (%d) in call to Eval", len(assignment))
+	}
+	k := *n
+	for k > 1 {
+		if assignment[b.level2var[b.level(k)]] {
+			k = b.high(k)
+		} else {
+			k = b.low(k)
+		}
+	}
+	return k == 1, nil
+}
+
+// EvalMany64 evaluates the function denoted by the first root of f on batches
+// of 64 assignments at a time, using bit-slicing. Each element of inputs is a
+// batch, with one word per variable, such that bit i of inputs[k][v] is the
+// value of variable v in the i-th assignment of batch k. Hence inputs[k] must
+// have (at least) f.Varnum words. The result has one word per batch, where bit
+// i is the value of the function for the i-th assignment of the batch.
+//
+// We compute the value of every node, in topological order, using only bitwise
+// operations, so that the cost of a batch is proportional to the number of
+// nodes and does not depend on the assignments. This is useful when a BDD is
+// used as a high-throughput classifier, for instance for packets or records.
+// Since a Flat value is independent from the BDD it was extracted from, it can
+// be used concurrently by several goroutines.
+func (f *Flat) EvalMany64(inputs [][]uint64) []uint64 {
+	res := make([]uint64, len(inputs))
+	if len(f.Roots) == 0 {
+		return res
+	}
+	root := f.Roots[0]
+	val := make([]uint64, len(f.Nodes)+2)
+	val[1] = ^uint64(0)
+	for k, x := range inputs {
+		for j, n := range f.Nodes {
+			v := x[n[0]]
+			val[j+2] = (v & val[n[2]]) | (^v & val[n[1]])
+		}
+		res[k] = val[root]
+	}
+	return res
+}
+
+// QuasiReduced is a read-only representation of a BDD, for fast evaluation,
+// where every path from the root tests the same sequence of variables, in the
+// same order. It is obtained from a BDD by keeping only the levels where the
+// function depends on a variable (level compression) and by adding redundant
+// nodes, with identical successors, for the edges that skip some of these
+// levels (quasi-reduction). This may increase the number of nodes, but an
+// evaluation is a simple loop over the levels, with one table lookup per
+// level, and without having to compare the level of the current node with
+// the one of its successor.
+type QuasiReduced struct {
+	Vars  []int      // Vars[i] is the variable tested at step i
+	Nodes [][][2]int // Nodes[i][k] gives the index of the low and high successors of node k at step i in Nodes[i+1], or a constant (0 or 1) after the last step
+	Root  int        // Index of the root in Nodes[0], or a constant if Vars is empty
+}
+
+// QuasiReduce returns the quasi-reduced representation of n, using the
+// variable order of b. We return an error if n is not a valid node.
+func (b *BDD) QuasiReduce(n Node) (*QuasiReduced, error) {
+	if err := b.checkptr(n); err != nil {
+		return nil, fmt.Errorf("wrong node in call to QuasiReduce; %s", err)
+	}
+	nodes := b.topo(*n)
+	step := make(map[int32]int)
+	for _, v := range nodes {
+		step[b.level(v)] = 0
+	}
+	levels := make([]int32, 0, len(step))
+	for l := range step {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	res := &QuasiReduced{
+		Vars:  make([]int, len(levels)),
+		Nodes: make([][][2]int, len(levels)),
+	}
+	for i, l := range levels {
+		step[l] = i
+		res.Vars[i] = int(b.level2var[l])
+	}
+	// index[i] associates the id of a node in b with its index in Nodes[i]
+	index := make([]map[int]int, len(levels))
+	for i := range index {
+		index[i] = make(map[int]int)
+	}
+	var quasi func(n, i int) int
+	quasi = func(n, i int) int {
+		if i == len(levels) {
+			return n
+		}
+		if k, ok := index[i][n]; ok {
+			return k
+		}
+		var succ [2]int
+		if n > 1 && step[b.level(n)] == i {
+			succ = [2]int{quasi(b.low(n), i+1), quasi(b.high(n), i+1)}
+		} else {
+			next := quasi(n, i+1)
+			succ = [2]int{next, next}
+		}
+		k := len(res.Nodes[i])
+		res.Nodes[i] = append(res.Nodes[i], succ)
+		index[i][n] = k
+		return k
+	}
+	res.Root = quasi(*n, 0)
+	return res, nil
+}
+
+// Eval returns the value of the function for the given assignment, where
+// assignment[v] is the value of variable v.
+func (q *QuasiReduced) Eval(assignment []bool) bool {
+	n := q.Root
+	for i, v := range q.Vars {
+		bit := 0
+		if assignment[v] {
+			bit = 1
+		}
+		n = q.Nodes[i][n][bit]
+	}
+	return n == 1
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEvalMany64(t *testing.T) {
+	bdd, _ := New(6)
+	// f is true if an even number of variables are true, or if x0 and x5
+	f := bdd.Not(bdd.Ithvar(0))
+	for v := 1; v < 6; v++ {
+		f = bdd.Apply(f, bdd.Ithvar(v), OPxor)
+	}
+	f = bdd.Or(f, bdd.And(bdd.Ithvar(0), bdd.Ithvar(5)))
+	flat, _ := bdd.Flatten(f)
+	rng := rand.New(rand.NewSource(1))
+	inputs := make([][]uint64, 3)
+	for k := range inputs {
+		inputs[k] = make([]uint64, 6)
+		for v := range inputs[k] {
+			inputs[k][v] = rng.Uint64()
+		}
+	}
+	res := flat.EvalMany64(inputs)
+	if len(res) != len(inputs) {
+		t.Fatalf("expected %d words, actual %d", len(inputs), len(res))
+	}
+	for k, x := range inputs {
+		for i := 0; i < 64; i++ {
+			ones := 0
+			for v := 0; v < 6; v++ {
+				ones += int(x[v]>>i) & 1
+			}
+			expected := ones%2 == 0 || (x[0]>>i)&(x[5]>>i)&1 == 1
+			if actual := (res[k]>>i)&1 == 1; actual != expected {
+				t.Errorf("wrong value for assignment %d of batch %d", i, k)
+			}
+		}
+	}
+	// constant functions
+	flat, _ = bdd.Flatten(bdd.True())
+	if res := flat.EvalMany64(inputs); res[0] != ^uint64(0) {
+		t.Errorf("True should evaluate to all ones, actual %x", res[0])
+	}
+}
+
+func TestQuasiReduce(t *testing.T) {
+	bdd, _ := New(6)
+	// f does not depend on x2 and has edges that skip levels
+	f := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(5)), bdd.And(bdd.NIthvar(1), bdd.Ithvar(3)))
+	f = bdd.Apply(f, bdd.Ithvar(4), OPxor)
+	q, err := bdd.QuasiReduce(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Vars) != 5 {
+		t.Errorf("expected 5 levels, actual %v", q.Vars)
+	}
+	for i := range q.Vars {
+		for _, succ := range q.Nodes[i] {
+			for _, s := range succ {
+				if (i < len(q.Vars)-1 && s >= len(q.Nodes[i+1])) || (i == len(q.Vars)-1 && s > 1) {
+					t.Fatalf("wrong successor %d at step %d", s, i)
+				}
+			}
+		}
+	}
+	assignment := make([]bool, 6)
+	for a := 0; a < 64; a++ {
+		for v := range assignment {
+			assignment[v] = a&(1<<v) != 0
+		}
+		x := func(v int) bool { return assignment[v] }
+		expected := ((x(0) && x(5)) || (!x(1) && x(3))) != x(4)
+		if actual := q.Eval(assignment); actual != expected {
+			t.Errorf("wrong value for assignment %v", assignment)
+		}
+	}
+	if q, _ := bdd.QuasiReduce(bdd.True()); len(q.Vars) != 0 || !q.Eval(assignment) {
+		t.Errorf("QuasiReduce(True) should always evaluate to true")
+	}
+}
+
+func TestEval(t *testing.T) {
+	bdd, _ := New(4)
+	// n = (x0 & !x2) | x3
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.NIthvar(2)), bdd.Ithvar(3))
+	produced := bdd.producednum()
+	assignment := make([]bool, 4)
+	for a := 0; a < 16; a++ {
+		for v := range assignment {
+			assignment[v] = a&(1<<v) != 0
+		}
+		expected := (assignment[0] && !assignment[2]) || assignment[3]
+		actual, err := bdd.Eval(n, assignment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("Eval(n, %v) should be %v", assignment, expected)
+		}
+	}
+	if bdd.producednum() != produced {
+		t.Errorf("Eval should not create nodes")
+	}
+	if _, err := bdd.Eval(n, assignment[:3]); err == nil {
+		t.Errorf("Eval should fail with an assignment of the wrong size")
+	}
+}
@@ -7,6 +7,7 @@ package rudd_test
 import (
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/dalzilio/rudd"
 )
@@ -70,3 +71,91 @@ func Example_allnodes() {
 	// Number of active nodes in BDD is 16
 	// Number of active nodes in node is 2
 }
+
+// This example shows how to generate the code of a Go function that evaluates
+// a BDD without depending on the library.
+func Example_generateGo() {
+	bdd, _ := rudd.New(3)
+	// n == (admin & owner) | !locked
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(1)), bdd.NIthvar(2))
+	bdd.GenerateGo(os.Stdout, "policy", "Allowed", n, []string{"admin", "owner", "locked"})
+	// Output:
+	// // Code generated by rudd; DO NOT EDIT.
+	//
+	// package policy
+	//
+	// // Allowed evaluates a Boolean function computed with a BDD.
+	// func Allowed(admin, owner, locked bool) bool {
+	// 	n := 4
+	// 	for {
+	// 		switch n {
+	// 		case 4:
+	// 			if admin {
+	// 				n = 3
+	// 			} else {
+	// 				n = 2
+	// 			}
+	// 		case 3:
+	// 			if owner {
+	// 				n = 1
+	// 			} else {
+	// 				n = 2
+	// 			}
+	// 		case 2:
+	// 			if locked {
+	// 				n = 0
+	// 			} else {
+	// 				n = 1
+	// 			}
+	// 		case 1:
+	// 			return true
+	// 		default:
+	// 			return false
+	// 		}
+	// 	}
+	// }
+}
+
+// This example shows how to generate the code of a C function that evaluates
+// a BDD over a bit-vector.
+func Example_generateC() {
+	bdd, _ := rudd.New(3)
+	// n == (x0 & x1) | !x2
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(1)), bdd.NIthvar(2))
+	bdd.GenerateC(os.Stdout, "eval", n)
+	// Output:
+	// /* Code generated by rudd; DO NOT EDIT. */
+	//
+	// /* triplets (variable, low, high) for nodes 2 to 4 */
+	// static const unsigned int eval_nodes[3][3] = {
+	// 	{2, 1, 0},
+	// 	{1, 2, 1},
+	// 	{0, 2, 3},
+	// };
+	//
+	// int eval(const unsigned char *x)
+	// {
+	// 	unsigned int n = 4;
+	// 	while (n > 1) {
+	// 		const unsigned int *node = eval_nodes[n - 2];
+	// 		n = ((x[node[0] >> 3] >> (node[0] & 7)) & 1) ? node[2] : node[1];
+	// 	}
+	// 	return (int)n;
+	// }
+}
+
+// This example shows how to use a multi-terminal diagram to compute the number
+// of true variables in each assignment.
+func Example_diagram() {
+	d, _ := rudd.NewDiagram[int](3)
+	plus := d.Operation(func(x, y int) int { return x + y })
+	n := d.Leaf(0)
+	for k := 0; k < 3; k++ {
+		n = d.Apply(n, d.Var(k, 0, 1), plus)
+	}
+	fmt.Println(d.Leaves(n))
+	fmt.Println(d.Eval(n, []bool{true, false, true}))
+	// Output:
+	// [0 1 2 3]
+	// 2
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr is a symbolic expression, built with Defer, that stands for a sequence
+// of operations over the nodes of a BDD. Operations on expressions, like Apply
+// or Exist, do not compute anything; they only record a plan that is executed
+// when calling Eval. Before execution, the plan is rewritten to use more
+// efficient operations when possible, see method Optimize. Expressions are
+// immutable and can be shared, in which case shared sub-expressions are only
+// evaluated once.
+type Expr struct {
+	b       *BDD
+	kind    exprkind
+	op      Operator // operator of Apply and AppEx
+	args    []*Expr
+	node    Node       // value of leaves
+	varsets []Node     // cubes of variables for Exist and AppEx, to be combined
+	rs      []Replacer // replacers of Replace, applied in sequence
+}
+
+type exprkind int
+
+const (
+	exprnode exprkind = iota
+	exprapply
+	exprexist
+	exprappex
+	exprreplace
+)
+
+// Defer returns an expression standing for the node n, used as a starting
+// point to plan a sequence of operations.
+func (b *BDD) Defer(n Node) *Expr {
+	return &Expr{b: b, kind: exprnode, node: n}
+}
+
+// Apply returns an expression for the result of applying the binary operator
+// op on e and f.
+func (e *Expr) Apply(f *Expr, op Operator) *Expr {
+	return &Expr{b: e.b, kind: exprapply, op: op, args: []*Expr{e, f}}
+}
+
+// And returns an expression for the conjunction of e and f.
+func (e *Expr) And(f *Expr) *Expr {
+	return e.Apply(f, OPand)
+}
+
+// Or returns an expression for the disjunction of e and f.
+func (e *Expr) Or(f *Expr) *Expr {
+	return e.Apply(f, OPor)
+}
+
+// Exist returns an expression for the existential quantification of e over the
+// variables in varset, where varset is a node built with a method such as
+// Makeset.
+func (e *Expr) Exist(varset Node) *Expr {
+	return &Expr{b: e.b, kind: exprexist, args: []*Expr{e}, varsets: []Node{varset}}
+}
+
+// Replace returns an expression for the result of substituting variables in e
+// using replacer r.
+func (e *Expr) Replace(r Replacer) *Expr {
+	return &Expr{b: e.b, kind: exprreplace, args: []*Expr{e}, rs: []Replacer{r}}
+}
+
+// Optimize returns an expression equivalent to e where we use more efficient
+// operations when possible. We fuse an Exist over the result of an Apply into
+// a single AppEx, when the operator is supported by AppEx; we merge
+// consecutive quantifications; and we group consecutive Replace, so that the
+// substitutions are combined during evaluation, when their composition over
+// the support of the operand can be described by a Replacer. An intermediate
+// result that
+// is used several times in e is never fused, so that it is computed only
+// once.
+func (e *Expr) Optimize() *Expr {
+	uses := make(map[*Expr]int)
+	e.countuses(uses)
+	return e.optimize(uses, make(map[*Expr]*Expr))
+}
+
+func (e *Expr) countuses(uses map[*Expr]int) {
+	uses[e]++
+	if uses[e] > 1 {
+		return
+	}
+	for _, a := range e.args {
+		a.countuses(uses)
+	}
+}
+
+func (e *Expr) optimize(uses map[*Expr]int, done map[*Expr]*Expr) *Expr {
+	if res, ok := done[e]; ok {
+		return res
+	}
+	res := &Expr{}
+	*res = *e
+	res.args = make([]*Expr, len(e.args))
+	for k, a := range e.args {
+		res.args[k] = a.optimize(uses, done)
+	}
+	if len(e.args) == 1 && uses[e.args[0]] == 1 {
+		arg := res.args[0]
+		switch {
+		case e.kind == exprexist && arg.kind == exprapply && arg.op >= OPand && arg.op <= OPnand:
+			res = &Expr{b: e.b, kind: exprappex, op: arg.op, args: arg.args, varsets: e.varsets}
+		case e.kind == exprexist && (arg.kind == exprexist || arg.kind == exprappex):
+			res = &Expr{}
+			*res = *arg
+			res.varsets = append(append([]Node{}, arg.varsets...), e.varsets...)
+		case e.kind == exprreplace && arg.kind == exprreplace:
+			rs := append(append([]Replacer{}, arg.rs...), e.rs...)
+			res = &Expr{b: e.b, kind: exprreplace, args: arg.args, rs: rs}
+		}
+	}
+	done[e] = res
+	return res
+}
+
+// composereplacers returns a replacer with the same effect than replacing with
+// r1 and then with r2 on a node whose variables are in support, or nil if the
+// composition cannot be described by a Replacer, for instance if it maps two
+// variables to the same one. The variables outside of support are left
+// unchanged.
+func (b *BDD) composereplacers(r1, r2 Replacer, support []int) Replacer {
+	p1, ok1 := r1.(*replacer)
+	p2, ok2 := r2.(*replacer)
+	if !ok1 || !ok2 || len(p1.vimage) != len(p2.vimage) {
+		return nil
+	}
+	oldvars, newvars := []int{}, []int{}
+	image := make(map[int32]bool)
+	for _, v := range support {
+		w := p2.vimage[p1.vimage[v]]
+		if image[w] {
+			return nil
+		}
+		image[w] = true
+		if int(w) != v {
+			oldvars = append(oldvars, v)
+			newvars = append(newvars, int(w))
+		}
+	}
+	r, err := b.NewReplacer(oldvars, newvars)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// Eval executes the plan described by e, after a call to Optimize, and returns
+// the resulting node. We return nil, and set the error flag of the BDD, if one
+// of the operations fails.
+func (e *Expr) Eval() Node {
+	return e.Optimize().eval(make(map[*Expr]Node))
+}
+
+func (e *Expr) eval(memo map[*Expr]Node) Node {
+	if res, ok := memo[e]; ok {
+		return res
+	}
+	b := e.b
+	args := make([]Node, len(e.args))
+	for k, a := range e.args {
+		if a.b != b {
+			return b.seterror("expression from another BDD in call to Eval")
+		}
+		if args[k] = a.eval(memo); args[k] == nil {
+			return nil
+		}
+	}
+	for _, v := range e.varsets {
+		if b.checkptr(v) != nil {
+			return b.seterror("Wrong varset in call to Eval")
+		}
+	}
+	varset := b.True()
+	if len(e.varsets) == 1 {
+		varset = e.varsets[0]
+	} else if len(e.varsets) > 1 {
+		if varset = b.And(e.varsets...); varset == nil {
+			return nil
+		}
+	}
+	var res Node
+	switch e.kind {
+	case exprnode:
+		if b.checkptr(e.node) != nil {
+			return b.seterror("Wrong operand in call to Eval")
+		}
+		res = e.node
+	case exprapply:
+		res = b.Apply(args[0], args[1], e.op)
+	case exprexist:
+		res = b.Exist(args[0], varset)
+	case exprappex:
+		res = b.AppEx(args[0], args[1], e.op, varset)
+	case exprreplace:
+		res = args[0]
+		for k := 0; k < len(e.rs) && res != nil; {
+			r := e.rs[k]
+			support := b.SupportSet(res)
+			for k++; k < len(e.rs); k++ {
+				c := b.composereplacers(r, e.rs[k], support)
+				if c == nil {
+					break
+				}
+				r = c
+			}
+			res = b.Replace(res, r)
+		}
+	}
+	memo[e] = res
+	return res
+}
+
+func (e *Expr) String() string {
+	var sb strings.Builder
+	e.write(&sb)
+	return sb.String()
+}
+
+func (e *Expr) write(sb *strings.Builder) {
+	args := func() {
+		for k, a := range e.args {
+			if k > 0 {
+				sb.WriteString(", ")
+			}
+			a.write(sb)
+		}
+	}
+	varsets := func() {
+		for _, v := range e.varsets {
+			if v == nil {
+				sb.WriteString(", <nil>")
+			} else {
+				fmt.Fprintf(sb, ", @%d", *v)
+			}
+		}
+	}
+	switch e.kind {
+	case exprnode:
+		if e.node == nil {
+			sb.WriteString("<nil>")
+		} else {
+			fmt.Fprintf(sb, "@%d", *e.node)
+		}
+	case exprapply:
+		fmt.Fprintf(sb, "%s(", e.op)
+		args()
+		sb.WriteString(")")
+	case exprexist:
+		sb.WriteString("exist(")
+		args()
+		varsets()
+		sb.WriteString(")")
+	case exprappex:
+		fmt.Fprintf(sb, "appex[%s](", e.op)
+		args()
+		varsets()
+		sb.WriteString(")")
+	case exprreplace:
+		sb.WriteString("replace(")
+		args()
+		sb.WriteString(")")
+	}
+}
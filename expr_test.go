@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefer(t *testing.T) {
+	bdd, _ := New(6, Nodesize(1000), Cachesize(1000))
+	x := bdd.Defer(bdd.Or(bdd.Ithvar(0), bdd.Ithvar(1)))
+	y := bdd.Defer(bdd.Equiv(bdd.Ithvar(1), bdd.Ithvar(2)))
+	vs1 := bdd.Makeset([]int{1})
+	vs2 := bdd.Makeset([]int{2})
+	r1, _ := bdd.NewReplacer([]int{0}, []int{3})
+	r2, _ := bdd.NewReplacer([]int{3}, []int{4})
+
+	e := x.And(y).Exist(vs1).Exist(vs2).Replace(r1).Replace(r2)
+	opt := e.Optimize().String()
+	if strings.Count(opt, "replace(") != 1 || strings.Count(opt, "appex[and](") != 1 || strings.Contains(opt, "exist(") {
+		t.Errorf("expected a single appex and a single replace, actual %s", opt)
+	}
+	expected := bdd.Replace(bdd.Replace(bdd.Exist(bdd.Exist(bdd.And(bdd.Or(bdd.Ithvar(0), bdd.Ithvar(1)), bdd.Equiv(bdd.Ithvar(1), bdd.Ithvar(2))), vs1), vs2), r1), r2)
+	if actual := e.Eval(); !bdd.Equal(actual, expected) {
+		t.Errorf("optimized plan %s gives a different result", opt)
+	}
+
+	// a shared sub-expression is not fused
+	a := x.And(y)
+	e = a.Exist(vs1).Or(a)
+	if opt := e.Optimize().String(); strings.Contains(opt, "appex") {
+		t.Errorf("shared sub-expression should not be fused, actual %s", opt)
+	}
+	if actual := e.Eval(); !bdd.Equal(actual, bdd.Exist(bdd.And(x.node, y.node), vs1)) {
+		t.Error("wrong result with a shared sub-expression")
+	}
+
+	// a replacer cannot map 0 to 3 and 4 to 0 at the same time
+	r3, _ := bdd.NewReplacer([]int{4}, []int{0})
+	e = x.Replace(r1).Replace(r3)
+	if actual := e.Eval(); !bdd.Equal(actual, bdd.Replace(bdd.Replace(x.node, r1), r3)) {
+		t.Error("wrong result with replacers that cannot be composed")
+	}
+	// AppEx only supports the operators from OPand to OPnand
+	e = x.Apply(y, OPimp).Exist(vs1)
+	if opt := e.Optimize().String(); strings.Contains(opt, "appex") {
+		t.Errorf("expected no appex with operator OPimp, actual %s", opt)
+	}
+	if actual := e.Eval(); !bdd.Equal(actual, bdd.Exist(bdd.Imp(x.node, y.node), vs1)) {
+		t.Error("wrong result with an exist over an implication")
+	}
+	if bdd.Defer(nil).And(x).Eval() != nil || !bdd.Errored() {
+		t.Error("expected an error with a nil node")
+	}
+
+	// the target of r1 occurs in f, so Replace fails with r1 and we should not
+	// merge r1 and r2 into a replacer from 0 to 4.
+	bdd, _ = New(6, Nodesize(1000), Cachesize(1000))
+	f := bdd.And(bdd.Ithvar(0), bdd.NIthvar(3))
+	r1, _ = bdd.NewReplacer([]int{0}, []int{3})
+	r2, _ = bdd.NewReplacer([]int{3}, []int{4})
+	if actual := bdd.Defer(f).Replace(r1).Replace(r2).Eval(); actual != nil || !bdd.Errored() {
+		t.Errorf("expected an error with consecutive replacers, actual %v", actual)
+	}
+}
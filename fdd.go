@@ -0,0 +1,218 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// domain is a finite domain block, allocated with ExtDomain, that encodes an
+// integer in the interval [0..size) using a vector of variables. Like in
+// BuDDy, vars[k] is the variable for bit k, starting with the least
+// significant bit.
+type domain struct {
+	size int
+	vars []int
+}
+
+// ExtDomain allocates new finite domain blocks, one for each value in sizes,
+// like fdd_extdomain in BuDDy, and returns their indices, to be used with the
+// other Fdd functions. A domain of size n encodes the integers in [0..n) with
+// the smallest number of variables possible (at least one), using new
+// variables added at the bottom of the order with ExtVarnum. When several
+// domains are allocated in the same call, their variables are interleaved, as
+// with NewInterleaved, which is usually a good order for relations between
+// domains, such as FddEquals. We return an error if a size is not positive, or
+// if there are too many variables.
+func (b *BDD) ExtDomain(sizes []int) ([]int, error) {
+	if b.sealed != nil {
+		return nil, fmt.Errorf("error in call to ExtDomain; %w", ErrSealed)
+	}
+	width := make([]int, len(sizes))
+	for k, size := range sizes {
+		if size < 1 {
+			return nil, fmt.Errorf("bad domain size (%d) in call to ExtDomain", size)
+		}
+		width[k] = bits.Len(uint(size - 1))
+		if width[k] == 0 {
+			width[k] = 1
+		}
+	}
+	groups, err := NewInterleaved(width...)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, w := range width {
+		total += w
+	}
+	old, err := b.ExtVarnum(total)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int, len(sizes))
+	for k, g := range groups {
+		vars := make([]int, g.Len())
+		for i, v := range g.Vars {
+			vars[i] = old + v
+		}
+		res[k] = len(b.domains)
+		b.domains = append(b.domains, domain{size: sizes[k], vars: vars})
+	}
+	return res, nil
+}
+
+// checkdomain returns an error, and sets the error condition in b, if dom is
+// not the index of a domain.
+func (b *BDD) checkdomain(dom int, op string) error {
+	if dom < 0 || dom >= len(b.domains) {
+		b.seterror("unknown domain (%d) in call to %s", dom, op)
+		return b.error
+	}
+	return nil
+}
+
+// FddDomainNum returns the number of finite domain blocks allocated with
+// ExtDomain.
+func (b *BDD) FddDomainNum() int {
+	return len(b.domains)
+}
+
+// FddDomainSize returns the number of values of domain dom, or -1 if there is
+// an error.
+func (b *BDD) FddDomainSize(dom int) int {
+	if b.checkdomain(dom, "FddDomainSize") != nil {
+		return -1
+	}
+	return b.domains[dom].size
+}
+
+// FddVars returns the variables encoding domain dom, starting with the least
+// significant bit, or nil if there is an error.
+func (b *BDD) FddVars(dom int) []int {
+	if b.checkdomain(dom, "FddVars") != nil {
+		return nil
+	}
+	return append([]int{}, b.domains[dom].vars...)
+}
+
+// FddVarset returns the set of variables encoding domain dom, built with
+// Makeset, that can be used to quantify over the values of the domain.
+func (b *BDD) FddVarset(dom int) Node {
+	if b.checkdomain(dom, "FddVarset") != nil {
+		return nil
+	}
+	return b.Makeset(b.domains[dom].vars)
+}
+
+// FddIthVar returns the BDD encoding value val of domain dom, meaning the cube
+// over the variables of dom for the binary representation of val.
+func (b *BDD) FddIthVar(dom, val int) Node {
+	if b.checkdomain(dom, "FddIthVar") != nil {
+		return nil
+	}
+	d := b.domains[dom]
+	if val < 0 || val >= d.size {
+		return b.seterror("value %d out of domain %d in call to FddIthVar", val, dom)
+	}
+	polarity := make([]bool, len(d.vars))
+	for k := range d.vars {
+		polarity[k] = val&(1<<k) != 0
+	}
+	return b.Makecube(d.vars, polarity)
+}
+
+// FddDomain returns the BDD for the valid values of domain dom, meaning the
+// encodings of the integers in [0..size). This is useful when the size of dom
+// is not a power of 2.
+func (b *BDD) FddDomain(dom int) Node {
+	if b.checkdomain(dom, "FddDomain") != nil {
+		return nil
+	}
+	d := b.domains[dom]
+	// we build the comparison x <= size - 1, starting from the least
+	// significant bit
+	res := b.True()
+	for k, v := range d.vars {
+		if (d.size-1)&(1<<k) != 0 {
+			res = b.Or(b.NIthvar(v), res)
+		} else {
+			res = b.And(b.NIthvar(v), res)
+		}
+	}
+	return res
+}
+
+// FddEquals returns the BDD for the assignments where domains dom1 and dom2
+// have the same value. The two domains must have the same size.
+func (b *BDD) FddEquals(dom1, dom2 int) Node {
+	if b.checkdomain(dom1, "FddEquals") != nil || b.checkdomain(dom2, "FddEquals") != nil {
+		return nil
+	}
+	d1, d2 := b.domains[dom1], b.domains[dom2]
+	if d1.size != d2.size {
+		return b.seterror("domains of different sizes (%d and %d) in call to FddEquals", d1.size, d2.size)
+	}
+	res := b.True()
+	for k := range d1.vars {
+		res = b.And(res, b.Equiv(b.Ithvar(d1.vars[k]), b.Ithvar(d2.vars[k])))
+	}
+	return res
+}
+
+// FddDecode returns the value of domain dom in a complete assignment of the
+// variables, given as a slice of Varnum Booleans indexed by variable (as with
+// Eval), or -1 if there is an error.
+func (b *BDD) FddDecode(dom int, assignment []bool) int {
+	if b.checkdomain(dom, "FddDecode") != nil {
+		return -1
+	}
+	if len(assignment) != int(b.varnum) {
+		b.seterror("wrong size of assignment (%d) in call to FddDecode", len(assignment))
+		return -1
+	}
+	res := 0
+	for k, v := range b.domains[dom].vars {
+		if assignment[v] {
+			res |= 1 << k
+		}
+	}
+	return res
+}
+
+// FddScanAllVar returns the value of every domain in one of the assignments
+// satisfying n, like fdd_scanallvar in BuDDy, where the variables that are not
+// constrained are false. We return nil if n is unsatisfiable or if there is an
+// error.
+func (b *BDD) FddScanAllVar(n Node) []int {
+	cube := b.Fullsatone(n, 0)
+	if cube == nil || *cube == 0 {
+		return nil
+	}
+	assignment := make([]bool, b.varnum)
+	for _, l := range b.Scanlits(cube) {
+		assignment[l.Var()] = l.Positive()
+	}
+	res := make([]int, len(b.domains))
+	for dom := range b.domains {
+		res[dom] = b.FddDecode(dom, assignment)
+	}
+	return res
+}
+
+// FddScanVar returns the value of domain dom in one of the assignments
+// satisfying n, like fdd_scanvar in BuDDy, or -1 if n is unsatisfiable or if
+// there is an error.
+func (b *BDD) FddScanVar(n Node, dom int) int {
+	if b.checkdomain(dom, "FddScanVar") != nil {
+		return -1
+	}
+	values := b.FddScanAllVar(n)
+	if values == nil {
+		return -1
+	}
+	return values[dom]
+}
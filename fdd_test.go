@@ -0,0 +1,73 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+func TestFdd(t *testing.T) {
+	bdd, _ := New(2, Nodesize(1000))
+	doms, err := bdd.ExtDomain([]int{5, 5, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdd.FddDomainNum() != 3 || bdd.Varnum() != 2+3+3+1 {
+		t.Fatalf("expected 3 domains and 9 variables, actual %d and %d", bdd.FddDomainNum(), bdd.Varnum())
+	}
+	// the variables of the three domains are interleaved
+	if vars := bdd.FddVars(doms[1]); len(vars) != 3 || vars[0] != 3 || vars[1] != 6 || vars[2] != 8 {
+		t.Errorf("unexpected variables %v for domain %d", vars, doms[1])
+	}
+	x, y := doms[0], doms[1]
+	if count := bdd.Satcount(bdd.FddDomain(x)).Int64(); count != 5*(1<<6) {
+		t.Errorf("expected %d models for FddDomain, actual %d", 5*(1<<6), count)
+	}
+	eq := bdd.And(bdd.FddEquals(x, y), bdd.FddDomain(x))
+	for v := 0; v < 5; v++ {
+		n := bdd.And(eq, bdd.FddIthVar(x, v))
+		if got := bdd.FddScanVar(n, y); got != v {
+			t.Errorf("expected value %d for y, actual %d", v, got)
+		}
+		values := bdd.FddScanAllVar(n)
+		if len(values) != 3 || values[x] != v || values[y] != v || values[doms[2]] != 0 {
+			t.Errorf("unexpected values %v", values)
+		}
+	}
+	// decoding the models of a relation
+	count := 0
+	err = bdd.Allsat(func(varset []int) error {
+		assignment := make([]bool, len(varset))
+		for k, v := range varset {
+			assignment[k] = v == 1
+		}
+		if vx, vy := bdd.FddDecode(x, assignment), bdd.FddDecode(y, assignment); vx != 2 || vy >= 5 {
+			t.Errorf("unexpected values %d and %d", vx, vy)
+		}
+		count++
+		return nil
+	}, bdd.AndExist(bdd.FddVarset(y), bdd.FddIthVar(x, 2), bdd.FddDomain(y)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Errorf("expected at least one model")
+	}
+	if bdd.Errored() {
+		t.Fatal(bdd.Error())
+	}
+	if bdd.FddScanVar(bdd.False(), x) != -1 {
+		t.Errorf("expected -1 for an unsatisfiable BDD")
+	}
+	if bdd.FddIthVar(x, 5) != nil || !bdd.Errored() {
+		t.Errorf("expected an error with a value out of the domain")
+	}
+	other, _ := New(1)
+	doms, _ = other.ExtDomain([]int{4, 8})
+	if other.FddEquals(doms[0], doms[1]) != nil || !other.Errored() {
+		t.Errorf("expected an error with domains of different sizes")
+	}
+	if _, err := bdd.ExtDomain([]int{0}); err == nil {
+		t.Errorf("expected an error with an empty domain")
+	}
+}
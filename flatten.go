@@ -0,0 +1,211 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+)
+
+// Flat is a self-contained description of the DAG reachable from a sequence of
+// roots, where nodes are numbered from 0 in a topological order. As with BDD
+// nodes, ids 0 and 1 are reserved for the constants False and True, while
+// Nodes[k] describes the node with id k+2. We always have that the ids of the
+// low and high successors of a node are strictly smaller than its own id
+// (children always occur before their parents) and that nodes are listed using
+// variable indices, not levels, so that a Flat value does not depend on the
+// variable order of the BDD it was extracted from.
+type Flat struct {
+	Varnum int      // Number of variables in the BDD
+	Nodes  [][3]int // Each node is a triplet (variable, low, high)
+	Roots  []int    // Ids of the roots, in the order they were given
+}
+
+// topo returns the list of (non-constant) nodes reachable from the nodes in n,
+// such that children always occur before their parents. The traversal does
+// not create new nodes and does not use the marking bits of the node table.
+// This is the pass shared by all the functions that export a BDD.
+func (b *BDD) topo(n ...int) []int {
+	res := []int{}
+	visited := make(map[int]bool)
+	stack := [][2]int{}
+	for _, r := range n {
+		if r < 2 || visited[r] {
+			continue
+		}
+		visited[r] = true
+		stack = append(stack, [2]int{r, 0})
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			var next int
+			switch top[1] {
+			case 0:
+				next = b.low(top[0])
+			case 1:
+				next = b.high(top[0])
+			default:
+				res = append(res, top[0])
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			top[1]++
+			if next >= 2 && !visited[next] {
+				visited[next] = true
+				stack = append(stack, [2]int{next, 0})
+			}
+		}
+	}
+	return res
+}
+
+// flatten returns a Flat description of the nodes reachable from n, together
+// with the id map used to renumber the nodes of b.
+func (b *BDD) flatten(n ...int) (*Flat, map[int]int) {
+	nodes := b.topo(n...)
+	id := make(map[int]int, len(nodes)+2)
+	id[0] = 0
+	id[1] = 1
+	res := &Flat{
+		Varnum: int(b.varnum),
+		Nodes:  make([][3]int, len(nodes)),
+		Roots:  make([]int, len(n)),
+	}
+	for k, v := range nodes {
+		id[v] = k + 2
+		res.Nodes[k] = [3]int{int(b.level2var[b.level(v)]), id[b.low(v)], id[b.high(v)]}
+	}
+	for k, v := range n {
+		res.Roots[k] = id[v]
+	}
+	return res, id
+}
+
+// Flatten returns a Flat description of the DAG reachable from the nodes in n.
+// We return an error if one of the nodes is not valid.
+func (b *BDD) Flatten(n ...Node) (*Flat, error) {
+	roots := make([]int, len(n))
+	for k, v := range n {
+		if err := b.checkptr(v); err != nil {
+			return nil, fmt.Errorf("wrong node in call to Flatten; %s", err)
+		}
+		roots[k] = *v
+	}
+	res, _ := b.flatten(roots...)
+	return res, nil
+}
+
+// check returns an error if f is not a well-formed Flat value for a BDD with
+// varnum variables.
+func (f *Flat) check(varnum int) error {
+	if f.Varnum > varnum {
+		return fmt.Errorf("too many variables (%d)", f.Varnum)
+	}
+	for k, v := range f.Nodes {
+		if v[0] < 0 || v[0] >= f.Varnum {
+			return fmt.Errorf("unknown variable (%d) in node %d", v[0], k+2)
+		}
+		if v[1] < 0 || v[1] >= k+2 || v[2] < 0 || v[2] >= k+2 {
+			return fmt.Errorf("successors of node %d are not in topological order", k+2)
+		}
+	}
+	for _, r := range f.Roots {
+		if r < 0 || r >= len(f.Nodes)+2 {
+			return fmt.Errorf("unknown root (%d)", r)
+		}
+	}
+	return nil
+}
+
+// Unflatten rebuilds the nodes described by f in b and returns the nodes
+// corresponding to its roots. The BDD b must have at least f.Varnum variables,
+// but it can use a different variable order than the one from which f was
+// extracted. We return an error if f is not well-formed. When the Journal
+// option is set, nodes are always rebuilt with Ite, so that they are recorded
+// in the journal.
+func (b *BDD) Unflatten(f *Flat) ([]Node, error) {
+	if b.sealed != nil {
+		return nil, fmt.Errorf("error in call to Unflatten; %w", ErrSealed)
+	}
+	if err := f.check(int(b.varnum)); err != nil {
+		return nil, fmt.Errorf("error in call to Unflatten; %s", err)
+	}
+	// We can build the nodes directly if the variable order of b is
+	// compatible with the structure of f.
+	level := func(k int) int32 {
+		if k < 2 {
+			return b.varnum
+		}
+		return b.var2level[f.Nodes[k-2][0]]
+	}
+	ordered := true
+	for k, v := range f.Nodes {
+		if lvl := level(k + 2); lvl >= level(v[1]) || lvl >= level(v[2]) {
+			ordered = false
+			break
+		}
+	}
+	if ordered && b.journal == nil {
+		nodes := make([][3]int, len(f.Nodes))
+		for k, v := range f.Nodes {
+			nodes[k] = [3]int{int(b.var2level[v[0]]), v[1], v[2]}
+		}
+		b.Initref()
+		id := b.bulkmake([]int{0, 1}, nodes)
+		if id == nil {
+			return nil, fmt.Errorf("error in call to Unflatten; %s", errMemory)
+		}
+		res := make([]Node, len(f.Roots))
+		for k, r := range f.Roots {
+			res[k] = b.Retnode(id[r])
+		}
+		return res, nil
+	}
+	// Otherwise we use Ite to rebuild each node, keeping an external reference
+	// on every intermediate result.
+	nodes := make([]Node, len(f.Nodes)+2)
+	nodes[0] = bddzero
+	nodes[1] = bddone
+	for k, v := range f.Nodes {
+		nodes[k+2] = b.Ite(b.Ithvar(v[0]), nodes[v[2]], nodes[v[1]])
+		if nodes[k+2] == nil {
+			return nil, fmt.Errorf("error in call to Unflatten; %s", b.error)
+		}
+	}
+	res := make([]Node, len(f.Roots))
+	for k, r := range f.Roots {
+		res[k] = nodes[r]
+	}
+	return res, nil
+}
+
+// Extract returns a new BDD, with the same number of variables than b, that
+// contains only the nodes reachable from the nodes in n, together with the
+// nodes corresponding to n in this new BDD. The node table of the result is
+// compact, meaning that it is just large enough to hold these nodes, but it
+// can grow as usual. This is useful to drop the (possibly numerous) nodes that
+// are no longer needed before serializing a result or handing it over to a
+// long-lived service. The new BDD uses the default configuration; use Flatten
+// and Unflatten to extract nodes into a BDD with a different configuration. We
+// return nil and set the error condition in b if one of the nodes is not
+// valid.
+func (b *BDD) Extract(n ...Node) (*BDD, []Node) {
+	for _, v := range n {
+		if b.checkptr(v) != nil {
+			b.seterror("wrong node in call to Extract")
+			return nil, nil
+		}
+	}
+	f, _ := b.Flatten(n...)
+	res, err := New(int(b.varnum), Nodesize(2*int(b.varnum)+2+len(f.Nodes)))
+	if err != nil {
+		b.seterror("%s in call to Extract", err)
+		return nil, nil
+	}
+	nodes, err := res.Unflatten(f)
+	if err != nil {
+		b.seterror("%s in call to Extract", err)
+		return nil, nil
+	}
+	return res, nodes
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	bdd, _ := New(6)
+	n1 := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(4)), bdd.NIthvar(2))
+	n2 := bdd.Equiv(bdd.Ithvar(1), bdd.Ithvar(5))
+	f, err := bdd.Flatten(n1, n2, bdd.False())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range f.Nodes {
+		if v[1] >= k+2 || v[2] >= k+2 {
+			t.Errorf("node %d is not in topological order", k+2)
+		}
+	}
+	if f.Roots[2] != 0 {
+		t.Errorf("constant False should have id 0, actual %d", f.Roots[2])
+	}
+	// we rebuild the nodes in the same BDD and in a fresh one
+	res, err := bdd.Unflatten(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bdd.Equal(res[0], n1) || !bdd.Equal(res[1], n2) || !bdd.Equal(res[2], bdd.False()) {
+		t.Errorf("Unflatten(Flatten(n)) should return n")
+	}
+	other, _ := New(8, Nodesize(10))
+	res, err = other.Unflatten(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := other.Satcount(res[0]); actual.Int64() != 4*bdd.Satcount(n1).Int64() {
+		t.Errorf("Unflatten in a larger BDD, expected %d assignments, actual %s", 4*bdd.Satcount(n1).Int64(), actual)
+	}
+	// and we check that we detect malformed values
+	f.Nodes[0][1] = 5
+	if _, err := bdd.Unflatten(f); err == nil {
+		t.Errorf("Unflatten should fail when nodes are not in topological order")
+	}
+}
+
+func TestBulkmake(t *testing.T) {
+	bdd, _ := New(10)
+	n := bdd.True()
+	for k := 0; k < 10; k += 2 {
+		n = bdd.And(n, bdd.Or(bdd.Ithvar(k), bdd.Ithvar(k+1)))
+	}
+	n = bdd.Or(n, bdd.Apply(bdd.Ithvar(0), bdd.Ithvar(9), OPxor))
+	f, _ := bdd.Flatten(n)
+	// nodes that already exist in the table should be reused
+	res, err := bdd.Unflatten(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *res[0] != *n {
+		t.Errorf("Unflatten should return the same node, expected %d, actual %d", *n, *res[0])
+	}
+	// we create garbage in a small BDD so that bulk insertion needs a gc and
+	// a resize
+	other, _ := New(10, Nodesize(20))
+	for k := 0; k < 9; k++ {
+		other.Apply(other.Ithvar(k), other.Ithvar(k+1), OPxor)
+	}
+	res, err = other.Unflatten(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := other.Satcount(res[0]), bdd.Satcount(n); actual.Cmp(expected) != 0 {
+		t.Errorf("Unflatten in a fresh BDD, expected %s assignments, actual %s", expected, actual)
+	}
+	if g, _ := other.Flatten(res[0]); len(g.Nodes) != len(f.Nodes) {
+		t.Errorf("Unflatten in a fresh BDD, expected %d nodes, actual %d", len(f.Nodes), len(g.Nodes))
+	}
+}
+
+func TestExtract(t *testing.T) {
+	bdd, _ := New(10, Nodesize(5000))
+	n := bdd.False()
+	for k := 0; k < 10; k++ {
+		bdd.Apply(n, bdd.Ithvar(k), OPxor)
+		n = bdd.Or(n, bdd.And(bdd.Ithvar(k), bdd.NIthvar((k+3)%10)))
+	}
+	m := bdd.Ithvar(4)
+	small, res := bdd.Extract(n, m)
+	if small == nil || len(res) != 2 {
+		t.Fatalf("Extract failed; %s", bdd.Error())
+	}
+	if small.Allocated() >= bdd.Allocated() || small.Allocated() > 2*10+2+small.Live() {
+		t.Errorf("Extract should return a compact table, allocated %d, live %d", small.Allocated(), small.Live())
+	}
+	if small.Satcount(res[0]).Cmp(bdd.Satcount(n)) != 0 || !small.Equal(res[1], small.Ithvar(4)) {
+		t.Errorf("Extract should preserve the functions denoted by its arguments")
+	}
+	if s, _ := bdd.Extract(n, nil); s != nil || !bdd.Errored() {
+		t.Errorf("Extract should fail with a nil node")
+	}
+}
+
+func TestRelayout(t *testing.T) {
+	bdd, R := milner(t, true, 4, Nodesize(100), Cachesize(25))
+	for _, layout := range []Layout{DepthFirst, ByLevel} {
+		res, nodes := bdd.Relayout(layout, R)
+		if res == nil {
+			t.Fatalf("Relayout(%s) failed: %s", layout, bdd.Error())
+		}
+		if bdd.fingerprint(*R) != res.fingerprint(*nodes[0]) {
+			t.Errorf("Relayout(%s) should not change the function", layout)
+		}
+		// the nodes reachable from the root, except the variables that are
+		// created first, are contiguous in the table and, with ByLevel, sorted
+		// by decreasing level
+		ids := []int{}
+		for _, v := range res.topo(*nodes[0]) {
+			if v >= 2*res.Varnum()+2 {
+				ids = append(ids, v)
+			}
+		}
+		sort.Ints(ids)
+		if len(ids) == 0 || ids[len(ids)-1]-ids[0]+1 != len(ids) {
+			t.Errorf("Relayout(%s) should place nodes contiguously, found %v", layout, ids)
+		}
+		if layout == ByLevel {
+			for k := 1; k < len(ids); k++ {
+				if res.level(ids[k]) > res.level(ids[k-1]) {
+					t.Errorf("Relayout(ByLevel) should sort nodes by decreasing level")
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package graph
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/dalzilio/rudd"
+)
+
+// Checkpoint describes how the state of a long breadth-first search is saved,
+// in order to resume it after a crash. See BFSCheckpoint.
+type Checkpoint struct {
+	Path  string // Name of the file where the state is saved
+	Every int    // Number of layers between two saves (every layer if less than 1)
+}
+
+// BFSCheckpoint is like BFS but saves the state of the search, meaning the
+// current depth, the frontier and the set of vertices reached so far, in file
+// c.Path every c.Every layers. If this file exists when BFSCheckpoint is
+// called, we resume the search from the saved state instead of starting from
+// init; hence a program that crashed during a long search only needs to
+// rebuild the relation, in a BDD with the same variables, and to call
+// BFSCheckpoint again. Function f is only called on the layers computed after
+// resuming. The file is written atomically, using a temporary file that is
+// renamed, and it is removed when the search completes.
+//
+// The file starts with the depth, as an unsigned varint, followed by the
+// frontier and the reached set, in the format used by the Save method of
+// package rudd.
+func (r *Rel) BFSCheckpoint(init rudd.Node, c Checkpoint, f func(depth int, frontier rudd.Node) error) (rudd.Node, error) {
+	every := c.Every
+	if every < 1 {
+		every = 1
+	}
+	reached, frontier, depth := init, init, 0
+	if saved, err := os.Open(c.Path); err == nil {
+		depth, reached, frontier, err = r.loadcheckpoint(saved)
+		saved.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot resume from checkpoint %s; %s", c.Path, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	start := depth
+	save := func(depth int, reached, frontier rudd.Node) error {
+		if depth == start || (depth-start)%every != 0 {
+			return nil
+		}
+		return r.savecheckpoint(c.Path, depth, reached, frontier)
+	}
+	res, err := r.bfs(reached, frontier, depth, f, save)
+	if err != nil {
+		return res, err
+	}
+	if err := os.Remove(c.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return res, err
+	}
+	return res, nil
+}
+
+func (r *Rel) savecheckpoint(path string, depth int, reached, frontier rudd.Node) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	w.Write(binary.AppendUvarint(nil, uint64(depth)))
+	err = r.b.Save(w, frontier, reached)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("cannot write checkpoint %s; %s", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (r *Rel) loadcheckpoint(file *os.File) (int, rudd.Node, rudd.Node, error) {
+	br := bufio.NewReader(file)
+	depth, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	nodes, err := r.b.Load(br)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if len(nodes) != 2 {
+		return 0, nil, nil, fmt.Errorf("expected 2 sets, found %d", len(nodes))
+	}
+	return int(depth), nodes[1], nodes[0], nil
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Package graph provides symbolic algorithms over graphs, such as the state space
+of a transition system, where sets of vertices and the edge relation are
+encoded using BDDs. It includes a breadth-first search (BFS) and the
+decomposition of a set of vertices into strongly connected components (SCC),
+using the Lockstep algorithm of Bloem, Gabow and Somenzi.
+
+A graph is described by a Rel, that is a BDD over two disjoint sets of
+variables of the same size: the current variables, encoding the source of an
+edge, and the next variables, encoding its target. A set of vertices is a BDD
+over the current variables.
+*/
+package graph
+
+import (
+	"fmt"
+
+	"github.com/dalzilio/rudd"
+)
+
+// Rel is a relation between vertices, such as the transition relation of a
+// system, encoded by a BDD over current and next variables.
+type Rel struct {
+	b         *rudd.BDD
+	t         rudd.Node     // the relation, over current and next variables
+	current   []int         // the current variables
+	curcube   rudd.Node     // cube of the current variables
+	nextcube  rudd.Node     // cube of the next variables
+	tonext    rudd.Replacer // replace current variables with next variables
+	tocurrent rudd.Replacer // replace next variables with current variables
+}
+
+// NewRel returns the relation t, where current[k] and next[k] are the variables
+// encoding the same bit of, respectively, the source and the target of an edge.
+// We return an error if t is not a valid node or if the two lists of variables
+// do not have the same length or are not disjoint.
+func NewRel(b *rudd.BDD, t rudd.Node, current, next []int) (*Rel, error) {
+	if t == nil {
+		return nil, fmt.Errorf("nil relation in call to NewRel")
+	}
+	tonext, err := b.NewReplacer(current, next)
+	if err != nil {
+		return nil, fmt.Errorf("%s in call to NewRel", err)
+	}
+	tocurrent, err := b.NewReplacer(next, current)
+	if err != nil {
+		return nil, fmt.Errorf("%s in call to NewRel", err)
+	}
+	r := &Rel{
+		b:         b,
+		t:         t,
+		current:   append([]int{}, current...),
+		curcube:   b.Makeset(current),
+		nextcube:  b.Makeset(next),
+		tonext:    tonext,
+		tocurrent: tocurrent,
+	}
+	if b.Errored() {
+		return nil, fmt.Errorf("%s in call to NewRel", b.Error())
+	}
+	return r, nil
+}
+
+// Image returns the set of successors of the vertices in s.
+func (r *Rel) Image(s rudd.Node) rudd.Node {
+	return r.b.Replace(r.b.AppEx(s, r.t, rudd.OPand, r.curcube), r.tocurrent)
+}
+
+// Preimage returns the set of predecessors of the vertices in s.
+func (r *Rel) Preimage(s rudd.Node) rudd.Node {
+	return r.b.AppEx(r.t, r.b.Replace(s, r.tonext), rudd.OPand, r.nextcube)
+}
+
+// BFS computes the set of vertices reachable from init, in a breadth-first
+// manner. We call f, if it is not nil, on each layer of the search, starting
+// with init at depth 0, where the layer at depth k is the set of vertices whose
+// shortest distance from init is k. We stop at the first error returned by f.
+// We also return an error if an operation fails, for instance because we run
+// out of nodes.
+func (r *Rel) BFS(init rudd.Node, f func(depth int, frontier rudd.Node) error) (rudd.Node, error) {
+	return r.bfs(init, init, 0, f, nil)
+}
+
+// bfs continues a breadth-first search from the given frontier, at the given
+// depth, where reached is the set of vertices found so far. We call save, if
+// it is not nil, at each depth, before computing the next layer.
+func (r *Rel) bfs(reached, frontier rudd.Node, depth int, f func(depth int, frontier rudd.Node) error, save func(depth int, reached, frontier rudd.Node) error) (rudd.Node, error) {
+	b := r.b
+	for ; !b.Equal(frontier, b.False()); depth++ {
+		if b.Errored() {
+			return nil, fmt.Errorf("%s in BFS", b.Error())
+		}
+		if save != nil {
+			if err := save(depth, reached, frontier); err != nil {
+				return reached, err
+			}
+		}
+		if f != nil {
+			if err := f(depth, frontier); err != nil {
+				return reached, err
+			}
+		}
+		frontier = b.Apply(r.Image(frontier), reached, rudd.OPdiff)
+		reached = b.Or(reached, frontier)
+	}
+	if b.Errored() {
+		return nil, fmt.Errorf("%s in BFS", b.Error())
+	}
+	return reached, nil
+}
+
+// Reachable returns the set of vertices reachable from init, or nil if there
+// is an error.
+func (r *Rel) Reachable(init rudd.Node) rudd.Node {
+	res, _ := r.BFS(init, nil)
+	return res
+}
+
+// SCC decomposes the set of vertices in states into strongly connected
+// components, in the subgraph induced by states, and calls f on each of them.
+// Every vertex belongs to exactly one component, meaning that we also report
+// trivial components, made of a single vertex that is not on a cycle. We stop
+// at the first error returned by f, and we return an error if an operation
+// fails.
+//
+// We use the Lockstep algorithm, that needs a number of image and preimage
+// computations in O(n log n), where n is the number of vertices.
+func (r *Rel) SCC(states rudd.Node, f func(scc rudd.Node) error) error {
+	b := r.b
+	empty := func(n rudd.Node) bool { return b.Equal(n, b.False()) }
+	minus := func(n1, n2 rudd.Node) rudd.Node { return b.Apply(n1, n2, rudd.OPdiff) }
+	todo := []rudd.Node{states}
+	for len(todo) > 0 {
+		v := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if b.Errored() {
+			return fmt.Errorf("%s in SCC", b.Error())
+		}
+		if empty(v) {
+			continue
+		}
+		pivot := r.pick(v)
+		fwd, bwd := pivot, pivot
+		ffront, bfront := pivot, pivot
+		// we compute the forward and backward sets in lockstep, until one of
+		// them converges
+		for !empty(ffront) && !empty(bfront) {
+			ffront = minus(b.And(r.Image(ffront), v), fwd)
+			fwd = b.Or(fwd, ffront)
+			bfront = minus(b.And(r.Preimage(bfront), v), bwd)
+			bwd = b.Or(bwd, bfront)
+		}
+		// then we complete the other set inside the converged one
+		var conv rudd.Node
+		if empty(ffront) {
+			conv = fwd
+			bfront, bwd = b.And(bfront, conv), b.And(bwd, conv)
+			for !empty(bfront) {
+				bfront = minus(b.And(r.Preimage(bfront), conv), bwd)
+				bwd = b.Or(bwd, bfront)
+			}
+		} else {
+			conv = bwd
+			ffront, fwd = b.And(ffront, conv), b.And(fwd, conv)
+			for !empty(ffront) {
+				ffront = minus(b.And(r.Image(ffront), conv), fwd)
+				fwd = b.Or(fwd, ffront)
+			}
+		}
+		scc := b.And(fwd, bwd)
+		if b.Errored() {
+			return fmt.Errorf("%s in SCC", b.Error())
+		}
+		if err := f(scc); err != nil {
+			return err
+		}
+		todo = append(todo, minus(v, conv), minus(conv, scc))
+	}
+	return nil
+}
+
+// pick returns a single vertex in the (non empty) set s.
+func (r *Rel) pick(s rudd.Node) rudd.Node {
+	b := r.b
+	value := make(map[int]bool, len(r.current))
+	for n := s; !b.Equal(n, b.True()); {
+		v := b.Var(n)
+		if low := b.Low(n); !b.Equal(low, b.False()) {
+			value[v] = false
+			n = low
+		} else {
+			value[v] = true
+			n = b.High(n)
+		}
+	}
+	polarity := make([]bool, len(r.current))
+	for k, v := range r.current {
+		polarity[k] = value[v]
+	}
+	return b.Makecube(r.current, polarity)
+}
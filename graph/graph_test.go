@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package graph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/dalzilio/rudd"
+)
+
+// vertex returns the BDD encoding vertex v (with 3 bits) using variables vars.
+func vertex(b *rudd.BDD, vars []int, v int) rudd.Node {
+	return b.Makecube(vars, []bool{v&4 != 0, v&2 != 0, v&1 != 0})
+}
+
+func testgraph(t *testing.T, edges [][2]int) (*rudd.BDD, *Rel) {
+	b, _ := rudd.New(6)
+	current, next := []int{0, 2, 4}, []int{1, 3, 5}
+	rel := b.False()
+	for _, e := range edges {
+		rel = b.Or(rel, b.And(vertex(b, current, e[0]), vertex(b, next, e[1])))
+	}
+	r, err := NewRel(b, rel, current, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, r
+}
+
+// vertices returns the list of vertices in s.
+func vertices(b *rudd.BDD, s rudd.Node) []int {
+	res := []int{}
+	for v := 0; v < 8; v++ {
+		if !b.Equal(b.And(s, vertex(b, []int{0, 2, 4}, v)), b.False()) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func TestBFS(t *testing.T) {
+	b, r := testgraph(t, [][2]int{{0, 1}, {1, 2}, {0, 3}, {3, 4}, {2, 5}, {6, 7}})
+	layers := [][]int{}
+	reached, err := r.BFS(vertex(b, []int{0, 2, 4}, 0), func(depth int, frontier rudd.Node) error {
+		layers = append(layers, vertices(b, frontier))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := vertices(b, reached); len(actual) != 6 || actual[5] != 5 {
+		t.Errorf("reachable vertices from 0: expected [0 1 2 3 4 5], actual %v", actual)
+	}
+	if len(layers) != 4 || len(layers[1]) != 2 || layers[3][0] != 5 {
+		t.Errorf("unexpected layers in BFS: %v", layers)
+	}
+	if actual := vertices(b, r.Preimage(vertex(b, []int{0, 2, 4}, 4))); len(actual) != 1 || actual[0] != 3 {
+		t.Errorf("predecessors of 4: expected [3], actual %v", actual)
+	}
+}
+
+func TestSCC(t *testing.T) {
+	b, r := testgraph(t, [][2]int{{0, 1}, {1, 2}, {2, 0}, {2, 3}, {3, 4}, {4, 3}, {5, 5}, {6, 7}})
+	sccs := [][]int{}
+	err := r.SCC(b.True(), func(scc rudd.Node) error {
+		sccs = append(sccs, vertices(b, scc))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(sccs, func(i, j int) bool { return sccs[i][0] < sccs[j][0] })
+	expected := [][]int{{0, 1, 2}, {3, 4}, {5}, {6}, {7}}
+	if len(sccs) != len(expected) {
+		t.Fatalf("expected SCCs %v, actual %v", expected, sccs)
+	}
+	for k := range expected {
+		if len(sccs[k]) != len(expected[k]) || sccs[k][0] != expected[k][0] {
+			t.Errorf("expected SCCs %v, actual %v", expected, sccs)
+		}
+	}
+}
+
+func TestBFSCheckpoint(t *testing.T) {
+	edges := [][2]int{{0, 1}, {1, 2}, {0, 3}, {3, 4}, {2, 5}, {6, 7}}
+	c := Checkpoint{Path: filepath.Join(t.TempDir(), "bfs.ckpt"), Every: 1}
+	b, r := testgraph(t, edges)
+	crash := fmt.Errorf("crash")
+	_, err := r.BFSCheckpoint(vertex(b, []int{0, 2, 4}, 0), c, func(depth int, frontier rudd.Node) error {
+		if depth == 2 {
+			return crash
+		}
+		return nil
+	})
+	if err != crash {
+		t.Fatalf("expected the search to stop at depth 2, actual error %v", err)
+	}
+	if _, err := os.Stat(c.Path); err != nil {
+		t.Fatalf("expected a checkpoint, %s", err)
+	}
+	// we restart with a new BDD
+	b, r = testgraph(t, edges)
+	depths := []int{}
+	reached, err := r.BFSCheckpoint(vertex(b, []int{0, 2, 4}, 0), c, func(depth int, frontier rudd.Node) error {
+		depths = append(depths, depth)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(depths) != 2 || depths[0] != 2 {
+		t.Errorf("expected to resume at depth 2, actual depths %v", depths)
+	}
+	if actual := vertices(b, reached); len(actual) != 6 || actual[5] != 5 {
+		t.Errorf("reachable vertices from 0: expected [0 1 2 3 4 5], actual %v", actual)
+	}
+	if _, err := os.Stat(c.Path); !os.IsNotExist(err) {
+		t.Errorf("the checkpoint should be removed at the end of the search")
+	}
+}
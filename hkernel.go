@@ -2,13 +2,13 @@
 //
 // MIT License
 
+//go:build !buddy
 // +build !buddy
 
 package rudd
 
 import (
 	"log"
-	"math"
 	"runtime"
 	"sync/atomic"
 )
@@ -16,7 +16,7 @@ import (
 // Retnode is a kernel function of the BDD package. Use it at your own risk.
 // Retnode returns a valid node from the value returned by a call to Makenode.
 func (b *tables) Retnode(n int) Node {
-	if n < 0 || n > len(b.nodes) {
+	if n < 0 || n > len(b.core.Nodes) {
 		if _DEBUG {
 			log.Panicf("b.retnode(%d) not valid\n", n)
 		}
@@ -29,9 +29,14 @@ func (b *tables) Retnode(n int) Node {
 		return bddone
 	}
 	x := n
-	if b.nodes[n].refcou < _MAXREFCOUNT {
-		b.nodes[n].refcou++
-		runtime.SetFinalizer(&x, b.nodefinalizer)
+	if b.core.Nodes[n].Refcou < _MAXREFCOUNT {
+		b.core.Own()
+		b.core.Nodes[n].Refcou++
+		if b.audit != nil {
+			b.audit.setfinalizer(&x, b.nodefinalizer, len(b.history))
+		} else {
+			runtime.SetFinalizer(&x, b.nodefinalizer)
+		}
 		if _DEBUG {
 			atomic.AddUint64(&(b.setfinalizers), 1)
 			if _LOGLEVEL > 2 {
@@ -43,61 +48,44 @@ func (b *tables) Retnode(n int) Node {
 }
 
 func (b *tables) makenode(level int32, low int, high int, refstack []int) (int, error) {
-	if _DEBUG {
-		b.uniqueAccess++
-	}
-	// check whether children are equal, in which case we can skip the node
-	if low == high {
-		return low, nil
-	}
-	// otherwise try to find an existing node using the unique table
-	if res, ok := b.nodehash(level, low, high); ok {
-		if _DEBUG {
-			b.uniqueHit++
-		}
-		return res, nil
-	}
-	if _DEBUG {
-		b.uniqueMiss++
-	}
-	// If no existing node, we build one. If there is no available spot
-	// (b.freepos == 0), we try garbage collection and, as a last resort,
-	// resizing the BDD list.
-	var err error
-	if b.freepos == 0 {
-		// We garbage collect unused nodes to try and find spare space.
-		b.gbc(refstack)
-		err = errReset
-		// We also test if we are under the threshold for resising.
-		if (b.freenum*100)/len(b.nodes) <= b.minfreenodes {
-			err = b.noderesize()
-			if err != errResize {
-				return -1, errMemory
-			}
-		}
-		// Panic if we still have no free positions after all this
-		if b.freepos == 0 {
-			// b.seterror("Unable to resize BDD")
-			return -1, errMemory
-		}
-	}
-	// We can now build the new node in the first available spot
-	b.produced++
-	return b.setnode(level, low, high, 0), err
+	b.Lock()
+	defer b.Unlock()
+	return b.core.Makenode(level, low, high, refstack)
 }
 
-func (b *tables) gbc(refstack []int) {
-	if _LOGLEVEL > 0 {
-		log.Println("starting GC")
-	}
+// reserve makes sure that there are at least n free nodes in the table, using
+// garbage collection and, if this is not enough, a single resize of the node
+// table. We return the same errors than makenode.
+func (b *tables) reserve(n int, refstack []int) error {
+	b.Lock()
+	defer b.Unlock()
+	return b.core.Reserve(n, refstack)
+}
+
+// bulkmake inserts a sequence of nodes in the table, see dd.MapTable.Bulkmake.
+// There is no garbage collection during the insertion.
+func (b *tables) bulkmake(ids []int, nodes [][3]int, refstack []int) ([]int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.core.Bulkmake(ids, nodes, refstack)
+}
 
-	// runtime.GC()
+// gbc is the garbage collector called for reclaiming memory, inside a call to
+// makenode, when there are no free positions available. Allocated nodes that
+// are not reclaimed do not move.
+func (b *tables) gbc(refstack []int) {
+	b.Lock()
+	defer b.Unlock()
+	b.core.Gbc(refstack)
+}
 
-	// we append the current stats to the GC history
+// recordgc is called by the node table at the start of each garbage
+// collection. We append the current stats to the GC history.
+func (b *tables) recordgc() {
 	if _DEBUG {
 		b.gcstat.history = append(b.gcstat.history, gcpoint{
-			nodes:            len(b.nodes),
-			freenodes:        b.freenum,
+			nodes:            len(b.core.Nodes),
+			freenodes:        b.core.Freenum,
 			setfinalizers:    int(b.gcstat.setfinalizers),
 			calledfinalizers: int(b.gcstat.calledfinalizers),
 		})
@@ -108,111 +96,20 @@ func (b *tables) gbc(refstack []int) {
 		b.gcstat.calledfinalizers = 0
 	} else {
 		b.gcstat.history = append(b.gcstat.history, gcpoint{
-			nodes:     len(b.nodes),
-			freenodes: b.freenum,
+			nodes:     len(b.core.Nodes),
+			freenodes: b.core.Freenum,
 		})
 	}
-	// we mark the nodes in the refstack to avoid collecting them
-	for _, r := range refstack {
-		b.markrec(int(r))
-	}
-	// we also protect nodes with a positive refcount (and therefore also the
-	// ones with a MAXREFCOUNT, such has variables)
-	for k := range b.nodes {
-		if b.nodes[k].refcou > 0 {
-			b.markrec(k)
-		}
-	}
-	b.freepos = 0
-	b.freenum = 0
-	// we do a pass through the nodes list to void the unmarked nodes. After
-	// finishing this pass, b.freepos points to the first free position in
-	// b.nodes, or it is 0 if we found none.
-	for n := len(b.nodes) - 1; n > 1; n-- {
-		if b.ismarked(n) && (b.nodes[n].low != -1) {
-			b.unmarknode(n)
-		} else {
-			b.delnode(b.nodes[n])
-			b.nodes[n].low = -1
-			b.nodes[n].high = b.freepos
-			b.freepos = n
-			b.freenum++
-		}
-	}
-	// we also invalidate the caches
-	// b.cachereset()
-	if _LOGLEVEL > 0 {
-		log.Printf("end GC; freenum: %d\n", b.freenum)
-	}
-}
-
-func (b *tables) noderesize() error {
-	if _LOGLEVEL > 0 {
-		log.Printf("start resize: %d\n", len(b.nodes))
-	}
-	// if b.error != nil {
-	// 	b.seterror("Error before resizing; %s", b.error)
-	// 	return b.error
-	// }
-	oldsize := len(b.nodes)
-	nodesize := len(b.nodes)
-	if (oldsize >= b.maxnodesize) && (b.maxnodesize > 0) {
-		// b.seterror("Cannot resize BDD, already at max capacity (%d nodes)", b.maxnodesize)
-		return errMemory
-	}
-	if oldsize > (math.MaxInt32 >> 1) {
-		nodesize = math.MaxInt32 - 1
-	} else {
-		nodesize = nodesize << 1
-	}
-	if b.maxnodeincrease > 0 && nodesize > (oldsize+b.maxnodeincrease) {
-		nodesize = oldsize + b.maxnodeincrease
-	}
-	if (nodesize > b.maxnodesize) && (b.maxnodesize > 0) {
-		nodesize = b.maxnodesize
-	}
-	if nodesize <= oldsize {
-		// b.seterror("Unable to grow size of BDD (%d nodes)", nodesize)
-		return errMemory
-	}
-
-	tmp := b.nodes
-	b.nodes = make([]huddnode, nodesize)
-	copy(b.nodes, tmp)
-
-	for n := oldsize; n < nodesize; n++ {
-		b.nodes[n].refcou = 0
-		b.nodes[n].level = 0
-		b.nodes[n].low = -1
-		b.nodes[n].high = n + 1
-	}
-	b.nodes[nodesize-1].high = b.freepos
-	b.freepos = oldsize
-	b.freenum += (nodesize - oldsize)
-
-	// b.cacheresize(len(b.nodes))
-
-	if _LOGLEVEL > 0 {
-		log.Printf("end resize: %d\n", len(b.nodes))
-	}
-
-	return errResize
 }
 
 func (b *tables) markrec(n int) {
-	if n < 2 || b.ismarked(n) || (b.nodes[n].low == -1) {
-		return
-	}
-	b.marknode(n)
-	b.markrec(b.nodes[n].low)
-	b.markrec(b.nodes[n].high)
+	b.Lock()
+	defer b.Unlock()
+	b.core.Markrec(n)
 }
 
 func (b *tables) unmarkall() {
-	for k, v := range b.nodes {
-		if k < 2 || !b.ismarked(k) || (v.low == -1) {
-			continue
-		}
-		b.unmarknode(k)
-	}
+	b.Lock()
+	defer b.Unlock()
+	b.core.Unmarkall()
 }
@@ -13,52 +13,42 @@ import (
 	"sync"
 	"sync/atomic"
 	"unsafe"
+
+	"github.com/dalzilio/rudd/internal/dd"
 )
 
 // tables corresponds to Binary Decision Diagrams based on the runtime
-// hashmap. We hash a triplet (level, low, high) to a []byte and use the unique
-// table to associate this triplet to an entry in the nodes table. We use more
-// space but a benefit is that we can easily migrate to a concurrency-safe
-// hashmap if we want to test concurrent data structures.
+// hashmap. We hash a triplet (level, low, high) and use the unique table to
+// associate this triplet to an entry in the nodes table. We use more space but
+// a benefit is that we can easily migrate to a concurrency-safe hashmap if we
+// want to test concurrent data structures. The node table, the unique table
+// and the garbage collector are provided by type MapTable of package
+// internal/dd.
 type tables struct {
 	sync.RWMutex
-	nodes         []huddnode             // List of all the BDD nodes. Constants are always kept at index 0 and 1
-	unique        map[[huddsize]byte]int // Unicity table, used to associate each triplet to a single node
-	freenum       int                    // Number of free nodes
-	freepos       int                    // First free node
-	produced      int                    // Total number of new nodes ever produced
-	hbuff         [huddsize]byte         // Used to compute the hash of nodes. A Buffer needs no initialization.
-	nodefinalizer interface{}            // Finalizer used to decrement the ref count of external references
-	uniqueAccess  int                    // accesses to the unique node table
-	uniqueHit     int                    // entries actually found in the the unique node table
-	uniqueMiss    int                    // entries not found in the the unique node table
-	gcstat                               // Information about garbage collections
-	configs                              // Configurable parameters
-}
-
-type huddnode struct {
-	level  int32 // Order of the variable in the BDD
-	low    int   // Reference to the false branch
-	high   int   // Reference to the true branch
-	refcou int32 // Count the number of external references
+	core          dd.MapTable // List of all the BDD nodes, with the unique table. Constants are always kept at index 0 and 1
+	nodefinalizer interface{} // Finalizer used to decrement the ref count of external references
+	audit         *leakaudit  // Stack traces of the Nodes returned by Retnode (nil if disabled)
+	gcstat                    // Information about garbage collections
+	configs                   // Configurable parameters
 }
 
 func (b *tables) ismarked(n int) bool {
 	b.RLock()
 	defer b.RUnlock()
-	return (b.nodes[n].refcou & 0x200000) != 0
+	return b.core.IsMarked(n)
 }
 
 func (b *tables) marknode(n int) {
 	b.RLock()
 	defer b.RUnlock()
-	b.nodes[n].refcou |= 0x200000
+	b.core.Mark(n)
 }
 
 func (b *tables) unmarknode(n int) {
 	b.RLock()
 	defer b.RUnlock()
-	b.nodes[n].refcou &= 0x1FFFFF
+	b.core.Unmark(n)
 }
 
 // New returns a new BDD based on an implementation selected with the build tag;
@@ -83,188 +73,206 @@ func New(varnum int, options ...func(*configs)) (*BDD, error) {
 	for _, f := range options {
 		f(config)
 	}
-	b.varnum = int32(varnum)
 	if _LOGLEVEL > 0 {
-		log.Printf("set varnum to %d\n", b.varnum)
+		log.Printf("set varnum to %d\n", varnum)
 	}
 	b.varset = make([][2]int, varnum)
-	// We also initialize the refstack.
-	b.refstack = make([]int, 0, 2*varnum+4)
+	b.initorder(varnum)
+	// We also initialize the node table and the refstack.
+	b.initkernel(varnum, config)
 	b.Initref()
-	b.error = nil
-	impl := &tables{}
-	impl.minfreenodes = config.minfreenodes
-	impl.maxnodeincrease = config.maxnodeincrease
-	// initializing the list of nodes
-	nodesize := config.nodesize
-	impl.nodes = make([]huddnode, nodesize)
-	for k := range impl.nodes {
-		impl.nodes[k] = huddnode{
-			level:  0,
-			low:    -1,
-			high:   k + 1,
-			refcou: 0,
-		}
-	}
-	impl.nodes[nodesize-1].high = 0
-	impl.unique = make(map[[huddsize]byte]int, nodesize)
-	// creating bddzero and bddone. We do not add them to the unique table.
-	impl.nodes[0] = huddnode{
-		level:  int32(config.varnum),
-		low:    0,
-		high:   0,
-		refcou: _MAXREFCOUNT,
-	}
-	impl.nodes[1] = huddnode{
-		level:  int32(config.varnum),
-		low:    1,
-		high:   1,
-		refcou: _MAXREFCOUNT,
-	}
-	impl.freepos = 2
-	impl.freenum = len(impl.nodes) - 2
+	impl := b.tables
 	for k := 0; k < config.varnum; k++ {
 		v0, _ := impl.makenode(int32(k), 0, 1, nil)
 		if v0 < 0 {
 			b.seterror("cannot allocate new variable %d in setVarnum", k)
 			return nil, b.error
 		}
-		impl.nodes[v0].refcou = _MAXREFCOUNT
+		impl.core.Nodes[v0].Refcou = _MAXREFCOUNT
 		b.Pushref(v0)
 		v1, _ := impl.makenode(int32(k), 1, 0, nil)
 		if v1 < 0 {
 			b.seterror("cannot allocate new variable %d in setVarnum", k)
 			return nil, b.error
 		}
-		impl.nodes[v1].refcou = _MAXREFCOUNT
+		impl.core.Nodes[v1].Refcou = _MAXREFCOUNT
 		b.Popref(1)
 		b.varset[k] = [2]int{v0, v1}
 	}
+	b.cacheinit(config)
+	b.initjournal(config)
+	b.initwarnings(config)
+	b.config = config
+	if config.autoreorder != nil {
+		t := *config.autoreorder
+		t.period = config.reorderperiod
+		b.autoorder = &t
+	}
+	b.initages(config)
+	return b, nil
+}
+
+// lookup returns the ids of the nodes associated with a triplet in the unique
+// table, used to check that there is at most one of them.
+func (b *tables) lookup(level int32, low, high int) []int {
+	b.RLock()
+	defer b.RUnlock()
+	return b.core.Lookup(level, low, high)
+}
+
+// newtables returns a node table configured with config, with the two
+// constants at level config.varnum.
+func newtables(config *configs) *tables {
+	impl := &tables{}
+	impl.assertions = config.assertions
+	impl.core.Minfreenodes = config.minfreenodes
+	impl.core.Maxnodesize = config.maxnodesize
+	impl.core.Maxnodeincrease = config.maxnodeincrease
+	impl.core.BeforeGC = impl.recordgc
+	impl.core.Init(config.nodesize, int32(config.varnum))
 	impl.gcstat.history = []gcpoint{}
+	impl.audit = newleakaudit(config)
 	impl.nodefinalizer = func(n *int) {
-		b.Lock()
-		defer b.Unlock()
+		impl.Lock()
+		defer impl.Unlock()
 		if _DEBUG {
 			atomic.AddUint64(&(impl.gcstat.calledfinalizers), 1)
 			if _LOGLEVEL > 2 {
 				log.Printf("dec refcou %d\n", *n)
 			}
 		}
-		impl.nodes[*n].refcou--
+		// pinned nodes, for instance in a sealed BDD, are never released
+		if impl.core.Nodes[*n].Refcou < _MAXREFCOUNT {
+			impl.core.Nodes[*n].Refcou--
+		}
 	}
-	b.tables = impl
-	b.cacheinit(config)
-	return b, nil
+	return impl
+}
+
+func (b *tables) size() int {
+	b.RLock()
+	defer b.RUnlock()
+	return len(b.core.Nodes)
 }
 
-func (b *tables) huddhash(level int32, low, high int) {
-	b.hbuff[0] = byte(level)
-	b.hbuff[1] = byte(level >> 8)
-	b.hbuff[2] = byte(level >> 16)
-	b.hbuff[3] = byte(level >> 24)
-	b.hbuff[4] = byte(low)
-	b.hbuff[5] = byte(low >> 8)
-	b.hbuff[6] = byte(low >> 16)
-	b.hbuff[7] = byte(low >> 24)
-	if huddsize == 20 {
-		// 64 bits machine
-		b.hbuff[8] = byte(low >> 32)
-		b.hbuff[9] = byte(low >> 40)
-		b.hbuff[10] = byte(low >> 48)
-		b.hbuff[11] = byte(low >> 56)
-		b.hbuff[12] = byte(high)
-		b.hbuff[13] = byte(high >> 8)
-		b.hbuff[14] = byte(high >> 16)
-		b.hbuff[15] = byte(high >> 24)
-		b.hbuff[16] = byte(high >> 32)
-		b.hbuff[17] = byte(high >> 40)
-		b.hbuff[18] = byte(high >> 48)
-		b.hbuff[19] = byte(high >> 56)
-		return
+// used returns the number of nodes in use in the table, including the
+// constants.
+func (b *tables) used() int {
+	b.RLock()
+	defer b.RUnlock()
+	return len(b.core.Nodes) - b.core.Freenum
+}
+
+// setaftergc sets a function called at the end of each garbage collection.
+// The node table calls it while we hold the lock, and f uses the accessors of
+// tables, so we release the lock for the duration of the call.
+func (b *tables) setaftergc(f func()) {
+	b.core.AfterGC = func() {
+		b.Unlock()
+		defer b.Lock()
+		f()
 	}
-	// 32 bits machine
-	b.hbuff[8] = byte(high)
-	b.hbuff[9] = byte(high >> 8)
-	b.hbuff[10] = byte(high >> 16)
-	b.hbuff[11] = byte(high >> 24)
 }
 
-func (b *tables) nodehash(level int32, low, high int) (int, bool) {
-	b.huddhash(level, low, high)
-	hn, ok := b.unique[b.hbuff]
-	return hn, ok
+// setconstlevel sets the level of the constants, that is always equal to the
+// number of variables.
+func (b *tables) setconstlevel(level int32) {
+	b.Lock()
+	defer b.Unlock()
+	b.core.Own()
+	b.core.Nodes[0].Level = level
+	b.core.Nodes[1].Level = level
 }
 
-// When a slot is unused in b.nodes, we have low set to -1 and high set to the
-// next free position. The value of b.freepos gives the index of the lowest
-// unused slot, except when freenum is 0, in which case it is also 0.
+// pinnode sets the reference count of node n to the maximal value, so that it
+// is never reclaimed.
+func (b *tables) pinnode(n int) {
+	b.Lock()
+	defer b.Unlock()
+	b.core.Own()
+	b.core.Nodes[n].Refcou = _MAXREFCOUNT
+}
 
-func (b *tables) setnode(level int32, low int, high int, count int32) int {
+// sharetables makes b use the nodes and the unique table of src, that must not
+// change afterwards, until the first change to b; see dd.MapTable.Share.
+func (b *tables) sharetables(src *tables) {
 	b.Lock()
 	defer b.Unlock()
-	b.huddhash(level, low, high)
-	b.freenum--
-	b.unique[b.hbuff] = b.freepos
-	res := b.freepos
-	b.freepos = b.nodes[b.freepos].high
-	b.nodes[res] = huddnode{level, low, high, count}
-	return res
+	src.RLock()
+	defer src.RUnlock()
+	b.core.Share(&src.core)
 }
 
-func (b *tables) delnode(hn huddnode) {
-	b.huddhash(hn.level, hn.low, hn.high)
-	delete(b.unique, b.hbuff)
+// shared reports whether b still shares its nodes with another table.
+func (b *tables) shared() bool {
+	b.RLock()
+	defer b.RUnlock()
+	return b.core.Shared()
 }
 
-func (b *tables) size() int {
+// producednum returns the total number of nodes ever produced.
+func (b *tables) producednum() int {
 	b.RLock()
 	defer b.RUnlock()
-	return len(b.nodes)
+	return b.core.Produced
 }
 
 func (b *tables) level(n int) int32 {
 	b.RLock()
 	defer b.RUnlock()
-	return b.nodes[n].level
+	return b.core.Nodes[n].Level
 }
 
 func (b *tables) low(n int) int {
 	b.RLock()
 	defer b.RUnlock()
-	return b.nodes[n].low
+	return b.core.Nodes[n].Low
 }
 
 func (b *tables) high(n int) int {
 	b.RLock()
 	defer b.RUnlock()
-	return b.nodes[n].high
+	return b.core.Nodes[n].High
+}
+
+// refcount returns the number of external references to node n.
+func (b *tables) refcount(n int) int32 {
+	b.RLock()
+	defer b.RUnlock()
+	return b.core.Nodes[n].Refcou
+}
+
+// unhash removes node n from the unique table, before changing it in place.
+func (b *tables) unhash(n int) {
+	b.Lock()
+	defer b.Unlock()
+	b.core.Unhash(n)
+}
+
+// rehash sets the level and successors of node n, removed with unhash, and
+// adds it back to the unique table.
+func (b *tables) rehash(n int, level int32, low, high int) {
+	b.Lock()
+	defer b.Unlock()
+	b.core.Rehash(n, level, low, high)
+}
+
+// freenode reclaims node n, that must not be referenced anymore, without
+// waiting for the next garbage collection.
+func (b *tables) freenode(n int) {
+	b.Lock()
+	defer b.Unlock()
+	b.core.Free(n)
 }
 
 func (b *tables) allnodesfrom(f func(id, level, low, high int) error, n []Node) error {
 	for _, v := range n {
 		b.markrec(*v)
 	}
-	// if err := f(0, int(b.nodes[0].level), 0, 0); err != nil {
-	// 	b.unmarkall()
-	// 	return err
-	// }
-	// if err := f(1, int(b.nodes[1].level), 1, 1); err != nil {
-	// 	b.unmarkall()
-	// 	return err
-	// }
-	b.RLock()
-	count := len(b.nodes)
-	b.RUnlock()
-
-	for k := 0; k < count; k++ {
-		b.RLock()
-		if k >= len(b.nodes) {
-			break
-		}
-		b.RUnlock()
+	for k := 0; k < b.size(); k++ {
 		if b.ismarked(k) {
 			b.unmarknode(k)
-			if err := f(k, int(b.nodes[k].level), b.nodes[k].low, b.nodes[k].high); err != nil {
+			if err := f(k, int(b.level(k)), b.low(k), b.high(k)); err != nil {
 				b.unmarkall()
 				return err
 			}
@@ -274,25 +282,12 @@ func (b *tables) allnodesfrom(f func(id, level, low, high int) error, n []Node)
 }
 
 func (b *tables) allnodes(f func(id, level, low, high int) error) error {
-	// if err := f(0, int(b.nodes[0].level), 0, 0); err != nil {
-	// 	return err
-	// }
-	// if err := f(1, int(b.nodes[1].level), 1, 1); err != nil {
-	// 	return err
-	// }
-	b.RLock()
-	count := len(b.nodes)
-	b.RUnlock()
-
-	for k := 0; k < count; k++ {
+	for k := 0; k < b.size(); k++ {
 		b.RLock()
-		if k >= len(b.nodes) {
-			break
-		}
-		v := b.nodes[k]
+		v := b.core.Nodes[k]
 		b.RUnlock()
-		if v.low != -1 {
-			if err := f(k, int(v.level), v.low, v.high); err != nil {
+		if v.Low != -1 {
+			if err := f(k, int(v.Level), v.Low, v.High); err != nil {
 				return err
 			}
 		}
@@ -305,11 +300,11 @@ func (b *tables) stats() string {
 	b.RLock()
 	defer b.RUnlock()
 	res := "Impl.:      Hudd\n"
-	res += fmt.Sprintf("Allocated:  %d (%s)\n", len(b.nodes), humanSize(len(b.nodes), unsafe.Sizeof(huddnode{})))
-	res += fmt.Sprintf("Produced:   %d\n", b.produced)
-	r := (float64(b.freenum) / float64(len(b.nodes))) * 100
-	res += fmt.Sprintf("Free:       %d (%.3g %%)\n", b.freenum, r)
-	res += fmt.Sprintf("Used:       %d (%.3g %%)\n", len(b.nodes)-b.freenum, (100.0 - r))
+	res += fmt.Sprintf("Allocated:  %d (%s)\n", len(b.core.Nodes), humanSize(len(b.core.Nodes), unsafe.Sizeof(dd.Node{})))
+	res += fmt.Sprintf("Produced:   %d\n", b.core.Produced)
+	r := (float64(b.core.Freenum) / float64(len(b.core.Nodes))) * 100
+	res += fmt.Sprintf("Free:       %d (%.3g %%)\n", b.core.Freenum, r)
+	res += fmt.Sprintf("Used:       %d (%.3g %%)\n", len(b.core.Nodes)-b.core.Freenum, (100.0 - r))
 	res += "==============\n"
 	res += fmt.Sprintf("# of GC:    %d\n", len(b.gcstat.history))
 	if _DEBUG {
@@ -322,10 +317,27 @@ func (b *tables) stats() string {
 		res += fmt.Sprintf("Ext. refs:  %d\n", allocated)
 		res += fmt.Sprintf("Reclaimed:  %d\n", reclaimed)
 		res += "==============\n"
-		res += fmt.Sprintf("Unique Access:  %d\n", b.uniqueAccess)
-		res += fmt.Sprintf("Unique Hit:     %d (%.1f%% + %.1f%%)\n", b.uniqueHit, (float64(b.uniqueHit)*100)/float64(b.uniqueAccess),
-			(float64(b.uniqueAccess-b.uniqueMiss-b.uniqueHit)*100)/float64(b.uniqueAccess))
-		res += fmt.Sprintf("Unique Miss:    %d\n", b.uniqueMiss)
+		res += fmt.Sprintf("Unique Access:  %d\n", b.core.UniqueAccess)
+		res += fmt.Sprintf("Unique Hit:     %d (%.1f%% + %.1f%%)\n", b.core.UniqueHit, (float64(b.core.UniqueHit)*100)/float64(b.core.UniqueAccess),
+			(float64(b.core.UniqueAccess-b.core.UniqueMiss-b.core.UniqueHit)*100)/float64(b.core.UniqueAccess))
+		res += fmt.Sprintf("Unique Miss:    %d\n", b.core.UniqueMiss)
 	}
 	return res
 }
+
+// tablestats returns the name of the implementation and the state of the node
+// table, see StatsStruct.
+func (b *tables) tablestats() (string, TableStats) {
+	b.RLock()
+	defer b.RUnlock()
+	return "Hudd", TableStats{
+		Allocated:    len(b.core.Nodes),
+		Bytes:        len(b.core.Nodes) * int(unsafe.Sizeof(dd.Node{})),
+		Produced:     b.core.Produced,
+		Free:         b.core.Freenum,
+		Used:         len(b.core.Nodes) - b.core.Freenum,
+		UniqueAccess: b.core.UniqueAccess,
+		UniqueHit:    b.core.UniqueHit,
+		UniqueMiss:   b.core.UniqueMiss,
+	}
+}
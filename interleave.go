@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "fmt"
+
+// VarGroup is a family of variables, such as the current or next state
+// variables of a transition relation, returned by NewInterleaved. Vars[k] is
+// the variable used for the k-th element of the family.
+type VarGroup struct {
+	Vars []int
+}
+
+// Len returns the number of variables in g.
+func (g VarGroup) Len() int {
+	return len(g.Vars)
+}
+
+// Var returns the k-th variable of g.
+func (g VarGroup) Var(k int) int {
+	return g.Vars[k]
+}
+
+// NewInterleaved returns families of variables that are interleaved in the
+// variable order, where groups[i] is the number of variables in the i-th
+// family. With two families, for instance the current (x) and next (x')
+// state variables of a transition relation, the variables are allocated in the
+// order (x0, x0', x1, x1', ...), which is usually a good order for
+// computing reachable states. A family is skipped when it has no variables
+// left, so families can have different sizes. The variables are numbered from
+// 0, and the total number of variables, the sum of groups, can be used as the
+// varnum of New. Use NewReplacer(next.Vars, current.Vars) to rename the next
+// state variables, or RelSpec{current.Vars, next.Vars} with Unroll. We return
+// an error if a size is negative or if there are too many variables.
+func NewInterleaved(groups ...int) ([]VarGroup, error) {
+	total, longest := 0, 0
+	for _, n := range groups {
+		if n < 0 {
+			return nil, fmt.Errorf("negative size (%d) in call to NewInterleaved", n)
+		}
+		total += n
+		if n > longest {
+			longest = n
+		}
+	}
+	if total > int(_MAXVAR) {
+		return nil, fmt.Errorf("too many variables (%d) in call to NewInterleaved", total)
+	}
+	res := make([]VarGroup, len(groups))
+	for i, n := range groups {
+		res[i].Vars = make([]int, 0, n)
+	}
+	v := 0
+	for k := 0; k < longest; k++ {
+		for i, n := range groups {
+			if k < n {
+				res[i].Vars = append(res[i].Vars, v)
+				v++
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewInterleaved(t *testing.T) {
+	groups, err := NewInterleaved(3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []VarGroup{{Vars: []int{0, 2, 4}}, {Vars: []int{1, 3, 5}}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected %v, actual %v", expected, groups)
+	}
+	groups, _ = NewInterleaved(1, 3, 2)
+	expected = []VarGroup{{Vars: []int{0}}, {Vars: []int{1, 3, 5}}, {Vars: []int{2, 4}}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected %v, actual %v", expected, groups)
+	}
+	if _, err := NewInterleaved(2, -1); err == nil {
+		t.Errorf("expected an error with a negative size")
+	}
+	// the groups can be used to build and rename a transition relation
+	groups, _ = NewInterleaved(2, 2)
+	x, xp := groups[0], groups[1]
+	bdd, _ := New(x.Len() + xp.Len())
+	// next is the successor of a 2 bits counter
+	next := bdd.And(
+		bdd.Equiv(bdd.Ithvar(xp.Var(0)), bdd.Not(bdd.Ithvar(x.Var(0)))),
+		bdd.Equiv(bdd.Ithvar(xp.Var(1)), bdd.Apply(bdd.Ithvar(x.Var(1)), bdd.Ithvar(x.Var(0)), OPxor)),
+	)
+	r, err := bdd.NewReplacer(xp.Vars, x.Vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero := bdd.And(bdd.NIthvar(x.Var(0)), bdd.NIthvar(x.Var(1)))
+	one := bdd.Replace(bdd.AndExist(zero, next, bdd.Makeset(x.Vars)), r)
+	if !bdd.Equal(one, bdd.And(bdd.Ithvar(x.Var(0)), bdd.NIthvar(x.Var(1)))) {
+		t.Errorf("expected the successor of 0 to be 1")
+	}
+}
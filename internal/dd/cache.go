@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package dd
+
+// Entry4 is an entry in a Cache4, used to store the result of an operation
+// with (up to) three operands, such as Apply or Ite. An entry is empty when A
+// is -1.
+type Entry4 struct {
+	Res int
+	A   int
+	B   int
+	C   int
+}
+
+// Cache4 is a cache of operations, with entries of type Entry4, whose size can
+// be proportional to the size of the node table. The caller decides how
+// operations are hashed into the table.
+type Cache4 struct {
+	Ratio  int      // Ratio (%) between the size of the cache and of the node table, 0 if the size is constant
+	OpHit  int      // entries found in the cache
+	OpMiss int      // entries not found in the cache
+	Table  []Entry4 // Entries of the cache
+}
+
+// Init allocates the entries of the cache, with (at least) size entries.
+func (bc *Cache4) Init(size, ratio int) {
+	size = PrimeGte(size)
+	bc.Table = make([]Entry4, size)
+	bc.Ratio = ratio
+	bc.Reset()
+}
+
+// Resize updates the size of the cache when the node table has size nodes, if
+// there is a ratio, and clears all the entries.
+func (bc *Cache4) Resize(size int) {
+	if bc.Ratio > 0 {
+		size = PrimeGte((size * bc.Ratio) / 100)
+		bc.Table = make([]Entry4, size)
+	}
+	bc.Reset()
+}
+
+// Reset clears all the entries of the cache.
+func (bc *Cache4) Reset() {
+	for k := range bc.Table {
+		bc.Table[k].A = -1
+	}
+}
+
+// Entry3 is an entry in a Cache3, used to store the result of an operation
+// with one operand and a context, such as Replace. An entry is empty when A is
+// -1.
+type Entry3 struct {
+	Res int
+	A   int
+	C   int
+}
+
+// Cache3 is the same as Cache4 but with entries of type Entry3.
+type Cache3 struct {
+	Ratio  int      // Ratio (%) between the size of the cache and of the node table, 0 if the size is constant
+	OpHit  int      // entries found in the cache
+	OpMiss int      // entries not found in the cache
+	Table  []Entry3 // Entries of the cache
+}
+
+// Init allocates the entries of the cache, with (at least) size entries.
+func (bc *Cache3) Init(size, ratio int) {
+	size = PrimeGte(size)
+	bc.Table = make([]Entry3, size)
+	bc.Ratio = ratio
+	bc.Reset()
+}
+
+// Resize updates the size of the cache when the node table has size nodes, if
+// there is a ratio, and clears all the entries.
+func (bc *Cache3) Resize(size int) {
+	if bc.Ratio > 0 {
+		size = PrimeGte((size * bc.Ratio) / 100)
+		bc.Table = make([]Entry3, size)
+	}
+	bc.Reset()
+}
+
+// Reset clears all the entries of the cache.
+func (bc *Cache3) Reset() {
+	for k := range bc.Table {
+		bc.Table[k].A = -1
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package dd
+
+// Triple maps a triplet of integers (a, b, c) into a value in the interval
+// [0..len), using Pair twice.
+func Triple(a, b, c, len int) int {
+	return int(Pair(c, Pair(a, b, len), len))
+}
+
+// Pair is a mapping function that maps (bijectively) a pair of integer (a, b)
+// into a unique integer then cast it into a value in the interval [0..len)
+// using a modulo operation.
+func Pair(a, b, len int) int {
+	ua := uint64(a)
+	ub := uint64(b)
+	return int(((((ua + ub) * (ua + ub + 1)) / 2) + (ua)) % uint64(len))
+}
@@ -0,0 +1,391 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package dd
+
+import (
+	"log"
+	"math"
+)
+
+// triplet is the key of a node in the unique table of a MapTable.
+type triplet struct {
+	level int32
+	low   int
+	high  int
+}
+
+// MapTable is a table of nodes, like Table, where the unique table is based on
+// the runtime hashmap, which associates each triplet (level, low, high) with an
+// entry in the table of nodes. We use more space but a benefit is that we can
+// easily migrate to a concurrency-safe hashmap if we want to test concurrent
+// data structures. The Next field of a node is only used to link free nodes,
+// and the size of the table is not restricted to prime numbers.
+//
+// Nodes are never moved. Unused nodes are reclaimed by a mark and sweep
+// garbage collector, where the roots are the nodes with a positive reference
+// count and the ones in the refstack given by the caller.
+type MapTable struct {
+	Nodes        []Node // List of all the nodes. Constants are always kept at index 0 and 1
+	Freenum      int    // Number of free nodes
+	Produced     int    // Total number of new nodes ever produced
+	UniqueAccess int    // accesses to the unique node table
+	UniqueHit    int    // entries actually found in the the unique node table
+	UniqueMiss   int    // entries not found in the the unique node table
+
+	Minfreenodes    int    // Minimum number of nodes (%) that should be left after GC before triggering a resize
+	Maxnodesize     int    // Maximum total number of nodes (0 if no limit)
+	Maxnodeincrease int    // Maximum number of nodes that can be added at each resize (0 if no limit)
+	BeforeGC        func() // Called at the start of each garbage collection, if not nil
+	AfterGC         func() // Called at the end of each garbage collection, if not nil
+
+	unique  map[triplet]int // Unicity table, used to associate each triplet to a single node
+	freepos int             // First free node
+	shared  bool            // Nodes and unique belong to another table, see Share
+}
+
+// Init allocates the nodes of the table, with nodesize nodes, and builds the
+// two constants, with ids 0 and 1, at level level. The constants are not added
+// to the unique table.
+func (t *MapTable) Init(nodesize int, level int32) {
+	t.Nodes = make([]Node, nodesize)
+	for k := range t.Nodes {
+		t.Nodes[k] = Node{Low: -1, Next: k + 1}
+	}
+	t.Nodes[nodesize-1].Next = 0
+	t.unique = make(map[triplet]int, nodesize)
+	t.Nodes[0] = Node{Refcou: MaxRefcount, Level: level, Low: 0, High: 0}
+	t.Nodes[1] = Node{Refcou: MaxRefcount, Level: level, Low: 1, High: 1}
+	t.freepos = 2
+	t.Freenum = nodesize - 2
+}
+
+// Share makes t use the nodes and the unique table of src without copying
+// them, like Table.Share. The arrays are copied by Own before the first change
+// to t, so src must not be modified while it is shared.
+func (t *MapTable) Share(src *MapTable) {
+	t.Nodes = src.Nodes[:len(src.Nodes):len(src.Nodes)]
+	t.unique = src.unique
+	t.freepos = src.freepos
+	t.Freenum = src.Freenum
+	t.shared = true
+}
+
+// Own copies the nodes and the unique table of t, if they are shared with
+// another table, so that they can be changed. It should be called before
+// changing a node directly in Nodes.
+func (t *MapTable) Own() {
+	if !t.shared {
+		return
+	}
+	nodes := make([]Node, len(t.Nodes))
+	copy(nodes, t.Nodes)
+	t.Nodes = nodes
+	unique := make(map[triplet]int, len(t.unique))
+	for k, v := range t.unique {
+		unique[k] = v
+	}
+	t.unique = unique
+	t.shared = false
+}
+
+// Shared reports whether t still shares its nodes with another table, see
+// Share.
+func (t *MapTable) Shared() bool {
+	return t.shared
+}
+
+// CopyFrom replaces the nodes and the unique table of t with a copy of the ones
+// of src, like Share followed by Own.
+func (t *MapTable) CopyFrom(src *MapTable) {
+	t.Share(src)
+	t.Own()
+}
+
+// IsMarked reports whether node n is marked.
+func (t *MapTable) IsMarked(n int) bool {
+	return (t.Nodes[n].Level & 0x200000) != 0
+}
+
+// Mark sets the mark of node n.
+func (t *MapTable) Mark(n int) {
+	t.Own()
+	t.Nodes[n].Level = t.Nodes[n].Level | 0x200000
+}
+
+// Unmark clears the mark of node n.
+func (t *MapTable) Unmark(n int) {
+	t.Own()
+	t.Nodes[n].Level = t.Nodes[n].Level & MaxLevel
+}
+
+// Lookup returns the ids of the nodes associated with a triplet in the unique
+// table, used to check that there is at most one of them.
+func (t *MapTable) Lookup(level int32, low, high int) []int {
+	if res, ok := t.unique[triplet{level, low, high}]; ok {
+		return []int{res}
+	}
+	return nil
+}
+
+// Makenode returns the id of the node (level, low, high), or of low if the two
+// successors are equal, creating a new node if needed. It has the same
+// behavior, and returns the same errors, than Table.Makenode.
+func (t *MapTable) Makenode(level int32, low, high int, refstack []int) (int, error) {
+	if _DEBUG {
+		t.UniqueAccess++
+	}
+	// check whether children are equal, in which case we can skip the node
+	if low == high {
+		return low, nil
+	}
+	// otherwise try to find an existing node using the unique table
+	if res, ok := t.unique[triplet{level, low, high}]; ok {
+		if _DEBUG {
+			t.UniqueHit++
+		}
+		return res, nil
+	}
+	if _DEBUG {
+		t.UniqueMiss++
+	}
+	// If no existing node, we build one. If there is no available spot
+	// (t.freepos == 0), we try garbage collection and, as a last resort,
+	// resizing the node table.
+	var err error
+	if t.freepos == 0 {
+		// We garbage collect unused nodes to try and find spare space.
+		t.Gbc(refstack)
+		err = ErrReset
+		// We also test if we are under the threshold for resising.
+		if (t.Freenum*100)/len(t.Nodes) <= t.Minfreenodes {
+			err = t.noderesize()
+			if err != ErrResize {
+				return -1, ErrMemory
+			}
+		}
+		// Fail if we still have no free positions after all this
+		if t.freepos == 0 {
+			return -1, ErrMemory
+		}
+	}
+	// We can now build the new node in the first available spot
+	return t.insert(level, low, high), err
+}
+
+// insert builds node (level, low, high) in the first free spot and adds it to
+// the unique table. There must be a free node.
+func (t *MapTable) insert(level int32, low, high int) int {
+	t.Own()
+	res := t.freepos
+	t.freepos = t.Nodes[res].Next
+	t.Freenum--
+	t.Produced++
+	t.Nodes[res] = Node{Level: level, Low: low, High: high}
+	t.unique[triplet{level, low, high}] = res
+	return res
+}
+
+func (t *MapTable) noderesize() error {
+	if _LOGLEVEL > 0 {
+		log.Printf("start resize: %d\n", len(t.Nodes))
+	}
+	oldsize := len(t.Nodes)
+	nodesize := len(t.Nodes)
+	if (oldsize >= t.Maxnodesize) && (t.Maxnodesize > 0) {
+		return ErrMemory
+	}
+	if oldsize > (math.MaxInt32 >> 1) {
+		nodesize = math.MaxInt32 - 1
+	} else {
+		nodesize = nodesize << 1
+	}
+	if t.Maxnodeincrease > 0 && nodesize > (oldsize+t.Maxnodeincrease) {
+		nodesize = oldsize + t.Maxnodeincrease
+	}
+	if (nodesize > t.Maxnodesize) && (t.Maxnodesize > 0) {
+		nodesize = t.Maxnodesize
+	}
+	if nodesize <= oldsize {
+		return ErrMemory
+	}
+	return t.Growto(nodesize)
+}
+
+// Growto extends the node table to nodesize nodes, that should be greater than
+// its current size, and adds the new nodes to the list of free nodes. It
+// returns ErrResize.
+func (t *MapTable) Growto(nodesize int) error {
+	t.Own()
+	oldsize := len(t.Nodes)
+	tmp := t.Nodes
+	t.Nodes = make([]Node, nodesize)
+	copy(t.Nodes, tmp)
+	for n := oldsize; n < nodesize; n++ {
+		t.Nodes[n] = Node{Low: -1, Next: n + 1}
+	}
+	t.Nodes[nodesize-1].Next = t.freepos
+	t.freepos = oldsize
+	t.Freenum += (nodesize - oldsize)
+	if _LOGLEVEL > 0 {
+		log.Printf("end resize: %d\n", len(t.Nodes))
+	}
+	return ErrResize
+}
+
+// Reserve makes sure that there are at least n free nodes in the table, using
+// garbage collection and, if this is not enough, a single resize of the node
+// table. We return the same errors than Makenode.
+func (t *MapTable) Reserve(n int, refstack []int) error {
+	if t.Freenum >= n {
+		return nil
+	}
+	t.Gbc(refstack)
+	if (t.Freenum >= n) && ((t.Freenum-n)*100)/len(t.Nodes) > t.Minfreenodes {
+		return ErrReset
+	}
+	// we keep the ratio of free nodes above Minfreenodes after the insertion
+	nodesize := len(t.Nodes) - t.Freenum + n
+	if t.Minfreenodes < 100 {
+		nodesize = (nodesize * 100) / (100 - t.Minfreenodes)
+	}
+	if nodesize > math.MaxInt32-1 {
+		nodesize = math.MaxInt32 - 1
+	}
+	if (nodesize > t.Maxnodesize) && (t.Maxnodesize > 0) {
+		nodesize = t.Maxnodesize
+	}
+	if nodesize > len(t.Nodes) {
+		t.Growto(nodesize)
+		if t.Freenum >= n {
+			return ErrResize
+		}
+	}
+	if t.Freenum >= n {
+		return ErrReset
+	}
+	return ErrMemory
+}
+
+// Bulkmake inserts a sequence of nodes in the table, with the same format
+// than Table.Bulkmake. We reserve space for all the nodes before starting, so
+// there is no garbage collection during the insertion and no need to protect
+// intermediate results.
+func (t *MapTable) Bulkmake(ids []int, nodes [][3]int, refstack []int) ([]int, error) {
+	err := t.Reserve(len(nodes), refstack)
+	if err == ErrMemory {
+		return nil, err
+	}
+	for _, v := range nodes {
+		level, low, high := int32(v[0]), ids[v[1]], ids[v[2]]
+		if low == high {
+			ids = append(ids, low)
+			continue
+		}
+		if res, ok := t.unique[triplet{level, low, high}]; ok {
+			ids = append(ids, res)
+			continue
+		}
+		ids = append(ids, t.insert(level, low, high))
+	}
+	return ids, err
+}
+
+// Unhash removes node n from the unique table, for instance before changing
+// its level or successors in place with Rehash.
+func (t *MapTable) Unhash(n int) {
+	t.Own()
+	delete(t.unique, triplet{t.Nodes[n].Level & MaxLevel, t.Nodes[n].Low, t.Nodes[n].High})
+}
+
+// Rehash sets the level and successors of node n, that must have been removed
+// from the unique table with Unhash, and adds it back to the unique table.
+func (t *MapTable) Rehash(n int, level int32, low, high int) {
+	t.Own()
+	t.Nodes[n].Level = level
+	t.Nodes[n].Low = low
+	t.Nodes[n].High = high
+	t.unique[triplet{level, low, high}] = n
+}
+
+// Free removes node n from the unique table and adds it to the list of free
+// nodes, without waiting for the next garbage collection. The caller must
+// make sure that n is not referenced anymore.
+func (t *MapTable) Free(n int) {
+	t.Unhash(n)
+	t.Nodes[n] = Node{Low: -1, Next: t.freepos}
+	t.freepos = n
+	t.Freenum++
+}
+
+// Gbc is the garbage collector called for reclaiming memory, inside a call to
+// Makenode, when there are no free positions available. Allocated nodes that
+// are not reclaimed do not move.
+func (t *MapTable) Gbc(refstack []int) {
+	t.Own()
+	if _LOGLEVEL > 0 {
+		log.Println("starting GC")
+	}
+	if t.BeforeGC != nil {
+		t.BeforeGC()
+	}
+	// we mark the nodes in the refstack to avoid collecting them
+	for _, r := range refstack {
+		t.Markrec(r)
+	}
+	// we also protect nodes with a positive refcount (and therefore also the
+	// ones with a MaxRefcount, such has variables)
+	for k := range t.Nodes {
+		if t.Nodes[k].Refcou > 0 {
+			t.Markrec(k)
+		}
+	}
+	t.freepos = 0
+	t.Freenum = 0
+	// we do a pass through the nodes list to void the unmarked nodes. After
+	// finishing this pass, t.freepos points to the first free position in
+	// t.Nodes, or it is 0 if we found none.
+	for n := len(t.Nodes) - 1; n > 1; n-- {
+		if t.IsMarked(n) && (t.Nodes[n].Low != -1) {
+			t.Unmark(n)
+			continue
+		}
+		if t.Nodes[n].Low != -1 {
+			t.Unhash(n)
+		}
+		t.Nodes[n].Low = -1
+		t.Nodes[n].Next = t.freepos
+		t.freepos = n
+		t.Freenum++
+	}
+	if t.AfterGC != nil {
+		t.AfterGC()
+	}
+	if _LOGLEVEL > 0 {
+		log.Printf("end GC; freenum: %d\n", t.Freenum)
+	}
+}
+
+// Markrec marks all the nodes reachable from n, besides the constants.
+func (t *MapTable) Markrec(n int) {
+	if n < 2 || t.IsMarked(n) || (t.Nodes[n].Low == -1) {
+		return
+	}
+	t.Mark(n)
+	if t.Nodes[n].High == Leaf {
+		return
+	}
+	t.Markrec(t.Nodes[n].Low)
+	t.Markrec(t.Nodes[n].High)
+}
+
+// Unmarkall clears the mark of all the nodes.
+func (t *MapTable) Unmarkall() {
+	for k, v := range t.Nodes {
+		if k < 2 || !t.IsMarked(k) || (v.Low == -1) {
+			continue
+		}
+		t.Unmark(k)
+	}
+}
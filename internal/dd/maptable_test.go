@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package dd
+
+import (
+	"testing"
+)
+
+// TestMapTable builds chains of nodes in a small table, like TestTable, and
+// checks that nodes are unique, that protected nodes survive garbage
+// collections and that Bulkmake finds existing nodes.
+func TestMapTable(t *testing.T) {
+	tbl := &MapTable{Minfreenodes: 20, Maxnodeincrease: 1 << 20}
+	tbl.Init(10, 16)
+	gcs := 0
+	tbl.BeforeGC = func() { gcs++ }
+	var refstack []int
+	for round := 0; round < 20; round++ {
+		n := 1
+		for level := int32(15); level >= 0; level-- {
+			var err error
+			n, err = tbl.Makenode(level, 0, n, append(refstack, n))
+			if err == ErrMemory {
+				t.Fatalf("unexpected error %s", err)
+			}
+		}
+		if round%2 == 0 {
+			refstack = append(refstack, n)
+		}
+	}
+	if gcs == 0 {
+		t.Errorf("expected at least one garbage collection")
+	}
+	for _, r := range refstack {
+		if r != refstack[0] {
+			t.Errorf("expected a single node for identical chains, found %d and %d", refstack[0], r)
+		}
+	}
+	for n := refstack[0]; n > 1; n = tbl.Nodes[n].High {
+		v := tbl.Nodes[n]
+		if ids := tbl.Lookup(v.Level, v.Low, v.High); len(ids) != 1 || ids[0] != n {
+			t.Errorf("lookup of node %d returns %v", n, ids)
+		}
+	}
+	ids, err := tbl.Bulkmake([]int{0, 1}, [][3]int{{15, 0, 1}, {14, 0, 2}, {13, 3, 3}}, refstack)
+	if err == ErrMemory {
+		t.Fatal(err)
+	}
+	if ids[4] != ids[3] {
+		t.Errorf("a node with equal successors should be its successor")
+	}
+	if n, _ := tbl.Makenode(14, 0, ids[2], nil); n != ids[3] {
+		t.Errorf("Makenode should find the node built by Bulkmake")
+	}
+	n, _ := tbl.Makenode(3, 1, 0, refstack)
+	free := tbl.Freenum
+	tbl.Free(n)
+	if tbl.Freenum != free+1 || tbl.Lookup(3, 1, 0) != nil {
+		t.Errorf("Free should remove the node from the unique table")
+	}
+}
+
+func TestMapTableCopyFrom(t *testing.T) {
+	src := &MapTable{Minfreenodes: 20}
+	src.Init(10, 4)
+	n, _ := src.Makenode(3, 0, 1, nil)
+	dst := &MapTable{Minfreenodes: 20}
+	dst.Init(4, 4)
+	dst.CopyFrom(src)
+	if m, _ := dst.Makenode(3, 0, 1, nil); m != n || dst.Produced != 0 {
+		t.Errorf("the copy should find the nodes of the original, expected %d, actual %d", n, m)
+	}
+	dst.Gbc(nil)
+	if src.Nodes[n].Low == -1 || dst.Nodes[n].Low != -1 {
+		t.Errorf("the copy and the original should be independent")
+	}
+}
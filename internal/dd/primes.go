@@ -2,7 +2,7 @@
 //
 // MIT License
 
-package rudd
+package dd
 
 import "math/big"
 
@@ -19,7 +19,8 @@ func hasEasyFactors(src int) bool {
 	return hasFactor(src, 3) || hasFactor(src, 5) || hasFactor(src, 7) || hasFactor(src, 11) || hasFactor(src, 13)
 }
 
-func primeGte(src int) int {
+// PrimeGte returns the smallest prime number greater or equal to src.
+func PrimeGte(src int) int {
 	if src%2 == 0 {
 		src++
 	}
@@ -37,7 +38,9 @@ func primeGte(src int) int {
 	}
 }
 
-func primeLte(src int) int {
+// PrimeLte returns the largest prime number lower or equal to src, or 1 if src
+// is 0.
+func PrimeLte(src int) int {
 	if src == 0 {
 		return 1
 	}
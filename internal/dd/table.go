@@ -0,0 +1,659 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+/*
+Package dd provides the memory management machinery shared by the different
+families of decision diagrams in package rudd: a table of nodes with a unique
+table, garbage collection and resizing, based on the data structures and
+algorithms found in the BuDDy library; together with operation caches and the
+hash functions they use.
+
+Type Table is used by ZDD and by the BDD and Diagram built with the build tag
+buddy. The default implementation (Hudd) uses MapTable, with the same
+interface, where the unique table is based on the runtime hashmap.
+
+A node is a triplet (level, low, high) where low and high are the ids (indices
+in the table) of other nodes, with a level strictly greater than the level of
+the node. The interpretation of levels and of the two constant nodes, with ids
+0 and 1, is left to the client.
+*/
+package dd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+)
+
+// MaxLevel is the maximal level of a node. We use only the first 21 bits for
+// encoding levels and the other bits for markings.
+const MaxLevel int32 = 0x1FFFFF
+
+// MaxRefcount is the maximal value of the reference counter of a node, also
+// used to stick nodes (like constants and variables) in the table.
+const MaxRefcount int32 = 0x3FF
+
+// splitsteps is the maximal number of buckets in the unique table that can be
+// split during a call to Makenode, when the table grows incrementally.
+const splitsteps int = 2
+
+// Errors returned by the functions that may add nodes to the table. Only
+// ErrMemory is a failure; the two others tell the caller that nodes have been
+// reclaimed (and possibly that the table was resized), which means that
+// caches must be invalidated.
+var (
+	ErrMemory = errors.New("unable to free memory or resize BDD")
+	ErrResize = errors.New("should cache resize") // when gbc and then noderesize
+	ErrReset  = errors.New("should cache reset")  // when gbc only, without resizing
+)
+
+// Leaf is the value of the High field of a leaf, that is a node without
+// successors whose Low field holds a (non-negative) value chosen by the client,
+// such as the terminals of a multi-terminal diagram. Leaves are built with
+// Makenode, like other nodes, so the client should use a different value for
+// each leaf at the same level.
+const Leaf = -2
+
+// Node is an entry in the node table.
+type Node struct {
+	Refcou int32 // Count the number of external references
+	Level  int32 // Level of the node
+	Low    int   // Reference to the false branch, -1 if the node is free
+	High   int   // Reference to the true branch
+	Next   int   // Next index to check in case of a collision, 0 if last; or next free node
+}
+
+// Table is a table of nodes together with a unique table, that associates each
+// triplet (level, low, high) with at most one node. We use linear hashing to
+// map a hash value to a bucket of the unique table, so that we never have to
+// rehash all the nodes when the node table grows. Instead, the number of
+// buckets grows one at a time, by splitting the chain of one bucket into two;
+// meaning that we only rehash the nodes in this chain.
+//
+// Nodes are never moved. Unused nodes are reclaimed by a mark and sweep
+// garbage collector, where the roots are the nodes with a positive reference
+// count and the ones in the refstack given by the caller.
+type Table struct {
+	Nodes          []Node // List of all the nodes. Constants are always kept at index 0 and 1
+	Freenum        int    // Number of free nodes
+	Produced       int    // Total number of new nodes ever produced
+	UniqueAccess   int    // accesses to the unique node table
+	UniqueChain    int    // iterations through the cache chains in the unique node table
+	UniqueMaxChain int    // length of the longest chain followed in the unique node table
+	UniqueHit      int    // entries actually found in the the unique node table
+	UniqueMiss     int    // entries not found in the the unique node table
+
+	Minfreenodes    int                                     // Minimum number of nodes (%) that should be left after GC before triggering a resize
+	Maxnodesize     int                                     // Maximum total number of nodes (0 if no limit)
+	Maxnodeincrease int                                     // Maximum number of nodes that can be added at each resize (0 if no limit)
+	Maxchain        int                                     // Maximal length of a chain before triggering a GC (0 if no limit)
+	StopTheWorld    bool                                    // Split all the buckets of the unique table in one go when resizing
+	ZeroSuppressed  bool                                    // Use the reduction rule of ZDD, where nodes with a high successor equal to 0 are removed
+	Complemented    bool                                    // Successors are edges that can be complemented, see Makenode
+	Hash            func(level int32, low, high int) uint64 // Hash function for the unique table
+	BeforeGC        func()                                  // Called at the start of each garbage collection, if not nil
+	AfterGC         func()                                  // Called at the end of each garbage collection, if not nil
+
+	buckets []int // Heads of the collision chains in the unique table, 0 if empty
+	hbase   int   // Initial number of buckets in the unique table
+	hround  uint  // Current round of linear hashing; there are at least hbase << hround buckets
+	hsplit  int   // Next bucket to be split during this round
+	freepos int   // First free node
+	chaingc int   // value of Produced before which we cannot trigger a GC because of a long chain
+	shared  bool  // Nodes and buckets belong to another table, see Share
+}
+
+// Init allocates the nodes of the table, with (at least) nodesize nodes, and
+// builds the two constants, with ids 0 and 1, at level level. The
+// configuration fields of t, and notably Hash, should be set before calling
+// Init.
+func (t *Table) Init(nodesize int, level int32) {
+	nodesize = PrimeGte(nodesize)
+	t.Nodes = make([]Node, nodesize)
+	for k := range t.Nodes {
+		t.Nodes[k] = Node{
+			Refcou: 0,
+			Level:  0,
+			Low:    -1,
+			High:   0,
+			Next:   k + 1,
+		}
+	}
+	t.Nodes[nodesize-1].Next = 0
+	t.buckets = make([]int, nodesize)
+	t.hbase = nodesize
+	t.hround = 0
+	t.hsplit = 0
+	t.Nodes[0] = Node{Refcou: MaxRefcount, Level: level, Low: 0, High: 0}
+	t.Nodes[1] = Node{Refcou: MaxRefcount, Level: level, Low: 1, High: 1}
+	t.freepos = 2
+	t.Freenum = nodesize - 2
+}
+
+// Share makes t use the nodes and the unique table of src, that should use the
+// same hash function, without copying them. The arrays are copied in one
+// block, by Own, only before the first change to t, for instance when we add
+// a node or during a garbage collection, so src must not be modified while it
+// is shared. Looking up existing nodes does not change the table, meaning that
+// tables sharing the same arrays can be used concurrently as long as they only
+// look up nodes. The configuration fields, the hooks and the statistics of t
+// are unchanged.
+func (t *Table) Share(src *Table) {
+	t.Nodes = src.Nodes[:len(src.Nodes):len(src.Nodes)]
+	t.buckets = src.buckets[:len(src.buckets):len(src.buckets)]
+	t.hbase = src.hbase
+	t.hround = src.hround
+	t.hsplit = src.hsplit
+	t.freepos = src.freepos
+	t.Freenum = src.Freenum
+	t.shared = true
+}
+
+// Own copies the nodes and the unique table of t, if they are shared with
+// another table, so that they can be changed. It should be called before
+// changing a node directly in Nodes.
+func (t *Table) Own() {
+	if !t.shared {
+		return
+	}
+	nodes := make([]Node, len(t.Nodes))
+	copy(nodes, t.Nodes)
+	t.Nodes = nodes
+	buckets := make([]int, len(t.buckets))
+	copy(buckets, t.buckets)
+	t.buckets = buckets
+	t.shared = false
+}
+
+// Shared reports whether t still shares its nodes with another table, see
+// Share.
+func (t *Table) Shared() bool {
+	return t.shared
+}
+
+// CopyFrom replaces the nodes and the unique table of t with a copy of the ones
+// of src, like Share followed by Own.
+func (t *Table) CopyFrom(src *Table) {
+	t.Share(src)
+	t.Own()
+}
+
+// IsMarked reports whether node n is marked.
+func (t *Table) IsMarked(n int) bool {
+	return (t.Nodes[n].Level & 0x200000) != 0
+}
+
+// Mark sets the mark of node n.
+func (t *Table) Mark(n int) {
+	t.Own()
+	t.Nodes[n].Level = t.Nodes[n].Level | 0x200000
+}
+
+// Unmark clears the mark of node n.
+func (t *Table) Unmark(n int) {
+	t.Own()
+	t.Nodes[n].Level = t.Nodes[n].Level & MaxLevel
+}
+
+// Buckets returns the number of buckets in the unique table.
+func (t *Table) Buckets() int {
+	return len(t.buckets)
+}
+
+func (t *Table) bucket(h uint64) int {
+	size := uint64(t.hbase) << t.hround
+	addr := h % size
+	if addr < uint64(t.hsplit) {
+		addr = h % (size << 1)
+	}
+	return int(addr)
+}
+
+func (t *Table) ptrhash(n int) int {
+	return t.bucket(t.Hash(t.Nodes[n].Level, t.Nodes[n].Low, t.Nodes[n].High))
+}
+
+func (t *Table) nodehash(level int32, low, high int) int {
+	return t.bucket(t.Hash(level, low, high))
+}
+
+// Lookup returns the ids of the nodes associated with a triplet in the unique
+// table, used to check that there is at most one of them.
+func (t *Table) Lookup(level int32, low, high int) []int {
+	res := []int{}
+	for n := t.buckets[t.nodehash(level, low, high)]; n != 0; n = t.Nodes[n].Next {
+		if t.Nodes[n].Level == level && t.Nodes[n].Low == low && t.Nodes[n].High == high {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// Check verifies the invariants of the unique table: there are no more buckets
+// than nodes and every allocated node, besides the constants, can be found in
+// the chain of its bucket.
+func (t *Table) Check() error {
+	if len(t.buckets) > len(t.Nodes) {
+		return fmt.Errorf("too many buckets (%d) for %d nodes", len(t.buckets), len(t.Nodes))
+	}
+	for n := 2; n < len(t.Nodes); n++ {
+		if t.Nodes[n].Low == -1 {
+			continue
+		}
+		k := t.buckets[t.ptrhash(n)]
+		for k != 0 && k != n {
+			k = t.Nodes[k].Next
+		}
+		if k == 0 {
+			return fmt.Errorf("node %d not found in its bucket", n)
+		}
+	}
+	return nil
+}
+
+// split adds a new bucket to the unique table and redistributes the nodes in
+// the chain of bucket hsplit.
+func (t *Table) split() {
+	t.Own()
+	size := t.hbase << t.hround
+	old := t.hsplit
+	t.buckets = append(t.buckets, 0)
+	t.hsplit++
+	if t.hsplit == size {
+		t.hround++
+		t.hsplit = 0
+	}
+	n := t.buckets[old]
+	t.buckets[old] = 0
+	for n != 0 {
+		next := t.Nodes[n].Next
+		hash := t.ptrhash(n)
+		t.Nodes[n].Next = t.buckets[hash]
+		t.buckets[hash] = n
+		n = next
+	}
+}
+
+// Makenode returns the id of the node (level, low, high), or of low if the node
+// is redundant, creating a new node if needed. The nodes reachable
+// from the ids in refstack are protected in case of garbage collection. We
+// return -1 and ErrMemory if there is no room left for a new node; ErrReset if
+// a garbage collection occurred; and ErrResize if the table was also resized.
+//
+// When t.Complemented is set, the successors low and high, the ids in
+// refstack and the value returned by Makenode are edges: the id of a node
+// shifted left by one, with bit 0 set when the edge is complemented, meaning
+// that it denotes the negation of the node. The constant with id 0 is the
+// only terminal, and the constant with id 1 is not used. We only store nodes
+// whose high edge is not complemented, so that each function has a single
+// representation, and return a complemented edge when needed.
+func (t *Table) Makenode(level int32, low, high int, refstack []int) (int, error) {
+	if _DEBUG {
+		t.UniqueAccess++
+	}
+	// check whether the node is redundant
+	if t.redundant(low, high) {
+		return low, nil
+	}
+	comp := 0
+	if t.Complemented && high&1 != 0 {
+		low, high, comp = low^1, high^1, 1
+	}
+	// otherwise try to find an existing node using the hash and next fields
+	hash := t.nodehash(level, low, high)
+	res := t.buckets[hash]
+	chain := 0
+	for res != 0 {
+		if t.Nodes[res].Level == level && t.Nodes[res].Low == low && t.Nodes[res].High == high {
+			if _DEBUG {
+				t.UniqueHit++
+			}
+			return t.edge(res, comp), nil
+		}
+		res = t.Nodes[res].Next
+		chain++
+		if _DEBUG {
+			t.UniqueChain++
+		}
+	}
+	if chain > t.UniqueMaxChain {
+		t.UniqueMaxChain = chain
+	}
+	if _DEBUG {
+		t.UniqueMiss++
+	}
+	// If no existing node, we build one. If there is no available spot
+	// (t.freepos == 0), we try garbage collection and, as a last resort,
+	// resizing the node table. We also garbage collect if the chain is too
+	// long and we have produced enough nodes since the last time.
+	var err error
+	if (t.freepos == 0) || (t.Maxchain > 0 && chain > t.Maxchain && t.Produced >= t.chaingc) {
+		// We garbage collect unused nodes to try and find spare space.
+		t.Gbc(refstack)
+		t.chaingc = t.Produced + len(t.Nodes)/4
+		err = ErrReset
+		// We also test if we are under the threshold for resising.
+		if (t.Freenum*100)/len(t.Nodes) <= t.Minfreenodes {
+			err = t.noderesize()
+			if err != ErrResize {
+				if t.freepos != 0 && t.Maxchain > 0 && chain > t.Maxchain {
+					// we can still use some free nodes
+					err = ErrReset
+				} else {
+					return -1, ErrMemory
+				}
+			}
+			hash = t.nodehash(level, low, high)
+		}
+		// Fail if we still have no free positions after all this
+		if t.freepos == 0 {
+			return -1, ErrMemory
+		}
+	}
+	// We can now build the new node in the first available spot
+	res = t.insert(hash, level, low, high)
+	// we add new buckets to the unique table if there are less buckets than
+	// nodes, but only a bounded number of them at each call
+	for k := 0; (k < splitsteps) && (len(t.buckets) < len(t.Nodes)); k++ {
+		t.split()
+	}
+	return t.edge(res, comp), err
+}
+
+// edge returns the value returned by Makenode for node n, which is n itself
+// unless t uses complemented edges.
+func (t *Table) edge(n, comp int) int {
+	if !t.Complemented {
+		return n
+	}
+	return n<<1 | comp
+}
+
+// node returns the id of the node referenced by successor, or refstack entry,
+// e; see edge.
+func (t *Table) node(e int) int {
+	if !t.Complemented {
+		return e
+	}
+	return e >> 1
+}
+
+// redundant reports whether a node with successors low and high should be
+// replaced by low: when the two successors are equal, like in a BDD, or when
+// high is 0 if the table is zero-suppressed.
+func (t *Table) redundant(low, high int) bool {
+	if t.ZeroSuppressed {
+		return high == 0
+	}
+	return low == high
+}
+
+// insert builds node (level, low, high) in the first free spot and adds it to
+// the chain of bucket hash. There must be a free node.
+func (t *Table) insert(hash int, level int32, low, high int) int {
+	t.Own()
+	res := t.freepos
+	t.freepos = t.Nodes[res].Next
+	t.Freenum--
+	t.Produced++
+	t.Nodes[res].Level = level
+	t.Nodes[res].Low = low
+	t.Nodes[res].High = high
+	t.Nodes[res].Next = t.buckets[hash]
+	t.buckets[hash] = res
+	return res
+}
+
+func (t *Table) noderesize() error {
+	if _LOGLEVEL > 0 {
+		log.Printf("start resize: %d\n", len(t.Nodes))
+	}
+	oldsize := len(t.Nodes)
+	nodesize := len(t.Nodes)
+	if (oldsize >= t.Maxnodesize) && (t.Maxnodesize > 0) {
+		return ErrMemory
+	}
+	if oldsize > (math.MaxInt32 >> 1) {
+		nodesize = math.MaxInt32 - 1
+	} else {
+		nodesize = nodesize << 1
+	}
+	if t.Maxnodeincrease > 0 && nodesize > (oldsize+t.Maxnodeincrease) {
+		nodesize = oldsize + t.Maxnodeincrease
+	}
+	if (nodesize > t.Maxnodesize) && (t.Maxnodesize > 0) {
+		nodesize = t.Maxnodesize
+	}
+	nodesize = PrimeLte(nodesize)
+	if nodesize <= oldsize {
+		return ErrMemory
+	}
+
+	return t.Growto(nodesize)
+}
+
+// Growto extends the node table to nodesize nodes, that should be greater than
+// its current size, and adds the new nodes to the list of free nodes. It
+// returns ErrResize.
+func (t *Table) Growto(nodesize int) error {
+	t.Own()
+	oldsize := len(t.Nodes)
+	tmp := t.Nodes
+	t.Nodes = make([]Node, nodesize)
+	copy(t.Nodes, tmp)
+
+	// We only need to add the new nodes to the list of free nodes, since the
+	// unique table does not depend on the size of the node table. Buckets are
+	// added to the unique table in subsequent calls to Makenode.
+	for n := oldsize; n < nodesize; n++ {
+		t.Nodes[n].Refcou = 0
+		t.Nodes[n].Level = 0
+		t.Nodes[n].Low = -1
+		t.Nodes[n].Next = n + 1
+	}
+	t.Nodes[nodesize-1].Next = t.freepos
+	t.freepos = oldsize
+	t.Freenum += (nodesize - oldsize)
+
+	// With a stop-the-world strategy, we split all the buckets in one go.
+	if t.StopTheWorld {
+		for len(t.buckets) < nodesize {
+			t.split()
+		}
+	}
+
+	if _LOGLEVEL > 0 {
+		log.Printf("end resize: %d\n", len(t.Nodes))
+	}
+	return ErrResize
+}
+
+// Reserve makes sure that there are at least n free nodes in the table, using
+// garbage collection and, if this is not enough, a single resize of the node
+// table. We return the same errors than Makenode.
+func (t *Table) Reserve(n int, refstack []int) error {
+	if t.Freenum >= n {
+		return nil
+	}
+	t.Gbc(refstack)
+	if (t.Freenum >= n) && ((t.Freenum-n)*100)/len(t.Nodes) > t.Minfreenodes {
+		return ErrReset
+	}
+	// we keep the ratio of free nodes above Minfreenodes after the insertion
+	nodesize := len(t.Nodes) - t.Freenum + n
+	if t.Minfreenodes < 100 {
+		nodesize = (nodesize * 100) / (100 - t.Minfreenodes)
+	}
+	if nodesize > math.MaxInt32-1 {
+		nodesize = math.MaxInt32 - 1
+	}
+	if (nodesize > t.Maxnodesize) && (t.Maxnodesize > 0) {
+		nodesize = t.Maxnodesize
+	}
+	if nodesize > len(t.Nodes) {
+		t.Growto(nodesize)
+		if t.Freenum >= n {
+			return ErrResize
+		}
+	}
+	if t.Freenum >= n {
+		return ErrReset
+	}
+	return ErrMemory
+}
+
+// Bulkmake inserts a sequence of nodes in the table. Each element of nodes is a
+// triplet (level, low, high), where low and high are indices in ids, the list
+// of ids of the nodes inserted so far (with the constants at index 0 and 1).
+// Hence nodes must be listed children before parents. We reserve space for all
+// the nodes, and split all the buckets of the unique table, before starting;
+// so there is no garbage collection during the insertion and no need to
+// protect intermediate results. We return ids extended with the id of each
+// element of nodes. Bulkmake cannot be used with complemented edges.
+func (t *Table) Bulkmake(ids []int, nodes [][3]int, refstack []int) ([]int, error) {
+	err := t.Reserve(len(nodes), refstack)
+	if err == ErrMemory {
+		return nil, err
+	}
+	for len(t.buckets) < len(t.Nodes) {
+		t.split()
+	}
+	for _, v := range nodes {
+		level, low, high := int32(v[0]), ids[v[1]], ids[v[2]]
+		if t.redundant(low, high) {
+			ids = append(ids, low)
+			continue
+		}
+		hash := t.nodehash(level, low, high)
+		res := t.buckets[hash]
+		for res != 0 {
+			if t.Nodes[res].Level == level && t.Nodes[res].Low == low && t.Nodes[res].High == high {
+				break
+			}
+			res = t.Nodes[res].Next
+		}
+		if res == 0 {
+			res = t.insert(hash, level, low, high)
+		}
+		ids = append(ids, res)
+	}
+	return ids, err
+}
+
+// Unhash removes node n from the chain of its bucket in the unique table, for
+// instance before changing its level or successors in place with Rehash.
+func (t *Table) Unhash(n int) {
+	t.Own()
+	hash := t.ptrhash(n)
+	if t.buckets[hash] == n {
+		t.buckets[hash] = t.Nodes[n].Next
+		return
+	}
+	for k := t.buckets[hash]; k != 0; k = t.Nodes[k].Next {
+		if t.Nodes[k].Next == n {
+			t.Nodes[k].Next = t.Nodes[n].Next
+			return
+		}
+	}
+}
+
+// Rehash sets the level and successors of node n, that must have been removed
+// from the unique table with Unhash, and adds it back to the unique table.
+// There should be no other node with the same triplet.
+func (t *Table) Rehash(n int, level int32, low, high int) {
+	t.Own()
+	t.Nodes[n].Level = level
+	t.Nodes[n].Low = low
+	t.Nodes[n].High = high
+	hash := t.nodehash(level, low, high)
+	t.Nodes[n].Next = t.buckets[hash]
+	t.buckets[hash] = n
+}
+
+// Free removes node n from the unique table and adds it to the list of free
+// nodes, without waiting for the next garbage collection. The caller must
+// make sure that n is not referenced anymore.
+func (t *Table) Free(n int) {
+	t.Unhash(n)
+	t.Nodes[n].Refcou = 0
+	t.Nodes[n].Low = -1
+	t.Nodes[n].Next = t.freepos
+	t.freepos = n
+	t.Freenum++
+}
+
+// Gbc is the garbage collector called for reclaiming memory, inside a call to
+// Makenode, when there are no free positions available. Allocated nodes that
+// are not reclaimed do not move.
+func (t *Table) Gbc(refstack []int) {
+	t.Own()
+	if _LOGLEVEL > 0 {
+		log.Println("starting GC")
+	}
+	if t.BeforeGC != nil {
+		t.BeforeGC()
+	}
+	// we mark the nodes in the refstack to avoid collecting them
+	for _, r := range refstack {
+		t.Markrec(t.node(r))
+	}
+	// we also protect nodes with a positive refcount (and therefore also the
+	// ones with a MaxRefcount, such has variables)
+	for k := range t.Nodes {
+		if t.Nodes[k].Refcou > 0 {
+			t.Markrec(k)
+		}
+	}
+	for k := range t.buckets {
+		t.buckets[k] = 0
+	}
+	t.freepos = 0
+	t.Freenum = 0
+	// we do a pass through the nodes list to update the hash chains and void
+	// the unmarked nodes. After finishing this pass, t.freepos points to the
+	// first free position in t.Nodes, or it is 0 if we found none.
+	for n := len(t.Nodes) - 1; n > 1; n-- {
+		if t.IsMarked(n) && (t.Nodes[n].Low != -1) {
+			t.Unmark(n)
+			hash := t.ptrhash(n)
+			t.Nodes[n].Next = t.buckets[hash]
+			t.buckets[hash] = n
+		} else {
+			t.Nodes[n].Low = -1
+			t.Nodes[n].Next = t.freepos
+			t.freepos = n
+			t.Freenum++
+		}
+	}
+	if t.AfterGC != nil {
+		t.AfterGC()
+	}
+	if _LOGLEVEL > 0 {
+		log.Printf("end GC; freenum: %d\n", t.Freenum)
+	}
+}
+
+// Markrec marks all the nodes reachable from n, besides the constants.
+func (t *Table) Markrec(n int) {
+	if n < 2 || t.IsMarked(n) || (t.Nodes[n].Low == -1) {
+		return
+	}
+	t.Mark(n)
+	if t.Nodes[n].High == Leaf {
+		return
+	}
+	t.Markrec(t.node(t.Nodes[n].Low))
+	t.Markrec(t.node(t.Nodes[n].High))
+}
+
+// Unmarkall clears the mark of all the nodes.
+func (t *Table) Unmarkall() {
+	for k, v := range t.Nodes {
+		if k < 2 || !t.IsMarked(k) || (v.Low == -1) {
+			continue
+		}
+		t.Unmark(k)
+	}
+}
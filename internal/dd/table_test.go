@@ -0,0 +1,197 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package dd
+
+import (
+	"testing"
+)
+
+func newTable(nodesize int) *Table {
+	t := &Table{
+		Minfreenodes:    20,
+		Maxnodeincrease: 1 << 20,
+		Hash: func(level int32, low, high int) uint64 {
+			return uint64(level)*12582917 + uint64(low)*4256249 + uint64(high)*741457
+		},
+	}
+	t.Init(nodesize, 16)
+	return t
+}
+
+// TestTable builds chains of nodes in a small table, so that we trigger both
+// garbage collections and resizes, and checks that nodes are unique and that
+// protected nodes survive.
+func TestTable(t *testing.T) {
+	tbl := newTable(10)
+	gcs := 0
+	tbl.BeforeGC = func() { gcs++ }
+	var refstack []int
+	for round := 0; round < 20; round++ {
+		n := 1
+		for level := int32(15); level >= 0; level-- {
+			var err error
+			n, err = tbl.Makenode(level, 0, n, append(refstack, n))
+			if err == ErrMemory {
+				t.Fatalf("unexpected error %s", err)
+			}
+		}
+		if round%2 == 0 {
+			refstack = append(refstack, n)
+		}
+	}
+	if gcs == 0 {
+		t.Errorf("expected at least one garbage collection")
+	}
+	if err := tbl.Check(); err != nil {
+		t.Fatal(err)
+	}
+	// the nodes of the protected chains are still there and unique
+	for _, r := range refstack {
+		if r != refstack[0] {
+			t.Errorf("expected a single node for identical chains, found %d and %d", refstack[0], r)
+		}
+	}
+	for n := refstack[0]; n > 1; n = tbl.Nodes[n].High {
+		v := tbl.Nodes[n]
+		if ids := tbl.Lookup(v.Level, v.Low, v.High); len(ids) != 1 || ids[0] != n {
+			t.Errorf("lookup of node %d returns %v", n, ids)
+		}
+	}
+}
+
+func TestBulkmake(t *testing.T) {
+	tbl := newTable(10)
+	ids, err := tbl.Bulkmake([]int{0, 1}, [][3]int{{3, 0, 1}, {2, 1, 0}, {1, 2, 3}, {0, 4, 4}}, nil)
+	if err == ErrMemory {
+		t.Fatal(err)
+	}
+	if ids[5] != ids[4] {
+		t.Errorf("a node with equal successors should be its successor")
+	}
+	if n, _ := tbl.Makenode(1, ids[2], ids[3], nil); n != ids[4] {
+		t.Errorf("Makenode should find the node built by Bulkmake")
+	}
+	if err := tbl.Check(); err != nil {
+		t.Fatal(err)
+	}
+	tbl.Maxnodesize = len(tbl.Nodes)
+	if _, err := tbl.Bulkmake([]int{0, 1}, make([][3]int, len(tbl.Nodes)), []int{ids[4]}); err != ErrMemory {
+		t.Errorf("Bulkmake should fail when the table cannot grow, actual %v", err)
+	}
+}
+
+func TestZeroSuppressed(t *testing.T) {
+	tbl := newTable(10)
+	tbl.ZeroSuppressed = true
+	if n, _ := tbl.Makenode(3, 1, 0, nil); n != 1 {
+		t.Errorf("a node with a high successor equal to 0 should be its low successor")
+	}
+	n, _ := tbl.Makenode(3, 1, 1, nil)
+	if n < 2 {
+		t.Errorf("a node with equal successors should be kept in a zero-suppressed table")
+	}
+	ids, _ := tbl.Bulkmake([]int{0, 1}, [][3]int{{3, 1, 1}, {2, 2, 0}}, nil)
+	if ids[2] != n || ids[3] != n {
+		t.Errorf("Bulkmake should use the same reduction rule than Makenode, actual %v", ids)
+	}
+}
+
+// TestMaxnodesize checks that, without a limit on the length of chains, we
+// report an error when the table cannot grow and a garbage collection leaves
+// too few free nodes, like in BuDDy.
+func TestMaxnodesize(t *testing.T) {
+	tbl := newTable(10)
+	tbl.Maxnodesize = len(tbl.Nodes)
+	var refstack []int
+	for level := int32(0); tbl.Freenum > 0; level++ {
+		n, err := tbl.Makenode(level, 0, 1, refstack)
+		if err == ErrMemory {
+			t.Fatalf("unexpected error %s", err)
+		}
+		refstack = append(refstack, n)
+	}
+	if _, err := tbl.Makenode(0, 1, 0, refstack[1:]); err != ErrMemory {
+		t.Errorf("expected ErrMemory with a single free node, actual %v", err)
+	}
+}
+
+// TestComplemented checks the normalization of nodes with complemented edges,
+// and that garbage collection follows edges.
+func TestComplemented(t *testing.T) {
+	tbl := newTable(10)
+	tbl.Complemented = true
+	x, _ := tbl.Makenode(3, 1, 0, nil)
+	if x&1 != 0 {
+		t.Errorf("a node with a regular high edge should be returned as a regular edge, actual %d", x)
+	}
+	if nx, _ := tbl.Makenode(3, 0, 1, nil); nx != x^1 {
+		t.Errorf("the negation of a node should be its complemented edge, expected %d, actual %d", x^1, nx)
+	}
+	if n, _ := tbl.Makenode(2, x^1, x^1, nil); n != x^1 {
+		t.Errorf("a node with equal successors should be its successor")
+	}
+	y, _ := tbl.Makenode(2, x, 1, nil)
+	if y&1 == 0 || tbl.Nodes[y>>1].High&1 != 0 {
+		t.Errorf("nodes should be stored with a regular high edge")
+	}
+	tbl.Gbc([]int{y})
+	if tbl.Nodes[x>>1].Low == -1 || tbl.Nodes[y>>1].Low == -1 {
+		t.Errorf("garbage collection should keep the nodes reachable from the refstack")
+	}
+	tbl.Gbc(nil)
+	if tbl.Nodes[x>>1].Low != -1 {
+		t.Errorf("garbage collection should reclaim unreachable nodes")
+	}
+}
+
+// TestCopyFrom checks that a copy of a table finds the nodes of the original,
+// and that the two tables are independent.
+func TestCopyFrom(t *testing.T) {
+	src := newTable(10)
+	n := 1
+	for level := int32(15); level >= 0; level-- {
+		n, _ = src.Makenode(level, 0, n, []int{n})
+	}
+	src.Nodes[n].Refcou = 1
+	dst := newTable(10)
+	gcs := 0
+	dst.BeforeGC = func() { gcs++ }
+	dst.CopyFrom(src)
+	if m, _ := dst.Makenode(0, 0, src.Nodes[n].High, nil); m != n || dst.Produced != 0 {
+		t.Errorf("the copy should find the nodes of the original, expected %d, actual %d", n, m)
+	}
+	if err := dst.Check(); err != nil {
+		t.Fatal(err)
+	}
+	dst.Gbc(nil)
+	if gcs != 1 {
+		t.Errorf("the copy should keep its own hooks")
+	}
+	dst.Nodes[n].Refcou = 0
+	dst.Gbc(nil)
+	if src.Nodes[n].Low == -1 || dst.Nodes[n].Low != -1 {
+		t.Errorf("the copy and the original should be independent")
+	}
+}
+
+// TestShare checks that a table sharing the nodes of another one only copies
+// them before its first change.
+func TestShare(t *testing.T) {
+	src := newTable(10)
+	x, _ := src.Makenode(3, 0, 1, nil)
+	src.Nodes[x].Refcou = MaxRefcount
+	dst := newTable(10)
+	dst.Share(src)
+	if m, _ := dst.Makenode(3, 0, 1, nil); m != x || !dst.Shared() {
+		t.Errorf("looking up a node should not copy the table")
+	}
+	y, _ := dst.Makenode(2, 0, x, nil)
+	if dst.Shared() || src.Nodes[y].Low != -1 || len(src.Lookup(2, 0, x)) != 0 {
+		t.Errorf("adding a node should copy the table and leave the original unchanged")
+	}
+	if err := dst.Check(); err != nil {
+		t.Fatal(err)
+	}
+}
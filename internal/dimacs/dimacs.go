@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+// Package dimacs provides a reader for formulas in conjunctive normal form
+// written in the DIMACS CNF format, shared by the commands of module rudd.
+package dimacs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Parse reads a formula in DIMACS CNF format and returns its number of
+// variables and its clauses, where literal v (resp. -v) stands for variable
+// v-1 (resp. its negation). Comment lines, starting with c, are ignored, as
+// well as the lines starting with % found at the end of some benchmark files.
+// We return an error if the problem line ("p cnf variables clauses") is
+// missing or badly formed, or if a literal is not an integer in the range of
+// variables. The last clause does not need to end with 0.
+func Parse(r io.Reader) (int, [][]int, error) {
+	scanner := bufio.NewScanner(r)
+	varnum, header := 0, false
+	clauses := [][]int{}
+	clause := []int{}
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "c" || fields[0] == "%" {
+			continue
+		}
+		if fields[0] == "p" {
+			if header || len(fields) != 4 || fields[1] != "cnf" {
+				return 0, nil, fmt.Errorf("line %d: bad problem line", line)
+			}
+			v, err := strconv.Atoi(fields[2])
+			if err != nil || v < 1 {
+				return 0, nil, fmt.Errorf("line %d: bad number of variables", line)
+			}
+			varnum, header = v, true
+			continue
+		}
+		if !header {
+			return 0, nil, fmt.Errorf("line %d: clause before the problem line", line)
+		}
+		for _, f := range fields {
+			lit, err := strconv.Atoi(f)
+			if err != nil || lit > varnum || -lit > varnum {
+				return 0, nil, fmt.Errorf("line %d: bad literal %q", line, f)
+			}
+			if lit == 0 {
+				clauses = append(clauses, clause)
+				clause = []int{}
+				continue
+			}
+			clause = append(clause, lit)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, nil, err
+	}
+	if !header {
+		return 0, nil, fmt.Errorf("missing problem line")
+	}
+	if len(clause) > 0 {
+		clauses = append(clauses, clause)
+	}
+	return varnum, clauses, nil
+}
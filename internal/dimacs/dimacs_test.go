@@ -0,0 +1,27 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package dimacs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cnf := "c a comment\np cnf 3 2\n1 -2 0\n2 3\n0\n"
+	varnum, clauses, err := Parse(strings.NewReader(cnf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if varnum != 3 || !reflect.DeepEqual(clauses, [][]int{{1, -2}, {2, 3}}) {
+		t.Errorf("wrong CNF formula, %d variables and clauses %v", varnum, clauses)
+	}
+	for _, bad := range []string{"1 2 0\n", "p cnf 2 1\n1 3 0\n", "p dnf 2 1\n"} {
+		if _, _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for formula %q", bad)
+		}
+	}
+}
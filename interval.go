@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "fmt"
+
+// Interval is a pair of BDDs (lower, upper), with lower ⇒ upper, that denotes
+// the set of Boolean functions f such that lower ⇒ f ⇒ upper. This is also
+// called an incompletely specified function, where the assignments in upper &
+// !lower are "don't care". Intervals are used, for instance, in abstract
+// interpretation to represent a function that is only partially known.
+//
+// The operations on intervals are lifted from the ones on BDDs so that the
+// result contains op(f, g) for every f and g in the operands. Since negation is
+// antitone, Not swaps (and negates) the two bounds. When an operation fails,
+// the bounds of the result are nil and the error is recorded in the BDD.
+type Interval struct {
+	b     *BDD
+	lower Node
+	upper Node
+}
+
+// NewInterval returns the interval [lower, upper]. We return an error if one of
+// the nodes is not valid or if lower does not imply upper.
+func (b *BDD) NewInterval(lower, upper Node) (Interval, error) {
+	if b.checkptr(lower) != nil || b.checkptr(upper) != nil {
+		return Interval{b: b}, fmt.Errorf("wrong node in call to NewInterval; %s", b.error)
+	}
+	if *b.Imp(lower, upper) != 1 {
+		return Interval{b: b}, fmt.Errorf("lower bound does not imply upper bound in call to NewInterval")
+	}
+	return Interval{b: b, lower: lower, upper: upper}, nil
+}
+
+// Exact returns the interval [n, n], that contains only the function n.
+func (b *BDD) Exact(n Node) Interval {
+	return Interval{b: b, lower: n, upper: n}
+}
+
+// Lower returns the lower bound of i.
+func (i Interval) Lower() Node {
+	return i.lower
+}
+
+// Upper returns the upper bound of i.
+func (i Interval) Upper() Node {
+	return i.upper
+}
+
+// DontCare returns the set of assignments where the functions in i may differ,
+// that is upper & !lower.
+func (i Interval) DontCare() Node {
+	return i.b.Apply(i.upper, i.lower, OPdiff)
+}
+
+// Contains returns true if function f is in i, meaning lower ⇒ f ⇒ upper.
+func (i Interval) Contains(f Node) bool {
+	if i.b.checkptr(f) != nil || i.b.checkptr(i.lower) != nil || i.b.checkptr(i.upper) != nil {
+		return false
+	}
+	return *i.b.Imp(i.lower, f) == 1 && *i.b.Imp(f, i.upper) == 1
+}
+
+// And returns the interval [i.lower & j.lower, i.upper & j.upper].
+func (i Interval) And(j Interval) Interval {
+	return Interval{b: i.b, lower: i.b.And(i.lower, j.lower), upper: i.b.And(i.upper, j.upper)}
+}
+
+// Or returns the interval [i.lower | j.lower, i.upper | j.upper].
+func (i Interval) Or(j Interval) Interval {
+	return Interval{b: i.b, lower: i.b.Or(i.lower, j.lower), upper: i.b.Or(i.upper, j.upper)}
+}
+
+// Not returns the interval [!i.upper, !i.lower].
+func (i Interval) Not() Interval {
+	return Interval{b: i.b, lower: i.b.Not(i.upper), upper: i.b.Not(i.lower)}
+}
+
+// Exist returns the interval obtained by existential quantification of the
+// variables in varset, a set of variables built with Makeset, over both
+// bounds.
+func (i Interval) Exist(varset Node) Interval {
+	return Interval{b: i.b, lower: i.b.Exist(i.lower, varset), upper: i.b.Exist(i.upper, varset)}
+}
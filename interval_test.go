@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"testing"
+)
+
+func TestInterval(t *testing.T) {
+	bdd, _ := New(4)
+	x0, x1, x2 := bdd.Ithvar(0), bdd.Ithvar(1), bdd.Ithvar(2)
+	if _, err := bdd.NewInterval(x0, x1); err == nil {
+		t.Errorf("NewInterval should fail when lower does not imply upper")
+	}
+	// i contains x0 & x1, x0 and x0 | x1, but not x1
+	i, err := bdd.NewInterval(bdd.And(x0, x1), bdd.Or(x0, x1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []Node{bdd.And(x0, x1), x0, bdd.Or(x0, x1)} {
+		if !i.Contains(f) {
+			t.Errorf("interval should contain %d", *f)
+		}
+	}
+	if i.Contains(x1) == i.Contains(bdd.Not(bdd.Equiv(x0, x1))) {
+		t.Errorf("interval should contain x0 xor x1 but not x1")
+	}
+	if !bdd.Equal(i.DontCare(), bdd.Not(bdd.Equiv(x0, x1))) {
+		t.Errorf("wrong don't care set")
+	}
+	// the negation of a function in i is in Not(i), which swaps the bounds
+	n := i.Not()
+	if !n.Contains(bdd.Not(x0)) || n.Contains(x0) {
+		t.Errorf("Not should contain the negation of the functions in the interval")
+	}
+	if !bdd.Equal(n.Lower(), bdd.Not(i.Upper())) || !bdd.Equal(n.Upper(), bdd.Not(i.Lower())) {
+		t.Errorf("Not should swap the bounds")
+	}
+	j := bdd.Exact(x2)
+	if a := i.And(j); !a.Contains(bdd.And(x0, x2)) || a.Contains(x0) {
+		t.Errorf("wrong result for And")
+	}
+	if o := i.Or(j); !o.Contains(bdd.Or(x0, x2)) || o.Contains(x0) {
+		t.Errorf("wrong result for Or")
+	}
+	e := i.Exist(bdd.Makeset([]int{1}))
+	if !bdd.Equal(e.Lower(), x0) || !bdd.Equal(e.Upper(), bdd.True()) {
+		t.Errorf("wrong result for Exist")
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// journal records the sequence of public operations performed on a BDD, see
+// the Journal configuration option.
+type journal struct {
+	w    io.Writer
+	seq  int // number of operations recorded so far
+	used int // number of nodes in use after the last operation
+}
+
+// Journal is a configuration option (function). Used as a parameter in New it
+// records the sequence of public operations performed on the BDD, one per line
+// of w, that can be re-executed on a fresh BDD using Replay. This is useful to
+// reproduce a problem outside of the application where it occurred.
+//
+// Each line gives the sequence number of the operation, its name and its
+// arguments, where nodes are given by their id in the node table, followed by
+// the id of the result, a fingerprint of the result (a hash of its structure
+// that does not depend on the ids of nodes), and the variation in the number
+// of nodes used in the table. The journal is written without buffering, so
+// that it is complete even if the application crashes, and computing the
+// fingerprint of each result is costly. Hence this option should only be used
+// for debugging. Errors while writing to w are ignored.
+//
+// Operations that build nodes from external data, such as Load or Unflatten,
+// are recorded as the sequence of calls to Ithvar and Ite used to rebuild each
+// node. The BDDs returned by Extract, Relayout or Template.Instantiate do not
+// inherit the journal. Each change of the variable order, with Reorder,
+// SetVarOrder or option Autoreorder, is recorded as a call to SetVarOrder with
+// the resulting order, since fingerprints depend on the order.
+func Journal(w io.Writer) func(*configs) {
+	return func(c *configs) {
+		c.journal = w
+	}
+}
+
+func (b *BDD) initjournal(c *configs) {
+	if c.journal == nil {
+		return
+	}
+	b.journal = &journal{w: c.journal, used: b.used()}
+	fmt.Fprintf(c.journal, "rudd journal 1 %d\n", b.varnum)
+}
+
+// record adds an entry for operation op, with arguments args, and result res
+// to the journal of b.
+func (b *BDD) record(res Node, op string, args ...interface{}) {
+	var sb strings.Builder
+	b.journal.seq++
+	fmt.Fprintf(&sb, "%d %s", b.journal.seq, op)
+	for _, a := range args {
+		sb.WriteByte(' ')
+		switch a := a.(type) {
+		case Node:
+			if a == nil {
+				sb.WriteString("nil")
+			} else {
+				sb.WriteString(strconv.Itoa(*a))
+			}
+		case []int:
+			sb.WriteString(journalInts(a))
+		case []uint64:
+			if len(a) == 0 {
+				sb.WriteByte('.')
+			}
+			for k, v := range a {
+				if k > 0 {
+					sb.WriteByte(',')
+				}
+				sb.WriteString(strconv.FormatUint(v, 10))
+			}
+		case []bool:
+			for _, v := range a {
+				if v {
+					sb.WriteByte('1')
+				} else {
+					sb.WriteByte('0')
+				}
+			}
+			sb.WriteByte('.')
+		case Replacer:
+			fmt.Fprintf(&sb, "r%d", a.Id())
+		case Composer:
+			fmt.Fprintf(&sb, "c%d", a.Id())
+		default:
+			fmt.Fprint(&sb, a)
+		}
+	}
+	used := b.used()
+	if res == nil {
+		fmt.Fprintf(&sb, " = nil 0 %+d\n", used-b.journal.used)
+	} else {
+		fmt.Fprintf(&sb, " = %d %016x %+d\n", *res, b.fingerprint(*res), used-b.journal.used)
+	}
+	b.journal.used = used
+	io.WriteString(b.journal.w, sb.String())
+}
+
+// recordreplacer adds an entry for the creation of replacer r to the journal
+// of b. We use the same format than for other operations, with an empty
+// fingerprint.
+func (b *BDD) recordreplacer(r Replacer, oldvars, newvars []int) {
+	b.journal.seq++
+	fmt.Fprintf(b.journal.w, "%d newreplacer %s %s = r%d 0 +0\n", b.journal.seq, journalInts(oldvars), journalInts(newvars), r.Id())
+}
+
+// recordcomposer adds an entry for the creation of composer c to the journal
+// of b, in the same format than for replacers.
+func (b *BDD) recordcomposer(c Composer, vars []int, funcs []Node) {
+	ids := make([]int, len(funcs))
+	for k, n := range funcs {
+		ids[k] = *n
+	}
+	b.journal.seq++
+	fmt.Fprintf(b.journal.w, "%d newcomposer %s %s = c%d 0 +0\n", b.journal.seq, journalInts(vars), journalInts(ids), c.Id())
+}
+
+// recordorder adds an entry for a change of the variable order to the journal
+// of b, with the new order and an empty fingerprint.
+func (b *BDD) recordorder() {
+	b.journal.seq++
+	fmt.Fprintf(b.journal.w, "%d setvarorder %s = . 0 +0\n", b.journal.seq, journalInts(b.VarOrder()))
+}
+
+func journalInts(a []int) string {
+	if len(a) == 0 {
+		return "."
+	}
+	s := make([]string, len(a))
+	for k, v := range a {
+		s[k] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// fingerprint returns a hash of the structure of the BDD rooted at n that does
+// not depend on the ids of its nodes. We use variable indices, so the result
+// depends only on the function denoted by n and on the variable order.
+func (b *BDD) fingerprint(n int) uint64 {
+	hash := map[int]uint64{0: 0x9e3779b97f4a7c15, 1: 0xc2b2ae3d27d4eb4f}
+	for _, v := range b.topo(n) {
+		h := uint64(b.level2var[b.level(v)]) + 1
+		h = (h ^ hash[b.low(v)]) * 0xff51afd7ed558ccd
+		h = (h ^ (hash[b.high(v)] >> 1)) * 0xc4ceb9fe1a85ec53
+		hash[v] = h ^ (h >> 33)
+	}
+	return hash[n]
+}
+
+// Replay re-executes the operations recorded in a journal (see option Journal)
+// on a new BDD, built using the given configuration options, and returns it.
+// We return an error, giving the line in the journal, if an operation
+// refers to a node or a replacer that was not produced by a previous
+// operation, or if the result of an operation does not have the same
+// fingerprint than the one recorded in the journal. The variation in the
+// number of nodes is not checked, since it depends on when garbage collection
+// occurs. Option Autoreorder is ignored, since the changes of the variable
+// order are replayed from the journal.
+func Replay(r io.Reader, options ...func(*configs)) (*BDD, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("empty journal")
+	}
+	var version, varnum int
+	if _, err := fmt.Sscanf(scanner.Text(), "rudd journal %d %d", &version, &varnum); err != nil || version != 1 {
+		return nil, fmt.Errorf("bad journal header %q", scanner.Text())
+	}
+	b, err := New(varnum, options...)
+	if err != nil {
+		return nil, err
+	}
+	b.autoorder = nil
+	nodes := map[int]Node{0: bddzero, 1: bddone}
+	replacers := make(map[string]Replacer)
+	composers := make(map[string]Composer)
+	line := 1
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if err := b.replay(fields, nodes, replacers, composers); err != nil {
+			return b, fmt.Errorf("line %d of journal: %s", line, err)
+		}
+	}
+	return b, scanner.Err()
+}
+
+// replay re-executes one line of a journal, split in fields.
+func (b *BDD) replay(fields []string, nodes map[int]Node, replacers map[string]Replacer, composers map[string]Composer) error {
+	if len(fields) < 5 || fields[len(fields)-4] != "=" {
+		return fmt.Errorf("malformed entry")
+	}
+	op, args := fields[1], fields[2:len(fields)-4]
+	result, fp := fields[len(fields)-3], fields[len(fields)-2]
+	var err error
+	node := func(s string) Node {
+		if s == "nil" {
+			return nil
+		}
+		id, e := strconv.Atoi(s)
+		if n, ok := nodes[id]; e == nil && ok {
+			return n
+		}
+		if err == nil {
+			err = fmt.Errorf("unknown node %s", s)
+		}
+		return nil
+	}
+	ints := func(s string) []int {
+		res := []int{}
+		if s == "." {
+			return res
+		}
+		for _, v := range strings.Split(s, ",") {
+			i, e := strconv.Atoi(v)
+			if e != nil && err == nil {
+				err = fmt.Errorf("bad list of variables %s", s)
+			}
+			res = append(res, i)
+		}
+		return res
+	}
+	operator := func(s string) Operator {
+		for k, name := range opnames {
+			if name == s && Operator(k) < opnot {
+				return Operator(k)
+			}
+		}
+		if err == nil {
+			err = fmt.Errorf("unknown operator %s", s)
+		}
+		return OPand
+	}
+	arity := map[string]int{
+		"ithvar": 1, "nithvar": 1, "not": 1, "apply": 3, "ite": 3, "exist": 2, "unique": 2,
+		"appex": 4, "replace": 2, "makeset": 1, "makecube": 2, "newreplacer": 2,
+		"makelits": 1, "fromminterindices": 2,
+		"extvarnum": 1, "setvarorder": 1, "compose": 3, "simplify": 2, "fullsatone": 2, "satoneset": 3, "veccompose": 2, "newcomposer": 2,
+	}
+	if a, ok := arity[op]; !ok || a != len(args) {
+		return fmt.Errorf("unknown operation %s with %d arguments", op, len(args))
+	}
+	if op == "newreplacer" {
+		r, e := b.NewReplacer(ints(args[0]), ints(args[1]))
+		if err == nil {
+			err = e
+		}
+		if err == nil {
+			replacers[result] = r
+		}
+		return err
+	}
+	if op == "newcomposer" {
+		ids := ints(args[1])
+		funcs := make([]Node, len(ids))
+		for k, id := range ids {
+			funcs[k] = node(strconv.Itoa(id))
+		}
+		if err != nil {
+			return err
+		}
+		c, e := b.NewComposer(ints(args[0]), funcs)
+		if e != nil {
+			return e
+		}
+		composers[result] = c
+		return nil
+	}
+	if op == "extvarnum" {
+		num, e := strconv.Atoi(args[0])
+		if e != nil {
+			return fmt.Errorf("bad number of variables %s", args[0])
+		}
+		_, err = b.ExtVarnum(num)
+		return err
+	}
+	if op == "setvarorder" {
+		order := ints(args[0])
+		if err != nil {
+			return err
+		}
+		return b.SetVarOrder(order)
+	}
+	var res Node
+	switch op {
+	case "ithvar", "nithvar":
+		v, e := strconv.Atoi(args[0])
+		if e != nil {
+			return fmt.Errorf("bad variable %s", args[0])
+		}
+		if op == "ithvar" {
+			res = b.Ithvar(v)
+		} else {
+			res = b.NIthvar(v)
+		}
+	case "not":
+		res = b.Not(node(args[0]))
+	case "apply":
+		res = b.Apply(node(args[1]), node(args[2]), operator(args[0]))
+	case "ite":
+		res = b.Ite(node(args[0]), node(args[1]), node(args[2]))
+	case "exist":
+		res = b.Exist(node(args[0]), node(args[1]))
+	case "unique":
+		res = b.Unique(node(args[0]), node(args[1]))
+	case "appex":
+		res = b.AppEx(node(args[1]), node(args[2]), operator(args[0]), node(args[3]))
+	case "replace":
+		r, ok := replacers[args[1]]
+		if !ok {
+			return fmt.Errorf("unknown replacer %s", args[1])
+		}
+		res = b.Replace(node(args[0]), r)
+	case "fullsatone":
+		polarity, e := strconv.Atoi(args[1])
+		if e != nil {
+			return fmt.Errorf("bad polarity %s", args[1])
+		}
+		res = b.Fullsatone(node(args[0]), polarity)
+	case "satoneset":
+		res = b.Satoneset(node(args[0]), node(args[1]), node(args[2]))
+	case "simplify":
+		res = b.Simplify(node(args[0]), node(args[1]))
+	case "compose":
+		v, e := strconv.Atoi(args[1])
+		if e != nil {
+			return fmt.Errorf("bad variable %s", args[1])
+		}
+		res = b.Compose(node(args[0]), v, node(args[2]))
+	case "veccompose":
+		c, ok := composers[args[1]]
+		if !ok {
+			return fmt.Errorf("unknown composer %s", args[1])
+		}
+		res = b.VecCompose(node(args[0]), c)
+	case "makeset":
+		res = b.Makeset(ints(args[0]))
+	case "makelits":
+		ids := ints(args[0])
+		lits := make([]Literal, len(ids))
+		for k, l := range ids {
+			lits[k] = Literal(l)
+		}
+		res = b.Makelits(lits)
+	case "fromminterindices":
+		values := []uint64{}
+		if args[1] != "." {
+			for _, v := range strings.Split(args[1], ",") {
+				x, e := strconv.ParseUint(v, 10, 64)
+				if e != nil {
+					return fmt.Errorf("bad list of values %s", args[1])
+				}
+				values = append(values, x)
+			}
+		}
+		res = b.FromMinterIndices(ints(args[0]), func(yield func(uint64) bool) {
+			for _, x := range values {
+				if !yield(x) {
+					return
+				}
+			}
+		})
+	case "makecube":
+		polarity := []bool{}
+		for _, c := range strings.TrimSuffix(args[1], ".") {
+			polarity = append(polarity, c == '1')
+		}
+		res = b.Makecube(ints(args[0]), polarity)
+	}
+	if err != nil {
+		return err
+	}
+	if result == "nil" {
+		if res != nil {
+			return fmt.Errorf("%s should fail", op)
+		}
+		return nil
+	}
+	if res == nil {
+		return fmt.Errorf("%s failed; %s", op, b.error)
+	}
+	if actual := fmt.Sprintf("%016x", b.fingerprint(*res)); actual != fp {
+		return fmt.Errorf("result of %s differs from the journal (expected %s, actual %s)", op, fp, actual)
+	}
+	id, e := strconv.Atoi(result)
+	if e != nil {
+		return fmt.Errorf("bad result %s", result)
+	}
+	nodes[id] = res
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	var buf bytes.Buffer
+	bdd, R := milner(t, true, 6, Nodesize(100), Cachesize(25), Journal(&buf))
+	r, _ := bdd.NewReplacer([]int{0}, []int{1})
+	bdd.Replace(R, r)
+	bdd.Compose(R, 3, bdd.Ithvar(5))
+	bdd.Simplify(R, bdd.Ithvar(3))
+	bdd.Fullsatone(R, 1)
+	bdd.Satoneset(R, bdd.Makeset([]int{0, 6}), bdd.False())
+	c, _ := bdd.NewComposer([]int{1, 4}, []Node{bdd.Ithvar(7), bdd.Or(bdd.Ithvar(2), bdd.Ithvar(9))})
+	bdd.VecCompose(R, c)
+	bdd.Makecube([]int{2, 0}, []bool{true, false})
+	journal := buf.String()
+	if !strings.HasPrefix(journal, "rudd journal 1 ") {
+		t.Fatalf("journal should start with a header, actual %q", journal[:20])
+	}
+	// we replay the journal with a different configuration
+	if _, err := Replay(strings.NewReader(journal), Nodesize(10000)); err != nil {
+		t.Fatal(err)
+	}
+	// and check that we detect a divergence
+	lines := strings.Split(journal, "\n")
+	fields := strings.Fields(lines[len(lines)-2])
+	fields[len(fields)-2] = "0123456789abcdef"
+	lines[len(lines)-2] = strings.Join(fields, " ")
+	_, err := Replay(strings.NewReader(strings.Join(lines, "\n")))
+	if err == nil || !strings.Contains(err.Error(), "differs from the journal") {
+		t.Errorf("Replay should detect a different result, actual error %v", err)
+	}
+}
+
+// TestJournalData checks that we can replay the operations building nodes from
+// external data.
+func TestJournalData(t *testing.T) {
+	other, _ := New(6)
+	n := other.Or(other.And(other.Ithvar(0), other.NIthvar(4)), other.Ithvar(2))
+	var saved bytes.Buffer
+	if err := other.Save(&saved, n); err != nil {
+		t.Fatal(err)
+	}
+	flat, _ := other.Flatten(n)
+
+	var buf bytes.Buffer
+	bdd, _ := New(6, Journal(&buf))
+	bdd.Makelits([]Literal{Pos(3), Neg(1), Pos(3)})
+	bdd.Makelits([]Literal{Pos(3), Neg(3)})
+	bdd.Makelits([]Literal{Pos(8)})
+	bdd.FromMinterIndices([]int{4, 1, 2}, func(yield func(uint64) bool) {
+		for _, x := range []uint64{1, 2, 5, 6} {
+			if !yield(x) {
+				return
+			}
+		}
+	})
+	loaded, err := bdd.Load(bytes.NewReader(saved.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unflat, err := bdd.Unflatten(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bdd.Equal(loaded[0], unflat[0]) {
+		t.Error("Load and Unflatten should give the same node")
+	}
+	bdd.And(loaded[0], bdd.Ithvar(5))
+	for _, op := range []string{" makelits ", " fromminterindices ", " ite "} {
+		if !strings.Contains(buf.String(), op) {
+			t.Errorf("expected operation%sin the journal", op)
+		}
+	}
+	if _, err := Replay(strings.NewReader(buf.String())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestJournalReorder checks that we can replay a journal after changes of the
+// variable order, explicit or automatic.
+func TestJournalReorder(t *testing.T) {
+	const half = 6
+	var buf bytes.Buffer
+	bdd, _ := New(2*half, Nodesize(30), Cachesize(100), Minfreenodes(50), Autoreorder(ReorderSift, 0), Journal(&buf))
+	f := bdd.False()
+	for i := 0; i < half; i++ {
+		f = bdd.Or(f, bdd.And(bdd.Ithvar(i), bdd.Ithvar(i+half)))
+	}
+	if bdd.reorders == 0 {
+		t.Fatalf("expected an automatic reordering")
+	}
+	if err := bdd.Reorder(ReorderSymSift); err != nil {
+		t.Fatal(err)
+	}
+	bdd.SetVarOrder([]int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0})
+	bdd.And(f, bdd.Ithvar(3))
+	if n := strings.Count(buf.String(), " setvarorder "); n != bdd.reorders {
+		t.Errorf("expected %d changes of order in the journal, actual %d", bdd.reorders, n)
+	}
+	other, err := Replay(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(other.VarOrder()) != fmt.Sprint(bdd.VarOrder()) {
+		t.Errorf("Replay should give the same order, expected %v, actual %v", bdd.VarOrder(), other.VarOrder())
+	}
+}
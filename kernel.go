@@ -5,7 +5,11 @@
 package rudd
 
 import (
-	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/dalzilio/rudd/internal/dd"
 )
 
 // number of bytes in a int (adapted from uintSize in the math/bits package)
@@ -19,18 +23,142 @@ const _MINFREENODES int = 20
 // bits for encoding levels (so also the max number of variables). We use 11
 // other bits for markings. Hence we make sure to always use int32 to avoid
 // problem when we change architecture.
-const _MAXVAR int32 = 0x1FFFFF
+const _MAXVAR int32 = dd.MaxLevel
 
 // _MAXREFCOUNT is the maximal value of the reference counter (refcou), also
 // used to stick nodes (like constants and variables) in the node list. It is
 // egal to 1023 (10 bits).
-const _MAXREFCOUNT int32 = 0x3FF
+const _MAXREFCOUNT int32 = dd.MaxRefcount
 
 // _DEFAULTMAXNODEINC is the default value for the maximal increase in the
 // number of nodes during a resize. It is approx. one million nodes (1 048 576)
 // (could be interesting to change it to 1 << 23 = 8 388 608).
 const _DEFAULTMAXNODEINC int = 1 << 20
 
-var errMemory = errors.New("unable to free memory or resize BDD")
-var errResize = errors.New("should cache resize") // when gbc and then noderesize
-var errReset = errors.New("should cache reset")   // when gbc only, without resizing
+// ErrMemory is the error used when there is no room left in the node table,
+// for instance as the value of the panic raised with policy OOMPanic.
+var ErrMemory = dd.ErrMemory
+
+// Errors returned by the kernel functions that may add nodes to the table; we
+// use the same values than the node table of package internal/dd.
+var errMemory = ErrMemory
+var errResize = dd.ErrResize // when gbc and then noderesize
+var errReset = dd.ErrReset   // when gbc only, without resizing
+
+// kernel is the core shared by BDD and Diagram: a table of nodes, where the
+// leaves are nodes at level varnum labelled with values of type T, together
+// with the refstack and the error status. A BDD is a kernel[bool], where the
+// two leaves are the constants False and True with ids 0 and 1; so that a BDD
+// is, internally, a Diagram[bool] with a fixed set of operations.
+type kernel[T comparable] struct {
+	varnum   int32     // Number of variables; also the level of the leaves
+	refstack []int     // Internal node reference stack, used to avoid collecting nodes while they are being processed.
+	error              // Error status: we use nil Nodes to signal a problem and store the error in this field. This help chain operations together.
+	strict   bool      // Panic on errors raised after the first one, see Strict
+	errstack []byte    // Stack trace of the first error, only in strict mode
+	*tables            // Underlying struct that encapsulates the list of nodes
+	leaves   map[T]int // Id of the leaf associated with each value
+	values   map[int]T // Value of each leaf, indexed by its id
+	nextkey  int       // Key used for the next leaf, stored in its low field
+}
+
+// initkernel allocates the node table of k, configured with config, and the
+// refstack. With Booleans, the two leaves are the constants.
+func (k *kernel[T]) initkernel(varnum int, config *configs) {
+	k.varnum = int32(varnum)
+	k.refstack = make([]int, 0, 2*varnum+4)
+	k.strict = config.strict
+	k.tables = newtables(config)
+	k.leaves = make(map[T]int)
+	k.values = make(map[int]T)
+	if leaves, ok := any(k.leaves).(map[bool]int); ok {
+		values := any(k.values).(map[int]bool)
+		leaves[false], leaves[true] = 0, 1
+		values[0], values[1] = false, true
+	}
+}
+
+// Varnum returns the number of defined variables.
+func (k *kernel[T]) Varnum() int {
+	return int(k.varnum)
+}
+
+// Error returns the error status of the diagram.
+func (k *kernel[T]) Error() string {
+	if k.error == nil {
+		return ""
+	}
+	return k.error.Error()
+}
+
+// Errored returns true if there was an error during a computation.
+func (k *kernel[T]) Errored() bool {
+	return k.error != nil
+}
+
+func (k *kernel[T]) seterror(format string, a ...interface{}) Node {
+	if k.error != nil {
+		if k.strict {
+			panic(fmt.Errorf("rudd: %s, after error: %w\nfirst error raised at:\n%s", fmt.Sprintf(format, a...), k.error, k.errstack))
+		}
+		format = format + "; " + k.error.Error()
+		k.error = fmt.Errorf(format, a...)
+		return nil
+	}
+	k.error = fmt.Errorf(format, a...)
+	if k.strict {
+		k.errstack = debug.Stack()
+	}
+	if _DEBUG {
+		log.Println(k.error)
+	}
+	return nil
+}
+
+// checknode returns an error if n is not a valid node, without changing the
+// error status of k.
+func (k *kernel[T]) checknode(n Node) error {
+	switch {
+	case n == nil:
+		return fmt.Errorf("Illegal acces to node (nil value)")
+	case (*n < 0) || (*n >= k.size()):
+		return fmt.Errorf("Illegal acces to node %d", *n)
+	case (*n >= 2) && (k.low(*n) == -1):
+		return fmt.Errorf("Illegal acces to node %d", *n)
+	case *n < 2:
+		if _, ok := k.values[*n]; !ok {
+			return fmt.Errorf("Illegal acces to node %d", *n)
+		}
+	}
+	return nil
+}
+
+// isleaf reports whether node n is a leaf.
+func (k *kernel[T]) isleaf(n int) bool {
+	return k.level(n) == k.varnum
+}
+
+// sweepleaves removes the leaves reclaimed by the garbage collector from the
+// maps of values.
+func (k *kernel[T]) sweepleaves() {
+	for v, id := range k.leaves {
+		if id > 1 && k.low(id) == -1 {
+			delete(k.leaves, v)
+			delete(k.values, id)
+		}
+	}
+}
+
+func (k *kernel[T]) pushref(n int) int {
+	k.refstack = append(k.refstack, n)
+	return n
+}
+
+// retnode is the version of Retnode that returns nil, instead of panicking in
+// debug mode, when n is an error value (-1).
+func (k *kernel[T]) retnode(n int) Node {
+	if n < 0 {
+		return nil
+	}
+	return k.Retnode(n)
+}
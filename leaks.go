@@ -0,0 +1,149 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LeakAudit is a configuration option (function). Used as a parameter in New it
+// records the stack trace of one out of rate calls to Retnode, that is of the
+// operations returning a Node to the user, and keeps it until the Node is
+// reclaimed by the Go runtime. Nodes that are accidentally retained, for
+// instance in a map that is never cleared, prevent the garbage collection of
+// the BDD nodes they reference, and method LeakReport can be used to find where
+// they were created. Use a rate of 1 to record every call; a rate of 0, the
+// default, disables the audit.
+func LeakAudit(rate int) func(*configs) {
+	return func(c *configs) {
+		c.leakrate = rate
+	}
+}
+
+// leakaudit records the stack traces of a sample of the Nodes returned by
+// Retnode that have not been reclaimed yet. The finalizers of Nodes run in a
+// separate goroutine, hence the lock.
+type leakaudit struct {
+	sync.Mutex
+	rate   int                  // we sample one call to Retnode out of rate
+	calls  int                  // number of calls to Retnode so far
+	serial int                  // serial number of the last sampled Node
+	live   map[int]leakedsample // sampled Nodes still referenced, by serial number
+}
+
+type leakedsample struct {
+	gen   int       // number of garbage collections when the Node was created
+	stack []uintptr // program counters of the call stack
+}
+
+func newleakaudit(c *configs) *leakaudit {
+	if c.leakrate <= 0 {
+		return nil
+	}
+	return &leakaudit{rate: c.leakrate, live: make(map[int]leakedsample)}
+}
+
+// setfinalizer sets the finalizer of Node x. If the call is sampled, we record
+// the current stack trace and wrap the finalizer so that the record is removed
+// when x is reclaimed.
+func (a *leakaudit) setfinalizer(x *int, finalizer interface{}, gen int) {
+	a.Lock()
+	a.calls++
+	if a.calls%a.rate != 0 {
+		a.Unlock()
+		runtime.SetFinalizer(x, finalizer)
+		return
+	}
+	a.serial++
+	serial := a.serial
+	stack := make([]uintptr, 32)
+	// we skip runtime.Callers, setfinalizer and Retnode
+	stack = stack[:runtime.Callers(3, stack)]
+	a.live[serial] = leakedsample{gen: gen, stack: stack}
+	a.Unlock()
+	f := finalizer.(func(*int))
+	runtime.SetFinalizer(x, func(n *int) {
+		a.Lock()
+		delete(a.live, serial)
+		a.Unlock()
+		f(n)
+	})
+}
+
+// LeakReport writes a "BDD reference leak report" to w, listing the places
+// where the sampled Nodes still referenced were created, grouped by call stack
+// and starting with the most frequent. Since Nodes are reclaimed by the Go
+// garbage collector, and their finalizers run asynchronously, it is better to
+// call runtime.GC before building a report. A Node created several garbage
+// collections ago (the generation of a Node is the number of garbage
+// collections of the BDD before its creation) is a good candidate for a leak.
+// We return an error if the BDD was not created with option LeakAudit.
+func (b *BDD) LeakReport(w io.Writer) error {
+	a := b.audit
+	if a == nil {
+		return fmt.Errorf("leak audit not enabled, use option LeakAudit")
+	}
+	type site struct {
+		count  int
+		oldest int
+		trace  string
+	}
+	sites := make(map[string]*site)
+	a.Lock()
+	total := len(a.live)
+	for _, s := range a.live {
+		trace := formatstack(s.stack)
+		if e, ok := sites[trace]; ok {
+			e.count++
+			if s.gen < e.oldest {
+				e.oldest = s.gen
+			}
+		} else {
+			sites[trace] = &site{count: 1, oldest: s.gen, trace: trace}
+		}
+	}
+	calls, rate := a.calls, a.rate
+	a.Unlock()
+	res := make([]*site, 0, len(sites))
+	for _, s := range sites {
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].count != res[j].count {
+			return res[i].count > res[j].count
+		}
+		return res[i].trace < res[j].trace
+	})
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "BDD reference leak report: %d live references sampled (1 out of %d, %d calls to Retnode), current generation %d\n", total, rate, calls, len(b.history))
+	for _, s := range res {
+		fmt.Fprintf(&sb, "\n%d references, oldest from generation %d, created at:\n%s", s.count, s.oldest, s.trace)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// formatstack returns a description of a call stack, without the frames of
+// the internal methods of the BDD.
+func formatstack(stack []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(stack)
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "github.com/dalzilio/rudd.(*BDD)") &&
+			!strings.HasPrefix(f.Function, "github.com/dalzilio/rudd.(*tables)") {
+			fmt.Fprintf(&sb, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// retain keeps nodes in a map, as a user would do by accident.
+func retain(bdd *BDD, m map[int]Node) {
+	for k := 0; k < 8; k++ {
+		m[k] = bdd.And(bdd.Ithvar(k), bdd.NIthvar(k+1))
+	}
+}
+
+func TestLeakReport(t *testing.T) {
+	bdd, _ := New(10)
+	if err := bdd.LeakReport(&bytes.Buffer{}); err == nil {
+		t.Errorf("LeakReport should fail without option LeakAudit")
+	}
+	bdd, _ = New(10, LeakAudit(1))
+	leaked := make(map[int]Node)
+	retain(bdd, leaked)
+	for k := 0; k < 8; k++ {
+		bdd.Or(bdd.Ithvar(k), bdd.Ithvar(k+2))
+	}
+	for k := 0; k < 3; k++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	var buf bytes.Buffer
+	if err := bdd.LeakReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	if !strings.HasPrefix(report, "BDD reference leak report:") {
+		t.Errorf("unexpected report header: %q", report)
+	}
+	if !strings.Contains(report, "8 references, oldest from generation") || !strings.Contains(report, "rudd.retain") {
+		t.Errorf("the report should point to function retain, actual:\n%s", report)
+	}
+	if strings.Contains(report, "(*BDD)") {
+		t.Errorf("the report should not include internal methods, actual:\n%s", report)
+	}
+	runtime.KeepAlive(leaked)
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "fmt"
+
+// Literal is a variable together with a polarity, used to describe cubes with
+// both positive and negative occurrences of variables, see Makelits and
+// Scanlits. Literals are built with functions Pos and Neg.
+type Literal int
+
+// Pos returns the positive literal for variable v.
+func Pos(v int) Literal {
+	return Literal(2 * v)
+}
+
+// Neg returns the negative literal for variable v.
+func Neg(v int) Literal {
+	return Literal(2*v + 1)
+}
+
+// Var returns the variable of literal l.
+func (l Literal) Var() int {
+	return int(l) >> 1
+}
+
+// Positive returns true if l is a positive literal.
+func (l Literal) Positive() bool {
+	return l&1 == 0
+}
+
+// String returns a textual representation of l, such as +2 or -2.
+func (l Literal) String() string {
+	if l.Positive() {
+		return fmt.Sprintf("+%d", l.Var())
+	}
+	return fmt.Sprintf("-%d", l.Var())
+}
+
+// Makelits returns a node corresponding to the conjunction (the cube) of all
+// the literals in lits. This extends Makeset to cubes with negative literals
+// and it is such that Scanlits(Makelits(a)) == a (up-to the order of the
+// literals and repetitions). The result is False if lits contains both the
+// positive and the negative literal of the same variable. It returns nil and
+// sets the error condition in b if one of the variables is outside the scope
+// of the BDD.
+func (b *BDD) Makelits(lits []Literal) (result Node) {
+	if b.journal != nil {
+		ints := make([]int, len(lits))
+		for k, l := range lits {
+			ints[k] = int(l)
+		}
+		defer func() { b.record(result, "makelits", ints) }()
+	}
+	polarity := make(map[int]bool, len(lits))
+	varset := []int{}
+	for _, l := range lits {
+		v := l.Var()
+		if (v < 0) || (int32(v) >= b.varnum) {
+			return b.seterror("unknown variable (%d) in call to Makelits", v)
+		}
+		p, ok := polarity[v]
+		if !ok {
+			polarity[v] = l.Positive()
+			varset = append(varset, v)
+			continue
+		}
+		if p != l.Positive() {
+			return b.False()
+		}
+	}
+	if len(varset) == 0 {
+		return b.True()
+	}
+	pol := make([]bool, len(varset))
+	for k, v := range varset {
+		pol[k] = polarity[v]
+	}
+	return b.buildcube(varset, pol)
+}
+
+// Scanlits returns the literals of the cube n, sorted following the order
+// between levels. This is the dual of function Makelits and it extends Scanset
+// with the polarity of each variable. The result is nil, and we set the error
+// condition in b, if there is an error or if n is not a cube (see IsCube). It
+// is an empty slice if n is True.
+func (b *BDD) Scanlits(n Node) []Literal {
+	if b.checkptr(n) != nil {
+		return nil
+	}
+	if !b.iscube(*n) {
+		b.seterror("node %d is not a cube in call to Scanlits", *n)
+		return nil
+	}
+	res := []Literal{}
+	for i := *n; i > 1; {
+		v := int(b.level2var[b.level(i)])
+		if low := b.low(i); low != 0 {
+			res = append(res, Neg(v))
+			i = low
+		} else {
+			res = append(res, Pos(v))
+			i = b.high(i)
+		}
+	}
+	return res
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLiterals(t *testing.T) {
+	bdd, _ := New(6)
+	lits := []Literal{Neg(4), Pos(1), Neg(2), Pos(1)}
+	n := bdd.Makelits(lits)
+	expected := bdd.Makecube([]int{1, 2, 4}, []bool{true, false, false})
+	if !bdd.Equal(n, expected) {
+		t.Error("Makelits([+1 -2 -4]) should be equal to the cube x1 & !x2 & !x4")
+	}
+	if actual := bdd.Scanlits(n); fmt.Sprint(actual) != "[+1 -2 -4]" {
+		t.Errorf("Scanlits(Makelits(%v)): expected [+1 -2 -4], actual %v", lits, actual)
+	}
+	if !bdd.Equal(bdd.Makelits([]Literal{Pos(0), Pos(3)}), bdd.Makeset([]int{0, 3})) {
+		t.Error("Makelits with positive literals should be equal to Makeset")
+	}
+	if !bdd.Equal(bdd.Makelits([]Literal{Pos(3), Neg(3)}), bdd.False()) {
+		t.Error("Makelits with opposite literals should be False")
+	}
+	if !bdd.Equal(bdd.Makelits(nil), bdd.True()) {
+		t.Error("Makelits of an empty list should be True")
+	}
+	if bdd.Errored() {
+		t.Errorf("unexpected error: %s", bdd.Error())
+	}
+	if bdd.Scanlits(bdd.Or(bdd.Ithvar(1), bdd.Ithvar(2))) != nil || !bdd.Errored() {
+		t.Error("Scanlits should return nil and set an error when its argument is not a cube")
+	}
+	bdd, _ = New(6)
+	if bdd.Makelits([]Literal{Neg(6)}) != nil || !bdd.Errored() {
+		t.Error("Makelits with unknown variable should return nil")
+	}
+}
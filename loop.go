@@ -0,0 +1,156 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "runtime"
+
+// Frame is used to create the intermediate results of one iteration of a call
+// to Loop. The Nodes returned by the methods of a Frame, or registered with
+// Track, are released at the end of the iteration, except the one returned by
+// the body of the loop, instead of waiting for the Go garbage collector to run
+// their finalizers. This means that these Nodes must not be used after the
+// iteration that created them.
+type Frame struct {
+	b         *BDD
+	iteration int
+	previous  Node
+	nodes     map[Node]bool
+}
+
+// Loop calls body repeatedly, with a new Frame each time, until it returns the
+// same node than in the previous iteration, meaning a fixpoint, or nil, for
+// instance when there is an error. We return the last result of body. At the
+// first iteration, the previous result (see Frame.Previous) is the constant
+// False, so that the usual computation of the states reachable from init can
+// be written as follows, without keeping alive the frontiers computed at each
+// iteration until the next run of the Go garbage collector.
+//
+//	reach := b.Loop(func(f *rudd.Frame) rudd.Node {
+//		r := f.Previous()
+//		return f.Or(init, r, f.Replace(f.AndExist(r, trans, vars), next))
+//	})
+//
+// When the BDD was created with option LeakAudit, the intermediate results are
+// left to the Go garbage collector, like outside of Loop.
+func (b *BDD) Loop(body func(*Frame) Node) Node {
+	f := &Frame{b: b, previous: bddzero, nodes: make(map[Node]bool)}
+	for {
+		res := body(f)
+		for n := range f.nodes {
+			if n != res {
+				b.release(n)
+			}
+		}
+		if res == nil || *res == *f.previous {
+			return res
+		}
+		f.iteration++
+		f.previous = res
+		f.nodes = map[Node]bool{res: true}
+	}
+}
+
+// release drops the reference to the node held by n, like the finalizer set
+// by Retnode, and removes this finalizer. The Node n must not be used after
+// this call.
+func (b *BDD) release(n Node) {
+	if n == nil || b.audit != nil {
+		return
+	}
+	runtime.SetFinalizer(n, nil)
+	b.nodefinalizer.(func(*int))(n)
+}
+
+// Iteration returns the number of iterations before the current one.
+func (f *Frame) Iteration() int {
+	return f.iteration
+}
+
+// Previous returns the result of the previous iteration, or False during the
+// first iteration. It is released at the end of the current iteration, unless
+// it is also the result of this iteration.
+func (f *Frame) Previous() Node {
+	return f.previous
+}
+
+// Track registers n as an intermediate result that is released at the end of
+// the current iteration, unless it is the result of the iteration, and returns
+// n. The Node n must be a result returned by an operation of the BDD that is
+// not one of its operands (for instance Exist returns its operand when varset
+// is empty) and it should not be registered twice.
+func (f *Frame) Track(n Node) Node {
+	if n != nil {
+		f.nodes[n] = true
+	}
+	return n
+}
+
+// track is like Track but we do not register n if it is one of the operands
+// of the operation that returned it.
+func (f *Frame) track(n Node, operands ...Node) Node {
+	for _, v := range operands {
+		if n == v {
+			return n
+		}
+	}
+	return f.Track(n)
+}
+
+// fold combines the nodes in n with op, like And and Or in BDD, but keeps
+// track of the intermediate results.
+func (f *Frame) fold(op Operator, unit Node, n []Node) Node {
+	if len(n) == 0 {
+		return unit
+	}
+	res := n[len(n)-1]
+	for k := len(n) - 2; k >= 0 && res != nil; k-- {
+		res = f.Apply(n[k], res, op)
+	}
+	return res
+}
+
+// And is like BDD.And but the result is released at the end of the iteration.
+func (f *Frame) And(n ...Node) Node {
+	return f.fold(OPand, bddone, n)
+}
+
+// Or is like BDD.Or but the result is released at the end of the iteration.
+func (f *Frame) Or(n ...Node) Node {
+	return f.fold(OPor, bddzero, n)
+}
+
+// Not is like BDD.Not but the result is released at the end of the iteration.
+func (f *Frame) Not(n Node) Node {
+	return f.track(f.b.Not(n), n)
+}
+
+// Apply is like BDD.Apply but the result is released at the end of the
+// iteration.
+func (f *Frame) Apply(n1, n2 Node, op Operator) Node {
+	return f.track(f.b.Apply(n1, n2, op), n1, n2)
+}
+
+// Ite is like BDD.Ite but the result is released at the end of the iteration.
+func (f *Frame) Ite(n1, n2, n3 Node) Node {
+	return f.track(f.b.Ite(n1, n2, n3), n1, n2, n3)
+}
+
+// Exist is like BDD.Exist but the result is released at the end of the
+// iteration.
+func (f *Frame) Exist(n, varset Node) Node {
+	return f.track(f.b.Exist(n, varset), n, varset)
+}
+
+// AndExist is like BDD.AndExist but the result is released at the end of the
+// iteration.
+func (f *Frame) AndExist(n1, n2, varset Node) Node {
+	return f.track(f.b.AndExist(n1, n2, varset), n1, n2, varset)
+}
+
+// Replace is like BDD.Replace but the result is released at the end of the
+// iteration.
+func (f *Frame) Replace(n Node, r Replacer) Node {
+	return f.track(f.b.Replace(n, r), n)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+// TestLoop computes the reachable states of a counter from 0 to 10, encoded
+// with the even variables for the current state and the odd variables for the
+// next state, and checks that the intermediate results are released.
+func TestLoop(t *testing.T) {
+	const bits = 4
+	bdd, _ := New(2*bits, Nodesize(1000))
+	x, next := bdd.BvecVar(bits, 0, 2), bdd.BvecVar(bits, 1, 2)
+	trans := bdd.And(bdd.BvecLth(x, bdd.BvecCon(bits, 10)), bdd.BvecEqu(next, bdd.BvecAdd(x, bdd.BvecCon(bits, 1))))
+	init := bdd.BvecEqu(x, bdd.BvecCon(bits, 0))
+	current, primed := []int{}, []int{}
+	for k := 0; k < bits; k++ {
+		current, primed = append(current, 2*k), append(primed, 2*k+1)
+	}
+	varset := bdd.Makeset(current)
+	r, _ := bdd.NewReplacer(primed, current)
+	// the nodes with an external reference before the loop
+	bdd.gbc(nil)
+	before := bdd.Live()
+	iterations := 0
+	reach := bdd.Loop(func(f *Frame) Node {
+		iterations = f.Iteration() + 1
+		prev := f.Previous()
+		return f.Or(init, prev, f.Replace(f.AndExist(prev, trans, varset), r))
+	})
+	if bdd.Errored() {
+		t.Fatal(bdd.Error())
+	}
+	if count := bdd.SatcountSet(reach, varset); count.Int64() != 11 {
+		t.Errorf("expected 11 reachable states, actual %s", count)
+	}
+	if iterations != 12 {
+		t.Errorf("expected 12 iterations, actual %d", iterations)
+	}
+	// the only new external reference is the result
+	bdd.gbc(nil)
+	if live, kept := bdd.Live(), before+bdd.AnodeCount(reach); live > kept {
+		t.Errorf("expected at most %d live nodes, actual %d", kept, live)
+	}
+	// the loop stops on errors
+	if n := bdd.Loop(func(f *Frame) Node { return f.And(nil) }); n != nil {
+		t.Errorf("expected nil after an error")
+	}
+}
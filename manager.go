@@ -0,0 +1,169 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// _MANAGERMAGIC is the magic number at the start of a stream written by
+// SaveManager and _MANAGERVERSION is the current version of the format.
+const (
+	_MANAGERMAGIC   = "RUDM"
+	_MANAGERVERSION = 2
+)
+
+// Tag of the optional section, in the format used by Save, with the names of
+// the roots written by SaveManager.
+const _SECTIONNAMES = 4
+
+// SaveManager writes the whole state of b to w, meaning its variables, its
+// configuration and the nodes reachable from the named roots, so that it can be
+// restored with LoadManager; for instance to checkpoint and restart a long
+// computation. The stream starts with the magic number "RUDM", the version of
+// the format (one byte) and the configuration of b, as a sequence of unsigned
+// varints: the number of variables, the current size of the node table and of
+// the caches, followed by the values of the options Cacheratio, Maxnodesize,
+// Maxnodeincrease, Minfreenodes, Maxchain, Resizemode, Assertions, Strict,
+// TrackAges, LeakAudit, the method and threshold of Autoreorder, Reorderperiod
+// and Outofmemory. The rest of the stream is in the same format as Save, with
+// the roots sorted by name and their names in an optional section; it also
+// records the current variable order. Options that cannot be serialized, such
+// as Nodehash, Memoryhandler, Journal or the logger, are not saved. We return
+// an error if one of the nodes is not valid.
+func (b *BDD) SaveManager(w io.Writer, named map[string]Node) error {
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	roots := make([]int, len(names))
+	for k, name := range names {
+		if err := b.checkptr(named[name]); err != nil {
+			return fmt.Errorf("wrong node for %q in call to SaveManager; %s", name, err)
+		}
+		roots[k] = *named[name]
+	}
+	c := b.config
+	flag := func(x bool) int {
+		if x {
+			return 1
+		}
+		return 0
+	}
+	method, threshold := ReorderNone, 0
+	if c.autoreorder != nil {
+		method, threshold = c.autoreorder.method, c.autoreorder.threshold
+	}
+	buf := append([]byte(_MANAGERMAGIC), _MANAGERVERSION)
+	for _, v := range []int{int(b.varnum), b.size(), len(b.applycache.cache.Table),
+		c.cacheratio, c.maxnodesize, c.maxnodeincrease, c.minfreenodes, c.maxchain,
+		int(c.resizemode), flag(c.assertions), flag(c.strict), flag(c.trackages), c.leakrate,
+		int(method), threshold, c.reorderperiod, int(c.oompolicy)} {
+		buf = binary.AppendUvarint(buf, uint64(v))
+	}
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	sections := func(id map[int]int) []byte {
+		content := binary.AppendUvarint(nil, uint64(len(names)))
+		for _, name := range names {
+			content = binary.AppendUvarint(content, uint64(len(name)))
+			content = append(content, name...)
+		}
+		res := binary.AppendUvarint(nil, _SECTIONNAMES)
+		res = binary.AppendUvarint(res, uint64(len(content)))
+		return append(res, content...)
+	}
+	return b.save(w, nil, roots, nil, sections)
+}
+
+// LoadManager reads a stream written by SaveManager from r and returns a new
+// BDD, with the same variables, variable order and configuration than the
+// saved one, together with the named roots. The options given as parameters
+// are applied after the saved configuration, so they can be used to override
+// it or to set options that are not saved, such as Journal. We also accept
+// streams written with version 1 of the format, that do not include the
+// options Autoreorder, Reorderperiod and Outofmemory. We return an error if
+// the stream is not well-formed.
+func LoadManager(r io.Reader, options ...func(*configs)) (*BDD, map[string]Node, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(_MANAGERMAGIC)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, nil, fmt.Errorf("error in call to LoadManager; cannot read header, %s", err)
+	}
+	if string(header[:len(_MANAGERMAGIC)]) != _MANAGERMAGIC {
+		return nil, nil, fmt.Errorf("error in call to LoadManager; bad magic number, not a BDD manager file")
+	}
+	version := header[len(_MANAGERMAGIC)]
+	if version < 1 || version > _MANAGERVERSION {
+		return nil, nil, fmt.Errorf("error in call to LoadManager; unsupported format version (%d)", version)
+	}
+	v := make([]int, 13, 17)
+	if version > 1 {
+		v = v[:17]
+	}
+	for k := range v {
+		x, err := binary.ReadUvarint(br)
+		if err == nil && x > math.MaxInt32 {
+			err = fmt.Errorf("value out of range (%d)", x)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error in call to LoadManager; bad configuration, %s", err)
+		}
+		v[k] = int(x)
+	}
+	config := []func(*configs){
+		Nodesize(v[1]),
+		Cachesize(v[2]),
+		Cacheratio(v[3]),
+		Maxnodesize(v[4]),
+		Maxnodeincrease(v[5]),
+		Minfreenodes(v[6]),
+		Maxchain(v[7]),
+		Resizemode(ResizeMode(v[8])),
+		Assertions(v[9] != 0),
+		Strict(v[10] != 0),
+		TrackAges(v[11] != 0),
+		LeakAudit(v[12]),
+	}
+	if version > 1 {
+		config = append(config,
+			Autoreorder(ReorderMethod(v[13]), v[14]),
+			Reorderperiod(v[15]),
+			Outofmemory(OOMPolicy(v[16])),
+		)
+	}
+	b, err := New(v[0], append(config, options...)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in call to LoadManager; %s", err)
+	}
+	roots, _, sections, err := b.load(br, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in call to LoadManager; %s", err)
+	}
+	sr := sectionReader{bytes.NewReader(sections[_SECTIONNAMES])}
+	named := make(map[string]Node, len(roots))
+	n, err := sr.get(len(roots) + 1)
+	if err == nil && n != len(roots) {
+		err = fmt.Errorf("%d names for %d roots", n, len(roots))
+	}
+	for k := 0; err == nil && k < n; k++ {
+		var name string
+		if name, err = sr.getstring(); err == nil {
+			named[name] = roots[k]
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in call to LoadManager; bad section %d, %s", _SECTIONNAMES, err)
+	}
+	return b, named, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestSaveManager(t *testing.T) {
+	bdd, R := milner(t, true, 4, Nodesize(1000), Cachesize(500), Cacheratio(25), Minfreenodes(30))
+	x := bdd.And(bdd.Ithvar(0), bdd.NIthvar(3))
+	var buf bytes.Buffer
+	if err := bdd.SaveManager(&buf, map[string]Node{"R": R, "x": x, "false": bdd.False()}); err != nil {
+		t.Fatal(err)
+	}
+	other, named, err := LoadManager(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Varnum() != bdd.Varnum() {
+		t.Errorf("LoadManager, expected %d variables, actual %d", bdd.Varnum(), other.Varnum())
+	}
+	if other.Allocated() < bdd.Allocated() || other.config.cacheratio != 25 || other.config.minfreenodes != 30 {
+		t.Errorf("LoadManager should restore the configuration")
+	}
+	if len(named) != 3 {
+		t.Fatalf("LoadManager, expected 3 roots, actual %d", len(named))
+	}
+	if other.Satcount(named["R"]).Cmp(bdd.Satcount(R)) != 0 || other.fingerprint(*named["R"]) != bdd.fingerprint(*R) {
+		t.Errorf("LoadManager, wrong root R")
+	}
+	if !other.Equal(named["x"], other.And(other.Ithvar(0), other.NIthvar(3))) || !other.Equal(named["false"], other.False()) {
+		t.Errorf("LoadManager, wrong roots")
+	}
+	// options given to LoadManager override the saved configuration
+	other, _, _ = LoadManager(bytes.NewReader(buf.Bytes()), Minfreenodes(10))
+	if other.config.minfreenodes != 10 {
+		t.Errorf("LoadManager should apply the options after the saved configuration")
+	}
+	if _, _, err := LoadManager(bytes.NewReader(buf.Bytes()[:len(buf.Bytes())-1])); err == nil {
+		t.Errorf("LoadManager should fail with a truncated stream")
+	}
+	var plain bytes.Buffer
+	bdd.Save(&plain, R)
+	if _, _, err := LoadManager(&plain); err == nil {
+		t.Errorf("LoadManager should fail with a stream written by Save")
+	}
+}
+
+// TestSaveManagerOrder checks that LoadManager restores the variable order and
+// the options of the automatic reordering.
+func TestSaveManagerOrder(t *testing.T) {
+	bdd, _ := New(4, Autoreorder(ReorderSift, 5000), Reorderperiod(3), Outofmemory(OOMPanic))
+	x := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.NIthvar(3)), bdd.And(bdd.Ithvar(1), bdd.Ithvar(2)))
+	if err := bdd.SetVarOrder([]int{3, 2, 1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := bdd.SaveManager(&buf, map[string]Node{"x": x}); err != nil {
+		t.Fatal(err)
+	}
+	other, named, err := LoadManager(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := fmt.Sprint(bdd.VarOrder()), fmt.Sprint(other.VarOrder()); actual != expected {
+		t.Errorf("LoadManager should restore the order, expected %s, actual %s", expected, actual)
+	}
+	if other.fingerprint(*named["x"]) != bdd.fingerprint(*x) {
+		t.Errorf("LoadManager, wrong root x")
+	}
+	if !other.Equal(named["x"], other.Or(other.And(other.Ithvar(0), other.NIthvar(3)), other.And(other.Ithvar(1), other.Ithvar(2)))) {
+		t.Errorf("LoadManager, wrong root x")
+	}
+	c := other.config
+	if c.autoreorder == nil || c.autoreorder.method != ReorderSift || c.autoreorder.threshold != 5000 || c.reorderperiod != 3 || c.oompolicy != OOMPanic {
+		t.Errorf("LoadManager should restore the options of the automatic reordering and Outofmemory")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Memo is a registry of results of (top-level) operations, keyed by the name
+// of an operation and its operands, under the explicit control of the user.
+// Unlike the operation caches of a BDD, that are invalidated after each garbage
+// collection or resize, entries in a Memo stay valid until they are removed
+// with Forget or Clear. This is useful, for instance, in fixpoint computations
+// that re-check the same invariant at each iteration.
+//
+// Since BDDs are canonical, the id of a node is a fingerprint of the function
+// it denotes. A Memo keeps a reference to the operands and to the result of each
+// entry, so that their ids cannot be reused for other nodes; this also means
+// that these nodes cannot be reclaimed as long as the entry exists.
+type Memo struct {
+	b       *BDD
+	entries map[string]memoentry
+	hits    int
+	misses  int
+}
+
+type memoentry struct {
+	op   string
+	args []Node // keep a reference to the operands
+	res  Node
+}
+
+// NewMemo returns an empty registry of results for b.
+func (b *BDD) NewMemo() *Memo {
+	return &Memo{b: b, entries: make(map[string]memoentry)}
+}
+
+// memokey returns the key associated with operation op and operands args, or
+// false if one of the operands is not valid.
+func (m *Memo) memokey(op string, args []Node) (string, bool) {
+	var sb strings.Builder
+	sb.WriteString(op)
+	for _, n := range args {
+		if m.b.checkptr(n) != nil {
+			return "", false
+		}
+		sb.WriteByte(0)
+		sb.WriteString(strconv.Itoa(*n))
+	}
+	return sb.String(), true
+}
+
+// Get returns the result recorded for operation op with operands args, and
+// true, or nil and false if there is none.
+func (m *Memo) Get(op string, args ...Node) (Node, bool) {
+	key, ok := m.memokey(op, args)
+	if !ok {
+		return nil, false
+	}
+	e, ok := m.entries[key]
+	if !ok {
+		m.misses++
+		return nil, false
+	}
+	m.hits++
+	return e.res, true
+}
+
+// Put records res as the result of operation op with operands args. We ignore
+// the call if one of the nodes is not valid, for instance if res is nil
+// because the operation failed.
+func (m *Memo) Put(op string, res Node, args ...Node) {
+	key, ok := m.memokey(op, args)
+	if !ok || m.b.checkptr(res) != nil {
+		return
+	}
+	m.entries[key] = memoentry{op: op, args: append([]Node{}, args...), res: res}
+}
+
+// Do returns the result recorded for operation op with operands args, if any.
+// Otherwise it calls f, records its result and returns it.
+func (m *Memo) Do(op string, f func() Node, args ...Node) Node {
+	if res, ok := m.Get(op, args...); ok {
+		return res
+	}
+	res := f()
+	m.Put(op, res, args...)
+	return res
+}
+
+// Forget removes all the entries for operation op.
+func (m *Memo) Forget(op string) {
+	for k, e := range m.entries {
+		if e.op == op {
+			delete(m.entries, k)
+		}
+	}
+}
+
+// Clear removes all the entries.
+func (m *Memo) Clear() {
+	m.entries = make(map[string]memoentry)
+}
+
+// Len returns the number of entries.
+func (m *Memo) Len() int {
+	return len(m.entries)
+}
+
+// Stats returns the number of calls to Get (or Do) that found an entry, and the
+// number of calls that did not.
+func (m *Memo) Stats() (hits, misses int) {
+	return m.hits, m.misses
+}
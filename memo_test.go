@@ -0,0 +1,50 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestMemo(t *testing.T) {
+	bdd, _ := New(10, Nodesize(50), Cachesize(20))
+	memo := bdd.NewMemo()
+	x := bdd.Ithvar(0)
+	calls := 0
+	f := func() Node {
+		calls++
+		n := x
+		for k := 1; k < 10; k++ {
+			n = bdd.Apply(n, bdd.Ithvar(k), OPxor)
+		}
+		return n
+	}
+	n := memo.Do("parity", f, x)
+	// we create garbage to trigger garbage collections and resizes
+	for k := 0; k < 20; k++ {
+		runtime.GC()
+		f()
+	}
+	calls = 0
+	if m := memo.Do("parity", f, x); calls != 0 || *m != *n {
+		t.Errorf("Do should return the recorded result after garbage collections")
+	}
+	if _, ok := memo.Get("parity", bdd.Ithvar(1)); ok {
+		t.Errorf("Get should not find an entry for other operands")
+	}
+	if hits, misses := memo.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("expected 1 hit and 2 misses, actual %d and %d", hits, misses)
+	}
+	memo.Put("not", bdd.Not(x), x)
+	memo.Forget("parity")
+	if _, ok := memo.Get("parity", x); ok || memo.Len() != 1 {
+		t.Errorf("Forget should remove the entries of an operation")
+	}
+	memo.Clear()
+	if memo.Len() != 0 {
+		t.Errorf("Clear should remove all the entries")
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/dalzilio/rudd/bitmap"
+)
+
+// ToBitmap returns the set of satisfying assignments of n, projected on the
+// variables in vars, as a compressed bitmap. Each assignment is encoded as an
+// integer where vars[0] gives the most significant bit and the last variable
+// in vars gives the least significant one. Hence there can be at most 32
+// variables in vars. The variables of n that are not in vars are
+// existentially quantified, meaning that we return the assignments of vars
+// that can be extended into a satisfying assignment of n. We do not enumerate
+// the values one by one when a constant is found and the remaining variables
+// are in the same order in vars and in the BDD; instead we add a whole range
+// of values at once. We return an error if n is not valid or if vars is not a
+// list of at most 32 distinct variables.
+func (b *BDD) ToBitmap(n Node, vars []int) (*bitmap.Bitmap, error) {
+	if err := b.checkptr(n); err != nil {
+		return nil, fmt.Errorf("wrong node in call to ToBitmap; %s", err)
+	}
+	if len(vars) > 32 {
+		return nil, fmt.Errorf("too many variables (%d) in call to ToBitmap", len(vars))
+	}
+	size := len(vars)
+	pos := make(map[int]int, size)
+	for k, v := range vars {
+		if v < 0 || v >= int(b.varnum) {
+			return nil, fmt.Errorf("unknown variable (%d) in call to ToBitmap", v)
+		}
+		if _, ok := pos[v]; ok {
+			return nil, fmt.Errorf("duplicate variable (%d) in call to ToBitmap", v)
+		}
+		pos[v] = k
+	}
+	others := []int{}
+	for v := 0; v < int(b.varnum); v++ {
+		if _, ok := pos[v]; !ok {
+			others = append(others, v)
+		}
+	}
+	m := n
+	if len(others) > 0 {
+		if m = b.Exist(n, b.Makeset(others)); m == nil {
+			return nil, fmt.Errorf("error in call to ToBitmap; %s", b.Error())
+		}
+	}
+	// we visit the variables by increasing level; weight[k] is the value of the
+	// bit of the k-th variable in this order, and tail[k] is true when the
+	// remaining variables give the least significant bits in decreasing order.
+	order := append([]int{}, vars...)
+	sort.Slice(order, func(i, j int) bool { return b.var2level[order[i]] < b.var2level[order[j]] })
+	weight := make([]uint64, size)
+	tail := make([]bool, size+1)
+	tail[size] = true
+	for k := size - 1; k >= 0; k-- {
+		weight[k] = 1 << (size - 1 - pos[order[k]])
+		tail[k] = tail[k+1] && weight[k] == 1<<(size-1-k)
+	}
+	res := &bitmap.Bitmap{}
+	var visit func(n, k int, index uint64)
+	visit = func(n, k int, index uint64) {
+		switch {
+		case n == 0:
+			return
+		case n == 1 && tail[k]:
+			res.AddRange(index, index+1<<(size-k))
+			return
+		}
+		low, high := n, n
+		if n > 1 && b.level(n) == b.var2level[order[k]] {
+			low, high = b.low(n), b.high(n)
+		}
+		visit(low, k+1, index)
+		visit(high, k+1, index|weight[k])
+	}
+	visit(*m, 0, 0)
+	return res, nil
+}
+
+// FromMinterIndices returns the node of the function whose satisfying
+// assignments, over the variables in vars, are the integers produced by it,
+// with the same encoding than in ToBitmap: vars[0] gives the most significant
+// bit of an assignment. Parameter it has the same type than iter.Seq[uint64]
+// and must produce the values in increasing order (duplicates are ignored).
+// Instead of computing a disjunction for each minterm, we build the BDD bottom
+// up, in a single pass, so that consecutive values (intervals) are merged as
+// soon as they are complete. When vars is not in the same order than the
+// variables of the BDD, we need to collect and sort the values first. We
+// return nil, and set the error flag of b, if a value is out of range or not
+// sorted, or if vars is not a list of at most 64 distinct variables.
+func (b *BDD) FromMinterIndices(vars []int, it func(yield func(uint64) bool)) (result Node) {
+	if b.journal != nil {
+		// we record the values produced by it, since it can be used only once
+		values := []uint64{}
+		source := it
+		it = func(yield func(uint64) bool) {
+			source(func(x uint64) bool {
+				values = append(values, x)
+				return yield(x)
+			})
+		}
+		defer func() { b.record(result, "fromminterindices", vars, values) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("FromMinterIndices")
+	}
+	size := len(vars)
+	if size > 64 {
+		return b.seterror("too many variables (%d) in call to FromMinterIndices", size)
+	}
+	it = b.guarditer(it)
+	seen := make(map[int]bool, size)
+	for _, v := range vars {
+		if v < 0 || v >= int(b.varnum) {
+			return b.seterror("unknown variable (%d) in call to FromMinterIndices", v)
+		}
+		if seen[v] {
+			return b.seterror("duplicate variable (%d) in call to FromMinterIndices", v)
+		}
+		seen[v] = true
+	}
+	// perm[k] is the position in vars of the k-th variable by increasing level
+	perm := make([]int, size)
+	for k := range perm {
+		perm[k] = k
+	}
+	sort.Slice(perm, func(i, j int) bool { return b.var2level[vars[perm[i]]] < b.var2level[vars[perm[j]]] })
+	levels := make([]int32, size)
+	sorted := true
+	for k, p := range perm {
+		levels[k] = b.var2level[vars[p]]
+		sorted = sorted && p == k
+	}
+	inrange := func(x uint64) bool {
+		return size == 64 || x>>size == 0
+	}
+	if !sorted {
+		// we encode the values with the bits in the order of levels
+		values := []uint64{}
+		bad := false
+		it(func(x uint64) bool {
+			if bad = !inrange(x); bad {
+				return false
+			}
+			y := uint64(0)
+			for _, p := range perm {
+				y = y<<1 | (x>>(size-1-p))&1
+			}
+			values = append(values, y)
+			return true
+		})
+		if bad {
+			return b.seterror("value out of range in call to FromMinterIndices")
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		it = func(yield func(uint64) bool) {
+			for _, x := range values {
+				if !yield(x) {
+					return
+				}
+			}
+		}
+	}
+	// We keep the path to the last value, where ishigh[k] is the k-th bit of
+	// this value. When ishigh[k] is true, the node for the low half of the
+	// subtree at depth k, that is already complete, is in the refstack at
+	// position base+k.
+	b.Initref()
+	base := len(b.refstack)
+	for k := 0; k < size; k++ {
+		b.Pushref(0)
+	}
+	ishigh := make([]bool, size)
+	finish := func(k, n int) int {
+		b.Pushref(n)
+		var res int
+		if ishigh[k] {
+			res = b.makenode(levels[k], b.refstack[base+k], n)
+		} else {
+			res = b.makenode(levels[k], n, 0)
+		}
+		b.Popref(1)
+		return res
+	}
+	var prev uint64
+	first, failed := true, ""
+	it(func(x uint64) bool {
+		switch {
+		case !inrange(x):
+			failed = "value out of range"
+			return false
+		case first:
+			first = false
+		case x == prev:
+			return true
+		case x < prev:
+			failed = "values not sorted"
+			return false
+		default:
+			// d is the first bit, from the most significant one, where x and
+			// prev differ; all the subtrees below depth d are complete.
+			d := bits.LeadingZeros64(prev^x) - (64 - size)
+			n := 1
+			for k := size - 1; k > d; k-- {
+				n = finish(k, n)
+			}
+			b.refstack[base+d] = n
+			// the subtrees of x below depth d have no other values yet
+			for k := d + 1; k < size; k++ {
+				b.refstack[base+k] = 0
+			}
+		}
+		for k := range ishigh {
+			ishigh[k] = (x>>(size-1-k))&1 == 1
+		}
+		prev = x
+		return true
+	})
+	if failed != "" {
+		b.Popref(size)
+		return b.seterror("%s in call to FromMinterIndices", failed)
+	}
+	if first {
+		b.Popref(size)
+		return bddzero
+	}
+	n := 1
+	for k := size - 1; k >= 0; k-- {
+		n = finish(k, n)
+	}
+	b.Popref(size)
+	return b.Retnode(n)
+}
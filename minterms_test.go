@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+func TestToBitmap(t *testing.T) {
+	bdd, _ := New(6, Nodesize(1000), Cachesize(1000))
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.NIthvar(2), bdd.Ithvar(5)), bdd.And(bdd.Ithvar(1), bdd.Ithvar(3)))
+	for _, vars := range [][]int{{0, 1, 2, 3, 4, 5}, {5, 4, 3, 2, 1, 0}, {3, 0, 5}, {}} {
+		bm, err := bdd.ToBitmap(n, vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// we compute the expected set by enumerating all the assignments
+		expected := make(map[uint32]bool)
+		assignment := make([]bool, 6)
+		for k := 0; k < 64; k++ {
+			for v := range assignment {
+				assignment[v] = k&(1<<v) != 0
+			}
+			if ok, _ := bdd.Eval(n, assignment); ok {
+				index := uint32(0)
+				for _, v := range vars {
+					index <<= 1
+					if assignment[v] {
+						index |= 1
+					}
+				}
+				expected[index] = true
+			}
+		}
+		if bm.Cardinality() != uint64(len(expected)) {
+			t.Errorf("ToBitmap(%v): expected %d values, actual %d", vars, len(expected), bm.Cardinality())
+		}
+		for x := range expected {
+			if !bm.Contains(x) {
+				t.Errorf("ToBitmap(%v): expected %d in the result", vars, x)
+			}
+		}
+	}
+	bm, _ := bdd.ToBitmap(bdd.True(), []int{0, 1, 2, 3, 4, 5})
+	if arrays, bitsets := bm.Containers(); bm.Cardinality() != 64 || arrays != 1 || bitsets != 0 {
+		t.Errorf("expected 64 values in one array, actual %d", bm.Cardinality())
+	}
+	if _, err := bdd.ToBitmap(n, []int{0, 0}); err == nil {
+		t.Error("expected an error with a duplicate variable")
+	}
+	if _, err := bdd.ToBitmap(nil, []int{0}); err == nil {
+		t.Error("expected an error with a nil node")
+	}
+}
+
+func TestFromMinterIndices(t *testing.T) {
+	bdd, _ := New(6, Nodesize(1000), Cachesize(1000))
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.NIthvar(2), bdd.Ithvar(5)), bdd.And(bdd.Ithvar(1), bdd.Ithvar(3)))
+	for _, vars := range [][]int{{0, 1, 2, 3, 4, 5}, {5, 4, 3, 2, 1, 0}, {3, 0, 5, 1, 4, 2}} {
+		bm, _ := bdd.ToBitmap(n, vars)
+		m := bdd.FromMinterIndices(vars, func(yield func(uint64) bool) {
+			bm.Iterate(func(x uint32) bool { return yield(uint64(x)) })
+		})
+		if !bdd.Equal(n, m) {
+			t.Errorf("FromMinterIndices(%v): expected the initial node", vars)
+		}
+	}
+	// values over a subset of the variables, with a complete interval
+	seq := func(values ...uint64) func(yield func(uint64) bool) {
+		return func(yield func(uint64) bool) {
+			for _, x := range values {
+				if !yield(x) {
+					return
+				}
+			}
+		}
+	}
+	m := bdd.FromMinterIndices([]int{1, 4}, seq(0, 1, 1, 3))
+	expected := bdd.Or(bdd.NIthvar(1), bdd.Ithvar(4))
+	if !bdd.Equal(m, expected) {
+		t.Error("FromMinterIndices([1 4], {0, 1, 3}): unexpected result")
+	}
+	if bdd.FromMinterIndices([]int{0}, seq()) != bdd.False() {
+		t.Error("expected False with no values")
+	}
+	if bdd.FromMinterIndices([]int{0, 1, 2}, seq(0, 1, 2, 3, 4, 5, 6, 7)) != bdd.True() {
+		t.Error("expected True with all the values")
+	}
+	if bdd.Errored() {
+		t.Error(bdd.Error())
+	}
+	if bdd.FromMinterIndices([]int{0, 1}, seq(2, 1)) != nil || !bdd.Errored() {
+		t.Error("expected an error with values not sorted")
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Model is a description of a problem encoded using BDDs that can be saved, and
+// loaded back, as a whole with SaveModel and LoadModel. Besides a list of
+// functions, a model can carry the names of the variables, together with
+// named sets of variables (cubes), such as the ones used with Exist or AppEx,
+// and named substitutions (replacers). All fields are optional.
+type Model struct {
+	Roots     []Node              // Functions of the model
+	Varnames  []string            // Name of each variable, in the order of indices
+	Cubes     map[string]Node     // Named sets of variables, for instance built with Makeset
+	Replacers map[string]Replacer // Named substitutions, built with NewReplacer
+}
+
+// Tags of the optional sections in the binary format used by Save.
+const (
+	_SECTIONVARNAMES  = 1
+	_SECTIONCUBES     = 2
+	_SECTIONREPLACERS = 3
+)
+
+// SaveModel writes model m to w, using the same binary format than SaveWith.
+// The roots of the model are the roots of the stream, while the variable
+// names, cubes and replacers are stored in optional sections; meaning that the
+// result can also be read using Load, in which case we only get the roots. We
+// return an error if one of the nodes is not valid, if there is not one name
+// for each variable, or if a replacer was not built with NewReplacer.
+func (b *BDD) SaveModel(w io.Writer, c Codec, m *Model) error {
+	roots := make([]int, len(m.Roots))
+	for k, v := range m.Roots {
+		if err := b.checkptr(v); err != nil {
+			return fmt.Errorf("wrong node in call to SaveModel; %s", err)
+		}
+		roots[k] = *v
+	}
+	if m.Varnames != nil && len(m.Varnames) != int(b.varnum) {
+		return fmt.Errorf("wrong number of variable names (%d) in call to SaveModel", len(m.Varnames))
+	}
+	cubes := make([]string, 0, len(m.Cubes))
+	for name := range m.Cubes {
+		cubes = append(cubes, name)
+	}
+	sort.Strings(cubes)
+	extra := make([]int, len(cubes))
+	for k, name := range cubes {
+		if err := b.checkptr(m.Cubes[name]); err != nil {
+			return fmt.Errorf("wrong node for cube %q in call to SaveModel; %s", name, err)
+		}
+		extra[k] = *m.Cubes[name]
+	}
+	replacers := make([]string, 0, len(m.Replacers))
+	for name, r := range m.Replacers {
+		if _, ok := r.(*replacer); !ok {
+			return fmt.Errorf("replacer %q was not built with NewReplacer in call to SaveModel", name)
+		}
+		replacers = append(replacers, name)
+	}
+	sort.Strings(replacers)
+
+	sections := func(id map[int]int) []byte {
+		var res []byte
+		section := func(tag int, content []byte) {
+			res = binary.AppendUvarint(res, uint64(tag))
+			res = binary.AppendUvarint(res, uint64(len(content)))
+			res = append(res, content...)
+		}
+		putstring := func(buf []byte, s string) []byte {
+			return append(binary.AppendUvarint(buf, uint64(len(s))), s...)
+		}
+		if m.Varnames != nil {
+			buf := binary.AppendUvarint(nil, uint64(len(m.Varnames)))
+			for _, s := range m.Varnames {
+				buf = putstring(buf, s)
+			}
+			section(_SECTIONVARNAMES, buf)
+		}
+		if len(cubes) > 0 {
+			buf := binary.AppendUvarint(nil, uint64(len(cubes)))
+			for k, name := range cubes {
+				buf = putstring(buf, name)
+				buf = binary.AppendUvarint(buf, uint64(id[extra[k]]))
+			}
+			section(_SECTIONCUBES, buf)
+		}
+		if len(replacers) > 0 {
+			buf := binary.AppendUvarint(nil, uint64(len(replacers)))
+			for _, name := range replacers {
+				buf = putstring(buf, name)
+				r := m.Replacers[name].(*replacer)
+				pairs := []int{}
+				for v, w := range r.vimage {
+					if int32(v) != w {
+						pairs = append(pairs, v, int(w))
+					}
+				}
+				buf = binary.AppendUvarint(buf, uint64(len(pairs)/2))
+				for _, v := range pairs {
+					buf = binary.AppendUvarint(buf, uint64(v))
+				}
+			}
+			section(_SECTIONREPLACERS, buf)
+		}
+		return res
+	}
+	return b.save(w, c, roots, extra, sections)
+}
+
+// LoadModel reads a model written with SaveModel from r. The cubes and
+// replacers of the result belong to b. A stream written with Save gives a
+// model with only roots. We return an error in the same cases than Load, or
+// if the optional sections are not well-formed.
+func (b *BDD) LoadModel(r io.Reader) (*Model, error) {
+	if b.sealed != nil {
+		return nil, fmt.Errorf("error in call to LoadModel; %w", ErrSealed)
+	}
+	roots, node, sections, err := b.load(r, false)
+	if err != nil {
+		return nil, fmt.Errorf("error in call to LoadModel; %s", err)
+	}
+	res := &Model{Roots: roots}
+	for tag, content := range sections {
+		var err error
+		switch tag {
+		case _SECTIONVARNAMES:
+			err = res.loadvarnames(content)
+		case _SECTIONCUBES:
+			err = res.loadcubes(content, node)
+		case _SECTIONREPLACERS:
+			err = res.loadreplacers(content, b)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error in call to LoadModel; bad section %d, %s", tag, err)
+		}
+	}
+	return res, nil
+}
+
+// sectionReader is used to decode the content of a section.
+type sectionReader struct {
+	*bytes.Reader
+}
+
+func (r sectionReader) get(bound int) (int, error) {
+	v, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil && v >= uint64(bound) {
+		err = fmt.Errorf("value out of range (%d)", v)
+	}
+	return int(v), err
+}
+
+func (r sectionReader) getstring() (string, error) {
+	n, err := r.get(r.Len() + 1)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+func (m *Model) loadvarnames(content []byte) error {
+	r := sectionReader{bytes.NewReader(content)}
+	n, err := r.get(len(content) + 1)
+	if err != nil {
+		return err
+	}
+	m.Varnames = make([]string, n)
+	for k := range m.Varnames {
+		if m.Varnames[k], err = r.getstring(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Model) loadcubes(content []byte, node func(int) Node) error {
+	r := sectionReader{bytes.NewReader(content)}
+	n, err := r.get(len(content) + 1)
+	if err != nil {
+		return err
+	}
+	m.Cubes = make(map[string]Node, n)
+	for k := 0; k < n; k++ {
+		name, err := r.getstring()
+		if err != nil {
+			return err
+		}
+		id, err := r.get(math.MaxInt32)
+		if err != nil {
+			return err
+		}
+		if m.Cubes[name] = node(id); m.Cubes[name] == nil {
+			return fmt.Errorf("unknown node (%d) for cube %q", id, name)
+		}
+	}
+	return nil
+}
+
+func (m *Model) loadreplacers(content []byte, b *BDD) error {
+	r := sectionReader{bytes.NewReader(content)}
+	n, err := r.get(len(content) + 1)
+	if err != nil {
+		return err
+	}
+	m.Replacers = make(map[string]Replacer, n)
+	for k := 0; k < n; k++ {
+		name, err := r.getstring()
+		if err != nil {
+			return err
+		}
+		size, err := r.get(len(content) + 1)
+		if err != nil {
+			return err
+		}
+		oldvars := make([]int, size)
+		newvars := make([]int, size)
+		for i := 0; i < size; i++ {
+			if oldvars[i], err = r.get(int(b.varnum)); err != nil {
+				return err
+			}
+			if newvars[i], err = r.get(int(b.varnum)); err != nil {
+				return err
+			}
+		}
+		if m.Replacers[name], err = b.NewReplacer(oldvars, newvars); err != nil {
+			return fmt.Errorf("replacer %q, %s", name, err)
+		}
+	}
+	return nil
+}
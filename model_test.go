@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSaveModel(t *testing.T) {
+	bdd, _ := New(4)
+	f := bdd.And(bdd.Ithvar(0), bdd.Ithvar(1))
+	r, _ := bdd.NewReplacer([]int{0, 1}, []int{2, 3})
+	m := &Model{
+		Roots:     []Node{f},
+		Varnames:  []string{"x", "y", "x'", "y'"},
+		Cubes:     map[string]Node{"current": bdd.Makeset([]int{0, 1}), "next": bdd.Makeset([]int{2, 3})},
+		Replacers: map[string]Replacer{"prime": r},
+	}
+	var buf bytes.Buffer
+	if err := bdd.SaveModel(&buf, Gzip, m); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	other, _ := New(5)
+	res, err := other.LoadModel(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Varnames) != 4 || res.Varnames[2] != "x'" {
+		t.Errorf("LoadModel, wrong variable names %v", res.Varnames)
+	}
+	if !other.Equal(res.Cubes["next"], other.Makeset([]int{2, 3})) || len(res.Cubes) != 2 {
+		t.Errorf("LoadModel, wrong cubes")
+	}
+	g := other.Replace(res.Roots[0], res.Replacers["prime"])
+	if !other.Equal(g, other.And(other.Ithvar(2), other.Ithvar(3))) {
+		t.Errorf("LoadModel, wrong replacer")
+	}
+	// the stream can also be read with Load
+	roots, err := other.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || !other.Equal(roots[0], res.Roots[0]) {
+		t.Errorf("Load of a model should return its roots")
+	}
+	// and a stream written with Save gives a model without metadata
+	buf.Reset()
+	bdd.Save(&buf, f)
+	if res, err := other.LoadModel(&buf); err != nil || len(res.Roots) != 1 || res.Cubes != nil {
+		t.Errorf("LoadModel of a simple stream, unexpected result %v (%v)", res, err)
+	}
+	m.Varnames = m.Varnames[:2]
+	if err := bdd.SaveModel(&buf, nil, m); err == nil {
+		t.Errorf("SaveModel should fail with a wrong number of variable names")
+	}
+}
@@ -7,62 +7,143 @@ package rudd
 import (
 	"fmt"
 	"log"
+	"math"
 	"math/big"
+	"sort"
 )
 
-// Scanset returns the set of variables (levels) found when following the high
-// branch of node n. This is the dual of function Makeset. The result may be nil
-// if there is an error and it is sorted following the natural order between
-// levels.
+// Scanset returns the set of variables occurring in the cube n, meaning a
+// conjunction of literals such as the ones built with Makeset or Makecube. This
+// is the dual of function Makeset. The result is nil, and we set the error
+// condition in b, if there is an error or if n is not a cube (see IsCube). It
+// is sorted following the order between levels (which is also the natural
+// order between variables if the variable order has not been changed). The
+// result is an empty slice if n is True. Use Scanlits to also get the polarity
+// of the variables.
 func (b *BDD) Scanset(n Node) []int {
 	if b.checkptr(n) != nil {
 		return nil
 	}
-	if *n < 2 {
+	if !b.iscube(*n) {
+		b.seterror("node %d is not a cube in call to Scanset", *n)
 		return nil
 	}
 	res := []int{}
-	for i := *n; i > 1; i = b.high(i) {
-		res = append(res, int(b.level(i)))
+	for i := *n; i > 1; {
+		res = append(res, int(b.level2var[b.level(i)]))
+		if low := b.low(i); low != 0 {
+			i = low
+		} else {
+			i = b.high(i)
+		}
 	}
 	return res
 }
 
+// IsCube returns true if n is a cube, that is a conjunction of literals (with
+// a positive or negative polarity), like the result of Makeset or Makecube. The
+// constant True is the empty cube, whereas False is not a cube. We return false
+// if n is not a valid node.
+func (b *BDD) IsCube(n Node) bool {
+	if b.checkptr(n) != nil {
+		return false
+	}
+	return b.iscube(*n)
+}
+
+// iscube checks that, for every node along the path from n, exactly one of the
+// successors is False, and that the path ends with True.
+func (b *BDD) iscube(n int) bool {
+	for n > 1 {
+		low, high := b.low(n), b.high(n)
+		switch {
+		case low == 0:
+			n = high
+		case high == 0:
+			n = low
+		default:
+			return false
+		}
+	}
+	return n == 1
+}
+
+// levelsort returns the levels of the variables in varset, sorted in
+// increasing order, together with a permutation perm such that level[k] is the
+// level of variable varset[perm[k]]. We return an error if one of the
+// variables is outside the interval [0..Varnum).
+func (b *BDD) levelsort(varset []int) ([]int32, []int, error) {
+	levels := make([]int32, len(varset))
+	perm := make([]int, len(varset))
+	for k, v := range varset {
+		if (v < 0) || (int32(v) >= b.varnum) {
+			return nil, nil, fmt.Errorf("unknown variable (%d)", v)
+		}
+		levels[k] = b.var2level[v]
+		perm[k] = k
+	}
+	sort.Slice(perm, func(i, j int) bool { return levels[perm[i]] < levels[perm[j]] })
+	sorted := make([]int32, len(varset))
+	for k, p := range perm {
+		sorted[k] = levels[p]
+	}
+	return sorted, perm, nil
+}
+
 // Makeset returns a node corresponding to the conjunction of all the variables
-// in varset, in their positive form. It is such that scanset(Makeset(a)) == a.
-// It returns False and sets the error condition in b if one of the variables is
-// outside the scope of the BDD (see documentation for function *Ithvar*).
-func (b *BDD) Makeset(varset []int) Node {
-	// res := bddone
-	// for _, level := range varset {
-	// 	// FIXME: should find a way to do it without adding references
-	// 	tmp := b.Apply(res, b.Ithvar(level), OPand)
-	// 	if b.error != nil {
-	// 		return bddzero
-	// 	}
-	// 	res = tmp
-	// }
-	// return res
+// in varset, in their positive form. It is such that scanset(Makeset(a)) == a
+// (up-to the order of the variables). It returns nil and sets the error
+// condition in b if one of the variables is outside the scope of the BDD (see
+// documentation for function *Ithvar*). Use Makelits to build cubes with
+// negative literals.
+func (b *BDD) Makeset(varset []int) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "makeset", varset) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Makeset")
+	}
+	levels, _, err := b.levelsort(varset)
+	if err != nil {
+		return b.seterror("%s in call to Makeset", err)
+	}
 	res := 1
 	b.Initref()
-	for k := len(varset) - 1; k >= 0; k-- {
-		res = b.Makenode(int32(varset[k]), 0, res)
-		b.Pushref(res)
+	for k := len(levels) - 1; k >= 0; k-- {
+		if k > 0 && levels[k] == levels[k-1] {
+			continue
+		}
+		res = b.Pushref(b.makenode(levels[k], 0, res))
 	}
-	b.Popref(len(varset))
+	b.Initref()
 	return b.Retnode(res)
 }
 
 // Makecube returns a node corresponding to the conjunction (the cube) of all
 // the variables in varset. Unlike with Makeset, variable varset[k] occurs in
 // positive form in the result if polarity[k] is true, and in negative form if
-// false. It returns nil and sets the error condition if the length of varset
-// and polarity are different. As a special case, when varset is empty
-// (len(varset) == 0), we consider that polarity operates over all the variables
-// in b (and therefore we expect that len(polarity) == Varnum). This method is
-// more efficient than using Apply iteratively. The computation may panic if
-// indices in varset are not sorted by order of increasing values.
-func (b *BDD) Makecube(varset []int, polarity []bool) Node {
+// false. A variable can occur several times in varset, but always with the
+// same polarity. It returns nil and sets the error condition if the length of
+// varset and polarity are different, if one of the variables is outside the
+// scope of the BDD, or if it occurs with both polarities. As a special case, when varset is empty (len(varset) == 0), we
+// consider that polarity operates over all the variables in b (and therefore
+// we expect that len(polarity) == Varnum). This method is more efficient than
+// using Apply iteratively.
+func (b *BDD) Makecube(varset []int, polarity []bool) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "makecube", varset, polarity) }()
+	}
+	return b.buildcube(varset, polarity)
+}
+
+// buildcube is the version of Makecube that is not recorded in the journal,
+// used also by Makelits.
+func (b *BDD) buildcube(varset []int, polarity []bool) (result Node) {
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Makecube")
+	}
 	res := 1
 	if len(varset) == 0 {
 		if len(polarity) != int(b.varnum) {
@@ -70,10 +151,10 @@ func (b *BDD) Makecube(varset []int, polarity []bool) Node {
 		}
 		b.Initref()
 		for k := len(polarity) - 1; k >= 0; k-- {
-			if polarity[k] {
-				res = b.Makenode(int32(k), 0, res)
+			if polarity[b.level2var[k]] {
+				res = b.makenode(int32(k), 0, res)
 			} else {
-				res = b.Makenode(int32(k), res, 0)
+				res = b.makenode(int32(k), res, 0)
 			}
 			b.Pushref(res)
 		}
@@ -83,23 +164,41 @@ func (b *BDD) Makecube(varset []int, polarity []bool) Node {
 	if len(varset) != len(polarity) {
 		return b.seterror("wrong size for slices in Makecube")
 	}
+	levels, perm, err := b.levelsort(varset)
+	if err != nil {
+		return b.seterror("%s in call to Makecube", err)
+	}
 	b.Initref()
-	for k := len(polarity) - 1; k >= 0; k-- {
-		if polarity[k] {
-			res = b.Makenode(int32(varset[k]), 0, res)
+	for k := len(levels) - 1; k >= 0; k-- {
+		if k > 0 && levels[k] == levels[k-1] {
+			if polarity[perm[k]] != polarity[perm[k-1]] {
+				b.Initref()
+				return b.seterror("variable %d occurs with both polarities in call to Makecube", varset[perm[k]])
+			}
+			continue
+		}
+		if polarity[perm[k]] {
+			res = b.makenode(levels[k], 0, res)
 		} else {
-			res = b.Makenode(int32(varset[k]), res, 0)
+			res = b.makenode(levels[k], res, 0)
 		}
 		b.Pushref(res)
 	}
-	b.Popref(len(polarity))
+	b.Initref()
 	return b.Retnode(res)
 }
 
 // Not returns the negation of the expression corresponding to node n; it
 // computes the result of !n. We negate a BDD by exchanging all references to
 // the zero-terminal with references to the one-terminal and vice versa.
-func (b *BDD) Not(n Node) Node {
+func (b *BDD) Not(n Node) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "not", n) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Not")
+	}
 	if b.checkptr(n) != nil {
 		return b.seterror("Wrong operand in call to Not (%d)", *n)
 	}
@@ -123,7 +222,7 @@ func (b *BDD) not(n int) int {
 	}
 	low := b.Pushref(b.not(b.low(n)))
 	high := b.Pushref(b.not(b.high(n)))
-	res := b.Makenode(b.level(n), low, high)
+	res := b.makenode(b.level(n), low, high)
 	b.Popref(2)
 	return b.setnot(n, res)
 }
@@ -143,13 +242,24 @@ func (b *BDD) not(n int) int {
 //    OPdiff        set difference           [0,0,1,0]
 //    OPless        less than                [0,1,0,0]
 //    OPinvimp      reverse implication      [1,0,1,1]
-func (b *BDD) Apply(n1, n2 Node, op Operator) Node {
+func (b *BDD) Apply(n1, n2 Node, op Operator) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "apply", op, n1, n2) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Apply")
+	}
 	if b.checkptr(n1) != nil {
 		return b.seterror("Wrong operand in call to Apply %s(n1: %d, n2: ...)", op, *n1)
 	}
 	if b.checkptr(n2) != nil {
 		return b.seterror("Wrong operand in call to Apply %s(n1: ..., n2: %d)", op, *n2)
 	}
+	if op < OPand || op > OPinvimp {
+		// unary operations, such as opnot, should not be used in Apply
+		return b.seterror("Unauthorized operation (%d) in call to Apply", op)
+	}
 	b.applycache.op = int(op)
 	b.Initref()
 	b.Pushref(*n1)
@@ -232,11 +342,11 @@ func (b *BDD) apply(left int, right int) int {
 		if left == right {
 			return 0
 		}
-		if right == 1 {
+		if (left == 0) || (right == 1) {
 			return 0
 		}
-		if left == 0 {
-			return right
+		if right == 0 {
+			return left
 		}
 	case OPless:
 		if (left == right) || (left == 1) {
@@ -285,16 +395,16 @@ func (b *BDD) apply(left int, right int) int {
 	if leftlvl == rightlvl {
 		low := b.Pushref(b.apply(b.low(left), b.low(right)))
 		high := b.Pushref(b.apply(b.high(left), b.high(right)))
-		res = b.Makenode(leftlvl, low, high)
+		res = b.makenode(leftlvl, low, high)
 	} else {
 		if leftlvl < rightlvl {
 			low := b.Pushref(b.apply(b.low(left), right))
 			high := b.Pushref(b.apply(b.high(left), right))
-			res = b.Makenode(leftlvl, low, high)
+			res = b.makenode(leftlvl, low, high)
 		} else {
 			low := b.Pushref(b.apply(left, b.low(right)))
 			high := b.Pushref(b.apply(left, b.high(right)))
-			res = b.Makenode(rightlvl, low, high)
+			res = b.makenode(rightlvl, low, high)
 		}
 	}
 	b.Popref(2)
@@ -303,7 +413,14 @@ func (b *BDD) apply(left int, right int) int {
 
 // Ite (short for if-then-else operator) computes the BDD for the expression [(f
 // & g) | (!f & h)] more efficiently than doing the three operations separately.
-func (b *BDD) Ite(f, g, h Node) Node {
+func (b *BDD) Ite(f, g, h Node) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "ite", f, g, h) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Ite")
+	}
 	if b.checkptr(f) != nil {
 		return b.seterror("Wrong operand in call to Ite (f: %d)", *f)
 	}
@@ -383,15 +500,92 @@ func (b *BDD) ite(f, g, h int) int {
 	r := b.level(h)
 	low := b.Pushref(b.ite(b.iteLow(p, q, r, f), b.iteLow(q, p, r, g), b.iteLow(r, p, q, h)))
 	high := b.Pushref(b.ite(b.iteHigh(p, q, r, f), b.iteHigh(q, p, r, g), b.iteHigh(r, p, q, h)))
-	res := b.Makenode(min3(p, q, r), low, high)
+	res := b.makenode(min3(p, q, r), low, high)
 	b.Popref(2)
 	return b.setite(f, g, h, res)
 }
 
+// Simplify tries to simplify the BDD f by restricting it to the domain covered
+// by d, meaning that the result agrees with f on all the assignments that
+// satisfy d, but can take any value outside of d (d is a care set and its
+// negation is a set of don't cares). The result is usually smaller than f,
+// but this is not guaranteed. This is the same operation than bdd_simplify in
+// BuDDy, also known as restrict.
+func (b *BDD) Simplify(f, d Node) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "simplify", f, d) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Simplify")
+	}
+	if b.checkptr(f) != nil {
+		return b.seterror("Wrong operand in call to Simplify (f: %d)", *f)
+	}
+	if b.checkptr(d) != nil {
+		return b.seterror("Wrong operand in call to Simplify (d: %d)", *d)
+	}
+	b.applycache.op = int(opsimplify)
+	b.Initref()
+	b.Pushref(*f)
+	b.Pushref(*d)
+	res := b.simplify(*f, *d)
+	b.Popref(2)
+	return b.Retnode(res)
+}
+
+func (b *BDD) simplify(f, d int) int {
+	switch {
+	case d == 1 || f < 2:
+		return f
+	case d == f:
+		return 1
+	case d == 0:
+		return 0
+	}
+	if res := b.matchapply(f, d); res >= 0 {
+		return res
+	}
+	var res int
+	lf, ld := b.level(f), b.level(d)
+	switch {
+	case lf == ld:
+		switch {
+		case b.low(d) == 0:
+			res = b.simplify(b.high(f), b.high(d))
+		case b.high(d) == 0:
+			res = b.simplify(b.low(f), b.low(d))
+		default:
+			low := b.Pushref(b.simplify(b.low(f), b.low(d)))
+			high := b.Pushref(b.simplify(b.high(f), b.high(d)))
+			res = b.makenode(lf, low, high)
+			b.Popref(2)
+		}
+	case lf < ld:
+		low := b.Pushref(b.simplify(b.low(f), d))
+		high := b.Pushref(b.simplify(b.high(f), d))
+		res = b.makenode(lf, low, high)
+		b.Popref(2)
+	default:
+		low := b.Pushref(b.simplify(f, b.low(d)))
+		high := b.Pushref(b.simplify(f, b.high(d)))
+		res = b.makenode(ld, low, high)
+		b.Popref(2)
+	}
+	return b.setapply(f, d, res)
+}
+
 // Exist returns the existential quantification of n for the variables in
 // varset, where varset is a node built with a method such as Makeset. We return
 // nil and set the error flag in b if there is an error.
-func (b *BDD) Exist(n, varset Node) Node {
+func (b *BDD) Exist(n, varset Node) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "exist", n, varset) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Exist")
+	}
 	if b.checkptr(n) != nil {
 		return b.seterror("Wrong node in call to Exist (n: %d)", *n)
 	}
@@ -415,6 +609,80 @@ func (b *BDD) Exist(n, varset Node) Node {
 	return b.Retnode(res)
 }
 
+// Unique returns the unique quantification of n for the variables in varset,
+// where varset is a node built with a method such as Makeset. This is the same
+// as Exist, except that the variables are abstracted using an exclusive or
+// instead of a disjunction; meaning that, for a single variable x, the result
+// is n[x:=0] ⊕ n[x:=1]. Hence an assignment satisfies the result when it has
+// an odd number of extensions satisfying n, which is useful for reasoning about
+// parity. Note that, unlike with BuDDy, the result is False when a variable of
+// varset does not occur in n, since n ⊕ n = 0. We return nil and set the error
+// flag in b if there is an error.
+func (b *BDD) Unique(n, varset Node) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "unique", n, varset) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Unique")
+	}
+	if b.checkptr(n) != nil {
+		return b.seterror("Wrong node in call to Unique")
+	}
+	if b.checkptr(varset) != nil {
+		return b.seterror("Wrong varset in call to Unique")
+	}
+	if *varset < 2 { // we have an empty set or a constant
+		return n
+	}
+	if err := b.quantset2cache(*varset); err != nil {
+		return nil
+	}
+	// qcount[l] is the number of quantified levels strictly smaller than l
+	qcount := make([]int32, b.varnum+1)
+	for l := int32(0); l < b.varnum; l++ {
+		qcount[l+1] = qcount[l]
+		if b.quantset[l] == b.quantsetID {
+			qcount[l+1]++
+		}
+	}
+	b.quantcache.id = cacheidUNIQUE
+	b.applycache.op = int(OPxor)
+	b.Initref()
+	b.Pushref(*n)
+	b.Pushref(*varset)
+	res := b.uniquefrom(*n, *varset, 0, qcount)
+	b.Popref(2)
+	return b.Retnode(res)
+}
+
+// uniquefrom returns the unique quantification of n for the quantified
+// variables with a level greater or equal to from. The result is False if
+// there is a quantified level between from and the level of n, since n does
+// not depend on this variable.
+func (b *BDD) uniquefrom(n, varset int, from int32, qcount []int32) int {
+	if qcount[b.level(n)] != qcount[from] {
+		return 0
+	}
+	if n < 2 {
+		return n
+	}
+	if res := b.matchquant(n, varset); res >= 0 {
+		return res
+	}
+	level := b.level(n)
+	low := b.Pushref(b.uniquefrom(b.low(n), varset, level+1, qcount))
+	high := b.Pushref(b.uniquefrom(b.high(n), varset, level+1, qcount))
+	var res int
+	if b.quantset[level] == b.quantsetID {
+		res = b.apply(low, high)
+	} else {
+		res = b.makenode(level, low, high)
+	}
+	b.Popref(2)
+	return b.setquant(n, varset, res)
+}
+
 func (b *BDD) quant(n, varset int) int {
 	if (n < 2) || (b.level(n) > b.quantlast) {
 		return n
@@ -429,7 +697,7 @@ func (b *BDD) quant(n, varset int) int {
 	if b.quantset[b.level(n)] == b.quantsetID {
 		res = b.apply(low, high)
 	} else {
-		res = b.Makenode(b.level(n), low, high)
+		res = b.makenode(b.level(n), low, high)
 	}
 	b.Popref(2)
 	return b.setquant(n, varset, res)
@@ -442,10 +710,17 @@ func (b *BDD) quant(n, varset int) int {
 // before stepping up to the higher nodes. This makes AppEx much more efficient
 // than an apply operation followed by a quantification. Note that, when *op* is
 // a conjunction, this operation returns the relational product of two BDDs.
-func (b *BDD) AppEx(n1, n2 Node, op Operator, varset Node) Node {
+func (b *BDD) AppEx(n1, n2 Node, op Operator, varset Node) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "appex", op, n1, n2, varset) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("AppEx")
+	}
 	// FIXME: should check that op is a binary operation
 	if int(op) > 3 {
-		return b.seterror("operator %s not supported in call to AppEx")
+		return b.seterror("operator %s not supported in call to AppEx", op)
 	}
 	if b.checkptr(varset) != nil {
 		return b.seterror("wrong varset in call to AppEx (%d)", *varset)
@@ -526,7 +801,7 @@ func (b *BDD) appquant(left, right, varset int) int {
 		// OPnot and OPsimplify should not be used in apply.
 		//
 		// FIXME: we are raising an error for other operations that would be OK.
-		b.seterror("unauthorized operation (%s) in AppEx", b.applycache.op)
+		b.seterror("unauthorized operation (%s) in AppEx", Operator(b.applycache.op))
 		return -1
 	}
 
@@ -563,7 +838,7 @@ func (b *BDD) appquant(left, right, varset int) int {
 		if b.quantset[leftlvl] == b.quantsetID {
 			res = b.apply(low, high)
 		} else {
-			res = b.Makenode(leftlvl, low, high)
+			res = b.makenode(leftlvl, low, high)
 		}
 	} else {
 		if leftlvl < rightlvl {
@@ -572,7 +847,7 @@ func (b *BDD) appquant(left, right, varset int) int {
 			if b.quantset[leftlvl] == b.quantsetID {
 				res = b.apply(low, high)
 			} else {
-				res = b.Makenode(leftlvl, low, high)
+				res = b.makenode(leftlvl, low, high)
 			}
 		} else {
 			low := b.Pushref(b.appquant(left, b.low(right), varset))
@@ -580,7 +855,7 @@ func (b *BDD) appquant(left, right, varset int) int {
 			if b.quantset[rightlvl] == b.quantsetID {
 				res = b.apply(low, high)
 			} else {
-				res = b.Makenode(rightlvl, low, high)
+				res = b.makenode(rightlvl, low, high)
 			}
 		}
 	}
@@ -590,10 +865,20 @@ func (b *BDD) appquant(left, right, varset int) int {
 
 // Replace takes a Replacer and computes the result of n after replacing old
 // variables with new ones. See type Replacer.
-func (b *BDD) Replace(n Node, r Replacer) Node {
+func (b *BDD) Replace(n Node, r Replacer) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "replace", n, r) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Replace")
+	}
 	if b.checkptr(n) != nil {
 		return b.seterror("wrong operand in call to Replace (%d)", *n)
 	}
+	if p, ok := r.(*replacer); ok && p.order != b.reorders {
+		p.setlevels(b)
+	}
 	b.Initref()
 	b.Pushref(*n)
 	b.replacecache.id = r.Id()
@@ -620,18 +905,20 @@ func (b *BDD) replace(n int, r Replacer) int {
 func (b *BDD) correctify(level int32, low, high int) int {
 	/* FIXME: we do not use the cache here */
 	if (level < b.level(low)) && (level < b.level(high)) {
-		return b.Makenode(level, low, high)
+		return b.makenode(level, low, high)
 	}
 
 	if (level == b.level(low)) || (level == b.level(high)) {
+		// we unwind the recursion, like with makenode, so that the error
+		// value is not used as a node or stored in the caches.
 		b.seterror("error in replace level (%d) == low (%d:%d) or high (%d:%d)", level, low, b.level(low), high, b.level(high))
-		return -1
+		panic(oomunwind{})
 	}
 
 	if b.level(low) == b.level(high) {
 		left := b.Pushref(b.correctify(level, b.low(low), b.low(high)))
 		right := b.Pushref(b.correctify(level, b.high(low), b.high(high)))
-		res := b.Makenode(b.level(low), left, right)
+		res := b.makenode(b.level(low), left, right)
 		b.Popref(2)
 		return res
 	}
@@ -639,15 +926,187 @@ func (b *BDD) correctify(level int32, low, high int) int {
 	if b.level(low) < b.level(high) {
 		left := b.Pushref(b.correctify(level, b.low(low), high))
 		right := b.Pushref(b.correctify(level, b.high(low), high))
-		res := b.Makenode(b.level(low), left, right)
+		res := b.makenode(b.level(low), left, right)
 		b.Popref(2)
 		return res
 	}
 
 	left := b.Pushref(b.correctify(level, low, b.low(high)))
 	right := b.Pushref(b.correctify(level, low, b.high(high)))
-	res := b.Makenode(b.level(high), left, right)
+	res := b.makenode(b.level(high), left, right)
+	b.Popref(2)
+	return res
+}
+
+// Compose returns the functional composition of f and g for variable v,
+// meaning the result of substituting the function g for the variable of index v
+// in f. This is the same as Ite(g, f[v/1], f[v/0]), where f[v/1] is the
+// restriction of f when v is true.
+func (b *BDD) Compose(f Node, v int, g Node) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "compose", f, v, g) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Compose")
+	}
+	if b.checkptr(f) != nil {
+		return b.seterror("Wrong operand in call to Compose (f: %d)", *f)
+	}
+	if b.checkptr(g) != nil {
+		return b.seterror("Wrong operand in call to Compose (g: %d)", *g)
+	}
+	if v < 0 || v >= int(b.varnum) {
+		return b.seterror("Unknown variable used (%d) in call to Compose", v)
+	}
+	level := b.var2level[v]
+	b.Initref()
+	b.Pushref(*f)
+	b.Pushref(*g)
+	b.replacecache.id = (int(level) << 2) | cacheidCOMPOSE
+	res := b.compose(*f, *g, level)
+	b.Popref(2)
+	return b.Retnode(res)
+}
+
+func (b *BDD) compose(f, g int, level int32) int {
+	lf := b.level(f)
+	if lf > level {
+		return f
+	}
+	if res := b.matchcompose(f, g); res >= 0 {
+		return res
+	}
+	var res int
+	if lf == level {
+		res = b.ite(g, b.high(f), b.low(f))
+		return b.setcompose(f, g, res)
+	}
+	lg := b.level(g)
+	switch {
+	case lf == lg:
+		low := b.Pushref(b.compose(b.low(f), b.low(g), level))
+		high := b.Pushref(b.compose(b.high(f), b.high(g), level))
+		res = b.makenode(lf, low, high)
+	case lf < lg:
+		low := b.Pushref(b.compose(b.low(f), g, level))
+		high := b.Pushref(b.compose(b.high(f), g, level))
+		res = b.makenode(lf, low, high)
+	default:
+		low := b.Pushref(b.compose(f, b.low(g), level))
+		high := b.Pushref(b.compose(f, b.high(g), level))
+		res = b.makenode(lg, low, high)
+	}
 	b.Popref(2)
+	return b.setcompose(f, g, res)
+}
+
+// VecCompose takes a Composer and computes the result of n after substituting
+// variables with functions, simultaneously. See type Composer.
+func (b *BDD) VecCompose(n Node, c Composer) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "veccompose", n, c) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("VecCompose")
+	}
+	if b.checkptr(n) != nil {
+		return b.seterror("Wrong operand in call to VecCompose (%d)", *n)
+	}
+	if p, ok := c.(*composer); ok && p.order != b.reorders {
+		p.setlevels(b)
+	}
+	b.Initref()
+	b.Pushref(*n)
+	b.replacecache.id = c.Id()
+	res := b.veccompose(*n, c)
+	b.Popref(1)
+	return b.Retnode(res)
+}
+
+func (b *BDD) veccompose(n int, c Composer) int {
+	g, ok := c.Compose(b.level(n))
+	if !ok {
+		return n
+	}
+	if res := b.matchreplace(n); res >= 0 {
+		return res
+	}
+	low := b.Pushref(b.veccompose(b.low(n), c))
+	high := b.Pushref(b.veccompose(b.high(n), c))
+	res := b.ite(g, high, low)
+	b.Popref(2)
+	return b.setreplace(n, res)
+}
+
+// AnodeCount returns the number of nodes in the graph shared by the roots in n,
+// not counting the two constants, like bdd_anodecount in BuDDy. A node
+// reachable from several roots is counted only once, so the result gives the
+// footprint of a set of BDDs, which is often far less than the sum of their
+// sizes. The result is zero (and we set the error flag of b) if there is an
+// error.
+func (b *BDD) AnodeCount(n ...Node) int {
+	for _, v := range n {
+		if b.checkptr(v) != nil {
+			b.seterror("Wrong operand in call to AnodeCount")
+			return 0
+		}
+	}
+	if len(n) == 0 {
+		return 0
+	}
+	count := 0
+	b.Allnodes(func(id, level, low, high int) error {
+		if id > 1 {
+			count++
+		}
+		return nil
+	}, n...)
+	return count
+}
+
+// RetainedSize returns, for each named root, the number of nodes (not
+// counting the two constants) that are reachable from this root but from none
+// of the other roots in the map. This is the number of nodes that could be
+// reclaimed if we dropped this root, provided the nodes are not used by some
+// other Node, and is useful to find which stored result is responsible for
+// the size of the node table. The sum of the results is at most
+// AnodeCount(roots...), and the difference gives the number of shared nodes.
+// Two names for the same node both have a retained size of zero. The result is
+// nil (and we set the error flag of b) if there is an error.
+func (b *BDD) RetainedSize(roots map[string]Node) map[string]int {
+	for name, v := range roots {
+		if b.checkptr(v) != nil {
+			b.seterror("Wrong operand (%s) in call to RetainedSize", name)
+			return nil
+		}
+	}
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	// owner[id] is the index of the only root from which id is reachable, or
+	// -1 if there are several.
+	owner := make(map[int]int)
+	for k, name := range names {
+		for _, id := range b.topo(*roots[name]) {
+			if o, ok := owner[id]; !ok {
+				owner[id] = k
+			} else if o != k {
+				owner[id] = -1
+			}
+		}
+	}
+	res := make(map[string]int, len(roots))
+	for _, name := range names {
+		res[name] = 0
+	}
+	for _, o := range owner {
+		if o >= 0 {
+			res[names[o]]++
+		}
+	}
 	return res
 }
 
@@ -667,6 +1126,38 @@ func (b *BDD) Satcount(n Node) *big.Int {
 	return res.Mul(res, b.satcount(*n, satc))
 }
 
+// SatcountSet computes the number of satisfying assignments of n over the
+// variables in varset, like bdd_satcountset in BuDDy, where varset is a set of
+// variables built with Makeset. This is useful for counting the solutions of a
+// projection, for instance the states of a system in a BDD that also has
+// variables for the next states. The result is zero (and we set the error flag
+// of b) if there is an error, for instance if n depends on a variable that is
+// not in varset.
+func (b *BDD) SatcountSet(n, varset Node) *big.Int {
+	res := big.NewInt(0)
+	if b.checkptr(n) != nil || b.checkptr(varset) != nil {
+		b.seterror("Wrong operand in call to SatcountSet")
+		return res
+	}
+	vars := b.Scanset(varset)
+	if vars == nil {
+		return res
+	}
+	in := make([]bool, b.varnum)
+	for _, v := range vars {
+		in[v] = true
+	}
+	for _, v := range b.SupportSet(n) {
+		if !in[v] {
+			b.seterror("Variable %d is not in varset in call to SatcountSet", v)
+			return res
+		}
+	}
+	// Satcount counts the assignments over all the variables, which is
+	// 2^(varnum - len(vars)) times more than what we want
+	return res.Rsh(b.Satcount(n), uint(int(b.varnum)-len(vars)))
+}
+
 func (b *BDD) satcount(n int, satc map[int]*big.Int) *big.Int {
 	if n < 2 {
 		return big.NewInt(int64(n))
@@ -691,6 +1182,155 @@ func (b *BDD) satcount(n int, satc map[int]*big.Int) *big.Int {
 	return res
 }
 
+// Satcountln returns the base 2 logarithm of the number of satisfying
+// assignments of n, like bdd_satcountln in BuDDy, and -Inf if n is False. The
+// result is computed using float64 arithmetic, in the log domain to avoid
+// overflows, which is much faster than Satcount when only the magnitude of the
+// result is needed, for instance in a heuristic. The result is zero (and we
+// set the error flag of b) if there is an error.
+func (b *BDD) Satcountln(n Node) float64 {
+	if b.checkptr(n) != nil {
+		b.seterror("Wrong operand in call to Satcountln")
+		return 0
+	}
+	return float64(b.level(*n)) + b.satcountln(*n, make(map[int]float64))
+}
+
+func (b *BDD) satcountln(n int, memo map[int]float64) float64 {
+	if n < 2 {
+		return math.Log2(float64(n))
+	}
+	if res, ok := memo[n]; ok {
+		return res
+	}
+	level := b.level(n)
+	low, high := b.low(n), b.high(n)
+	x := b.satcountln(low, memo) + float64(b.level(low)-level-1)
+	y := b.satcountln(high, memo) + float64(b.level(high)-level-1)
+	if x < y {
+		x, y = y, x
+	}
+	// we compute log2(2^x + 2^y) = x + log2(1 + 2^(y - x)), with x >= y
+	res := x
+	if !math.IsInf(y, -1) {
+		res += math.Log1p(math.Exp2(y-x)) / math.Ln2
+	}
+	memo[n] = res
+	return res
+}
+
+// Density returns the fraction of the 2^Varnum assignments that satisfy n,
+// computed using float64 arithmetic. The result may be rounded to zero when
+// it is smaller than the smallest float64; use Satcountln in this case. The
+// result is zero (and we set the error flag of b) if there is an error.
+func (b *BDD) Density(n Node) float64 {
+	if b.checkptr(n) != nil {
+		b.seterror("Wrong operand in call to Density")
+		return 0
+	}
+	return b.density(*n, make(map[int]float64))
+}
+
+func (b *BDD) density(n int, memo map[int]float64) float64 {
+	if n < 2 {
+		return float64(n)
+	}
+	if res, ok := memo[n]; ok {
+		return res
+	}
+	// the density does not depend on the variables that are skipped
+	res := (b.density(b.low(n), memo) + b.density(b.high(n), memo)) / 2
+	memo[n] = res
+	return res
+}
+
+// Varprofile returns, for each variable, the number of nodes labelled with
+// this variable in the graph shared by the roots in n, or among all the active
+// nodes if n is empty, like bdd_varprofile in BuDDy. The result is indexed by
+// variable, like the rest of the public API; use Var2Level to obtain the
+// profile by level. This is a good indicator of where a variable order leads
+// to a blow-up in size. We return nil, and set the error flag of b, if there
+// is an error.
+func (b *BDD) Varprofile(n ...Node) []int {
+	for _, v := range n {
+		if b.checkptr(v) != nil {
+			b.seterror("Wrong operand in call to Varprofile")
+			return nil
+		}
+	}
+	res := make([]int, b.varnum)
+	b.Allnodes(func(id, level, low, high int) error {
+		if id > 1 {
+			res[b.level2var[level]]++
+		}
+		return nil
+	}, n...)
+	return res
+}
+
+// PathCount returns the number of paths from n to the True constant, like
+// bdd_pathcount in BuDDy. Unlike Satcount, a path where a variable does not
+// occur is counted only once, so the result is the number of cubes in the
+// disjoint cover of n obtained by enumerating its paths, see Allsat. The
+// result is zero (and we set the error flag of b) if there is an error.
+func (b *BDD) PathCount(n Node) *big.Int {
+	if b.checkptr(n) != nil {
+		b.seterror("Wrong operand in call to PathCount")
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(b.pathcount(*n, make(map[int]*big.Int)))
+}
+
+func (b *BDD) pathcount(n int, memo map[int]*big.Int) *big.Int {
+	if n < 2 {
+		return big.NewInt(int64(n))
+	}
+	if res, ok := memo[n]; ok {
+		return res
+	}
+	res := new(big.Int).Add(b.pathcount(b.low(n), memo), b.pathcount(b.high(n), memo))
+	memo[n] = res
+	return res
+}
+
+// Prob returns the probability that n is true when each variable i is
+// independently true with probability p[i], for instance the reliability of a
+// network where variables stand for the links that are up. We compute the
+// result with the Shannon expansion of n, in time linear in its number of
+// nodes. Variables that do not occur in n are not relevant. The result is zero
+// (and we set the error flag of b) if there is an error, for instance if p
+// does not have one probability, between 0 and 1, for each variable.
+func (b *BDD) Prob(n Node, p []float64) float64 {
+	if b.checkptr(n) != nil {
+		b.seterror("Wrong operand in call to Prob")
+		return 0
+	}
+	if len(p) != int(b.varnum) {
+		b.seterror("Wrong number of probabilities (%d) in call to Prob", len(p))
+		return 0
+	}
+	for k, v := range p {
+		if !(v >= 0 && v <= 1) {
+			b.seterror("Wrong probability (%g) for variable %d in call to Prob", v, k)
+			return 0
+		}
+	}
+	return b.prob(*n, p, make(map[int]float64))
+}
+
+func (b *BDD) prob(n int, p []float64, memo map[int]float64) float64 {
+	if n < 2 {
+		return float64(n)
+	}
+	if res, ok := memo[n]; ok {
+		return res
+	}
+	v := p[b.level2var[b.level(n)]]
+	res := (1-v)*b.prob(b.low(n), p, memo) + v*b.prob(b.high(n), p, memo)
+	memo[n] = res
+	return res
+}
+
 // Allsat Iterates through all legal variable assignments for n and calls the
 // function f on each of them. We pass an int slice of length varnum to f where
 // each entry is either  0 if the variable is false, 1 if it is true, and -1 if
@@ -705,7 +1345,12 @@ func (b *BDD) Allsat(f func([]int) error, n Node) error {
 		prof[k] = -1
 	}
 	// the function does not create new nodes, so we do not need to take care of
-	// possible resizing
+	// possible resizing; but f can, and we should not reorder during the
+	// traversal.
+	if b.sealed == nil {
+		b.traversal++
+		defer func() { b.traversal-- }()
+	}
 	return b.allsat(*n, prof, f)
 }
 
@@ -718,9 +1363,9 @@ func (b *BDD) allsat(n int, prof []int, f func([]int) error) error {
 	}
 
 	if low := b.low(n); low != 0 {
-		prof[b.level(n)] = 0
+		prof[b.level2var[b.level(n)]] = 0
 		for v := b.level(low) - 1; v > b.level(n); v-- {
-			prof[v] = -1
+			prof[b.level2var[v]] = -1
 		}
 		if err := b.allsat(low, prof, f); err != nil {
 			return nil
@@ -728,9 +1373,9 @@ func (b *BDD) allsat(n int, prof []int, f func([]int) error) error {
 	}
 
 	if high := b.high(n); high != 0 {
-		prof[b.level(n)] = 1
+		prof[b.level2var[b.level(n)]] = 1
 		for v := b.level(high) - 1; v > b.level(n); v-- {
-			prof[v] = -1
+			prof[b.level2var[v]] = -1
 		}
 		if err := b.allsat(high, prof, f); err != nil {
 			return nil
@@ -754,10 +1399,29 @@ func (b *BDD) Allnodes(f func(id, level, low, high int) error, n ...Node) error
 		}
 	}
 	// the function does not create new nodes, so we do not need to take care of
-	// possible resizing.
+	// possible resizing. But nodes are marked during the traversal, so we
+	// cannot call Allnodes from f.
+	if b.sealed == nil {
+		b.checkreentry("Allnodes")
+		f = b.guardnodes(f)
+	}
 	if len(n) == 0 {
 		// we call f over all active nodes
 		return b.allnodes(f)
 	}
+	if b.sealed != nil {
+		// we cannot mark nodes in a sealed BDD, that may be shared between
+		// goroutines, so we use a local set of visited nodes instead.
+		roots := make([]int, len(n))
+		for k, v := range n {
+			roots[k] = *v
+		}
+		for _, k := range b.topo(roots...) {
+			if err := f(k, int(b.level(k)), b.low(k), b.high(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	return b.allnodesfrom(f, n)
 }
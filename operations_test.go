@@ -6,6 +6,8 @@ package rudd
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"math/rand"
 	"testing"
 )
@@ -20,6 +22,21 @@ func TestIte(t *testing.T) {
 	}
 }
 
+// TestDiff checks the terminal cases of OPdiff, where diff(f, g) is f & !g,
+// against the same operation computed with And and Not.
+func TestDiff(t *testing.T) {
+	bdd, _ := New(3, Nodesize(100))
+	x := bdd.Or(bdd.Ithvar(0), bdd.And(bdd.Ithvar(1), bdd.NIthvar(2)))
+	for _, f := range []Node{bdd.False(), bdd.True(), x} {
+		for _, g := range []Node{bdd.False(), bdd.True(), x, bdd.Ithvar(2)} {
+			expected := bdd.And(f, bdd.Not(g))
+			if actual := bdd.Apply(f, g, OPdiff); !bdd.Equal(actual, expected) {
+				t.Errorf("diff(%d, %d): expected %d, actual %d", *f, *g, *expected, *actual)
+			}
+		}
+	}
+}
+
 // TestOperations implements the same tests than the bddtest program in the
 // Buddy distribution. It uses function Allsat for checking that all assignments
 // are detected.
@@ -111,3 +128,567 @@ func TestOperations(t *testing.T) {
 		check(set)
 	}
 }
+
+func TestMakeset(t *testing.T) {
+	bdd, _ := New(6)
+	n1 := bdd.Makeset([]int{5, 1, 3})
+	n2 := bdd.Makeset([]int{1, 3, 5})
+	if !bdd.Equal(n1, n2) {
+		t.Error("Makeset should not depend on the order of variables")
+	}
+	if actual := bdd.Scanset(n1); fmt.Sprint(actual) != "[1 3 5]" {
+		t.Errorf("Scanset(Makeset([1 3 5])): expected [1 3 5], actual %v", actual)
+	}
+	if bdd.Makeset([]int{2, 6}) != nil || !bdd.Errored() {
+		t.Error("Makeset with unknown variable should return nil")
+	}
+}
+
+func TestMakecubeDuplicates(t *testing.T) {
+	bdd, _ := New(6)
+	n := bdd.Makecube([]int{1, 3, 1}, []bool{false, true, false})
+	if !bdd.Equal(n, bdd.Makecube([]int{1, 3}, []bool{false, true})) {
+		t.Error("Makecube should ignore a variable repeated with the same polarity")
+	}
+	if actual := bdd.Satcount(n); actual.Int64() != 16 {
+		t.Errorf("Satcount(Makecube([1 3 1])): expected 16, actual %s", actual)
+	}
+	if bdd.Makecube([]int{1, 1}, []bool{true, false}) != nil || !bdd.Errored() {
+		t.Error("Makecube with a variable of both polarities should return nil")
+	}
+}
+
+func TestIsCube(t *testing.T) {
+	bdd, _ := New(6)
+	cube := bdd.Makecube([]int{4, 1, 2}, []bool{true, false, true})
+	if !bdd.IsCube(cube) || !bdd.IsCube(bdd.True()) || bdd.IsCube(bdd.False()) {
+		t.Error("IsCube should accept cubes with any polarity and the constant True")
+	}
+	if actual := bdd.Scanset(cube); fmt.Sprint(actual) != "[1 2 4]" {
+		t.Errorf("Scanset(Makecube([4 1 2])): expected [1 2 4], actual %v", actual)
+	}
+	if actual := bdd.Scanset(bdd.True()); actual == nil || len(actual) != 0 {
+		t.Errorf("Scanset(True): expected [], actual %v", actual)
+	}
+	if bdd.Errored() {
+		t.Errorf("unexpected error: %s", bdd.Error())
+	}
+	n := bdd.Or(bdd.Ithvar(1), bdd.Ithvar(2))
+	if bdd.IsCube(n) {
+		t.Error("IsCube(x1 | x2) should be false")
+	}
+	if bdd.Scanset(n) != nil || !bdd.Errored() {
+		t.Error("Scanset should return nil and set an error when its argument is not a cube")
+	}
+}
+
+func TestPeek(t *testing.T) {
+	bdd, _ := New(6)
+	n := bdd.Makeset([]int{1, 3, 5})
+	levels := []int{}
+	for k := *n; k > 1; k = bdd.PeekHigh(&k) {
+		if bdd.PeekLow(&k) != 0 {
+			t.Errorf("low branch of a positive cube should be False")
+		}
+		levels = append(levels, bdd.PeekLevel(&k))
+	}
+	if fmt.Sprint(levels) != "[1 3 5]" {
+		t.Errorf("levels in cube: expected [1 3 5], actual %v", levels)
+	}
+	if bdd.PeekLevel(bdd.True()) != bdd.Varnum() {
+		t.Errorf("level of constant should be Varnum")
+	}
+}
+
+func TestVar(t *testing.T) {
+	bdd, _ := New(4)
+	n := bdd.Ithvar(2)
+	if bdd.Var(n) != 2 || bdd.LevelOf(n) != 2 {
+		t.Errorf("with the initial order, the variable and level of x2 should be 2")
+	}
+	// we swap variables 0 and 2 in the order, without moving nodes
+	bdd.var2level[0], bdd.var2level[2] = 2, 0
+	bdd.level2var[0], bdd.level2var[2] = 2, 0
+	m := bdd.Retnode(bdd.Makenode(0, 0, 1))
+	if bdd.Var(m) != 2 || bdd.LevelOf(m) != 0 {
+		t.Errorf("expected variable 2 at level 0, actual %d at level %d", bdd.Var(m), bdd.LevelOf(m))
+	}
+	if bdd.Var(bdd.True()) != -1 || bdd.LevelOf(nil) != -1 || !bdd.Errored() {
+		t.Errorf("Var and LevelOf should fail on constants and nil nodes")
+	}
+}
+
+func TestEqualCanonical(t *testing.T) {
+	bdd, _ := New(4)
+	f := bdd.And(bdd.Ithvar(0), bdd.NIthvar(1))
+	g := bdd.Not(bdd.Or(bdd.NIthvar(0), bdd.Ithvar(1)))
+	if !bdd.EqualCanonical(f, g) || bdd.EqualCanonical(f, bdd.Ithvar(0)) {
+		t.Errorf("EqualCanonical should test the equality of functions")
+	}
+	// the signature of a function does not depend on the variable order; we
+	// build f in a BDD where variables 0 and 1 are swapped
+	other, _ := New(4)
+	other.var2level[0], other.var2level[1] = 1, 0
+	other.level2var[0], other.level2var[1] = 1, 0
+	other.Initref()
+	x0 := other.Pushref(other.Makenode(1, 0, 1))
+	h := other.Makenode(0, x0, 0)
+	if other.signature(h) != bdd.signature(*f) {
+		t.Errorf("signatures should not depend on the variable order")
+	}
+	if bdd.signature(*f) == bdd.signature(*bdd.Ithvar(0)) {
+		t.Errorf("different functions should have different signatures")
+	}
+}
+
+func TestLive(t *testing.T) {
+	bdd, _ := New(6, Nodesize(100))
+	live, allocated := bdd.Live(), bdd.Allocated()
+	if live != 2+2*6 || allocated < live {
+		t.Errorf("unexpected number of nodes in a fresh BDD: live %d, allocated %d", live, allocated)
+	}
+	bdd.Makeset([]int{0, 1, 2, 3, 4, 5})
+	if bdd.Live() <= live || bdd.Allocated() < bdd.Live() {
+		t.Errorf("the number of live nodes should grow after Makeset: live %d, allocated %d", bdd.Live(), bdd.Allocated())
+	}
+}
+
+func TestFlushReleasedRefs(t *testing.T) {
+	bdd, _ := New(10, Nodesize(1000))
+	keep := bdd.Makeset([]int{0, 1})
+	live := bdd.Live()
+	for k := 0; k < 20; k++ {
+		bdd.Or(bdd.Ithvar(k%10), bdd.And(bdd.NIthvar((k+3)%10), bdd.Ithvar((k+7)%10)))
+	}
+	bdd.FlushReleasedRefs()
+	bdd.gbc(bdd.refstack)
+	if actual := bdd.Live(); actual != live {
+		t.Errorf("expected %d live nodes, actual %d", live, actual)
+	}
+	if bdd.Satcount(keep).Int64() != 1<<8 {
+		t.Errorf("the nodes of keep should not be reclaimed")
+	}
+}
+
+func TestAnodeCount(t *testing.T) {
+	bdd, _ := New(4, Nodesize(100))
+	n1 := bdd.Makeset([]int{0, 1, 2, 3})
+	n2 := bdd.Makeset([]int{1, 2, 3})
+	if c := bdd.AnodeCount(n1); c != 4 {
+		t.Errorf("expected 4 nodes in a cube of 4 variables, actual %d", c)
+	}
+	// n2 is a subgraph of n1
+	if c := bdd.AnodeCount(n1, n2, bdd.True()); c != 4 {
+		t.Errorf("expected 4 shared nodes, actual %d", c)
+	}
+	if c := bdd.AnodeCount(bdd.Ithvar(0), bdd.NIthvar(0)); c != 2 {
+		t.Errorf("expected 2 nodes for a variable and its negation, actual %d", c)
+	}
+	if c := bdd.AnodeCount(n1, nil); c != 0 || !bdd.Errored() {
+		t.Errorf("expected an error with a nil node, actual %d", c)
+	}
+}
+
+func TestRetainedSize(t *testing.T) {
+	bdd, _ := New(4, Nodesize(100))
+	n1 := bdd.Makeset([]int{0, 1, 2, 3})
+	n2 := bdd.Makeset([]int{2, 3})
+	n3 := bdd.Or(bdd.Ithvar(0), bdd.Ithvar(1))
+	roots := map[string]Node{"n1": n1, "n2": n2, "n3": n3, "same": n3}
+	res := bdd.RetainedSize(roots)
+	// n2 is a subgraph of n1, and n3 has two names
+	if res["n1"] != 2 || res["n2"] != 0 || res["n3"] != 0 || res["same"] != 0 {
+		t.Errorf("unexpected retained sizes %v", res)
+	}
+	delete(roots, "same")
+	if res := bdd.RetainedSize(roots); res["n3"] != 2 {
+		t.Errorf("expected 2 nodes retained by n3, actual %v", res)
+	}
+	if bdd.RetainedSize(map[string]Node{"bad": nil}) != nil || !bdd.Errored() {
+		t.Errorf("expected an error with a nil node")
+	}
+}
+
+func TestSatcountSet(t *testing.T) {
+	bdd, _ := New(6, Nodesize(100))
+	n := bdd.Or(bdd.Ithvar(0), bdd.And(bdd.Ithvar(2), bdd.NIthvar(4)))
+	for _, tc := range []struct {
+		vars     []int
+		expected int64
+	}{
+		{[]int{0, 2, 4}, 5},
+		{[]int{0, 1, 2, 4}, 10},
+		{[]int{0, 1, 2, 3, 4, 5}, 40},
+	} {
+		if c := bdd.SatcountSet(n, bdd.Makeset(tc.vars)); c.Int64() != tc.expected {
+			t.Errorf("expected %d models over %v, actual %s", tc.expected, tc.vars, c)
+		}
+	}
+	if c := bdd.SatcountSet(bdd.True(), bdd.True()); c.Int64() != 1 {
+		t.Errorf("expected 1 model for True over the empty set, actual %s", c)
+	}
+	if c := bdd.SatcountSet(n, bdd.Makeset([]int{0, 2})); c.Sign() != 0 || !bdd.Errored() {
+		t.Errorf("expected an error when n depends on a variable not in varset")
+	}
+}
+
+func TestSatcountln(t *testing.T) {
+	bdd, _ := New(200, Nodesize(10000))
+	// on a few variables, we compare with Satcount
+	for round := 0; round < 50; round++ {
+		n := bdd.False()
+		for k := rand.Intn(5); k >= 0; k-- {
+			n = bdd.Or(n, bdd.And(bdd.Ithvar(rand.Intn(8)), bdd.NIthvar(rand.Intn(8))))
+		}
+		expected, _ := new(big.Float).SetInt(bdd.Satcount(n)).Float64()
+		if actual := math.Exp2(bdd.Satcountln(n)); math.Abs(actual-expected) > 1e-6*expected {
+			t.Fatalf("Satcountln: expected %g, actual %g", expected, actual)
+		}
+		if actual := bdd.Density(n) * math.Exp2(200); math.Abs(actual-expected) > 1e-6*expected {
+			t.Fatalf("Density: expected %g, actual %g", expected/math.Exp2(200), bdd.Density(n))
+		}
+	}
+	// the number of models of True is too large for a float64
+	if actual := bdd.Satcountln(bdd.True()); actual != 200 {
+		t.Errorf("expected 200, actual %g", actual)
+	}
+	if actual := bdd.Satcountln(bdd.Makeset([]int{0, 1, 2, 3})); actual != 196 {
+		t.Errorf("expected 196, actual %g", actual)
+	}
+	if !math.IsInf(bdd.Satcountln(bdd.False()), -1) || bdd.Density(bdd.False()) != 0 {
+		t.Errorf("expected -Inf and 0 for False")
+	}
+	if bdd.Density(bdd.Ithvar(100)) != 0.5 {
+		t.Errorf("expected a density of 0.5 for a variable, actual %g", bdd.Density(bdd.Ithvar(100)))
+	}
+}
+
+func TestVarprofile(t *testing.T) {
+	bdd, _ := New(4, Nodesize(100))
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(2)), bdd.And(bdd.Ithvar(1), bdd.Ithvar(2)))
+	if actual := fmt.Sprint(bdd.Varprofile(n)); actual != "[1 1 1 0]" {
+		t.Errorf("expected profile [1 1 1 0], actual %s", actual)
+	}
+	if actual := fmt.Sprint(bdd.Varprofile(n, bdd.Ithvar(3))); actual != "[1 1 1 1]" {
+		t.Errorf("expected profile [1 1 1 1], actual %s", actual)
+	}
+	if bdd.Varprofile(nil) != nil || !bdd.Errored() {
+		t.Error("expected an error with a nil node")
+	}
+}
+
+func TestPathCount(t *testing.T) {
+	bdd, _ := New(4, Nodesize(100))
+	tests := []struct {
+		n        Node
+		expected int64
+	}{
+		{bdd.False(), 0},
+		{bdd.True(), 1},
+		{bdd.Ithvar(2), 1},
+		{bdd.Or(bdd.Ithvar(0), bdd.Ithvar(1)), 2},
+		{bdd.Apply(bdd.Ithvar(0), bdd.Apply(bdd.Ithvar(1), bdd.Ithvar(2), OPxor), OPxor), 4},
+	}
+	for _, tt := range tests {
+		count := 0
+		bdd.Allsat(func([]int) error { count++; return nil }, tt.n)
+		if actual := bdd.PathCount(tt.n); actual.Int64() != tt.expected || actual.Int64() != int64(count) {
+			t.Errorf("expected %d paths (%d assignments with Allsat), actual %s", tt.expected, count, actual)
+		}
+	}
+}
+
+// TestProb compares Prob with the sum of the probabilities of the models of
+// random functions, computed by brute force.
+func TestProb(t *testing.T) {
+	const varnum = 5
+	bdd, _ := New(varnum, Nodesize(1000))
+	p := []float64{0.5, 0.1, 0.9, 0, 0.25}
+	for round := 0; round < 50; round++ {
+		n := bdd.False()
+		expected := 0.0
+		for a := 0; a < 1<<varnum; a++ {
+			if rand.Intn(3) != 0 {
+				continue
+			}
+			assignment := make([]bool, varnum)
+			pa := 1.0
+			for v := range assignment {
+				assignment[v] = a&(1<<v) != 0
+				if assignment[v] {
+					pa *= p[v]
+				} else {
+					pa *= 1 - p[v]
+				}
+			}
+			n = bdd.Or(n, bdd.Makecube(nil, assignment))
+			expected += pa
+		}
+		if actual := bdd.Prob(n, p); actual < expected-1e-9 || actual > expected+1e-9 {
+			t.Fatalf("expected %g, actual %g", expected, actual)
+		}
+	}
+	if bdd.Prob(bdd.True(), p) != 1 || bdd.Prob(bdd.Ithvar(1), p) != 0.1 {
+		t.Errorf("wrong probability for a constant or a variable")
+	}
+	if bdd.Prob(bdd.True(), p[1:]) != 0 || !bdd.Errored() {
+		t.Errorf("expected an error with a wrong number of probabilities")
+	}
+}
+
+func TestLeq(t *testing.T) {
+	bdd, _ := New(5, Nodesize(1000), Cachesize(1000))
+	nodes := []Node{
+		bdd.False(),
+		bdd.True(),
+		bdd.Ithvar(2),
+		bdd.And(bdd.Ithvar(0), bdd.Ithvar(2)),
+		bdd.Or(bdd.Ithvar(1), bdd.NIthvar(3)),
+		bdd.Apply(bdd.Ithvar(0), bdd.Ithvar(4), OPxor),
+		bdd.And(bdd.Ithvar(0), bdd.NIthvar(4), bdd.Ithvar(1)),
+	}
+	for _, f := range nodes {
+		for _, g := range nodes {
+			expected := bdd.Imp(f, g) == bdd.True()
+			if actual := bdd.Leq(f, g); actual != expected {
+				t.Errorf("Leq(%d, %d): expected %v, actual %v", *f, *g, expected, actual)
+			}
+		}
+	}
+	if bdd.Leq(nil, bdd.True()) || !bdd.Errored() {
+		t.Error("expected an error with a nil node")
+	}
+}
+
+func TestIntersects(t *testing.T) {
+	bdd, _ := New(5, Nodesize(1000), Cachesize(1000))
+	nodes := []Node{
+		bdd.False(),
+		bdd.True(),
+		bdd.Ithvar(2),
+		bdd.NIthvar(2),
+		bdd.And(bdd.Ithvar(0), bdd.Ithvar(2)),
+		bdd.Or(bdd.Ithvar(1), bdd.NIthvar(3)),
+		bdd.And(bdd.NIthvar(1), bdd.Ithvar(3)),
+		bdd.Apply(bdd.Ithvar(0), bdd.Ithvar(4), OPxor),
+		bdd.And(bdd.Ithvar(0), bdd.NIthvar(4), bdd.Ithvar(1)),
+	}
+	for _, f := range nodes {
+		for _, g := range nodes {
+			expected := *bdd.And(f, g) != 0
+			if actual := bdd.Intersects(f, g); actual != expected {
+				t.Errorf("Intersects(%d, %d): expected %v, actual %v", *f, *g, expected, actual)
+			}
+		}
+	}
+	if bdd.Intersects(bdd.True(), nil) || !bdd.Errored() {
+		t.Error("expected an error with a nil node")
+	}
+}
+
+func TestIteConstant(t *testing.T) {
+	bdd, _ := New(5, Nodesize(1000), Cachesize(1000))
+	nodes := []Node{
+		bdd.False(),
+		bdd.True(),
+		bdd.Ithvar(2),
+		bdd.NIthvar(2),
+		bdd.And(bdd.Ithvar(0), bdd.Ithvar(2)),
+		bdd.Or(bdd.Ithvar(1), bdd.NIthvar(3)),
+		bdd.And(bdd.NIthvar(1), bdd.Ithvar(3)),
+		bdd.Apply(bdd.Ithvar(0), bdd.Ithvar(4), OPxor),
+	}
+	for _, f := range nodes {
+		for _, g := range nodes {
+			for _, h := range nodes {
+				ite := *bdd.Ite(f, g, h)
+				expected := -1
+				if ite < 2 {
+					expected = ite
+				}
+				actual, ok := bdd.IteConstant(f, g, h)
+				if actual != expected || ok != (expected >= 0) {
+					t.Errorf("IteConstant(%d, %d, %d): expected %d, actual %d (%v)", *f, *g, *h, expected, actual, ok)
+				}
+			}
+		}
+	}
+	if _, ok := bdd.IteConstant(bdd.True(), nil, bdd.True()); ok || !bdd.Errored() {
+		t.Error("expected an error with a nil node")
+	}
+}
+
+func TestUnique(t *testing.T) {
+	bdd, _ := New(5)
+	// cofactor returns n[v:=c]
+	cofactor := func(n Node, v int, c bool) Node {
+		lit := bdd.Ithvar(v)
+		if !c {
+			lit = bdd.NIthvar(v)
+		}
+		return bdd.Exist(bdd.And(n, lit), bdd.Makeset([]int{v}))
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		n := bdd.False()
+		for k := 0; k < 6; k++ {
+			m := bdd.True()
+			for v := 0; v < 5; v++ {
+				switch rng.Intn(3) {
+				case 0:
+					m = bdd.And(m, bdd.Ithvar(v))
+				case 1:
+					m = bdd.And(m, bdd.NIthvar(v))
+				}
+			}
+			n = bdd.Or(n, m)
+		}
+		expected := bdd.False()
+		for _, c1 := range []bool{false, true} {
+			for _, c3 := range []bool{false, true} {
+				expected = bdd.Apply(expected, cofactor(cofactor(n, 1, c1), 3, c3), OPxor)
+			}
+		}
+		if actual := bdd.Unique(n, bdd.Makeset([]int{1, 3})); !bdd.Equal(actual, expected) {
+			t.Errorf("Unique(n, {1, 3}) should be the xor of the four cofactors of n")
+		}
+	}
+	if !bdd.Equal(bdd.Unique(bdd.Ithvar(0), bdd.Makeset([]int{2})), bdd.False()) {
+		t.Errorf("Unique of a variable that does not occur should be False")
+	}
+	if !bdd.Equal(bdd.Unique(bdd.Ithvar(0), bdd.Makeset([]int{0})), bdd.True()) {
+		t.Errorf("Unique x0 . x0 should be True")
+	}
+}
+
+func TestCompose(t *testing.T) {
+	bdd, _ := New(5)
+	cofactor := func(n Node, v int, c bool) Node {
+		lit := bdd.Ithvar(v)
+		if !c {
+			lit = bdd.NIthvar(v)
+		}
+		return bdd.Exist(bdd.And(n, lit), bdd.Makeset([]int{v}))
+	}
+	rng := rand.New(rand.NewSource(1))
+	random := func() Node {
+		n := bdd.False()
+		for k := 0; k < 4; k++ {
+			m := bdd.True()
+			for v := 0; v < 5; v++ {
+				switch rng.Intn(3) {
+				case 0:
+					m = bdd.And(m, bdd.Ithvar(v))
+				case 1:
+					m = bdd.And(m, bdd.NIthvar(v))
+				}
+			}
+			n = bdd.Or(n, m)
+		}
+		return n
+	}
+	for i := 0; i < 20; i++ {
+		f, g := random(), random()
+		v := rng.Intn(5)
+		expected := bdd.Ite(g, cofactor(f, v, true), cofactor(f, v, false))
+		if actual := bdd.Compose(f, v, g); !bdd.Equal(actual, expected) {
+			t.Errorf("Compose(f, %d, g) should be equal to Ite(g, f[%d/1], f[%d/0])", v, v, v)
+		}
+	}
+	x0, x1 := bdd.Ithvar(0), bdd.Ithvar(1)
+	if !bdd.Equal(bdd.Compose(x0, 0, x1), x1) {
+		t.Errorf("Compose(x0, 0, x1) should be x1")
+	}
+	if !bdd.Equal(bdd.Compose(x0, 2, x1), x0) {
+		t.Errorf("Compose(x0, 2, x1) should be x0")
+	}
+	if bdd.Compose(x0, 5, x1) != nil || !bdd.Errored() {
+		t.Errorf("Compose should fail with an unknown variable")
+	}
+}
+
+func TestVecCompose(t *testing.T) {
+	bdd, _ := New(6)
+	rng := rand.New(rand.NewSource(1))
+	// random returns a random function over the variables in vars
+	random := func(vars []int) Node {
+		n := bdd.False()
+		for k := 0; k < 4; k++ {
+			m := bdd.True()
+			for _, v := range vars {
+				switch rng.Intn(3) {
+				case 0:
+					m = bdd.And(m, bdd.Ithvar(v))
+				case 1:
+					m = bdd.And(m, bdd.NIthvar(v))
+				}
+			}
+			n = bdd.Or(n, m)
+		}
+		return n
+	}
+	all := []int{0, 1, 2, 3, 4, 5}
+	for i := 0; i < 20; i++ {
+		f := random(all)
+		g1, g3 := random([]int{0, 2, 5}), random([]int{2, 4})
+		c, err := bdd.NewComposer([]int{3, 1}, []Node{g3, g1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// when the functions do not depend on the substituted variables, we can
+		// compose them one at a time
+		expected := bdd.Compose(bdd.Compose(f, 1, g1), 3, g3)
+		if actual := bdd.VecCompose(f, c); !bdd.Equal(actual, expected) {
+			t.Errorf("VecCompose(f, [1<-g1, 3<-g3]) should be equal to Compose(Compose(f, 1, g1), 3, g3)")
+		}
+	}
+	// the substitutions are simultaneous
+	swap, _ := bdd.NewComposer([]int{0, 1}, []Node{bdd.Ithvar(1), bdd.Ithvar(0)})
+	if actual := bdd.VecCompose(bdd.And(bdd.Ithvar(0), bdd.NIthvar(1)), swap); !bdd.Equal(actual, bdd.And(bdd.Ithvar(1), bdd.NIthvar(0))) {
+		t.Errorf("VecCompose should perform the substitutions simultaneously")
+	}
+	if _, err := bdd.NewComposer([]int{0, 0}, []Node{bdd.True(), bdd.False()}); err == nil {
+		t.Errorf("NewComposer should fail with a duplicate variable")
+	}
+	if _, err := bdd.NewComposer([]int{0}, []Node{bdd.True(), bdd.False()}); err == nil {
+		t.Errorf("NewComposer should fail with slices of different lengths")
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	bdd, _ := New(5)
+	rng := rand.New(rand.NewSource(1))
+	random := func() Node {
+		n := bdd.False()
+		for k := 0; k < 4; k++ {
+			m := bdd.True()
+			for v := 0; v < 5; v++ {
+				switch rng.Intn(3) {
+				case 0:
+					m = bdd.And(m, bdd.Ithvar(v))
+				case 1:
+					m = bdd.And(m, bdd.NIthvar(v))
+				}
+			}
+			n = bdd.Or(n, m)
+		}
+		return n
+	}
+	for i := 0; i < 20; i++ {
+		f, d := random(), random()
+		if !bdd.Equal(bdd.And(bdd.Simplify(f, d), d), bdd.And(f, d)) {
+			t.Errorf("Simplify(f, d) should agree with f on d")
+		}
+		if !bdd.Equal(bdd.Simplify(f, bdd.True()), f) {
+			t.Errorf("Simplify(f, True) should be f")
+		}
+	}
+	x0, x1, x2 := bdd.Ithvar(0), bdd.Ithvar(1), bdd.Ithvar(2)
+	f := bdd.Or(bdd.And(x0, x1), bdd.And(bdd.Not(x0), x2))
+	if !bdd.Equal(bdd.Simplify(f, x0), x1) {
+		t.Errorf("Simplify(x0 & x1 | !x0 & x2, x0) should be x1")
+	}
+	if bdd.Apply(f, x0, opsimplify) != nil {
+		t.Errorf("opsimplify should not be used in Apply")
+	}
+}
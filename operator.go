@@ -22,20 +22,24 @@ const (
 	// opnot, for negation, is the only unary operation. It should not be used
 	// in Apply
 	opnot
+	// opsimplify is used to cache the results of Simplify. It should not be
+	// used in Apply
+	opsimplify
 )
 
 var opnames = [12]string{
-	OPand:    "and",
-	OPxor:    "xor",
-	OPor:     "or",
-	OPnand:   "nand",
-	OPnor:    "nor",
-	OPimp:    "imp",
-	OPbiimp:  "biimp",
-	OPdiff:   "diff",
-	OPless:   "less",
-	OPinvimp: "invimp",
-	opnot:    "not",
+	OPand:      "and",
+	OPxor:      "xor",
+	OPor:       "or",
+	OPnand:     "nand",
+	OPnor:      "nor",
+	OPimp:      "imp",
+	OPbiimp:    "biimp",
+	OPdiff:     "diff",
+	OPless:     "less",
+	OPinvimp:   "invimp",
+	opnot:      "not",
+	opsimplify: "simplify",
 }
 
 func (op Operator) String() string {
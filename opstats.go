@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpStats gives information about the cost of a single (top-level) operation,
+// see for instance AppExWithStats. Recursions is the number of recursive calls
+// that are not solved by a terminal case, meaning calls that look for their
+// result in one of the operation caches, and CacheHits is the number of calls
+// where the result was found. NewNodes is the number of nodes created during
+// the operation, that may include nodes that were later collected.
+type OpStats struct {
+	NewNodes   int           // Number of nodes created by the operation
+	CacheHits  int           // Number of results found in the caches
+	Recursions int           // Number of lookups in the caches
+	Duration   time.Duration // Time spent in the operation
+}
+
+// HitRate returns the ratio (between 0 and 1) of cache lookups that were
+// successful, or 0 if there was no lookup.
+func (s OpStats) HitRate() float64 {
+	if s.Recursions == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(s.Recursions)
+}
+
+func (s OpStats) String() string {
+	return fmt.Sprintf("created %d nodes in %s with %.0f%% hit rate (%d recursions)", s.NewNodes, s.Duration, 100*s.HitRate(), s.Recursions)
+}
+
+// cachecounters returns the total number of hits and of lookups in the
+// operation caches of b.
+func (b *BDD) cachecounters() (hits, lookups int) {
+	for _, c := range [...]struct{ hit, miss int }{
+		{b.applycache.cache.OpHit, b.applycache.cache.OpMiss},
+		{b.itecache.cache.OpHit, b.itecache.cache.OpMiss},
+		{b.quantcache.cache.OpHit, b.quantcache.cache.OpMiss},
+		{b.appexcache.cache.OpHit, b.appexcache.cache.OpMiss},
+		{b.replacecache.cache.OpHit, b.replacecache.cache.OpMiss},
+	} {
+		hits += c.hit
+		lookups += c.hit + c.miss
+	}
+	return hits, lookups
+}
+
+// withstats calls f and returns its result, together with statistics on the
+// computation.
+func (b *BDD) withstats(f func() Node) (Node, OpStats) {
+	produced := b.producednum()
+	hits, lookups := b.cachecounters()
+	start := time.Now()
+	res := f()
+	stats := OpStats{Duration: time.Since(start)}
+	stats.NewNodes = b.producednum() - produced
+	h, l := b.cachecounters()
+	stats.CacheHits, stats.Recursions = h-hits, l-lookups
+	return res, stats
+}
+
+// AppExWithStats is the same as AppEx but also returns statistics on the
+// computation, such as the number of nodes created and the cache hit rate.
+func (b *BDD) AppExWithStats(n1, n2 Node, op Operator, varset Node) (Node, OpStats) {
+	return b.withstats(func() Node { return b.AppEx(n1, n2, op, varset) })
+}
+
+// ExistWithStats is the same as Exist but also returns statistics on the
+// computation, such as the number of nodes created and the cache hit rate.
+func (b *BDD) ExistWithStats(n, varset Node) (Node, OpStats) {
+	return b.withstats(func() Node { return b.Exist(n, varset) })
+}
+
+// ReplaceWithStats is the same as Replace but also returns statistics on the
+// computation, such as the number of nodes created and the cache hit rate.
+func (b *BDD) ReplaceWithStats(n Node, r Replacer) (Node, OpStats) {
+	return b.withstats(func() Node { return b.Replace(n, r) })
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+func TestOpStats(t *testing.T) {
+	bdd, _ := New(8)
+	n := bdd.False()
+	for k := 0; k < 4; k++ {
+		n = bdd.Or(n, bdd.And(bdd.Ithvar(k), bdd.Ithvar(k+4)))
+	}
+	m := bdd.Or(bdd.Ithvar(0), bdd.Ithvar(5))
+	varset := bdd.Makeset([]int{0, 1, 2, 3})
+	res, stats := bdd.AppExWithStats(n, m, OPand, varset)
+	if !bdd.Equal(res, bdd.AppEx(n, m, OPand, varset)) {
+		t.Errorf("AppExWithStats should return the same result than AppEx")
+	}
+	if stats.Recursions == 0 || stats.NewNodes == 0 || stats.CacheHits > stats.Recursions {
+		t.Errorf("unexpected statistics for AppEx: %v", stats)
+	}
+	// the result is now in the cache
+	_, stats = bdd.AppExWithStats(n, m, OPand, varset)
+	if stats.CacheHits != 1 || stats.Recursions != 1 || stats.NewNodes != 0 || stats.HitRate() != 1 {
+		t.Errorf("expected a single cache hit, actual %v", stats)
+	}
+	if _, stats = bdd.ExistWithStats(n, varset); stats.Recursions == 0 {
+		t.Errorf("unexpected statistics for Exist: %v", stats)
+	}
+	r, _ := bdd.NewReplacer([]int{0}, []int{7})
+	if _, stats = bdd.ReplaceWithStats(bdd.Ithvar(0), r); stats.Recursions != 1 || stats.NewNodes != 0 {
+		t.Errorf("unexpected statistics for Replace: %v", stats)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "fmt"
+
+// ErrReentry is the value wrapped by the panic raised, in debug mode, when an
+// operation that can modify the node table is called from a callback.
+var ErrReentry = fmt.Errorf("operation not permitted inside a callback")
+
+// checkreentry panics, in debug mode, if op is called from a callback.
+//
+// Some operations call user code in the middle of a computation that is not
+// protected against the creation of new nodes: the function passed to
+// Allnodes, that visits nodes while they are marked (or while holding a lock
+// on the node table), the iterator passed to FromMinterIndices, that keeps
+// partial results in the refstack, and the handler registered with
+// Memoryhandler. A callback that creates new nodes would clear the refstack
+// of the operation that called it (with Initref), or trigger a garbage
+// collection that reclaims or unmarks the nodes still in use by this
+// operation. The concurrency contract is described in the package
+// documentation. In debug mode (build tag debug), we count the callbacks being
+// executed and panic when a method that modifies the node table or the
+// refstack is called from one of them.
+func (b *BDD) checkreentry(op string) {
+	if _DEBUG && b.callbacks > 0 {
+		panic(fmt.Errorf("rudd: %w (call to %s)", ErrReentry, op))
+	}
+}
+
+// checkreorder panics, in debug mode, if op, that changes the order of
+// variables, is called from a callback, including the ones of Allsat, since
+// nodes are changed in place during a reordering.
+func (b *BDD) checkreorder(op string) {
+	if _DEBUG && (b.callbacks > 0 || b.traversal > 0) {
+		panic(fmt.Errorf("rudd: %w (call to %s)", ErrReentry, op))
+	}
+}
+
+// guardnodes returns a version of f, used as a callback in Allnodes, that
+// records that we are executing a callback in debug mode.
+func (b *BDD) guardnodes(f func(id, level, low, high int) error) func(id, level, low, high int) error {
+	if !_DEBUG {
+		return f
+	}
+	return func(id, level, low, high int) error {
+		b.callbacks++
+		defer func() { b.callbacks-- }()
+		return f(id, level, low, high)
+	}
+}
+
+// guarditer is the same as guardnodes for the iterators used in
+// FromMinterIndices. The function yield is our own code, that may create
+// nodes, so we are not inside a callback while it executes.
+func (b *BDD) guarditer(it func(yield func(uint64) bool)) func(yield func(uint64) bool) {
+	if !_DEBUG {
+		return it
+	}
+	return func(yield func(uint64) bool) {
+		b.callbacks++
+		defer func() { b.callbacks-- }()
+		it(func(x uint64) bool {
+			b.callbacks--
+			defer func() { b.callbacks++ }()
+			return yield(x)
+		})
+	}
+}
+
+// oomhandler calls the handler registered with Memoryhandler.
+func (b *BDD) oomhandler() bool {
+	if _DEBUG {
+		b.callbacks++
+		defer func() { b.callbacks-- }()
+	}
+	return b.config.oomhandler(b)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// expectreentry checks that f panics with ErrReentry in debug mode, and does
+// nothing otherwise, since the forbidden calls are not detected.
+func expectreentry(t *testing.T, name string, f func()) {
+	t.Helper()
+	if !_DEBUG {
+		return
+	}
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok || !errors.Is(err, ErrReentry) {
+			t.Errorf("%s: expected a panic wrapping ErrReentry, actual %v", name, r)
+		}
+	}()
+	f()
+}
+
+func TestReentryAllowed(t *testing.T) {
+	bdd, _ := New(4, Nodesize(30), Cachesize(100), Autoreorder(ReorderSift, 0))
+	f := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(2)), bdd.And(bdd.Ithvar(1), bdd.Ithvar(3)))
+	// the callbacks of Allsat can create nodes, even with garbage collections
+	// and automatic reordering
+	sum := bdd.False()
+	err := bdd.Allsat(func(varset []int) error {
+		cube := bdd.True()
+		for v, val := range varset {
+			switch val {
+			case 0:
+				cube = bdd.And(cube, bdd.NIthvar(v))
+			case 1:
+				cube = bdd.And(cube, bdd.Ithvar(v))
+			}
+		}
+		sum = bdd.Or(sum, cube)
+		return nil
+	}, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bdd.Equal(sum, f) {
+		t.Errorf("expected the sum of the cubes of Allsat to be equal to f")
+	}
+	// the callbacks of Allnodes can call queries
+	count := 0
+	err = bdd.Allnodes(func(id, level, low, high int) error {
+		count += int(bdd.Satcount(bdd.Ithvar(bdd.Level2Var(level))).Int64())
+		return nil
+	}, f)
+	if err != nil || count == 0 {
+		t.Errorf("expected queries to be allowed in Allnodes, error: %v", err)
+	}
+	// queries on a sealed BDD can be called from several goroutines
+	bdd.SealForQueries()
+	var wg sync.WaitGroup
+	for k := 0; k < 4; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s := bdd.Satcount(f); s.Int64() != 7 {
+				t.Errorf("expected 7 solutions, actual %s", s)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReentryForbidden(t *testing.T) {
+	if !_DEBUG {
+		t.Skip("the calls from callbacks are only checked with build tag debug")
+	}
+	bdd, _ := New(4, Nodesize(1000), Cachesize(1000))
+	f := bdd.And(bdd.Ithvar(0), bdd.Ithvar(1))
+	expectreentry(t, "Allnodes", func() {
+		bdd.Allnodes(func(id, level, low, high int) error {
+			bdd.Not(f)
+			return nil
+		}, f)
+	})
+	expectreentry(t, "nested Allnodes", func() {
+		bdd.Allnodes(func(id, level, low, high int) error {
+			bdd.AnodeCount(f)
+			return nil
+		}, f)
+	})
+	expectreentry(t, "FromMinterIndices", func() {
+		bdd.FromMinterIndices([]int{0, 1}, func(yield func(uint64) bool) {
+			bdd.Or(f, bdd.Ithvar(2))
+			yield(1)
+		})
+	})
+	expectreentry(t, "Allsat", func() {
+		bdd.Allsat(func([]int) error {
+			return bdd.Reorder(ReorderSift)
+		}, f)
+	})
+	// the counters are restored after a panic, and the yield function of
+	// FromMinterIndices can create nodes
+	n := bdd.FromMinterIndices([]int{0, 1}, func(yield func(uint64) bool) {
+		yield(1)
+		yield(3)
+	})
+	if !bdd.Equal(n, bdd.Ithvar(1)) {
+		t.Errorf("expected FromMinterIndices to build x1, actual %v", n)
+	}
+	if bdd.callbacks != 0 || bdd.traversal != 0 {
+		t.Errorf("expected no callbacks in progress, actual %d and %d", bdd.callbacks, bdd.traversal)
+	}
+}
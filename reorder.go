@@ -0,0 +1,579 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ReorderMethod is the method used for changing the order of the variables in
+// a BDD, see Reorder.
+type ReorderMethod int
+
+const (
+	// ReorderNone leaves the order of variables unchanged.
+	ReorderNone ReorderMethod = iota
+	// ReorderSift is the sifting algorithm of Rudell, where each variable, in
+	// turn, is moved to all the possible levels and then placed at the level
+	// where the number of nodes is the smallest.
+	ReorderSift
+	// ReorderSymSift is the symmetric sifting of Panda and Somenzi, where
+	// variables that are symmetric in all the functions of the BDD, and at
+	// adjacent levels, are grouped together and moved as a block during
+	// sifting. Groups are also merged when sifting brings two symmetric blocks
+	// side by side. This gives better orders for functions with many
+	// symmetries, such as the ones found in arithmetic circuits.
+	ReorderSymSift
+)
+
+func (m ReorderMethod) String() string {
+	switch m {
+	case ReorderNone:
+		return "none"
+	case ReorderSift:
+		return "sift"
+	case ReorderSymSift:
+		return "symsift"
+	}
+	return fmt.Sprintf("ReorderMethod(%d)", int(m))
+}
+
+// _SIFTGROWTH is the maximal growth (in percent) of the number of nodes
+// allowed while moving a variable during sifting. We stop moving the variable
+// in one direction when we go past this limit.
+const _SIFTGROWTH = 120
+
+// Reorder changes the order of the variables in b, using the given method, in
+// order to reduce the number of nodes in the table, like bdd_reorder in BuDDy.
+// Nodes are changed in place, by swapping adjacent levels, so that the Nodes
+// already returned to the user still denote the same functions. The operation
+// caches are reset, and the replacers and composers are updated for the new
+// order. Use Var2Level and Level2Var to find the order that was selected. We
+// return an error if there is not enough memory to finish the reordering, in
+// which case the BDD is still valid but with a different order than the
+// original one.
+func (b *BDD) Reorder(method ReorderMethod) error {
+	if b.sealed != nil {
+		return fmt.Errorf("error in call to Reorder; %w", ErrSealed)
+	}
+	b.checkreorder("Reorder")
+	switch method {
+	case ReorderNone:
+		return nil
+	case ReorderSift, ReorderSymSift:
+	default:
+		return fmt.Errorf("unknown method (%s) in call to Reorder", method)
+	}
+	r := b.newreorderer()
+	var err error
+	if method == ReorderSift {
+		err = r.sift()
+	} else {
+		err = r.symsift()
+	}
+	r.done()
+	if err != nil {
+		return fmt.Errorf("error in call to Reorder; %w", err)
+	}
+	return nil
+}
+
+// SetVarOrder changes the order of the variables in b so that order[l] is the
+// variable at level l, for instance to impose an order found during a previous
+// run, see VarOrder. Parameter order must be a permutation of the variables.
+// Like with Reorder, nodes are changed in place, by swapping adjacent levels,
+// so that the Nodes already returned to the user still denote the same
+// functions. We return an error if order is not a permutation or if there is
+// not enough memory to finish, in which case the BDD is still valid but with
+// an order that may be different from the original one.
+func (b *BDD) SetVarOrder(order []int) error {
+	if b.sealed != nil {
+		return fmt.Errorf("error in call to SetVarOrder; %w", ErrSealed)
+	}
+	b.checkreorder("SetVarOrder")
+	if len(order) != int(b.varnum) {
+		return fmt.Errorf("wrong number of variables (%d) in call to SetVarOrder", len(order))
+	}
+	seen := make([]bool, b.varnum)
+	for _, v := range order {
+		if v < 0 || v >= int(b.varnum) || seen[v] {
+			return fmt.Errorf("order is not a permutation in call to SetVarOrder (%v)", order)
+		}
+		seen[v] = true
+	}
+	r := b.newreorderer()
+	defer r.done()
+	// we move the variables in place, one at a time, starting from the top
+	for target, v := range order {
+		for l := int(b.var2level[v]); l > target; l-- {
+			if err := r.swap(l - 1); err != nil {
+				return fmt.Errorf("error in call to SetVarOrder; %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// trigger is the state of the automatic reordering, see Autoreorder and
+// Reorderperiod. Flag pending is set by cacheupdate, after a garbage
+// collection, and the reordering is started by catchoom, at the end of the
+// current operation.
+type trigger struct {
+	method    ReorderMethod // method used for reordering
+	threshold int           // number of nodes above which we reorder
+	period    int           // number of garbage collections between two reorderings (0 if no limit)
+	gcs       int           // number of garbage collections since the last reordering
+	pending   bool          // a reordering is needed
+}
+
+// autoreorder starts the reordering requested by Autoreorder. An error during
+// the reordering is not reported since the BDD is still valid.
+func (b *BDD) autoreorder() {
+	t := b.autoorder
+	if b.traversal > 0 {
+		// we wait for the end of the enclosing call to Allsat
+		return
+	}
+	t.pending = false
+	if b.sealed != nil || b.error != nil {
+		return
+	}
+	_ = b.Reorder(t.method)
+	if used := b.used(); 2*used > t.threshold {
+		t.threshold = 2 * used
+	}
+	t.pending = false
+	t.gcs = 0
+}
+
+// reorderer keeps track of the nodes of a BDD during a reordering. We maintain
+// our own reference count for each node, equal to the number of live nodes
+// pointing to it, plus one if it has external references. A node is live if
+// its count is positive, and it is reclaimed as soon as its count drops to
+// zero, so that we always know the exact number of nodes at each level.
+type reorderer struct {
+	b      *BDD
+	refs   []int32 // number of references to each node
+	levels [][]int // live nodes at each level
+	where  []int   // position of each live node in its level
+	live   int     // number of live nodes, besides the constants
+	base   int     // size of the refstack before the reordering
+}
+
+// newreorderer collects the garbage in b and computes the reference counts of
+// all the remaining nodes. We push the nodes with external references on the
+// refstack, so that they stay protected if a finalizer runs during the
+// reordering.
+func (b *BDD) newreorderer() *reorderer {
+	r := &reorderer{b: b, levels: make([][]int, b.varnum), base: len(b.refstack)}
+	b.gbc(b.refstack)
+	r.grow()
+	for _, n := range b.refstack {
+		if n >= 2 {
+			r.refs[n]++
+		}
+	}
+	b.allnodes(func(id, level, low, high int) error {
+		if id < 2 {
+			return nil
+		}
+		if b.refcount(id) > 0 {
+			r.refs[id]++
+			b.Pushref(id)
+		}
+		r.refs[low]++
+		r.refs[high]++
+		r.add(id, level)
+		r.live++
+		return nil
+	})
+	return r
+}
+
+// done ends the reordering.
+func (r *reorderer) done() {
+	b := r.b
+	b.refstack = b.refstack[:r.base]
+	b.cachereset()
+	b.reorders++
+	if b.journal != nil {
+		b.recordorder()
+	}
+}
+
+// grow extends the slices indexed by nodes when the node table is resized.
+func (r *reorderer) grow() {
+	for size := r.b.size(); len(r.refs) < size; {
+		r.refs = append(r.refs, 0)
+		r.where = append(r.where, 0)
+	}
+}
+
+func (r *reorderer) add(n, level int) {
+	r.where[n] = len(r.levels[level])
+	r.levels[level] = append(r.levels[level], n)
+}
+
+func (r *reorderer) remove(n, level int) {
+	nodes := r.levels[level]
+	last := nodes[len(nodes)-1]
+	nodes[r.where[n]] = last
+	r.where[last] = r.where[n]
+	r.levels[level] = nodes[:len(nodes)-1]
+}
+
+// ref adds a reference to n, which becomes live if it was not.
+func (r *reorderer) ref(n int) {
+	if n < 2 {
+		return
+	}
+	r.refs[n]++
+	if r.refs[n] == 1 {
+		r.live++
+		r.add(n, int(r.b.level(n)))
+		r.ref(r.b.low(n))
+		r.ref(r.b.high(n))
+	}
+}
+
+// deref removes a reference to n and reclaims the node if it was the last one.
+func (r *reorderer) deref(n int) {
+	if n < 2 {
+		return
+	}
+	r.refs[n]--
+	if r.refs[n] == 0 {
+		b := r.b
+		r.live--
+		r.remove(n, int(b.level(n)))
+		r.deref(b.low(n))
+		r.deref(b.high(n))
+		b.freenode(n)
+	}
+}
+
+// swap exchanges the variables at levels l and l+1. Nodes at level l+1 (the
+// variable y) move up to level l without changes. Nodes at level l (the
+// variable x) move down to level l+1 if they do not depend on y; otherwise
+// they are rebuilt in place as nodes labelled by y, whose successors are
+// (possibly new) nodes labelled by x.
+func (r *reorderer) swap(l int) error {
+	b := r.b
+	// we reserve enough space for the new nodes, which may trigger a garbage
+	// collection, but all the live nodes are protected by the refstack.
+	if !b.reserve(2 * len(r.levels[l])) {
+		return errMemory
+	}
+	r.grow()
+	xnodes, ynodes := r.levels[l], r.levels[l+1]
+	r.levels[l], r.levels[l+1] = nil, nil
+	cofactors := make([][4]int, len(xnodes))
+	cofactor := func(n int) (int, int) {
+		if int(b.level(n)) == l+1 {
+			return b.low(n), b.high(n)
+		}
+		return n, n
+	}
+	for k, n := range xnodes {
+		f00, f01 := cofactor(b.low(n))
+		f10, f11 := cofactor(b.high(n))
+		cofactors[k] = [4]int{f00, f01, f10, f11}
+		b.unhash(n)
+	}
+	for _, n := range ynodes {
+		b.unhash(n)
+	}
+	for _, n := range ynodes {
+		b.rehash(n, int32(l), b.low(n), b.high(n))
+		r.add(n, l)
+	}
+	dependent := []int{}
+	for k, n := range xnodes {
+		if c := cofactors[k]; c[0] == c[1] && c[2] == c[3] {
+			b.rehash(n, int32(l+1), b.low(n), b.high(n))
+			r.add(n, l+1)
+		} else {
+			dependent = append(dependent, k)
+		}
+	}
+	for _, k := range dependent {
+		n, c := xnodes[k], cofactors[k]
+		low, err := r.makex(l+1, c[0], c[2])
+		if err != nil {
+			return err
+		}
+		high, err := r.makex(l+1, c[1], c[3])
+		if err != nil {
+			return err
+		}
+		r.ref(low)
+		r.ref(high)
+		r.deref(b.low(n))
+		r.deref(b.high(n))
+		b.rehash(n, int32(l), low, high)
+		r.add(n, l)
+	}
+	x, y := b.level2var[l], b.level2var[l+1]
+	b.level2var[l], b.level2var[l+1] = y, x
+	b.var2level[x], b.var2level[y] = int32(l+1), int32(l)
+	return nil
+}
+
+// makex returns the node (level, low, high), that may already exist. Space for
+// the new node was reserved before, so there is no garbage collection.
+func (r *reorderer) makex(level, low, high int) (int, error) {
+	if low == high {
+		return low, nil
+	}
+	ids := r.b.bulkmake([]int{0, 1, low, high}, [][3]int{{level, 2, 3}})
+	if ids == nil {
+		return -1, errMemory
+	}
+	r.grow()
+	return ids[4], nil
+}
+
+// sift applies the sifting algorithm, moving the variables in decreasing order
+// of the number of nodes that they label.
+func (r *reorderer) sift() error {
+	b := r.b
+	vars := make([]int, b.varnum)
+	size := make([]int, b.varnum)
+	for v := range vars {
+		vars[v] = v
+		size[v] = len(r.levels[b.var2level[v]])
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return size[vars[i]] > size[vars[j]] })
+	for _, v := range vars {
+		if err := r.siftvar(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// siftvar moves variable v to the level where the number of nodes is the
+// smallest. We start with the closest end of the order, and stop moving in one
+// direction when the number of nodes grows too much.
+func (r *reorderer) siftvar(v int) error {
+	b := r.b
+	last := int(b.varnum) - 1
+	level := int(b.var2level[v])
+	best, bestlevel, start := r.live, level, r.live
+	move := func(target int) error {
+		for level != target {
+			next := level + 1
+			if target < level {
+				next = level - 1
+			}
+			if err := r.swap(minint(level, next)); err != nil {
+				return err
+			}
+			level = next
+			if r.live < best {
+				best, bestlevel = r.live, level
+			}
+			if r.live*100 > start*_SIFTGROWTH {
+				return nil
+			}
+		}
+		return nil
+	}
+	ends := [2]int{0, last}
+	if level > last/2 {
+		ends = [2]int{last, 0}
+	}
+	for _, end := range ends {
+		if err := move(end); err != nil {
+			return err
+		}
+	}
+	for level != bestlevel {
+		next := level + 1
+		if bestlevel < level {
+			next = level - 1
+		}
+		if err := r.swap(minint(level, next)); err != nil {
+			return err
+		}
+		level = next
+	}
+	return nil
+}
+
+// symmetric returns true if the variables at levels l and l+1 are symmetric in
+// all the functions of the BDD. This is the case if, for every node at level
+// l, the cofactors for (x=0, y=1) and (x=1, y=0) are equal, and if the nodes
+// at level l+1 are only referenced from nodes at level l, since a function
+// that depends on y but not on x cannot be symmetric in x and y. Like in
+// CUDD, we ignore the nodes for the literals of x and y (see Ithvar), that
+// always exist. We do not group variables that do not label any other node.
+func (r *reorderer) symmetric(l int) bool {
+	b := r.b
+	x, y := b.level2var[l], b.level2var[l+1]
+	arcs, nodes := 0, 0
+	for _, n := range r.levels[l] {
+		if n == b.varset[x][0] || n == b.varset[x][1] {
+			continue
+		}
+		nodes++
+		f01, f10 := b.low(n), b.high(n)
+		if int(b.level(f01)) == l+1 {
+			f01 = b.high(f01)
+			arcs++
+		}
+		if int(b.level(f10)) == l+1 {
+			f10 = b.low(f10)
+			arcs++
+		}
+		if f01 != f10 {
+			return false
+		}
+	}
+	refs := 0
+	for _, n := range r.levels[l+1] {
+		refs += int(r.refs[n])
+		if n == b.varset[y][0] || n == b.varset[y][1] {
+			// we do not count the external reference to the literal
+			refs--
+		}
+	}
+	return nodes > 0 && refs == arcs
+}
+
+// symsift applies the symmetric sifting algorithm. We keep a partition of the
+// levels into blocks of adjacent levels, where sizes[i] is the number of
+// levels in the i-th block, from the top. Initially, blocks are the maximal
+// sequences of symmetric variables, and we merge two blocks each time they
+// are symmetric and side by side after sifting one of them. Blocks are
+// sifted in decreasing order of the number of nodes labelled by their
+// variables.
+func (r *reorderer) symsift() error {
+	b := r.b
+	sizes := []int{}
+	for l := 0; l < int(b.varnum); l++ {
+		if l > 0 && r.symmetric(l-1) {
+			sizes[len(sizes)-1]++
+		} else {
+			sizes = append(sizes, 1)
+		}
+	}
+	vars := make([]int, b.varnum)
+	size := make([]int, b.varnum)
+	for v := range vars {
+		vars[v] = v
+		size[v] = len(r.levels[b.var2level[v]])
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return size[vars[i]] > size[vars[j]] })
+	done := make([]bool, b.varnum)
+	for _, v := range vars {
+		if done[v] {
+			continue
+		}
+		// we find the block of v
+		i, top := 0, 0
+		for level := int(b.var2level[v]); top+sizes[i] <= level; i++ {
+			top += sizes[i]
+		}
+		for l := top; l < top+sizes[i]; l++ {
+			done[b.level2var[l]] = true
+		}
+		var err error
+		if sizes, err = r.siftblock(sizes, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// siftblock moves the i-th block of levels to the position where the number
+// of nodes is the smallest, like with siftvar, and merges it with the blocks
+// on each side if they are symmetric. We return the new partition of levels.
+func (r *reorderer) siftblock(sizes []int, i int) ([]int, error) {
+	best, besti, start := r.live, i, r.live
+	top := 0
+	for k := 0; k < i; k++ {
+		top += sizes[k]
+	}
+	// move exchanges the i-th block with the next one (when down is true) or
+	// with the previous one, and updates top and i.
+	move := func(down bool) error {
+		j := i - 1
+		if down {
+			j = i + 1
+		}
+		k, m := sizes[i], sizes[j]
+		if down {
+			// each variable of the block, starting with the lowest one, goes
+			// m levels down
+			for l := top + k - 1; l >= top; l-- {
+				for s := 0; s < m; s++ {
+					if err := r.swap(l + s); err != nil {
+						return err
+					}
+				}
+			}
+			top += m
+		} else {
+			for l := top; l < top+k; l++ {
+				for s := 1; s <= m; s++ {
+					if err := r.swap(l - s); err != nil {
+						return err
+					}
+				}
+			}
+			top -= m
+		}
+		sizes[i], sizes[j] = sizes[j], sizes[i]
+		i = j
+		return nil
+	}
+	sift := func(down bool) error {
+		for (down && i < len(sizes)-1) || (!down && i > 0) {
+			if err := move(down); err != nil {
+				return err
+			}
+			if r.live < best {
+				best, besti = r.live, i
+			}
+			if r.live*100 > start*_SIFTGROWTH {
+				return nil
+			}
+		}
+		return nil
+	}
+	dirs := [2]bool{false, true}
+	if 2*i > len(sizes)-1 {
+		dirs = [2]bool{true, false}
+	}
+	for _, down := range dirs {
+		if err := sift(down); err != nil {
+			return sizes, err
+		}
+	}
+	for i != besti {
+		if err := move(besti > i); err != nil {
+			return sizes, err
+		}
+	}
+	// we merge the block with its neighbours when they are symmetric
+	if i+1 < len(sizes) && r.symmetric(top+sizes[i]-1) {
+		sizes[i] += sizes[i+1]
+		sizes = append(sizes[:i+1], sizes[i+2:]...)
+	}
+	if i > 0 && r.symmetric(top-1) {
+		sizes[i-1] += sizes[i]
+		sizes = append(sizes[:i], sizes[i+1:]...)
+	}
+	return sizes, nil
+}
+
+func minint(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// truthtable returns the value of n for all the assignments of the variables.
+func truthtable(t *testing.T, bdd *BDD, n Node) []bool {
+	varnum := bdd.Varnum()
+	res := make([]bool, 1<<varnum)
+	assignment := make([]bool, varnum)
+	for k := range res {
+		for v := range assignment {
+			assignment[v] = k&(1<<v) != 0
+		}
+		val, err := bdd.Eval(n, assignment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res[k] = val
+	}
+	return res
+}
+
+func TestReorderSift(t *testing.T) {
+	const half = 6
+	bdd, _ := New(2*half, Nodesize(1000), Cachesize(1000))
+	// the disjunction of (x_i & x_{i+half}) has an exponential size with the
+	// initial order and a linear size when the two variables are adjacent
+	f := bdd.False()
+	for i := 0; i < half; i++ {
+		f = bdd.Or(f, bdd.And(bdd.Ithvar(i), bdd.Ithvar(i+half)))
+	}
+	sat := bdd.Satcount(f)
+	if err := bdd.Reorder(ReorderSift); err != nil {
+		t.Fatal(err)
+	}
+	if size := bdd.AnodeCount(f); size != 2*half {
+		t.Errorf("expected %d nodes after sifting, actual %d", 2*half, size)
+	}
+	for i := 0; i < half; i++ {
+		if d := bdd.Var2Level(i) - bdd.Var2Level(i+half); d != 1 && d != -1 {
+			t.Errorf("expected variables %d and %d to be adjacent", i, i+half)
+		}
+	}
+	if bdd.Satcount(f).Cmp(sat) != 0 {
+		t.Errorf("expected %s solutions after sifting, actual %s", sat, bdd.Satcount(f))
+	}
+}
+
+func TestReorder(t *testing.T) {
+	const half = 5
+	bdd, _ := New(2*half+2, Nodesize(1000), Cachesize(1000))
+	// the disjunction of (x_i & x_{i+half}) has an exponential size with the
+	// initial order and a linear size when the two variables are adjacent
+	f := bdd.False()
+	for i := 0; i < half; i++ {
+		f = bdd.Or(f, bdd.And(bdd.Ithvar(i), bdd.Ithvar(i+half)))
+	}
+	rng := rand.New(rand.NewSource(1))
+	g := bdd.False()
+	for k := 0; k < 6; k++ {
+		m := bdd.True()
+		for v := 0; v < 2*half; v++ {
+			switch rng.Intn(3) {
+			case 0:
+				m = bdd.And(m, bdd.Ithvar(v))
+			case 1:
+				m = bdd.And(m, bdd.NIthvar(v))
+			}
+		}
+		g = bdd.Or(g, m)
+	}
+	r, _ := bdd.NewReplacer([]int{0}, []int{2 * half})
+	tf, tg := truthtable(t, bdd, f), truthtable(t, bdd, g)
+	trf := truthtable(t, bdd, bdd.Replace(f, r))
+	before := bdd.AnodeCount(f)
+	if err := bdd.Reorder(ReorderSift); err != nil {
+		t.Fatal(err)
+	}
+	if after := bdd.AnodeCount(f); after >= before {
+		t.Errorf("expected less than %d nodes after sifting, actual %d", before, after)
+	}
+	bdd.Allnodes(func(id, level, low, high int) error {
+		if id > 1 && (level >= int(bdd.level(low)) || level >= int(bdd.level(high))) {
+			t.Errorf("node %d at level %d is not ordered", id, level)
+		}
+		return nil
+	})
+	check := func(name string, n Node, expected []bool) {
+		actual := truthtable(t, bdd, n)
+		for k := range expected {
+			if actual[k] != expected[k] {
+				t.Errorf("%s is not the same function after reordering", name)
+				return
+			}
+		}
+	}
+	check("f", f, tf)
+	check("g", g, tg)
+	fg := make([]bool, len(tf))
+	for k := range tf {
+		fg[k] = tf[k] && tg[k]
+	}
+	check("f & g", bdd.And(f, g), fg)
+	check("replace(f)", bdd.Replace(f, r), trf)
+	if bdd.Errored() {
+		t.Error(bdd.Error())
+	}
+	if err := bdd.Reorder(ReorderMethod(42)); err == nil {
+		t.Error("expected an error with an unknown method")
+	}
+	bdd.SealForQueries()
+	if err := bdd.Reorder(ReorderSift); err == nil {
+		t.Error("expected an error with a sealed BDD")
+	}
+}
+
+func TestSetVarOrder(t *testing.T) {
+	bdd, _ := New(6, Nodesize(1000), Cachesize(1000))
+	f := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(3)), bdd.And(bdd.Ithvar(1), bdd.Ithvar(4)), bdd.And(bdd.Ithvar(2), bdd.Ithvar(5)))
+	expected := truthtable(t, bdd, f)
+	order := []int{3, 0, 4, 1, 5, 2}
+	if err := bdd.SetVarOrder(order); err != nil {
+		t.Fatal(err)
+	}
+	for l, v := range order {
+		if bdd.Level2Var(l) != v {
+			t.Errorf("expected variable %d at level %d, actual %d", v, l, bdd.Level2Var(l))
+		}
+		if bdd.Var2Level(v) != l {
+			t.Errorf("expected level %d for variable %d, actual %d", l, v, bdd.Var2Level(v))
+		}
+	}
+	if actual := bdd.VarOrder(); fmt.Sprint(actual) != fmt.Sprint(order) {
+		t.Errorf("expected order %v, actual %v", order, actual)
+	}
+	if size := bdd.AnodeCount(f); size != 6 {
+		t.Errorf("expected 6 nodes with the interleaved order, actual %d", size)
+	}
+	actual := truthtable(t, bdd, f)
+	for k := range expected {
+		if actual[k] != expected[k] {
+			t.Fatal("f is not the same function after SetVarOrder")
+		}
+	}
+	for _, order := range [][]int{{0, 1, 2}, {0, 1, 2, 3, 4, 4}, {0, 1, 2, 3, 4, 6}} {
+		if err := bdd.SetVarOrder(order); err == nil {
+			t.Errorf("expected an error with order %v", order)
+		}
+	}
+}
+
+func TestAutoreorder(t *testing.T) {
+	const half = 6
+	build := func(bdd *BDD) Node {
+		f := bdd.False()
+		for i := 0; i < half; i++ {
+			f = bdd.Or(f, bdd.And(bdd.Ithvar(i), bdd.Ithvar(i+half)))
+		}
+		return f
+	}
+	ref, _ := New(2*half, Nodesize(1000), Cachesize(1000))
+	expected := truthtable(t, ref, build(ref))
+	bdd, _ := New(2*half, Nodesize(30), Cachesize(100), Minfreenodes(50), Autoreorder(ReorderSift, 0))
+	f := build(bdd)
+	if f == nil {
+		t.Fatal(bdd.Error())
+	}
+	if bdd.reorders == 0 {
+		t.Errorf("expected an automatic reordering")
+	}
+	actual := truthtable(t, bdd, f)
+	for k := range expected {
+		if expected[k] != actual[k] {
+			t.Fatalf("wrong value after automatic reordering for assignment %b", k)
+		}
+	}
+	if size, limit := bdd.AnodeCount(f), ref.AnodeCount(build(ref)); size >= limit {
+		t.Errorf("expected less than %d nodes after automatic reordering, actual %d", limit, size)
+	}
+}
+
+func TestReorderperiod(t *testing.T) {
+	const half = 6
+	build := func(bdd *BDD) Node {
+		f := bdd.False()
+		for i := 0; i < half; i++ {
+			f = bdd.Or(f, bdd.And(bdd.Ithvar(i), bdd.Ithvar(i+half)))
+		}
+		return f
+	}
+	// the threshold is never reached, so we only reorder with a period
+	for _, period := range []int{0, 1} {
+		bdd, _ := New(2*half, Nodesize(30), Cachesize(100), Minfreenodes(50), Autoreorder(ReorderSift, 1<<20), Reorderperiod(period))
+		if build(bdd) == nil {
+			t.Fatal(bdd.Error())
+		}
+		if reordered := bdd.reorders > 0; reordered != (period > 0) {
+			t.Errorf("with a period of %d garbage collections, expected reordering %v, actual %v", period, period > 0, reordered)
+		}
+	}
+}
+
+func TestReorderSymSift(t *testing.T) {
+	bdd, _ := New(12, Nodesize(1000), Cachesize(1000))
+	x := bdd.Ithvar
+	// threshold returns a function that is true when at least k of the
+	// variables in vs are true, and which is symmetric in vs
+	var threshold func(vs []int, k int) Node
+	threshold = func(vs []int, k int) Node {
+		if k == 0 {
+			return bdd.True()
+		}
+		if len(vs) < k {
+			return bdd.False()
+		}
+		return bdd.Ite(x(vs[0]), threshold(vs[1:], k-1), threshold(vs[1:], k))
+	}
+	f := threshold([]int{0, 3, 6, 9, 11}, 3)
+	g := bdd.Or(bdd.And(x(1), x(7)), bdd.And(x(4), x(10)), bdd.And(x(2), x(8)), bdd.And(x(5), x(11)))
+	h := bdd.Apply(f, g, OPxor)
+	// we release the intermediate results, that would break the symmetries
+	collectroots()
+	r := bdd.newreorderer()
+	if r.symmetric(0) || r.symmetric(1) {
+		t.Errorf("expected variables 0, 1 and 2 not to be symmetric")
+	}
+	r.done()
+	expected := truthtable(t, bdd, h)
+	if err := bdd.Reorder(ReorderSymSift); err != nil {
+		t.Fatal(err)
+	}
+	actual := truthtable(t, bdd, h)
+	for k := range expected {
+		if expected[k] != actual[k] {
+			t.Fatalf("wrong value after symmetric sifting for assignment %b", k)
+		}
+	}
+	groups := bdd.SymmetricVars(h)
+	if len(groups) == 0 {
+		t.Fatalf("expected symmetric variables in h")
+	}
+	r = bdd.newreorderer()
+	for _, group := range groups {
+		min, max := bdd.Varnum(), -1
+		for _, v := range group {
+			if l := bdd.Var2Level(v); l < min {
+				min = l
+			}
+			if l := bdd.Var2Level(v); l > max {
+				max = l
+			}
+		}
+		if max-min != len(group)-1 {
+			t.Errorf("expected the symmetric variables %v to be at adjacent levels", group)
+			continue
+		}
+		for l := min; l < max; l++ {
+			if !r.symmetric(l) {
+				t.Errorf("expected levels %d and %d to be symmetric", l, l+1)
+			}
+		}
+	}
+	r.done()
+}
@@ -22,15 +22,17 @@ type Replacer interface {
 }
 
 type replacer struct {
-	id    int     // unique identifier used for caching intermediate results
-	image []int32 // map the level of old variables to the level of new variables
-	last  int32   // last index in the Replacer, to speed up computations
+	id     int     // unique identifier used for caching intermediate results
+	vimage []int32 // map old variables to new variables (using variable indices)
+	image  []int32 // map the level of old variables to the level of new variables
+	last   int32   // last level in the Replacer, to speed up computations
+	order  int     // value of b.reorders when the levels were computed
 }
 
 func (r *replacer) String() string {
 	res := fmt.Sprintf("replacer(last: %d)[", r.last)
 	first := true
-	for k, v := range r.image {
+	for k, v := range r.vimage {
 		if k != int(v) {
 			if !first {
 				res += ", "
@@ -42,6 +44,21 @@ func (r *replacer) String() string {
 	return res + "]"
 }
 
+// setlevels computes the mapping between levels used in the Replace operation
+// from the mapping between variables in r.vimage, using the current variable
+// order of b.
+func (r *replacer) setlevels(b *BDD) {
+	r.order = b.reorders
+	r.last = 0
+	for v, w := range r.vimage {
+		level := b.var2level[v]
+		r.image[level] = b.var2level[w]
+		if (int32(v) != w) && (level > r.last) {
+			r.last = level
+		}
+	}
+}
+
 func (r *replacer) Replace(level int32) (int32, bool) {
 	if level > r.last {
 		return level, false
@@ -56,7 +73,7 @@ func (r *replacer) Id() int {
 // NewReplacer returns a Replacer that can be used for substituting variable
 // oldvars[k] with newvars[k] in the BDD b. We return an error if the two slices
 // do not have the same length or if we find the same index twice in either of
-// them. All values must be in the interval [0..Varnum).
+// them. All values must be variable indices in the interval [0..Varnum).
 func (b *BDD) NewReplacer(oldvars, newvars []int) (Replacer, error) {
 	res := &replacer{}
 	if len(oldvars) != len(newvars) {
@@ -69,30 +86,32 @@ func (b *BDD) NewReplacer(oldvars, newvars []int) (Replacer, error) {
 	_REPLACEID++
 	varnum := b.Varnum()
 	support := make([]bool, varnum)
+	res.vimage = make([]int32, varnum)
 	res.image = make([]int32, varnum)
-	for k := range res.image {
-		res.image[k] = int32(k)
+	for k := range res.vimage {
+		res.vimage[k] = int32(k)
 	}
 	for k, v := range oldvars {
+		if v < 0 || v >= varnum {
+			return nil, fmt.Errorf("invalid variable in oldvars (%d)", v)
+		}
 		if support[v] {
 			return nil, fmt.Errorf("duplicate variable (%d) in oldvars", v)
 		}
-		if v >= varnum {
-			return nil, fmt.Errorf("invalid variable in oldvars (%d)", v)
-		}
-		if newvars[k] >= varnum {
-			return nil, fmt.Errorf("invalid variable in newvars (%d)", v)
+		if newvars[k] < 0 || newvars[k] >= varnum {
+			return nil, fmt.Errorf("invalid variable in newvars (%d)", newvars[k])
 		}
 		support[v] = true
-		res.image[v] = int32(newvars[k])
-		if int32(v) > res.last {
-			res.last = int32(v)
-		}
+		res.vimage[v] = int32(newvars[k])
 	}
 	for _, v := range newvars {
-		if int(res.image[v]) != v {
+		if int(res.vimage[v]) != v {
 			return nil, fmt.Errorf("variable in newvars (%d) also occur in oldvars", v)
 		}
 	}
+	res.setlevels(b)
+	if b.journal != nil {
+		b.recordreplacer(res, oldvars, newvars)
+	}
 	return res, nil
 }
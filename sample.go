@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/big"
+	"math/rand"
+	"sort"
+)
+
+// SampleK returns k distinct satisfying assignments of n, drawn uniformly at
+// random (without replacement) among the Satcount(n) possible ones. Each
+// assignment is a slice of length Varnum, indexed by variables, where each entry
+// is either 0 or 1 (there are no don't care). Assignments are returned in
+// lexicographic order, following the variable order, with 0 before 1. We
+// return all the satisfying assignments of n if k is larger than Satcount(n).
+// We return nil, and set the error flag in b, if there is an error.
+//
+// Samples are obtained by choosing a random subset of k indices, using Floyd's
+// algorithm, then computing the assignment with each index by a descent in the
+// BDD that uses the number of satisfying assignments of each node.
+func (b *BDD) SampleK(n Node, k int, rng *rand.Rand) [][]int {
+	if b.checkptr(n) != nil {
+		b.seterror("Wrong operand in call to SampleK")
+		return nil
+	}
+	if rng == nil {
+		b.seterror("nil source of randomness in call to SampleK")
+		return nil
+	}
+	satc := make(map[int]*big.Int)
+	total := b.Satcount(n)
+	if total.Sign() == 0 || k <= 0 {
+		return [][]int{}
+	}
+	// we choose a random subset of k indices in [0..total)
+	var indices []*big.Int
+	if total.Cmp(big.NewInt(int64(k))) <= 0 {
+		k = int(total.Int64())
+		indices = make([]*big.Int, k)
+		for i := range indices {
+			indices[i] = big.NewInt(int64(i))
+		}
+	} else {
+		chosen := make(map[string]bool, k)
+		indices = make([]*big.Int, 0, k)
+		j := new(big.Int).Sub(total, big.NewInt(int64(k)))
+		for i := 0; i < k; i++ {
+			t := new(big.Int).Rand(rng, new(big.Int).Add(j, big.NewInt(1)))
+			if chosen[t.String()] {
+				t.Set(j)
+			}
+			chosen[t.String()] = true
+			indices = append(indices, t)
+			j = new(big.Int).Add(j, big.NewInt(1))
+		}
+		sort.Slice(indices, func(i, j int) bool { return indices[i].Cmp(indices[j]) < 0 })
+	}
+	res := make([][]int, k)
+	for i, r := range indices {
+		res[i] = b.unrank(*n, r, satc)
+	}
+	return res
+}
+
+// unrank returns the assignment with index r, in lexicographic order, among the
+// satisfying assignments of n. We use satc to memoize the result of satcount.
+// The value of r is modified.
+func (b *BDD) unrank(n int, r *big.Int, satc map[int]*big.Int) []int {
+	res := make([]int, b.varnum)
+	half := new(big.Int)
+	c := n
+	for l := int32(0); l < b.varnum; l++ {
+		v := b.level2var[l]
+		lc := b.level(c)
+		if lc > l {
+			// variable at level l is not constrained; there are
+			// 2^(lc - l - 1) * satcount(c) assignments for each value.
+			half.SetInt64(0)
+			half.SetBit(half, int(lc-l-1), 1)
+			half.Mul(half, b.satcount(c, satc))
+			if r.Cmp(half) < 0 {
+				res[v] = 0
+			} else {
+				res[v] = 1
+				r.Sub(r, half)
+			}
+			continue
+		}
+		low := b.low(c)
+		half.SetInt64(0)
+		half.SetBit(half, int(b.level(low)-l-1), 1)
+		half.Mul(half, b.satcount(low, satc))
+		if r.Cmp(half) < 0 {
+			res[v] = 0
+			c = low
+		} else {
+			res[v] = 1
+			r.Sub(r, half)
+			c = b.high(c)
+		}
+	}
+	return res
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestSampleK(t *testing.T) {
+	bdd, _ := New(6)
+	// n == (x1 | !x3 | x4) & x5, with 28 satisfying assignments
+	n := bdd.And(bdd.Or(bdd.Ithvar(1), bdd.NIthvar(3), bdd.Ithvar(4)), bdd.Ithvar(5))
+	rng := rand.New(rand.NewSource(42))
+	samples := bdd.SampleK(n, 10, rng)
+	if len(samples) != 10 {
+		t.Fatalf("expected 10 samples, actual %d", len(samples))
+	}
+	seen := make(map[string]bool)
+	for k, s := range samples {
+		key := fmt.Sprint(s)
+		if seen[key] {
+			t.Errorf("duplicate sample %s", key)
+		}
+		seen[key] = true
+		cube := bdd.True()
+		for v, val := range s {
+			if val == 1 {
+				cube = bdd.And(cube, bdd.Ithvar(v))
+			} else {
+				cube = bdd.And(cube, bdd.NIthvar(v))
+			}
+		}
+		if bdd.Equal(bdd.And(cube, n), bdd.False()) {
+			t.Errorf("sample %d (%s) is not a satisfying assignment", k, key)
+		}
+	}
+	if all := bdd.SampleK(n, 100, rng); len(all) != 28 {
+		t.Errorf("expected all 28 assignments, actual %d", len(all))
+	}
+	if none := bdd.SampleK(bdd.False(), 3, rng); len(none) != 0 {
+		t.Errorf("expected no sample for False, actual %d", len(none))
+	}
+}
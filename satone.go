@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+// Fullsatone returns a cube over all the variables of b that implies n,
+// meaning a complete assignment satisfying n, or False if n is unsatisfiable.
+// We follow the low branch of a node whenever possible, and the variables that
+// are not constrained on this path (don't cares) take the value polarity, that
+// should be either 0 or 1. This is the same as bdd_fullsatone in BuDDy, with a
+// polarity of 0.
+func (b *BDD) Fullsatone(n Node, polarity int) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "fullsatone", n, polarity) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Fullsatone")
+	}
+	if b.checkptr(n) != nil {
+		return b.seterror("Wrong operand in call to Fullsatone (%d)", *n)
+	}
+	if polarity != 0 && polarity != 1 {
+		return b.seterror("Wrong polarity (%d) in call to Fullsatone", polarity)
+	}
+	if *n == 0 {
+		return bddzero
+	}
+	// value[l] is the value of the variable at level l
+	value := make([]int, b.varnum)
+	for k := range value {
+		value[k] = polarity
+	}
+	for m := *n; m > 1; {
+		if low := b.low(m); low != 0 {
+			value[b.level(m)] = 0
+			m = low
+		} else {
+			value[b.level(m)] = 1
+			m = b.high(m)
+		}
+	}
+	b.Initref()
+	return b.Retnode(b.makecube(value))
+}
+
+// makecube returns the cube where the variable at level l is positive if
+// value[l] is 1, negative if it is 0, and does not occur otherwise.
+func (b *BDD) makecube(value []int) int {
+	res, count := 1, 0
+	for l := len(value) - 1; l >= 0; l-- {
+		switch value[l] {
+		case 0:
+			res = b.makenode(int32(l), res, 0)
+		case 1:
+			res = b.makenode(int32(l), 0, res)
+		default:
+			continue
+		}
+		b.Pushref(res)
+		count++
+	}
+	b.Popref(count)
+	return res
+}
+
+// Satoneset returns a cube, over exactly the variables in varset, that is
+// compatible with n, meaning that its conjunction with n is satisfiable; or
+// False if n is unsatisfiable. Like with Fullsatone, we follow the low branch
+// of a node whenever possible, and the variables of varset that are not
+// constrained on this path take the value given by pol, that should be either
+// True or False. The other variables do not occur in the result. This is
+// useful to extract a witness, such as a counterexample, over a subset of the
+// variables, for instance the state variables. The parameter varset should be a
+// set of variables built with Makeset.
+func (b *BDD) Satoneset(n, varset, pol Node) (result Node) {
+	if b.journal != nil {
+		defer func() { b.record(result, "satoneset", n, varset, pol) }()
+	}
+	defer b.catchoom(&result)
+	if b.sealed != nil {
+		return b.sealerror("Satoneset")
+	}
+	if b.checkptr(n) != nil || b.checkptr(varset) != nil || b.checkptr(pol) != nil {
+		return b.seterror("Wrong operand in call to Satoneset")
+	}
+	if *pol > 1 {
+		return b.seterror("Wrong polarity in call to Satoneset (should be True or False)")
+	}
+	if *n == 0 {
+		return bddzero
+	}
+	value := make([]int, b.varnum)
+	for k := range value {
+		value[k] = -1
+	}
+	for m := *varset; m != 1; m = b.high(m) {
+		if m == 0 || b.low(m) != 0 {
+			return b.seterror("Wrong varset in call to Satoneset (not built with Makeset)")
+		}
+		value[b.level(m)] = *pol
+	}
+	for m := *n; m > 1; {
+		level, v := b.level(m), 0
+		if low := b.low(m); low != 0 {
+			m = low
+		} else {
+			v, m = 1, b.high(m)
+		}
+		if value[level] >= 0 {
+			value[level] = v
+		}
+	}
+	b.Initref()
+	return b.Retnode(b.makecube(value))
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "testing"
+
+func TestFullsatone(t *testing.T) {
+	bdd, _ := New(5)
+	// n = (x1 & !x3) | x4
+	n := bdd.Or(bdd.And(bdd.Ithvar(1), bdd.NIthvar(3)), bdd.Ithvar(4))
+	for _, polarity := range []int{0, 1} {
+		res := bdd.Fullsatone(n, polarity)
+		if !bdd.Equal(bdd.Imp(res, n), bdd.True()) {
+			t.Errorf("Fullsatone(n, %d) should imply n", polarity)
+		}
+		if lits := bdd.Scanlits(res); len(lits) != 5 {
+			t.Errorf("Fullsatone(n, %d) should be a complete assignment, actual %v", polarity, lits)
+		}
+	}
+	// we follow low branches, so x1 is false, and x4 is true
+	expected := bdd.Makelits([]Literal{Pos(0), Neg(1), Pos(2), Pos(3), Pos(4)})
+	if res := bdd.Fullsatone(n, 1); !bdd.Equal(res, expected) {
+		t.Errorf("Fullsatone(n, 1) should be %v, actual %v", bdd.Scanlits(expected), bdd.Scanlits(res))
+	}
+	if !bdd.Equal(bdd.Fullsatone(bdd.False(), 0), bdd.False()) {
+		t.Errorf("Fullsatone(False) should be False")
+	}
+	if bdd.Fullsatone(n, 2) != nil {
+		t.Errorf("Fullsatone should fail with a wrong polarity")
+	}
+}
+
+func TestSatoneset(t *testing.T) {
+	bdd, _ := New(6)
+	// n = (x1 & !x3) | x4, with state variables 0, 1 and 2
+	n := bdd.Or(bdd.And(bdd.Ithvar(1), bdd.NIthvar(3)), bdd.Ithvar(4))
+	state := bdd.Makeset([]int{0, 1, 2})
+	for _, pol := range []Node{bdd.False(), bdd.True()} {
+		res := bdd.Satoneset(n, state, pol)
+		if bdd.Equal(bdd.And(res, n), bdd.False()) {
+			t.Errorf("Satoneset(n, {0, 1, 2}) should be compatible with n")
+		}
+		lits := bdd.Scanlits(res)
+		if len(lits) != 3 || lits[0].Var() != 0 || lits[2].Var() != 2 {
+			t.Errorf("Satoneset(n, {0, 1, 2}) should be a cube over the state variables, actual %v", lits)
+		}
+		if lits[0].Positive() != bdd.Equal(pol, bdd.True()) {
+			t.Errorf("don't cares in Satoneset should follow the polarity")
+		}
+	}
+	// the path found in n sets x1 to false
+	expected := bdd.Makelits([]Literal{Pos(0), Neg(1), Pos(2)})
+	if res := bdd.Satoneset(n, state, bdd.True()); !bdd.Equal(res, expected) {
+		t.Errorf("Satoneset(n, {0, 1, 2}, True) should be %v, actual %v", bdd.Scanlits(expected), bdd.Scanlits(res))
+	}
+	if !bdd.Equal(bdd.Satoneset(bdd.False(), state, bdd.True()), bdd.False()) {
+		t.Errorf("Satoneset(False) should be False")
+	}
+	if bdd.Satoneset(n, n, bdd.True()) != nil {
+		t.Errorf("Satoneset should fail if varset is not a set of variables")
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// _SAVEMAGIC is the magic number at the start of a stream written by Save and
+// _SAVEVERSION is the current version of the format. Version 1 has no codec
+// byte in its header and versions 1 and 2 have no sections; they are still
+// accepted by Load.
+const (
+	_SAVEMAGIC   = "RUDD"
+	_SAVEVERSION = 3
+)
+
+// Save writes a binary description of the nodes reachable from n to w, that
+// can be read back with Load, possibly in another BDD. This is the same as
+// SaveWith without compression.
+func (b *BDD) Save(w io.Writer, n ...Node) error {
+	return b.SaveWith(w, nil, n...)
+}
+
+// SaveWith writes a binary description of the nodes reachable from n to w,
+// compressed using codec c, or uncompressed if c is nil. The stream starts
+// with a header made of the magic number "RUDD", the version of the format
+// (one byte), the byte order used for fixed-size values (the byte 'L', for
+// little endian) and the ID of the codec (one byte, 0 if there is no
+// compression). The rest of the stream is compressed. It starts with the
+// number of variables and the variable found at each level, in the order of
+// levels. Then comes the payload: the number of nodes, a triplet (variable,
+// low, high) for each node, the number of roots and the id of each root. All
+// these values are unsigned varints (see package encoding/binary). Next is a
+// sequence of optional sections, used with SaveModel, each one made of a
+// non-zero tag, a length and the content of the section, ended by a zero tag.
+// Load ignores the sections it does not know. The stream ends with the CRC-32
+// checksum (IEEE) of all the preceding bytes, before compression, as a 4 bytes
+// integer.
+//
+// We use the same numbering of nodes as Flatten. Nodes are written children
+// before parents and have contiguous ids, starting from 2 in the order they
+// appear in the stream, with ids 0 and 1 for the constants False and True.
+// Hence a consumer can rebuild each node as soon as it is read, in a single
+// pass, and only needs to keep the map from ids to nodes, plus the roots.
+func (b *BDD) SaveWith(w io.Writer, c Codec, n ...Node) error {
+	roots := make([]int, len(n))
+	for k, v := range n {
+		if err := b.checkptr(v); err != nil {
+			return fmt.Errorf("wrong node in call to Save; %s", err)
+		}
+		roots[k] = *v
+	}
+	return b.save(w, c, roots, nil, nil)
+}
+
+// save writes the nodes reachable from roots and extra, but only lists roots
+// as the roots of the stream. The content of the optional sections, if any, is
+// computed by calling sections with the map from ids in b to ids in the
+// stream.
+func (b *BDD) save(w io.Writer, c Codec, roots, extra []int, sections func(id map[int]int) []byte) error {
+	nodes := b.topo(append(append([]int{}, roots...), extra...)...)
+	id := make(map[int]int, len(nodes)+2)
+	id[0] = 0
+	id[1] = 1
+	var cid byte
+	if c != nil {
+		cid = c.ID()
+	}
+	header := append([]byte(_SAVEMAGIC), _SAVEVERSION, 'L', cid)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	var cw io.WriteCloser = nopWriteCloser{w}
+	if c != nil {
+		var err error
+		if cw, err = c.NewWriter(w); err != nil {
+			return err
+		}
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(header)
+	bw := bufio.NewWriter(io.MultiWriter(cw, crc))
+	buf := make([]byte, 0, 3*binary.MaxVarintLen64)
+	put := func(x ...int) {
+		buf = buf[:0]
+		for _, v := range x {
+			buf = binary.AppendUvarint(buf, uint64(v))
+		}
+		bw.Write(buf)
+	}
+	put(int(b.varnum))
+	for _, v := range b.level2var {
+		put(int(v))
+	}
+	put(len(nodes))
+	for k, v := range nodes {
+		id[v] = k + 2
+		put(int(b.level2var[b.level(v)]), id[b.low(v)], id[b.high(v)])
+	}
+	put(len(roots))
+	for _, v := range roots {
+		put(id[v])
+	}
+	if sections != nil {
+		bw.Write(sections(id))
+	}
+	put(0)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if _, err := cw.Write(binary.LittleEndian.AppendUint32(nil, crc.Sum32())); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// Load reads a description of nodes written with Save, or SaveWith, from r and
+// returns the roots, in the order they were given to Save. The BDD b must have
+// at least as many variables as the one used with Save. We return an error if
+// the stream is not well-formed, for instance if it has a wrong header or
+// checksum, or if it uses a codec that has not been registered.
+//
+// Nodes are rebuilt on the fly, using the bulk insertion path of the node
+// table, as long as the variable order of b is compatible with the structure
+// of the saved BDD. Otherwise we switch to using Ite for the remaining nodes.
+// We always use Ite when the Journal option is set, so that the nodes are
+// recorded in the journal.
+// Since the checksum is at the end of the stream, new nodes may have been
+// added to b even when we return an error.
+func (b *BDD) Load(r io.Reader) ([]Node, error) {
+	if b.sealed != nil {
+		return nil, fmt.Errorf("error in call to Load; %w", ErrSealed)
+	}
+	res, _, _, err := b.load(r, false)
+	if err != nil {
+		return nil, fmt.Errorf("error in call to Load; %s", err)
+	}
+	return res, nil
+}
+
+// load reads a stream written by save. It returns the roots, a function giving
+// the node associated with an id of the stream (or nil if the id is not
+// valid) and the content of the optional sections, indexed by their tags. When
+// setorder is true, we change the variable order of b to the saved one before
+// inserting the nodes; b must then have the same number of variables than the
+// saved BDD.
+func (b *BDD) load(r io.Reader, setorder bool) ([]Node, func(int) Node, map[int][]byte, error) {
+	raw := bufio.NewReader(r)
+	header := make([]byte, len(_SAVEMAGIC)+2, len(_SAVEMAGIC)+3)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot read header, %s", err)
+	}
+	if string(header[:len(_SAVEMAGIC)]) != _SAVEMAGIC {
+		return nil, nil, nil, fmt.Errorf("bad magic number, not a BDD file")
+	}
+	version := header[len(_SAVEMAGIC)]
+	if version < 1 || version > _SAVEVERSION {
+		return nil, nil, nil, fmt.Errorf("unsupported format version (%d)", version)
+	}
+	if header[len(_SAVEMAGIC)+1] != 'L' {
+		return nil, nil, nil, fmt.Errorf("unsupported byte order (%q)", header[len(_SAVEMAGIC)+1])
+	}
+	var cr io.Reader = raw
+	if version > 1 {
+		cid, err := raw.ReadByte()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("cannot read header, %s", err)
+		}
+		header = append(header, cid)
+		if cid != 0 {
+			c := findCodec(cid)
+			if c == nil {
+				return nil, nil, nil, fmt.Errorf("unknown codec (%d)", cid)
+			}
+			rc, err := c.NewReader(raw)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			defer rc.Close()
+			cr = rc
+		}
+	}
+	br := &crcReader{
+		Reader: bufio.NewReader(cr),
+		buf:    make([]byte, 0, 4096),
+		crc:    crc32.ChecksumIEEE(header),
+	}
+	get := func(bound int) (int, error) {
+		v, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil && v >= uint64(bound) {
+			err = fmt.Errorf("value out of range (%d)", v)
+		}
+		return int(v), err
+	}
+	varnum, err := get(math.MaxInt32)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("wrong number of variables, %s", err)
+	}
+	if varnum > int(b.varnum) {
+		return nil, nil, nil, fmt.Errorf("the file uses %d variables but the BDD has only %d", varnum, b.varnum)
+	}
+	seen := make([]bool, varnum)
+	order := make([]int, varnum)
+	for k := 0; k < varnum; k++ {
+		v, err := get(varnum)
+		if err == nil && seen[v] {
+			err = fmt.Errorf("variable %d occurs twice", v)
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("bad variable order, %s", err)
+		}
+		seen[v] = true
+		order[k] = v
+	}
+	if setorder {
+		if varnum != int(b.varnum) {
+			return nil, nil, nil, fmt.Errorf("the file uses %d variables but the BDD has %d", varnum, b.varnum)
+		}
+		if err := b.SetVarOrder(order); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	count, err := get(math.MaxInt32)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("wrong number of nodes, %s", err)
+	}
+	if max := b.config.maxnodesize; max > 0 && count > max {
+		return nil, nil, nil, fmt.Errorf("the file has %d nodes but the BDD is limited to %d", count, max)
+	}
+	// count has not been checked against the checksum yet, so we only reserve
+	// room for a bounded number of nodes up front; the table grows with each
+	// call to bulkmake afterwards.
+	if !b.reserve(minint(count, _DEFAULTMAXNODEINC)) {
+		return nil, nil, nil, errMemory
+	}
+	// ids is the map from ids in the stream to ids in the node table; we
+	// switch to nodes, with external references, if we need to use Ite. The
+	// ids are pushed on the refstack, so that they are not reclaimed by a
+	// garbage collection when bulkmake needs to grow the table.
+	ids := []int{0, 1}
+	b.Initref()
+	var nodes []Node
+	if b.journal != nil {
+		// we only use Ite, so that the nodes are recorded in the journal
+		nodes, ids = []Node{bddzero, bddone}, nil
+	}
+	one := make([][3]int, 1)
+	for k := 2; k < count+2; k++ {
+		var v [3]int
+		for i, bound := range []int{varnum, k, k} {
+			if v[i], err = get(bound); err != nil {
+				return nil, nil, nil, fmt.Errorf("bad node %d, %s", k, err)
+			}
+		}
+		if nodes == nil {
+			level := b.var2level[v[0]]
+			if level < b.level(ids[v[1]]) && level < b.level(ids[v[2]]) {
+				one[0] = [3]int{int(level), v[1], v[2]}
+				if ids = b.bulkmake(ids, one); ids == nil {
+					return nil, nil, nil, errMemory
+				}
+				b.Pushref(ids[k])
+				continue
+			}
+			nodes = make([]Node, len(ids))
+			for i, id := range ids {
+				nodes[i] = b.Retnode(id)
+			}
+			ids = nil
+			b.Initref()
+		}
+		nodes = append(nodes, b.Ite(b.Ithvar(v[0]), nodes[v[2]], nodes[v[1]]))
+		if nodes[k] == nil {
+			return nil, nil, nil, b.error
+		}
+	}
+	node := func(k int) Node {
+		switch {
+		case k < 0 || k >= count+2:
+			return nil
+		case nodes == nil:
+			return b.Retnode(ids[k])
+		}
+		return nodes[k]
+	}
+	nroots, err := get(math.MaxInt32)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("wrong number of roots, %s", err)
+	}
+	res := []Node{}
+	for k := 0; k < nroots; k++ {
+		v, err := get(count + 2)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("bad root, %s", err)
+		}
+		res = append(res, node(v))
+	}
+	sections := make(map[int][]byte)
+	for version >= 3 {
+		tag, err := get(math.MaxInt32)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("bad section, %s", err)
+		}
+		if tag == 0 {
+			break
+		}
+		length, err := get(math.MaxInt32)
+		var content bytes.Buffer
+		if err == nil {
+			_, err = io.CopyN(&content, br, int64(length))
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("bad section %d, %s", tag, err)
+		}
+		sections[tag] = content.Bytes()
+	}
+	expected := br.sum()
+	checksum := make([]byte, 4)
+	if _, err := io.ReadFull(br.Reader, checksum); err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot read checksum, %s", err)
+	}
+	if actual := binary.LittleEndian.Uint32(checksum); actual != expected {
+		return nil, nil, nil, fmt.Errorf("checksum mismatch (expected %08x, actual %08x)", expected, actual)
+	}
+	return res, node, sections, nil
+}
+
+// crcReader is a ByteReader that computes the checksum of the bytes read so
+// far. We buffer the bytes to avoid updating the checksum for each of them.
+type crcReader struct {
+	*bufio.Reader
+	buf []byte
+	crc uint32
+}
+
+func (r *crcReader) ReadByte() (byte, error) {
+	c, err := r.Reader.ReadByte()
+	if err == nil {
+		r.buf = append(r.buf, c)
+		if len(r.buf) == cap(r.buf) {
+			r.sum()
+		}
+	}
+	return c, err
+}
+
+func (r *crcReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.crc = crc32.Update(r.sum(), crc32.IEEETable, p[:n])
+	return n, err
+}
+
+// sum returns the checksum of the bytes read so far.
+func (r *crcReader) sum() uint32 {
+	r.crc = crc32.Update(r.crc, crc32.IEEETable, r.buf)
+	r.buf = r.buf[:0]
+	return r.crc
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	bdd, _ := New(6)
+	n1 := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(4)), bdd.NIthvar(2))
+	n2 := bdd.Equiv(bdd.Ithvar(1), bdd.Ithvar(5))
+	var buf bytes.Buffer
+	if err := bdd.Save(&buf, n1, n2, bdd.True()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	res, err := bdd.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 3 || *res[0] != *n1 || *res[1] != *n2 || *res[2] != 1 {
+		t.Errorf("Load(Save(n)) should return n")
+	}
+	other, _ := New(7, Nodesize(10))
+	res, err = other.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := other.Satcount(res[0]); actual.Int64() != 2*bdd.Satcount(n1).Int64() {
+		t.Errorf("Load in a larger BDD, expected %d assignments, actual %s", 2*bdd.Satcount(n1).Int64(), actual)
+	}
+	// we cannot load a BDD with too many variables or a truncated stream
+	small, _ := New(5)
+	if _, err := small.Load(bytes.NewReader(data)); err == nil {
+		t.Errorf("Load should fail when there are too many variables")
+	}
+	for k := 0; k < len(data); k++ {
+		if _, err := bdd.Load(bytes.NewReader(data[:k])); err == nil {
+			t.Errorf("Load should fail with a stream truncated to %d bytes", k)
+		}
+	}
+}
+
+// TestSaveOrder checks that nodes are saved children before parents, with
+// contiguous ids, by reading the stream directly.
+func TestSaveOrder(t *testing.T) {
+	bdd, _ := New(8)
+	n := bdd.True()
+	for k := 0; k < 8; k += 2 {
+		n = bdd.And(n, bdd.Equiv(bdd.Ithvar(k), bdd.Ithvar(k+1)))
+	}
+	m := bdd.Exist(n, bdd.Makeset([]int{3, 4}))
+	var buf bytes.Buffer
+	if err := bdd.Save(&buf, m, n); err != nil {
+		t.Fatal(err)
+	}
+	get := func() int {
+		v, err := binary.ReadUvarint(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(v)
+	}
+	buf.Next(len(_SAVEMAGIC) + 3)
+	if varnum := get(); varnum != 8 {
+		t.Errorf("expected 8 variables, actual %d", varnum)
+	}
+	for k := 0; k < 8; k++ {
+		if v := get(); v != k {
+			t.Errorf("expected variable %d at level %d, actual %d", k, k, v)
+		}
+	}
+	count := get()
+	for k := 2; k < count+2; k++ {
+		if v, low, high := get(), get(), get(); v >= 8 || low >= k || high >= k {
+			t.Errorf("node %d (%d, %d, %d) is not in topological order", k, v, low, high)
+		}
+	}
+	if nroots := get(); nroots != 2 {
+		t.Fatalf("expected 2 roots, actual %d", nroots)
+	}
+	// n is the last root and is not reachable from m, so it should be the
+	// last node in the stream
+	get()
+	if root := get(); root != count+1 {
+		t.Errorf("expected last root to be %d, actual %d", count+1, root)
+	}
+	if tag := get(); tag != 0 {
+		t.Errorf("expected no sections, actual tag %d", tag)
+	}
+	if buf.Len() != 4 {
+		t.Errorf("expected a 4 bytes checksum at the end of the stream, actual %d bytes", buf.Len())
+	}
+}
+
+// TestLoadUnordered checks that we can load nodes that are not compatible with
+// the current variable order.
+func TestLoadUnordered(t *testing.T) {
+	bdd, _ := New(4)
+	// nodes (1, 0, 1) and (3, 0, 2), meaning x1 and then x3 & x1, with x3 above
+	// x1; followed by two roots and no sections.
+	data := []byte(_SAVEMAGIC)
+	data = append(data, _SAVEVERSION, 'L', 0, 4, 0, 1, 2, 3)
+	data = append(data, 2, 1, 0, 1, 3, 0, 2, 2, 3, 2, 0)
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+	res, err := bdd.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bdd.Equal(res[0], bdd.And(bdd.Ithvar(1), bdd.Ithvar(3))) || !bdd.Equal(res[1], bdd.Ithvar(1)) {
+		t.Errorf("Load of unordered nodes returns wrong result")
+	}
+}
+
+func TestLoadVersion2(t *testing.T) {
+	bdd, _ := New(4)
+	// the same nodes as in TestLoadUnordered, in version 2 of the format, with
+	// a codec byte but no sections.
+	data := []byte(_SAVEMAGIC)
+	data = append(data, 2, 'L', 0, 4, 0, 1, 2, 3)
+	data = append(data, 2, 1, 0, 1, 3, 0, 2, 2, 3, 2)
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+	res, err := bdd.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bdd.Equal(res[0], bdd.And(bdd.Ithvar(1), bdd.Ithvar(3))) || !bdd.Equal(res[1], bdd.Ithvar(1)) {
+		t.Errorf("Load of a version 2 stream returns wrong result")
+	}
+}
+
+func TestSaveHeader(t *testing.T) {
+	bdd, _ := New(4)
+	var buf bytes.Buffer
+	bdd.Save(&buf, bdd.And(bdd.Ithvar(0), bdd.NIthvar(3)))
+	data := buf.Bytes()
+	tests := []struct {
+		pos      int
+		value    byte
+		expected string
+	}{
+		{0, 'X', "bad magic number"},
+		{4, 99, "unsupported format version"},
+		{5, 'B', "unsupported byte order"},
+		{6, 77, "unknown codec"},
+		{7, 5, "the file uses 5 variables"},
+		{8, 1, "bad variable order"},
+		{len(data) - 1, 0, "checksum mismatch"},
+	}
+	for _, tt := range tests {
+		corrupt := append([]byte{}, data...)
+		corrupt[tt.pos] = tt.value
+		_, err := bdd.Load(bytes.NewReader(corrupt))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("corrupting byte %d, expected error %q, actual %v", tt.pos, tt.expected, err)
+		}
+	}
+	// a stream announcing a huge number of nodes should fail cleanly
+	huge := append([]byte{}, data[:12]...)
+	huge = binary.AppendUvarint(huge, 1<<30)
+	if _, err := bdd.Load(bytes.NewReader(huge)); err == nil || !strings.Contains(err.Error(), "bad node") {
+		t.Errorf("expected an error with a truncated stream, actual %v", err)
+	}
+	small, _ := New(4, Maxnodesize(1000))
+	if _, err := small.Load(bytes.NewReader(huge)); err == nil || !strings.Contains(err.Error(), "limited to 1000") {
+		t.Errorf("expected an error with more nodes than Maxnodesize, actual %v", err)
+	}
+	// we flip one bit in the payload, after the header
+	corrupt := append([]byte{}, data...)
+	corrupt[13] ^= 0x40
+	if _, err := bdd.Load(bytes.NewReader(corrupt)); err == nil {
+		t.Errorf("Load should detect a corrupted payload")
+	}
+}
+
+func TestSaveCodec(t *testing.T) {
+	bdd, R := milner(t, true, 8)
+	for _, c := range []Codec{nil, Gzip, GzipLevel(gzip.BestSpeed)} {
+		var buf bytes.Buffer
+		if err := bdd.SaveWith(&buf, c, R); err != nil {
+			t.Fatal(err)
+		}
+		other, _ := New(bdd.Varnum())
+		res, err := other.Load(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := other.Satcount(res[0]), bdd.Satcount(R); actual.Cmp(expected) != 0 {
+			t.Errorf("Load with codec %v, expected %s assignments, actual %s", c, expected, actual)
+		}
+	}
+	if err := RegisterCodec(Gzip); err == nil {
+		t.Errorf("RegisterCodec should fail with a reserved ID")
+	}
+}
+
+// BenchmarkSaveMilner measures the time and size needed to save (and load) the
+// state space of the milner example with each codec.
+func BenchmarkSaveMilner(b *testing.B) {
+	bdd, R := milner(b, true, 50, Nodesize(100000))
+	for _, c := range []struct {
+		name  string
+		codec Codec
+	}{{"none", nil}, {"gzip", Gzip}, {"gzipfast", GzipLevel(gzip.BestSpeed)}} {
+		var buf bytes.Buffer
+		b.Run("Save/"+c.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				buf.Reset()
+				bdd.SaveWith(&buf, c.codec, R)
+			}
+			b.ReportMetric(float64(buf.Len()), "bytes")
+		})
+		b.Run("Load/"+c.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				other, _ := New(bdd.Varnum())
+				if _, err := other.Load(bytes.NewReader(buf.Bytes())); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestLoadLarge checks that we can load a stream with more nodes than the
+// number reserved before the checksum is verified, which means that the node
+// table is garbage collected during the load.
+func TestLoadLarge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the load of a large stream in short mode")
+	}
+	varnum := 24
+	count := 2 * _DEFAULTMAXNODEINC
+	data := []byte(_SAVEMAGIC)
+	data = append(data, _SAVEVERSION, 'L', 0)
+	data = binary.AppendUvarint(data, uint64(varnum))
+	for v := 0; v < varnum; v++ {
+		data = binary.AppendUvarint(data, uint64(v))
+	}
+	data = binary.AppendUvarint(data, uint64(count))
+	// the nodes (v, a, a+1) for all the nodes a with a variable below v, which
+	// are all distinct; we compute the probability that each node is true and
+	// use the last node of each variable as a root.
+	prob := []float64{0, 1}
+	succ := [][2]int{{}, {}}
+	roots := []int{}
+	for v := varnum - 1; len(prob) < count+2; v-- {
+		n := len(prob)
+		for a := 0; a < n && len(prob) < count+2; a++ {
+			for _, x := range []int{v, a, (a + 1) % n} {
+				data = binary.AppendUvarint(data, uint64(x))
+			}
+			prob = append(prob, (prob[a]+prob[(a+1)%n])/2)
+			succ = append(succ, [2]int{a, (a + 1) % n})
+		}
+		roots = append(roots, len(prob)-1)
+	}
+	data = binary.AppendUvarint(data, uint64(len(roots)))
+	for _, k := range roots {
+		data = binary.AppendUvarint(data, uint64(k))
+	}
+	data = append(data, 0)
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+	// the number of nodes accessible from the roots, without the constants
+	seen := make([]bool, count+2)
+	var visit func(k int) int
+	visit = func(k int) int {
+		if k < 2 || seen[k] {
+			return 0
+		}
+		seen[k] = true
+		return 1 + visit(succ[k][0]) + visit(succ[k][1])
+	}
+	size := 0
+	for _, k := range roots {
+		size += visit(k)
+	}
+	bdd, _ := New(varnum)
+	res, err := bdd.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := 0
+	bdd.Allnodes(func(id, level, low, high int) error {
+		actual++
+		return nil
+	}, res...)
+	if actual != size {
+		t.Errorf("Load of a large stream, expected %d nodes, actual %d", size, actual)
+	}
+	for i, k := range roots {
+		expected := prob[k] * float64(uint64(1)<<varnum)
+		if actual, _ := new(big.Float).SetInt(bdd.Satcount(res[i])).Float64(); actual != expected {
+			t.Errorf("Load of a large stream, expected %f assignments for root %d, actual %f", expected, k, actual)
+		}
+	}
+}
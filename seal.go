@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrSealed is the error raised by operations that would modify a BDD after a
+// call to SealForQueries.
+var ErrSealed = errors.New("operation not permitted on a sealed BDD")
+
+// seal is the state of a BDD after a call to SealForQueries. We only need to
+// protect the error status, since queries do not modify the node table.
+type seal struct {
+	sync.Mutex
+}
+
+// SealForQueries turns b into a read-only BDD that can be queried from any
+// number of goroutines at the same time, which is useful when a BDD is built
+// once and then used to answer many queries. The queries are the operations
+// that do not create new nodes, such as Eval, Satcount, PathCount, Allsat,
+// Allnodes, Low, High, Scanset, Save or Flatten. All the other operations,
+// like Apply, Exist or Load, are refused: operations returning a Node return
+// nil and set the error flag of b, while operations returning an error return
+// an error wrapping ErrSealed.
+//
+// To avoid any write to the node table, we pin all the nodes (they will never
+// be reclaimed, even if their references are dropped) and we disable the
+// journal of operations. This method must be called before sharing b with
+// other goroutines and a BDD cannot be unsealed.
+func (b *BDD) SealForQueries() {
+	if b.sealed != nil {
+		return
+	}
+	b.allnodes(func(id, level, low, high int) error {
+		if id > 1 {
+			b.pinnode(id)
+		}
+		return nil
+	})
+	b.journal = nil
+	b.sealed = &seal{}
+}
+
+// Sealed returns true if b has been sealed with SealForQueries.
+func (b *BDD) Sealed() bool {
+	return b.sealed != nil
+}
+
+// sealerror sets the error flag of b when we try to modify a sealed BDD in
+// operation op.
+func (b *BDD) sealerror(op string) Node {
+	return b.seterror("%s in call to %s", ErrSealed, op)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSealForQueries(t *testing.T) {
+	bdd, R := milner(t, true, 4, Nodesize(1000), Cachesize(100))
+	expected := bdd.Satcount(R)
+	size := bdd.AnodeCount(R)
+	bdd.SealForQueries()
+	if !bdd.Sealed() {
+		t.Fatal("expected a sealed BDD")
+	}
+	var wg sync.WaitGroup
+	errs := make(chan string, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if bdd.Satcount(R).Cmp(expected) != 0 {
+					errs <- "wrong Satcount"
+					return
+				}
+				if bdd.AnodeCount(R) != size {
+					errs <- "wrong AnodeCount"
+					return
+				}
+				if _, err := bdd.Eval(R, make([]bool, bdd.Varnum())); err != nil {
+					errs <- err.Error()
+					return
+				}
+				if bdd.Low(R) == nil || bdd.High(R) == nil {
+					errs <- "nil successor"
+					return
+				}
+				if err := bdd.Save(&bytes.Buffer{}, R); err != nil {
+					errs <- err.Error()
+					return
+				}
+				runtime.GC()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+	if bdd.Errored() {
+		t.Fatalf("unexpected error: %s", bdd.Error())
+	}
+	if bdd.Not(R) != nil || !strings.Contains(bdd.Error(), ErrSealed.Error()) {
+		t.Errorf("Not should fail on a sealed BDD, actual error %q", bdd.Error())
+	}
+	var buf bytes.Buffer
+	bdd.Save(&buf, R)
+	if _, err := bdd.Load(&buf); !errors.Is(err, ErrSealed) {
+		t.Errorf("Load should fail with ErrSealed, actual %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"unsafe"
+
+	"github.com/dalzilio/rudd/internal/dd"
+)
+
+// Statistics is a snapshot of the state of a BDD, returned by StatsStruct. It
+// contains the same information than Stats, and more, but in a form that can
+// be used in tests or marshaled to JSON, for instance to feed a dashboard.
+type Statistics struct {
+	Impl   string       `json:"impl"`   // Implementation of the node table, either "Hudd" or "BuDDy"
+	Varnum int          `json:"varnum"` // Number of variables
+	Table  TableStats   `json:"table"`  // State of the node table
+	Caches []CacheStats `json:"caches"` // State of the operation caches
+	GC     []GCStats    `json:"gc"`     // State of the node table after each garbage collection, in order
+	Config ConfigStats  `json:"config"` // Configuration given to New
+}
+
+// TableStats describes the state of the node table and of the unique table
+// used to find existing nodes. Fields Buckets and MaxChain are only used with
+// the build tag buddy.
+type TableStats struct {
+	Allocated    int `json:"allocated"`          // Number of nodes allocated, including the free ones
+	Bytes        int `json:"bytes"`              // Memory used by the nodes, in bytes
+	Produced     int `json:"produced"`           // Total number of nodes ever produced
+	Free         int `json:"free"`               // Number of free nodes
+	Used         int `json:"used"`               // Number of nodes that are not free, see Live
+	Buckets      int `json:"buckets,omitempty"`  // Number of buckets in the unique table
+	MaxChain     int `json:"maxchain,omitempty"` // Length of the longest chain followed in the unique table
+	UniqueAccess int `json:"uniqueaccess"`       // Accesses to the unique table
+	UniqueHit    int `json:"uniquehit"`          // Nodes found in the unique table
+	UniqueMiss   int `json:"uniquemiss"`         // Nodes not found in the unique table
+}
+
+// CacheStats describes the state of one of the caches used by the operations.
+type CacheStats struct {
+	Name   string `json:"name"`   // Operations using this cache, such as "apply" or "ite"
+	Size   int    `json:"size"`   // Number of entries
+	Bytes  int    `json:"bytes"`  // Memory used by the entries, in bytes
+	Ratio  int    `json:"ratio"`  // Ratio (%) between the size of the cache and of the node table, 0 if the size is constant
+	Hits   int    `json:"hits"`   // Number of results found in the cache
+	Misses int    `json:"misses"` // Number of results not found in the cache
+}
+
+// GCStats describes the node table at the end of a garbage collection, with the
+// number of external references (Nodes returned to the user) created and
+// reclaimed by the Go runtime since the previous collection.
+type GCStats struct {
+	Nodes      int `json:"nodes"`      // Number of nodes allocated
+	Free       int `json:"free"`       // Number of free nodes
+	Finalizers int `json:"finalizers"` // Number of external references created
+	Reclaimed  int `json:"reclaimed"`  // Number of external references reclaimed
+}
+
+// ConfigStats lists the configuration options given to New, where 0 stands
+// for the default value.
+type ConfigStats struct {
+	Nodesize        int `json:"nodesize"`        // Initial size of the node table
+	Cachesize       int `json:"cachesize"`       // Initial size of the caches
+	Cacheratio      int `json:"cacheratio"`      // Ratio (%) between the size of the caches and of the node table
+	Maxnodesize     int `json:"maxnodesize"`     // Maximal number of nodes, 0 if there is no limit
+	Maxnodeincrease int `json:"maxnodeincrease"` // Maximal number of nodes added at each resize, 0 if there is no limit
+	Minfreenodes    int `json:"minfreenodes"`    // Minimal ratio (%) of free nodes after a GC before resizing
+}
+
+// StatsStruct returns the same information than Stats, but as a value of type
+// Statistics that can be inspected or marshaled to JSON. Unlike with Stats,
+// all the fields are always included, but some counters of the unique table
+// are only updated when compiling with the build tag debug.
+func (b *BDD) StatsStruct() Statistics {
+	res := Statistics{
+		Varnum: int(b.varnum),
+		GC:     []GCStats{},
+	}
+	res.Impl, res.Table = b.tablestats()
+	cache4 := func(name string, c *dd.Cache4) CacheStats {
+		return CacheStats{
+			Name:   name,
+			Size:   len(c.Table),
+			Bytes:  len(c.Table) * int(unsafe.Sizeof(dd.Entry4{})),
+			Ratio:  c.Ratio,
+			Hits:   c.OpHit,
+			Misses: c.OpMiss,
+		}
+	}
+	res.Caches = []CacheStats{
+		cache4("apply", &b.applycache.cache),
+		cache4("ite", &b.itecache.cache),
+		cache4("quant", &b.quantcache.cache),
+		cache4("appex", &b.appexcache.cache),
+		cache4("replace", &b.replacecache.cache),
+	}
+	for _, g := range b.gcstat.history {
+		res.GC = append(res.GC, GCStats{
+			Nodes:      g.nodes,
+			Free:       g.freenodes,
+			Finalizers: g.setfinalizers,
+			Reclaimed:  g.calledfinalizers,
+		})
+	}
+	res.Config = ConfigStats{
+		Nodesize:        b.config.nodesize,
+		Cachesize:       b.config.cachesize,
+		Cacheratio:      b.config.cacheratio,
+		Maxnodesize:     b.config.maxnodesize,
+		Maxnodeincrease: b.config.maxnodeincrease,
+		Minfreenodes:    b.config.minfreenodes,
+	}
+	return res
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStatsStruct(t *testing.T) {
+	bdd, _ := New(10, Nodesize(100), Cachesize(50), Cacheratio(25))
+	n := bdd.False()
+	for k := 0; k < 9; k++ {
+		n = bdd.Or(n, bdd.And(bdd.Ithvar(k), bdd.Ithvar(k+1)))
+	}
+	stats := bdd.StatsStruct()
+	if stats.Varnum != 10 || stats.Config.Cachesize != 50 || stats.Config.Cacheratio != 25 {
+		t.Errorf("unexpected configuration %+v", stats.Config)
+	}
+	if stats.Table.Allocated != bdd.Allocated() || stats.Table.Used != bdd.Live() {
+		t.Errorf("expected %d allocated and %d used nodes, actual %+v", bdd.Allocated(), bdd.Live(), stats.Table)
+	}
+	if len(stats.Caches) != 5 || stats.Caches[0].Name != "apply" || stats.Caches[0].Hits+stats.Caches[0].Misses == 0 {
+		t.Errorf("unexpected caches %+v", stats.Caches)
+	}
+	buf, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Statistics
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Impl != stats.Impl || decoded.Table != stats.Table || len(decoded.GC) != len(stats.GC) {
+		t.Errorf("JSON round trip failed: %s", buf)
+	}
+}
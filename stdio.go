@@ -5,9 +5,12 @@
 package rudd
 
 import (
+	"encoding/csv"
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 )
 
@@ -43,7 +46,8 @@ func humanSize(b int, unit uintptr) string {
 // We print all the nodes in b if n is absent (len(n) == 0), so a call to
 // b.Print(os.Stdout) prints a table containing all the active nodes of the BDD
 // to the standard output. We also simply print the string "True" and "False",
-// respectively, if len(n) == 1 and n[0] is the constant True or False.
+// respectively, if len(n) == 1 and n[0] is the constant True or False. Use
+// WriteCSV to get a table that can be parsed by scripts.
 func (b *BDD) Print(w io.Writer, n ...Node) {
 	if mesg := b.Error(); mesg != "" {
 		fmt.Fprintf(w, "Error: %s\n", mesg)
@@ -59,7 +63,17 @@ func (b *BDD) Print(w io.Writer, n ...Node) {
 			return
 		}
 	}
-	// we build a slice of nodes sorted by ids
+	nodes, err := b.sortednodes(n...)
+	if err != nil {
+		fmt.Fprintln(w, err.Error())
+		return
+	}
+	printSet(w, nodes)
+}
+
+// sortednodes returns the nodes reachable from n, or all the active nodes if n
+// is empty, as a slice of tuples (id, level, low, high) sorted by ids.
+func (b *BDD) sortednodes(n ...Node) ([][4]int, error) {
 	nodes := make([][4]int, 0)
 	err := b.Allnodes(func(id, level, low, high int) error {
 		i := sort.Search(len(nodes), func(i int) bool {
@@ -70,11 +84,7 @@ func (b *BDD) Print(w io.Writer, n ...Node) {
 		nodes[i] = [4]int{id, level, low, high}
 		return nil
 	}, n...)
-	if err != nil {
-		fmt.Fprintln(w, err.Error())
-		return
-	}
-	printSet(w, nodes)
+	return nodes, err
 }
 
 func printSet(w io.Writer, nodes [][4]int) {
@@ -87,6 +97,133 @@ func printSet(w io.Writer, nodes [][4]int) {
 	tw.Flush()
 }
 
+// Format is used to choose how the results of Stats and Print are laid out,
+// see StatsFormat and PrintFormat. The zero value gives the same output as
+// Stats and Print, which is meant to be read by humans. Numbers are always
+// printed with a dot as decimal separator, whatever the locale, so that the
+// output can be parsed by scripts.
+type Format struct {
+	Raw bool // Print sizes in bytes and ratios as plain numbers, instead of values such as "1.2 MB" or "12.5 %"
+	CSV bool // Use comma-separated values, with a header line, instead of aligned columns
+}
+
+// size returns a memory size, given in bytes.
+func (f Format) size(bytes int) string {
+	if f.Raw {
+		return strconv.Itoa(bytes)
+	}
+	return humanSize(bytes, 1)
+}
+
+// ratio returns the ratio (in percent) between a and b.
+func (f Format) ratio(a, b int) string {
+	r := 0.0
+	if b != 0 {
+		r = float64(a) * 100 / float64(b)
+	}
+	if f.Raw {
+		return strconv.FormatFloat(r, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(r, 'g', 3, 64) + " %"
+}
+
+// table writes rows to w, either as comma-separated values, after the header,
+// or as aligned columns without the header.
+func (f Format) table(w io.Writer, header []string, rows [][]string) error {
+	if f.CSV {
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		return cw.Error()
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range rows {
+		for k, c := range r {
+			if k > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, c)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// StatsFormat returns the information given by StatsStruct as a table with
+// one line for each value, using format f. With the CSV format, the first line
+// is the header "name,value".
+func (b *BDD) StatsFormat(f Format) string {
+	s := b.StatsStruct()
+	itoa := strconv.Itoa
+	rows := [][]string{
+		{"Impl.", s.Impl},
+		{"Varnum", itoa(s.Varnum)},
+		{"Allocated", itoa(s.Table.Allocated)},
+		{"Memory", f.size(s.Table.Bytes)},
+		{"Produced", itoa(s.Table.Produced)},
+		{"Free", itoa(s.Table.Free)},
+		{"Free ratio", f.ratio(s.Table.Free, s.Table.Allocated)},
+		{"Used", itoa(s.Table.Used)},
+		{"# of GC", itoa(len(s.GC))},
+	}
+	for _, c := range s.Caches {
+		rows = append(rows,
+			[]string{c.Name + " cache", itoa(c.Size)},
+			[]string{c.Name + " memory", f.size(c.Bytes)},
+			[]string{c.Name + " hits", itoa(c.Hits)},
+			[]string{c.Name + " hit ratio", f.ratio(c.Hits, c.Hits+c.Misses)},
+			[]string{c.Name + " misses", itoa(c.Misses)},
+		)
+	}
+	var buf strings.Builder
+	f.table(&buf, []string{"name", "value"}, rows)
+	return buf.String()
+}
+
+// PrintFormat is like Print, but it uses format f and returns an error instead
+// of printing it. With the CSV format, the result is the same as with WriteCSV.
+func (b *BDD) PrintFormat(w io.Writer, f Format, n ...Node) error {
+	if f.CSV {
+		return b.WriteCSV(w, n...)
+	}
+	if b.error != nil {
+		return b.error
+	}
+	nodes, err := b.sortednodes(n...)
+	if err != nil {
+		return err
+	}
+	printSet(w, nodes)
+	return nil
+}
+
+// WriteCSV writes the nodes reachable from n, or all the active nodes if n is
+// absent, as comma-separated values with a header line and one line for each
+// node, sorted by ids, with columns id, level, var, low and high. The
+// constants, with ids 0 and 1, are not listed.
+func (b *BDD) WriteCSV(w io.Writer, n ...Node) error {
+	if b.error != nil {
+		return b.error
+	}
+	nodes, err := b.sortednodes(n...)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, v := range nodes {
+		if v[0] > 1 {
+			rows = append(rows, []string{
+				strconv.Itoa(v[0]),
+				strconv.Itoa(v[1]),
+				strconv.Itoa(b.Level2Var(v[1])),
+				strconv.Itoa(v[2]),
+				strconv.Itoa(v[3]),
+			})
+		}
+	}
+	return Format{CSV: true}.table(w, []string{"id", "level", "var", "low", "high"}, rows)
+}
+
 // Dot  writes a graph-like description of the BDD with roots in n to an output
 // stream using Graphviz's dot format. The behavior of Dot is very similar to
 // the one of Print. In particular, we include all the active nodes of b if n is
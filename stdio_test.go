@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	bdd, _ := New(4)
+	if err := bdd.SetVarOrder([]int{3, 2, 1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	n := bdd.Or(bdd.And(bdd.Ithvar(0), bdd.Ithvar(1)), bdd.Ithvar(3))
+	var buf bytes.Buffer
+	if err := bdd.PrintFormat(&buf, Format{CSV: true}, n); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1+bdd.AnodeCount(n) || strings.Join(records[0], ",") != "id,level,var,low,high" {
+		t.Fatalf("unexpected records %v", records)
+	}
+	for _, r := range records[1:] {
+		level, _ := strconv.Atoi(r[1])
+		if v, _ := strconv.Atoi(r[2]); v != 3-level {
+			t.Errorf("expected variable %d at level %d, actual %d", 3-level, level, v)
+		}
+	}
+	// the default format is the one of Print
+	var p1, p2 bytes.Buffer
+	bdd.Print(&p1, n)
+	if err := bdd.PrintFormat(&p2, Format{}, n); err != nil || p1.String() != p2.String() {
+		t.Errorf("expected the output of Print:\n%s\nactual:\n%s", p1.String(), p2.String())
+	}
+}
+
+func TestStatsFormat(t *testing.T) {
+	bdd, _ := New(4, Cachesize(1000))
+	bdd.And(bdd.Ithvar(0), bdd.Ithvar(1))
+	records, err := csv.NewReader(strings.NewReader(bdd.StatsFormat(Format{Raw: true, CSV: true}))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{}
+	for _, r := range records[1:] {
+		values[r[0]] = r[1]
+	}
+	if values["Varnum"] != "4" || values["Allocated"] != strconv.Itoa(bdd.Allocated()) {
+		t.Errorf("unexpected values %v", values)
+	}
+	if _, err := strconv.Atoi(values["apply memory"]); err != nil {
+		t.Errorf("expected a raw size, actual %q", values["apply memory"])
+	}
+	if human := bdd.StatsFormat(Format{}); !strings.Contains(human, " kB") || !strings.Contains(human, " %") {
+		t.Errorf("expected humanized values, actual:\n%s", human)
+	}
+}
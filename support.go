@@ -0,0 +1,44 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "sort"
+
+// SupportSet returns the variables occurring in n, meaning the variables that
+// the function denoted by n depends on, sorted by increasing level, like with
+// Scanset. The result is an empty slice if n is a constant, and nil if there
+// is an error.
+func (b *BDD) SupportSet(n Node) []int {
+	if b.checkptr(n) != nil {
+		b.seterror("Wrong operand in call to SupportSet")
+		return nil
+	}
+	seen := make([]bool, b.varnum)
+	levels := []int{}
+	for _, k := range b.topo(*n) {
+		if l := b.level(k); !seen[l] {
+			seen[l] = true
+			levels = append(levels, int(l))
+		}
+	}
+	sort.Ints(levels)
+	res := make([]int, len(levels))
+	for k, l := range levels {
+		res[k] = int(b.level2var[l])
+	}
+	return res
+}
+
+// Support returns the cube of the variables occurring in n, like bdd_support in
+// BuDDy. The result can be used as the varset of a quantification, for
+// instance to project a relation on the variables of another BDD. We return
+// nil if there is an error.
+func (b *BDD) Support(n Node) Node {
+	vars := b.SupportSet(n)
+	if vars == nil {
+		return nil
+	}
+	return b.Makeset(vars)
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSupport(t *testing.T) {
+	bdd, _ := New(5, Nodesize(100))
+	tests := []struct {
+		n        Node
+		expected string
+	}{
+		{bdd.True(), "[]"},
+		{bdd.Ithvar(3), "[3]"},
+		{bdd.Or(bdd.And(bdd.Ithvar(4), bdd.Ithvar(0)), bdd.NIthvar(2)), "[0 2 4]"},
+		// x1 does not occur in the result, since (x1 | !x1) is True
+		{bdd.And(bdd.Ithvar(3), bdd.Or(bdd.Ithvar(1), bdd.NIthvar(1))), "[3]"},
+	}
+	for _, tt := range tests {
+		if actual := fmt.Sprint(bdd.SupportSet(tt.n)); actual != tt.expected {
+			t.Errorf("expected support %s, actual %s", tt.expected, actual)
+		}
+		if actual := fmt.Sprint(bdd.Scanset(bdd.Support(tt.n))); actual != tt.expected {
+			t.Errorf("expected cube over %s, actual %s", tt.expected, actual)
+		}
+	}
+	if bdd.Support(nil) != nil || !bdd.Errored() {
+		t.Error("expected an error with a nil node")
+	}
+}
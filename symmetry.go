@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+// SymmetricVars returns the groups of variables that are pairwise symmetric in
+// the function denoted by n, meaning that n is unchanged when we swap the
+// values of any two variables in the same group. We only consider the
+// variables in the support of n, and only return the groups with at least two
+// variables, sorted by increasing level, like with SupportSet. Symmetric
+// variables are good candidates for being kept together, at adjacent levels,
+// when changing the variable order (see ReorderSymSift), and can also be used
+// to speed up counting. We return nil, and set the error flag of b, if there
+// is an error.
+//
+// Two variables x and y, with x above y in the order, are symmetric in f if
+// the cofactors f[x=0,y=1] and f[x=1,y=0] are equal. We check this equality by
+// a traversal of f that compares pairs of nodes, without building the
+// cofactors, and with a cache of the pairs already compared. Since symmetry is
+// an equivalence relation, we only need to compare each variable with one
+// variable in each group.
+func (b *BDD) SymmetricVars(n Node) [][]int {
+	if b.checkptr(n) != nil {
+		b.seterror("Wrong operand in call to SymmetricVars")
+		return nil
+	}
+	support := b.SupportSet(n)
+	res := [][]int{}
+	grouped := make([]bool, len(support))
+	for i, x := range support {
+		if grouped[i] {
+			continue
+		}
+		group := []int{x}
+		for j := i + 1; j < len(support); j++ {
+			if !grouped[j] && b.symmetric(*n, b.var2level[x], b.var2level[support[j]]) {
+				grouped[j] = true
+				group = append(group, support[j])
+			}
+		}
+		if len(group) > 1 {
+			res = append(res, group)
+		}
+	}
+	return res
+}
+
+// symmetric returns true if the variables at levels lx < ly are symmetric in
+// n.
+func (b *BDD) symmetric(n int, lx, ly int32) bool {
+	s := &symcheck{b: b, lx: lx, ly: ly, visited: make(map[int]bool), pairs: make(map[[2]int]bool)}
+	return s.top(n)
+}
+
+// symcheck is the state of a symmetry check between the variables at levels lx
+// and ly.
+type symcheck struct {
+	b       *BDD
+	lx, ly  int32
+	visited map[int]bool    // nodes above level lx already checked
+	pairs   map[[2]int]bool // result of the comparison of pairs of nodes
+}
+
+// top looks for the nodes reached by the cofactors at level lx in n.
+func (s *symcheck) top(n int) bool {
+	if s.visited[n] {
+		return true
+	}
+	s.visited[n] = true
+	b := s.b
+	switch l := b.level(n); {
+	case l > s.lx:
+		return s.pair(n, n)
+	case l == s.lx:
+		return s.pair(b.low(n), b.high(n))
+	}
+	return s.top(b.low(n)) && s.top(b.high(n))
+}
+
+// pair returns true if the cofactor of g for y=1 is equal to the cofactor of h
+// for y=0, where y is the variable at level ly.
+func (s *symcheck) pair(g, h int) bool {
+	key := [2]int{g, h}
+	if res, ok := s.pairs[key]; ok {
+		return res
+	}
+	b := s.b
+	lg, lh := b.level(g), b.level(h)
+	top := lg
+	if lh < top {
+		top = lh
+	}
+	var res bool
+	switch {
+	case top > s.ly:
+		res = g == h
+	case top == s.ly:
+		if lg == s.ly {
+			g = b.high(g)
+		}
+		if lh == s.ly {
+			h = b.low(h)
+		}
+		res = g == h
+	default:
+		g0, g1, h0, h1 := g, g, h, h
+		if lg == top {
+			g0, g1 = b.low(g), b.high(g)
+		}
+		if lh == top {
+			h0, h1 = b.low(h), b.high(h)
+		}
+		res = s.pair(g0, h0) && s.pair(g1, h1)
+	}
+	s.pairs[key] = res
+	return res
+}
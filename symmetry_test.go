@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSymmetricVars(t *testing.T) {
+	bdd, _ := New(8, Nodesize(1000), Cachesize(1000))
+	x := func(v int) Node { return bdd.Ithvar(v) }
+	// majority of x0, x2 and x4, or (x1 and not x3), or (x5 xor x6)
+	maj := bdd.Or(bdd.And(x(0), x(2)), bdd.And(x(0), x(4)), bdd.And(x(2), x(4)))
+	f := bdd.Or(maj, bdd.And(x(1), bdd.NIthvar(3)), bdd.Apply(x(5), x(6), OPxor))
+	actual := bdd.SymmetricVars(f)
+	expected := [][]int{{0, 2, 4}, {5, 6}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected symmetric groups %v, actual %v", expected, actual)
+	}
+	if groups := bdd.SymmetricVars(bdd.True()); len(groups) != 0 {
+		t.Errorf("expected no symmetric groups for a constant, actual %v", groups)
+	}
+	// we compare with the definition on random functions
+	rng := rand.New(rand.NewSource(1))
+	for k := 0; k < 20; k++ {
+		g := bdd.False()
+		for m := 0; m < 4; m++ {
+			c := bdd.True()
+			for v := 0; v < 4; v++ {
+				switch rng.Intn(3) {
+				case 0:
+					c = bdd.And(c, x(v))
+				case 1:
+					c = bdd.And(c, bdd.NIthvar(v))
+				}
+			}
+			g = bdd.Or(g, c)
+		}
+		table := truthtable(t, bdd, g)
+		for i := 0; i < 4; i++ {
+			for j := i + 1; j < 4; j++ {
+				sym := true
+				for a := range table {
+					swapped := a &^ (1<<i | 1<<j)
+					swapped |= (a>>i&1)<<j | (a>>j&1)<<i
+					sym = sym && table[a] == table[swapped]
+				}
+				if got := bdd.symmetric(*g, int32(i), int32(j)); got != sym {
+					t.Errorf("expected symmetry of %d and %d to be %v in %v", i, j, sym, bdd.SupportSet(g))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "sort"
+
+// Template is a snapshot of a set of named BDDs, together with the
+// configuration, the variable order and the finite domain blocks of the BDD it
+// was taken from, that can be used to start new BDDs from the same base
+// constraints without recomputing them, for instance in the handlers of a
+// service. A Template is immutable and it is safe to call Instantiate from
+// several goroutines at the same time.
+type Template struct {
+	base    *BDD     // Sealed BDD holding only the nodes reachable from the roots
+	names   []string // Names of the roots, in alphabetical order
+	roots   []int    // Node of each root in base
+	config  configs  // Options of the source BDD, without the journal
+	domains []domain
+}
+
+// Template returns a Template for the BDDs in roots. The nodes reachable from
+// roots are compacted in a new node table, whatever the size of the node table
+// in b, that is shared by the BDDs returned by Instantiate. The template
+// keeps the options given to b, such as the cache size or the automatic
+// reordering, but not the journal. We return nil and set the error condition
+// in b if one of the nodes is not valid.
+func (b *BDD) Template(roots map[string]Node) *Template {
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	n := make([]Node, len(names))
+	for k, name := range names {
+		if b.checkptr(roots[name]) != nil {
+			b.seterror("wrong node (%s) in call to Template", name)
+			return nil
+		}
+		n[k] = roots[name]
+	}
+	f, err := b.Flatten(n...)
+	if err != nil {
+		b.seterror("%s in call to Template", err)
+		return nil
+	}
+	config := *b.config
+	config.varnum = int(b.varnum)
+	config.journal = nil
+	// the base uses the same hash function than the instances, so that its
+	// unique table can be copied as is, and it never grows nor reorders
+	base, err := New(int(b.varnum), func(c *configs) {
+		*c = config
+		c.nodesize = 2*int(b.varnum) + 2 + len(f.Nodes)
+		c.autoreorder = nil
+		c.trackages = false
+		c.leakrate = 0
+	})
+	if err != nil {
+		b.seterror("%s in call to Template", err)
+		return nil
+	}
+	if err := base.SetVarOrder(b.VarOrder()); err != nil {
+		b.seterror("%s in call to Template", err)
+		return nil
+	}
+	nodes, err := base.Unflatten(f)
+	if err != nil {
+		b.seterror("%s in call to Template", err)
+		return nil
+	}
+	base.SealForQueries()
+	t := &Template{base: base, names: names, roots: make([]int, len(nodes)), config: config}
+	for k, v := range nodes {
+		t.roots[k] = *v
+	}
+	for _, d := range b.domains {
+		t.domains = append(t.domains, domain{size: d.size, vars: append([]int{}, d.vars...)})
+	}
+	return t
+}
+
+// Instantiate returns a new BDD, with the same options, variables, variable
+// order and domains than the BDD used to build t, together with the nodes of
+// the named roots of t in this new BDD. We do not rebuild the nodes: the new
+// BDD shares the node table and the unique table of t until its first change,
+// for instance when it creates a node or collects garbage, at which point they
+// are copied in one block (copy-on-write). Hence a BDD that only queries the
+// roots of t, with operations such as Satcount, Eval or Allsat, costs almost
+// no memory. The nodes of t are never reclaimed in the new BDD. The only
+// possible error is a lack of memory, in which case the map is nil and the
+// BDD, if it could be allocated, has its error condition set.
+func (t *Template) Instantiate() (*BDD, map[string]Node) {
+	config := t.config
+	b, err := New(int(t.base.varnum), func(c *configs) {
+		*c = config
+		// the node table of b is replaced with the one of t
+		c.nodesize = 2*int(t.base.varnum) + 2
+	})
+	if err != nil {
+		return nil, nil
+	}
+	b.config.nodesize = config.nodesize
+	b.sharetables(t.base.tables)
+	copy(b.varset, t.base.varset)
+	copy(b.var2level, t.base.var2level)
+	copy(b.level2var, t.base.level2var)
+	b.cacheresize(b.size())
+	if b.ages != nil {
+		// the nodes of t are from generation 0, like the variables
+		b.initages(b.config)
+	}
+	for _, d := range t.domains {
+		b.domains = append(b.domains, domain{size: d.size, vars: append([]int{}, d.vars...)})
+	}
+	res := make(map[string]Node, len(t.names))
+	for k, name := range t.names {
+		res[name] = b.retnode(t.roots[k])
+	}
+	return b, res
+}
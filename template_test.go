@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	bdd, _ := New(1, Nodesize(1000))
+	doms, _ := bdd.ExtDomain([]int{6, 6})
+	if err := bdd.SetVarOrder([]int{5, 4, 3, 2, 1, 0, 6}); err != nil {
+		t.Fatal(err)
+	}
+	base := map[string]Node{
+		"eq":    bdd.FddEquals(doms[0], doms[1]),
+		"valid": bdd.And(bdd.FddDomain(doms[0]), bdd.FddDomain(doms[1])),
+	}
+	// garbage that should not be part of the template
+	for k := 0; k < 6; k++ {
+		bdd.Or(bdd.Ithvar(k), bdd.NIthvar(k+1))
+	}
+	tmpl := bdd.Template(base)
+	var wg sync.WaitGroup
+	for k := 0; k < 4; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, roots := tmpl.Instantiate()
+			if roots == nil {
+				t.Error(b.Error())
+				return
+			}
+			for name, n := range base {
+				if b.Satcount(roots[name]).Cmp(bdd.Satcount(n)) != 0 {
+					t.Errorf("%s: expected %s models, actual %s", name, bdd.Satcount(n), b.Satcount(roots[name]))
+				}
+			}
+			if order := b.VarOrder(); order[0] != 5 {
+				t.Errorf("expected the same variable order, actual %v", order)
+			}
+			n := b.And(roots["eq"], roots["valid"], b.FddIthVar(doms[0], 4))
+			if v := b.FddScanVar(n, doms[1]); v != 4 {
+				t.Errorf("expected value 4, actual %d", v)
+			}
+		}()
+	}
+	wg.Wait()
+	if bdd.Template(map[string]Node{"bad": nil}) != nil || !bdd.Errored() {
+		t.Errorf("expected an error with a wrong node")
+	}
+}
+
+// TestTemplateCopy checks that Instantiate reuses the node table of the
+// template, without producing nodes, and keeps the options of the source
+// without its journal.
+func TestTemplateCopy(t *testing.T) {
+	var journal bytes.Buffer
+	bdd, _ := New(8, Nodesize(5000), Cacheratio(50), Minfreenodes(30), Autoreorder(ReorderSift, 4000), Journal(&journal))
+	x := bdd.True()
+	for k := 0; k < 8; k += 2 {
+		x = bdd.And(x, bdd.Or(bdd.Ithvar(k), bdd.NIthvar(k+1)))
+	}
+	tmpl := bdd.Template(map[string]Node{"x": x})
+	b, roots := tmpl.Instantiate()
+	if roots == nil {
+		t.Fatal(b.Error())
+	}
+	if p := b.producednum(); p != 2*b.Varnum() {
+		t.Errorf("Instantiate should copy the nodes, expected %d new nodes, actual %d", 2*b.Varnum(), p)
+	}
+	if b.fingerprint(*roots["x"]) != bdd.fingerprint(*x) {
+		t.Errorf("Instantiate, wrong root x")
+	}
+	c := b.config
+	if c.nodesize != 5000 || c.cacheratio != 50 || c.minfreenodes != 30 || c.autoreorder == nil || c.autoreorder.threshold != 4000 {
+		t.Errorf("Instantiate should keep the options of the source")
+	}
+	if b.journal != nil {
+		t.Errorf("Instantiate should not inherit the journal")
+	}
+	// operations on the new BDD, including garbage collections, leave the
+	// template unchanged
+	size := journal.Len()
+	for k := 0; k < 200; k++ {
+		b.Exist(b.Apply(roots["x"], b.Ithvar(k%8), OPxor), b.Makeset([]int{k % 5}))
+		b.gbc(nil)
+	}
+	other, roots2 := tmpl.Instantiate()
+	if other.fingerprint(*roots2["x"]) != bdd.fingerprint(*x) || journal.Len() != size {
+		t.Errorf("the template should not be modified by its instances")
+	}
+}
+
+// TestTemplateShare checks that the BDDs returned by Instantiate share the
+// node table of the template until their first change, and that a change in
+// one of them is not visible in the others.
+func TestTemplateShare(t *testing.T) {
+	bdd, _ := New(10)
+	x := bdd.True()
+	for k := 0; k < 10; k += 2 {
+		x = bdd.And(x, bdd.Or(bdd.Ithvar(k), bdd.NIthvar(k+1)))
+	}
+	count := bdd.Satcount(x)
+	tmpl := bdd.Template(map[string]Node{"x": x})
+	var wg sync.WaitGroup
+	for k := 0; k < 8; k++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			b, roots := tmpl.Instantiate()
+			if !b.shared() {
+				t.Errorf("Instantiate should share the node table of the template")
+			}
+			if b.Satcount(roots["x"]).Cmp(count) != 0 || !b.shared() {
+				t.Errorf("a query should not copy the node table")
+			}
+			y := b.Apply(roots["x"], b.Ithvar(k), OPand)
+			if b.shared() {
+				t.Errorf("creating a node should copy the node table")
+			}
+			for j := 0; j < 50; j++ {
+				y = b.Exist(b.Apply(y, b.Ithvar(j%10), OPxor), b.Makeset([]int{j % 3}))
+			}
+			b.gbc(nil)
+			if b.Satcount(roots["x"]).Cmp(count) != 0 {
+				t.Errorf("wrong number of models after a change")
+			}
+		}(k)
+	}
+	wg.Wait()
+	b, roots := tmpl.Instantiate()
+	if b.fingerprint(*roots["x"]) != bdd.fingerprint(*x) || !b.shared() {
+		t.Errorf("the template should not be modified by its instances")
+	}
+}
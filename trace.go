@@ -0,0 +1,377 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TraceStep is the result of one statement in a trace, see RunTrace.
+type TraceStep struct {
+	Line        int           // line of the statement in the trace
+	Target      string        // name of the BDD assigned by the statement, empty for checks
+	Op          string        // name of the top-level operation
+	Fingerprint uint64        // fingerprint of the result (of the first operand for checks)
+	Nodes       int           // number of nodes in the result, not counting the constants
+	Satcount    *big.Int      // result of satisfy_count, nil otherwise
+	Equal       bool          // result of are_equal, false otherwise
+	Duration    time.Duration // time spent evaluating the statement
+}
+
+// RunTrace executes a trace, in the format of the BDD trace driver used to
+// benchmark BuDDy and other BDD libraries, on a new BDD built using the given
+// configuration options. This is useful to compare the results and the
+// performances of rudd with the ones of the C library, operation by operation.
+// We call f, if it is not nil, after each statement of the trace and stop at
+// the first error returned by f. We return the BDD and the nodes associated
+// with each name in the trace.
+//
+// A trace has the following structure, where the inputs are associated with
+// variables 0, 1, ... in the order of their declaration. Comments are either
+// C-style (/* ... */) or start with a # and extend to the end of the line.
+//
+//	MODULE name
+//	INPUT a, b, c;
+//	OUTPUT f;
+//	STRUCTURE
+//	g = and(a, b);
+//	f = ite(g, c, not(a));
+//	are_equal(f, g);
+//	ENDMODULE
+//
+// We support the following operations in expressions: and, or, nand, nor, xor,
+// xnor, imp and diff (with two or more operands for the associative ones),
+// not, ite, exists(cube, f), forall(cube, f) and rel_prod(cube, f, g), where
+// cube is a conjunction of positive variables, and the constants 0 and 1. The
+// other statements are are_equal(f, g), satisfy_count(f) and
+// check_point_for_force_reordering(n), which is ignored since rudd does not
+// implement dynamic reordering.
+//
+// The fingerprint of each result is the one used in journals (see option
+// Journal). It depends only on the function denoted by a node and on the
+// variable order, and not on the ids of the nodes.
+func RunTrace(r io.Reader, f func(TraceStep) error, options ...func(*configs)) (*BDD, map[string]Node, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &tracer{tokens: tracelex(string(src)), f: f}
+	err = t.run(options)
+	return t.b, t.nodes, err
+}
+
+// tracetoken is a token in a trace, with the line where it occurs.
+type tracetoken struct {
+	text string
+	line int
+}
+
+// tracelex splits a trace into identifiers and punctuation symbols, after
+// removing comments.
+func tracelex(src string) []tracetoken {
+	res := []tracetoken{}
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 4
+			}
+			line += strings.Count(src[i:i+end+4], "\n")
+			i += end + 4
+		case strings.ContainsRune("(),;=", rune(c)):
+			res = append(res, tracetoken{src[i : i+1], line})
+			i++
+		case unicode.IsSpace(rune(c)):
+			i++
+		default:
+			j := i
+			for j < len(src) && !unicode.IsSpace(rune(src[j])) && !strings.ContainsRune("(),;=#", rune(src[j])) {
+				j++
+			}
+			res = append(res, tracetoken{src[i:j], line})
+			i = j
+		}
+	}
+	return res
+}
+
+// tracer holds the state of the interpreter used in RunTrace.
+type tracer struct {
+	tokens []tracetoken
+	pos    int
+	b      *BDD
+	nodes  map[string]Node
+	f      func(TraceStep) error
+}
+
+func (t *tracer) peek() string {
+	if t.pos < len(t.tokens) {
+		return t.tokens[t.pos].text
+	}
+	return ""
+}
+
+func (t *tracer) line() int {
+	if t.pos < len(t.tokens) {
+		return t.tokens[t.pos].line
+	}
+	if len(t.tokens) > 0 {
+		return t.tokens[len(t.tokens)-1].line
+	}
+	return 1
+}
+
+func (t *tracer) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("line %d of trace: %s", t.line(), fmt.Sprintf(format, a...))
+}
+
+func (t *tracer) next() string {
+	s := t.peek()
+	t.pos++
+	return s
+}
+
+func (t *tracer) expect(s string) error {
+	if actual := t.peek(); actual != s {
+		return t.errorf("expected %q, found %q", s, actual)
+	}
+	t.pos++
+	return nil
+}
+
+// names parses a list of identifiers, separated by commas and ending with a
+// semicolon.
+func (t *tracer) names() ([]string, error) {
+	res := []string{}
+	for t.peek() != ";" {
+		if len(res) > 0 {
+			if err := t.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		name := t.next()
+		if name == "" || strings.ContainsAny(name, "(),=") {
+			return nil, t.errorf("bad identifier %q", name)
+		}
+		res = append(res, name)
+	}
+	t.pos++
+	return res, nil
+}
+
+func (t *tracer) run(options []func(*configs)) error {
+	if err := t.expect("MODULE"); err != nil {
+		return err
+	}
+	t.next()
+	if err := t.expect("INPUT"); err != nil {
+		return err
+	}
+	inputs, err := t.names()
+	if err != nil {
+		return err
+	}
+	if t.b, err = New(len(inputs), options...); err != nil {
+		return t.errorf("%s", err)
+	}
+	t.nodes = map[string]Node{"0": t.b.False(), "1": t.b.True()}
+	for k, name := range inputs {
+		t.nodes[name] = t.b.Ithvar(k)
+	}
+	if t.peek() == "OUTPUT" {
+		t.pos++
+		if _, err := t.names(); err != nil {
+			return err
+		}
+	}
+	if err := t.expect("STRUCTURE"); err != nil {
+		return err
+	}
+	for t.peek() != "ENDMODULE" {
+		if t.peek() == "" {
+			return t.errorf("unexpected end of trace")
+		}
+		if err := t.statement(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statement evaluates one statement of the STRUCTURE section.
+func (t *tracer) statement() error {
+	step := TraceStep{Line: t.line()}
+	start := time.Now()
+	name := t.next()
+	var res Node
+	switch t.peek() {
+	case "=":
+		t.pos++
+		step.Target = name
+		step.Op = "="
+		if t.pos+1 < len(t.tokens) && t.tokens[t.pos+1].text == "(" {
+			step.Op = t.peek()
+		}
+		n, err := t.expr()
+		if err != nil {
+			return err
+		}
+		t.nodes[name] = n
+		res = n
+	case "(":
+		step.Op = name
+		t.pos++
+		if name == "check_point_for_force_reordering" {
+			for t.peek() != ")" && t.peek() != "" {
+				t.pos++
+			}
+			t.pos++
+			break
+		}
+		args, err := t.args()
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "are_equal":
+			if len(args) != 2 {
+				return t.errorf("are_equal expects 2 operands")
+			}
+			step.Equal = t.b.Equal(args[0], args[1])
+			res = args[0]
+		case "satisfy_count":
+			if len(args) != 1 {
+				return t.errorf("satisfy_count expects 1 operand")
+			}
+			step.Satcount = t.b.Satcount(args[0])
+			res = args[0]
+		default:
+			return t.errorf("unknown statement %s", name)
+		}
+	default:
+		return t.errorf("expected \"=\" or \"(\" after %s", name)
+	}
+	if err := t.expect(";"); err != nil {
+		return err
+	}
+	if t.b.Errored() {
+		return t.errorf("%s", t.b.Error())
+	}
+	step.Duration = time.Since(start)
+	if res != nil {
+		step.Fingerprint = t.b.fingerprint(*res)
+		step.Nodes = len(t.b.topo(*res))
+	}
+	if t.f != nil {
+		return t.f(step)
+	}
+	return nil
+}
+
+// args parses a list of expressions, separated by commas and ending with a
+// closing parenthesis.
+func (t *tracer) args() ([]Node, error) {
+	res := []Node{}
+	for t.peek() != ")" {
+		if len(res) > 0 {
+			if err := t.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		n, err := t.expr()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, n)
+	}
+	t.pos++
+	return res, nil
+}
+
+var traceops = map[string]Operator{
+	"and": OPand, "or": OPor, "nand": OPnand, "nor": OPnor, "xor": OPxor,
+	"xnor": OPbiimp, "imp": OPimp, "diff": OPdiff,
+}
+
+// expr evaluates an expression, that is either a name or an operation.
+func (t *tracer) expr() (Node, error) {
+	name := t.next()
+	if t.peek() != "(" {
+		n, ok := t.nodes[name]
+		if !ok {
+			t.pos--
+			return nil, t.errorf("unknown BDD %q", name)
+		}
+		return n, nil
+	}
+	t.pos++
+	args, err := t.args()
+	if err != nil {
+		return nil, err
+	}
+	arity := func(k int) error {
+		if len(args) != k {
+			return t.errorf("%s expects %d operands, found %d", name, k, len(args))
+		}
+		return nil
+	}
+	b := t.b
+	if op, ok := traceops[name]; ok {
+		if len(args) < 2 {
+			return nil, t.errorf("%s expects at least 2 operands", name)
+		}
+		if len(args) > 2 && op != OPand && op != OPor && op != OPxor {
+			return nil, arity(2)
+		}
+		res := args[0]
+		for _, n := range args[1:] {
+			res = b.Apply(res, n, op)
+		}
+		return res, nil
+	}
+	switch name {
+	case "not":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		return b.Not(args[0]), nil
+	case "ite":
+		if err := arity(3); err != nil {
+			return nil, err
+		}
+		return b.Ite(args[0], args[1], args[2]), nil
+	case "exists":
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		return b.Exist(args[1], args[0]), nil
+	case "forall":
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		return b.Not(b.Exist(b.Not(args[1]), args[0])), nil
+	case "rel_prod":
+		if err := arity(3); err != nil {
+			return nil, err
+		}
+		return b.AppEx(args[1], args[2], OPand, args[0]), nil
+	}
+	return nil, t.errorf("unknown operation %s", name)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampletrace = `
+/* a small trace, with
+   comments */
+MODULE sample
+INPUT
+	a, b, c, d;
+OUTPUT
+	f;
+STRUCTURE
+	g = and(a, b, c);
+	h = not(nand(a, b));  # same as and(a, b)
+	k = and(h, c);
+	are_equal(g, k);
+	cube = and(b, c);
+	e = exists(cube, g);
+	r = rel_prod(cube, h, c);
+	are_equal(e, r);
+	f = ite(d, g, 0);
+	check_point_for_force_reordering(3);
+	satisfy_count(f);
+ENDMODULE
+`
+
+func TestRunTrace(t *testing.T) {
+	steps := []TraceStep{}
+	bdd, nodes, err := RunTrace(strings.NewReader(sampletrace), func(s TraceStep) error {
+		steps = append(steps, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 11 {
+		t.Fatalf("expected 11 steps, actual %d", len(steps))
+	}
+	if !steps[3].Equal || !steps[7].Equal {
+		t.Errorf("are_equal should succeed in the sample trace")
+	}
+	if steps[0].Fingerprint != steps[2].Fingerprint || steps[0].Line != 10 || steps[0].Op != "and" {
+		t.Errorf("unexpected steps %v and %v", steps[0], steps[2])
+	}
+	if steps[10].Satcount.Int64() != 1 || !bdd.Equal(nodes["e"], nodes["a"]) {
+		t.Errorf("unexpected results of the sample trace")
+	}
+	bad := strings.Replace(sampletrace, "exists(cube, g)", "exists(cube, z)", 1)
+	if _, _, err := RunTrace(strings.NewReader(bad), nil); err == nil || !strings.Contains(err.Error(), "line 15") {
+		t.Errorf("expected an error at line 15 for an unknown BDD, actual %v", err)
+	}
+}
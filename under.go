@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// assumptions returns, for each level, the value of the variable at this level
+// in the cube c (0 or 1), or -1 if the variable does not occur in c.
+func (b *BDD) assumptions(c int) ([]int, error) {
+	if !b.iscube(c) {
+		return nil, fmt.Errorf("assumptions are not a cube")
+	}
+	res := make([]int, b.varnum)
+	for k := range res {
+		res[k] = -1
+	}
+	for c > 1 {
+		if low := b.low(c); low != 0 {
+			res[b.level(c)] = 0
+			c = low
+		} else {
+			res[b.level(c)] = 1
+			c = b.high(c)
+		}
+	}
+	return res, nil
+}
+
+// SatcountUnder computes the number of satisfying variable assignments for the
+// function denoted by n where the variables in the cube assumptions have a
+// fixed value; meaning the same result than Satcount(And(n, assumptions)), but
+// without building new nodes. This is useful when the assumptions change often,
+// for instance in an interactive explorer. The result is zero (and we set the
+// error flag of b) if there is an error, for instance if assumptions is not a
+// cube.
+func (b *BDD) SatcountUnder(n, assumptions Node) *big.Int {
+	res := big.NewInt(0)
+	if b.checkptr(n) != nil || b.checkptr(assumptions) != nil {
+		b.seterror("Wrong operand in call to SatcountUnder")
+		return res
+	}
+	assign, err := b.assumptions(*assumptions)
+	if err != nil {
+		b.seterror("%s in call to SatcountUnder", err)
+		return res
+	}
+	// free[l] is the number of free levels strictly smaller than l
+	free := make([]int, b.varnum+1)
+	for l, v := range assign {
+		free[l+1] = free[l]
+		if v < 0 {
+			free[l+1]++
+		}
+	}
+	satc := make(map[int]*big.Int)
+	// count returns the number of assignments of the levels greater or equal
+	// to from that satisfy n
+	var count func(n int, from int32) *big.Int
+	count = func(n int, from int32) *big.Int {
+		res := big.NewInt(0)
+		if n == 0 {
+			return res
+		}
+		level := b.level(n)
+		res.SetBit(res, free[level]-free[from], 1)
+		if n == 1 {
+			return res
+		}
+		c, ok := satc[n]
+		if !ok {
+			c = big.NewInt(0)
+			if assign[level] != 1 {
+				c.Add(c, count(b.low(n), level+1))
+			}
+			if assign[level] != 0 {
+				c.Add(c, count(b.high(n), level+1))
+			}
+			satc[n] = c
+		}
+		return res.Mul(res, c)
+	}
+	return count(*n, 0)
+}
+
+// AllsatUnder iterates through all legal variable assignments for n where the
+// variables in the cube assumptions have a fixed value, like with Allsat(f,
+// And(n, assumptions)) but without building new nodes. The assumed variables
+// always have their fixed value in the slices passed to f. We stop and return
+// an error if f returns an error at some point, or if assumptions is not a
+// cube.
+func (b *BDD) AllsatUnder(f func([]int) error, n, assumptions Node) error {
+	if b.checkptr(n) != nil || b.checkptr(assumptions) != nil {
+		return fmt.Errorf("wrong node in call to AllsatUnder")
+	}
+	assign, err := b.assumptions(*assumptions)
+	if err != nil {
+		return fmt.Errorf("%s in call to AllsatUnder", err)
+	}
+	if b.sealed == nil {
+		b.traversal++
+		defer func() { b.traversal-- }()
+	}
+	prof := make([]int, b.varnum)
+	// skip sets the value of the variables at levels in [from, to)
+	skip := func(from, to int32) {
+		for l := from; l < to; l++ {
+			prof[b.level2var[l]] = assign[l]
+		}
+	}
+	var allsat func(n int) error
+	allsat = func(n int) error {
+		if n == 1 {
+			return f(prof)
+		}
+		if n == 0 {
+			return nil
+		}
+		level := b.level(n)
+		for _, v := range []int{0, 1} {
+			if assign[level] == 1-v {
+				continue
+			}
+			next := b.low(n)
+			if v == 1 {
+				next = b.high(n)
+			}
+			if next == 0 {
+				continue
+			}
+			prof[b.level2var[level]] = v
+			skip(level+1, b.level(next))
+			if err := allsat(next); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	skip(0, b.level(*n))
+	return allsat(*n)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestSatcountUnder(t *testing.T) {
+	bdd, _ := New(6)
+	rng := rand.New(rand.NewSource(2))
+	cube := bdd.Makecube([]int{4, 1}, []bool{true, false})
+	for i := 0; i < 20; i++ {
+		n := bdd.False()
+		for k := 0; k < 5; k++ {
+			m := bdd.True()
+			for v := 0; v < 6; v++ {
+				switch rng.Intn(3) {
+				case 0:
+					m = bdd.And(m, bdd.Ithvar(v))
+				case 1:
+					m = bdd.And(m, bdd.NIthvar(v))
+				}
+			}
+			n = bdd.Or(n, m)
+		}
+		expected := bdd.Satcount(bdd.And(n, cube))
+		if actual := bdd.SatcountUnder(n, cube); actual.Cmp(expected) != 0 {
+			t.Errorf("SatcountUnder: expected %s, actual %s", expected, actual)
+		}
+		total := big.NewInt(0)
+		err := bdd.AllsatUnder(func(prof []int) error {
+			if prof[4] != 1 || prof[1] != 0 {
+				t.Errorf("AllsatUnder should follow the assumptions, actual %v", prof)
+			}
+			count := big.NewInt(1)
+			for _, v := range prof {
+				if v < 0 {
+					count.Lsh(count, 1)
+				}
+			}
+			total.Add(total, count)
+			return nil
+		}, n, cube)
+		if err != nil || total.Cmp(expected) != 0 {
+			t.Errorf("AllsatUnder: expected %s assignments, actual %s (%v)", expected, total, err)
+		}
+	}
+	if bdd.SatcountUnder(bdd.True(), bdd.True()).Int64() != 64 {
+		t.Errorf("SatcountUnder(True, True) should be 2^6")
+	}
+	if bdd.SatcountUnder(bdd.True(), bdd.Or(bdd.Ithvar(0), bdd.Ithvar(1))).Sign() != 0 || !bdd.Errored() {
+		t.Errorf("SatcountUnder should fail when the assumptions are not a cube")
+	}
+}
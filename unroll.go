@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "fmt"
+
+// RelSpec describes the variables of a transition relation, where Current[k]
+// and Next[k] encode the same bit of, respectively, the source and the target
+// state of a transition.
+type RelSpec struct {
+	Current []int
+	Next    []int
+}
+
+// Unroll unrolls the transition relation trans k times, for bounded model
+// checking. The result is the set of paths of length k, over k+1 copies of the
+// state variables, that start from a state in init and go through a state in
+// bad; meaning the BDD for
+//
+//	init(s0) ∧ trans(s0, s1) ∧ ... ∧ trans(sk-1, sk) ∧ (bad(s0) ∨ ... ∨ bad(sk))
+//
+// where s0 are the current variables and s1 the next variables of rel. The
+// other copies, s2 to sk, are fresh variables, allocated when needed using
+// ExtVarnum and reused in the following calls to Unroll with the same current
+// variables. The boolean result is true if the result is not empty, meaning
+// that a bad state is reachable from init in at most k steps. We return nil and
+// false, and set the error condition in b, if there is an error.
+func (b *BDD) Unroll(trans, init, bad Node, k int, rel RelSpec) (Node, bool) {
+	if b.checkptr(trans) != nil || b.checkptr(init) != nil || b.checkptr(bad) != nil {
+		b.seterror("wrong node in call to Unroll")
+		return nil, false
+	}
+	if k < 0 || len(rel.Current) != len(rel.Next) {
+		b.seterror("wrong parameters in call to Unroll")
+		return nil, false
+	}
+	copies, err := b.statecopies(rel, k)
+	if err != nil {
+		b.seterror("%s in call to Unroll", err)
+		return nil, false
+	}
+	// rename returns n after replacing oldvars with newvars
+	rename := func(n Node, oldvars, newvars []int) Node {
+		r, err := b.NewReplacer(oldvars, newvars)
+		if err != nil {
+			return b.seterror("%s in call to Unroll", err)
+		}
+		return b.Replace(n, r)
+	}
+	path := init
+	reach := bad
+	for i := 1; i <= k; i++ {
+		step := trans
+		if i > 1 {
+			step = rename(rename(trans, rel.Next, copies[i]), rel.Current, copies[i-1])
+		}
+		path = b.And(path, step)
+		reach = b.Or(reach, rename(bad, rel.Current, copies[i]))
+	}
+	res := b.And(path, reach)
+	if res == nil {
+		return nil, false
+	}
+	return res, *res != 0
+}
+
+// copymap associates a list of copies of state variables to the variables of
+// a RelSpec.
+type copymap map[string][][]int
+
+// statecopies returns k+1 copies of the state variables of rel, where the
+// first two are the current and next variables, allocating new variables if
+// needed.
+func (b *BDD) statecopies(rel RelSpec, k int) ([][]int, error) {
+	key := fmt.Sprint(rel.Current, rel.Next)
+	if b.unrolled == nil {
+		b.unrolled = make(copymap)
+	}
+	copies, ok := b.unrolled[key]
+	if !ok {
+		copies = [][]int{append([]int{}, rel.Current...), append([]int{}, rel.Next...)}
+	}
+	width := len(rel.Current)
+	for len(copies) <= k {
+		old, err := b.ExtVarnum(width)
+		if err != nil {
+			return nil, err
+		}
+		fresh := make([]int, width)
+		for j := range fresh {
+			fresh[j] = old + j
+		}
+		copies = append(copies, fresh)
+	}
+	b.unrolled[key] = copies
+	return copies, nil
+}
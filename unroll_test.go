@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExtVarnum(t *testing.T) {
+	var journal bytes.Buffer
+	bdd, _ := New(3, Journal(&journal))
+	n := bdd.Or(bdd.Ithvar(0), bdd.Ithvar(2))
+	count := bdd.Satcount(n).Int64()
+	if old, err := bdd.ExtVarnum(2); err != nil || old != 3 || bdd.Varnum() != 5 {
+		t.Fatalf("ExtVarnum(2): expected 3 variables before and 5 after, actual %d and %d (%v)", old, bdd.Varnum(), err)
+	}
+	if actual := bdd.Satcount(n).Int64(); actual != 4*count {
+		t.Errorf("Satcount should take into account the new variables, expected %d, actual %d", 4*count, actual)
+	}
+	m := bdd.And(n, bdd.NIthvar(4))
+	if bdd.Var(bdd.Ithvar(4)) != 4 || bdd.Satcount(m).Int64() != 2*count {
+		t.Errorf("new variables should be usable after ExtVarnum")
+	}
+	other, err := Replay(bytes.NewReader(journal.Bytes()))
+	if err != nil || other.Varnum() != 5 {
+		t.Errorf("Replay of a journal with ExtVarnum failed: %v", err)
+	}
+	if _, err := bdd.ExtVarnum(-1); err == nil {
+		t.Errorf("ExtVarnum with a negative number should fail")
+	}
+}
+
+func TestUnroll(t *testing.T) {
+	// a 2-bit counter, with current variables 0 and 1, and next variables 2
+	// and 3, where variable 0 is the least significant bit
+	bdd, _ := New(4)
+	rel := RelSpec{Current: []int{0, 1}, Next: []int{2, 3}}
+	state := func(vars []int, v int) Node {
+		return bdd.Makecube(vars, []bool{v&1 != 0, v&2 != 0})
+	}
+	trans := bdd.False()
+	for v := 0; v < 4; v++ {
+		trans = bdd.Or(trans, bdd.And(state(rel.Current, v), state(rel.Next, (v+1)%4)))
+	}
+	init, bad := state(rel.Current, 0), state(rel.Current, 3)
+	if _, found := bdd.Unroll(trans, init, bad, 2, rel); found {
+		t.Errorf("state 3 should not be reachable in 2 steps")
+	}
+	varnum := bdd.Varnum()
+	paths, found := bdd.Unroll(trans, init, bad, 3, rel)
+	if !found || bdd.Satcount(paths).Int64() != 1 {
+		t.Errorf("there should be exactly one path of length 3 reaching state 3")
+	}
+	if bdd.Varnum() != varnum+2 {
+		t.Errorf("Unroll should reuse the copies of variables allocated before, expected %d variables, actual %d", varnum+2, bdd.Varnum())
+	}
+	if _, found := bdd.Unroll(trans, init, bad, 1, RelSpec{Current: []int{0}, Next: []int{2, 3}}); found || !bdd.Errored() {
+		t.Errorf("Unroll should fail when the lists of variables have different lengths")
+	}
+}
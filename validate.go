@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger is a configuration option (function). Used as a parameter in New it
+// sets a logger used to report the problems found in the configuration of the
+// BDD (see Validate) when it is created. By default, nothing is logged and the
+// problems can only be retrieved with method Warnings.
+func Logger(l *log.Logger) func(*configs) {
+	return func(c *configs) {
+		c.logger = l
+	}
+}
+
+// Validate returns a list of warnings about a configuration, given as the
+// number of variables and a list of configuration options, in the same way
+// than with New. Warnings are about values of the parameters that are legal,
+// but known to hurt performances, such as caches that are far smaller than the
+// node table, caches that do not grow with a table that can grow, a ratio of
+// free nodes so high that the table is resized after almost every garbage
+// collection, or a number of variables close to the maximum. We return nil if
+// there are no problems.
+func Validate(varnum int, options ...func(*configs)) []string {
+	c := makeconfigs(varnum)
+	for _, f := range options {
+		f(c)
+	}
+	return c.validate()
+}
+
+func (c *configs) validate() []string {
+	var res []string
+	warn := func(format string, a ...interface{}) {
+		res = append(res, fmt.Sprintf(format, a...))
+	}
+	if (c.varnum < 1) || (c.varnum > int(_MAXVAR)) {
+		warn("bad number of variables (%d)", c.varnum)
+	} else if c.varnum > int(_MAXVAR)/10*9 {
+		warn("number of variables (%d) is close to the maximum (%d)", c.varnum, _MAXVAR)
+	}
+	cachesize := c.cachesize
+	if cachesize == 0 {
+		cachesize = 10000
+	}
+	if 10*cachesize < c.nodesize {
+		warn("cache size (%d) is far smaller than the node table (%d nodes)", cachesize, c.nodesize)
+	}
+	canGrow := c.maxnodesize == 0 || c.maxnodesize > c.nodesize
+	if c.cacheratio == 0 && canGrow && cachesize < c.nodesize {
+		warn("cache ratio is 0, so the caches will not grow with the node table")
+	}
+	if c.maxnodesize > 0 && c.maxnodesize < c.nodesize {
+		warn("maximal number of nodes (%d) is less than the initial size of the node table (%d)", c.maxnodesize, c.nodesize)
+	}
+	switch {
+	case c.minfreenodes < 0 || c.minfreenodes >= 100:
+		warn("ratio of free nodes (%d%%) should be in the range [0..100)", c.minfreenodes)
+	case c.minfreenodes >= 50:
+		warn("ratio of free nodes (%d%%) is high and may trigger a resize after almost every garbage collection", c.minfreenodes)
+	}
+	return res
+}
+
+// initwarnings records, and logs if there is a logger, the problems found in
+// configuration c.
+func (b *BDD) initwarnings(c *configs) {
+	b.warnings = c.validate()
+	if c.logger == nil {
+		return
+	}
+	for _, w := range b.warnings {
+		c.logger.Printf("rudd: %s", w)
+	}
+}
+
+// Warnings returns the problems found in the configuration of the BDD when it
+// was created, see Validate.
+func (b *BDD) Warnings() []string {
+	return b.warnings
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import "fmt"
+
+// ExtVarnum extends the number of variables of b with num new variables, and
+// returns the previous number of variables, like bdd_extvarnum in BuDDy. The
+// new variables are added at the bottom of the variable order, meaning that
+// existing nodes are still valid and denote the same functions. Replacers
+// created before the call can still be used, but they do not affect the new
+// variables. We return an error, and set the error condition in b, if the
+// total number of variables is too large or if we cannot allocate the nodes for
+// the new variables.
+func (b *BDD) ExtVarnum(num int) (int, error) {
+	if b.sealed != nil {
+		return int(b.varnum), fmt.Errorf("error in call to ExtVarnum; %w", ErrSealed)
+	}
+	old := int(b.varnum)
+	if num < 0 || old+num > int(_MAXVAR) {
+		b.seterror("bad number of new variables (%d) in call to ExtVarnum", num)
+		return old, b.error
+	}
+	if num == 0 {
+		return old, nil
+	}
+	varnum := int32(old + num)
+	b.setconstlevel(varnum)
+	for k := int32(old); k < varnum; k++ {
+		b.var2level = append(b.var2level, k)
+		b.level2var = append(b.level2var, k)
+	}
+	b.varnum = varnum
+	b.quantset = make([]int32, varnum)
+	b.quantsetID = 0
+	// the result of cached operations does not change, except for the ones
+	// that depend on the level of constants
+	b.cachereset()
+	b.Initref()
+	for k := int32(old); k < varnum; k++ {
+		v0 := b.Makenode(k, 0, 1)
+		if v0 < 0 {
+			b.seterror("cannot allocate new variable %d in ExtVarnum", k)
+			return old, b.error
+		}
+		b.pinnode(v0)
+		b.Pushref(v0)
+		v1 := b.Makenode(k, 1, 0)
+		if v1 < 0 {
+			b.seterror("cannot allocate new variable %d in ExtVarnum", k)
+			return old, b.error
+		}
+		b.pinnode(v1)
+		b.Popref(1)
+		b.varset = append(b.varset, [2]int{v0, v1})
+	}
+	if b.journal != nil {
+		b.journal.seq++
+		fmt.Fprintf(b.journal.w, "%d extvarnum %d = %d 0 +0\n", b.journal.seq, num, old)
+	}
+	return old, nil
+}
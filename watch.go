@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SizeReport is the information passed to the function registered with
+// WatchSize when the number of live nodes goes above a threshold.
+type SizeReport struct {
+	Live      int       // Number of live nodes, including the two constants
+	Allocated int       // Size of the node table
+	Threshold int       // Threshold that was crossed
+	Time      time.Time // Time at which the size was observed
+}
+
+// watcher holds the size of the node table, published by Makenode so that it
+// can be read safely from the goroutines started by WatchSize.
+type watcher struct {
+	live      atomic.Int64
+	allocated atomic.Int64
+}
+
+func (w *watcher) publish(live, allocated int) {
+	w.live.Store(int64(live))
+	w.allocated.Store(int64(allocated))
+}
+
+// WatchSize starts a goroutine that checks the number of live nodes in b every
+// interval and calls f each time this number goes above threshold. The
+// threshold is doubled after each call, so that a blow-up is reported while it
+// happens, with a bounded number of calls. Function f is called from the
+// watching goroutine, concurrently with the operations on b, and therefore it
+// must not call any method of b; it can, for instance, log the report or
+// cancel the context of the computation. An interval less than a millisecond
+// is raised to one millisecond. We return a function that stops the watcher.
+func (b *BDD) WatchSize(interval time.Duration, threshold int, f func(SizeReport)) (stop func()) {
+	if b.watch == nil {
+		b.watch = &watcher{}
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	w := b.watch
+	w.publish(b.used(), b.size())
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				live := int(w.live.Load())
+				if live <= threshold {
+					continue
+				}
+				f(SizeReport{
+					Live:      live,
+					Allocated: int(w.allocated.Load()),
+					Threshold: threshold,
+					Time:      now,
+				})
+				for live > threshold {
+					threshold *= 2
+				}
+			}
+		}
+	}()
+	var once atomic.Bool
+	return func() {
+		if once.CompareAndSwap(false, true) {
+			close(done)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchSize(t *testing.T) {
+	bdd, _ := New(60, Nodesize(1000))
+	var mu sync.Mutex
+	var reports []SizeReport
+	stop := bdd.WatchSize(time.Millisecond, 200, func(r SizeReport) {
+		mu.Lock()
+		reports = append(reports, r)
+		mu.Unlock()
+	})
+	defer stop()
+	res := bdd.True()
+	for i := 0; i < 12; i++ {
+		res = bdd.And(res, bdd.Equiv(bdd.Ithvar(i), bdd.Ithvar(i+30)))
+		time.Sleep(2 * time.Millisecond)
+	}
+	stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) == 0 {
+		t.Fatalf("expected at least one report, live nodes %d", bdd.Live())
+	}
+	for k, r := range reports {
+		if r.Live <= r.Threshold || (k > 0 && r.Threshold <= reports[k-1].Threshold) {
+			t.Errorf("unexpected sequence of reports: %+v", reports)
+		}
+	}
+	// a null interval does not panic
+	called := make(chan struct{}, 1)
+	stop = bdd.WatchSize(0, 1, func(SizeReport) {
+		select {
+		case called <- struct{}{}:
+		default:
+		}
+	})
+	defer stop()
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Errorf("expected a report with a null interval")
+	}
+}
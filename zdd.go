@@ -0,0 +1,540 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"runtime"
+
+	"github.com/dalzilio/rudd/internal/dd"
+)
+
+// ZDD is a Zero-suppressed Decision Diagram, a variant of BDD used to encode
+// families of sets of variables (also called combination sets), such as the
+// covers of a graph or the itemsets of a database. The only difference with
+// BDD is the reduction rule: we remove the nodes whose high successor is the
+// constant 0, instead of the nodes with two equal successors, meaning that a
+// variable that does not appear on a path is absent from the set, instead of
+// being irrelevant. Hence families of sparse sets have much smaller diagrams
+// than their characteristic function.
+//
+// The constant 0 is the empty family, and 1 is the family containing only the
+// empty set. Like with Diagram, a ZDD uses the same node table, garbage
+// collector and operation cache than a BDD built with build tag buddy, and the
+// same configuration options (such as Nodesize or Cachesize). The level of a
+// variable is always equal to its index. A ZDD is not safe for concurrent use.
+type ZDD struct {
+	varnum        int32
+	core          dd.Table  // Node table; constants are at level varnum
+	cache         dd.Cache4 // Cache for all the operations, see zddop
+	refstack      []int
+	nodefinalizer interface{}
+	error         error
+}
+
+// zddop is the identifier of an operation in the cache of a ZDD.
+type zddop int
+
+const (
+	zddunion zddop = iota
+	zddintersection
+	zdddifference
+	zddproduct
+	zddsubset0
+	zddsubset1
+	zddchange
+)
+
+// NewZDD returns a new zero-suppressed decision diagram with varnum variables.
+// The configuration options are the same than with New, but options Journal
+// and Assertions are ignored. We return a nil value if there is an error.
+func NewZDD(varnum int, options ...func(*configs)) (*ZDD, error) {
+	if (varnum < 1) || (varnum > int(_MAXVAR)) {
+		return nil, fmt.Errorf("bad number of variable (%d)", varnum)
+	}
+	config := makeconfigs(varnum)
+	for _, f := range options {
+		f(config)
+	}
+	z := &ZDD{
+		varnum:   int32(varnum),
+		refstack: make([]int, 0, 2*varnum+4),
+	}
+	z.core.Minfreenodes = config.minfreenodes
+	z.core.Maxnodeincrease = config.maxnodeincrease
+	z.core.Hash = config.hashfunc
+	z.core.Maxchain = config.maxchain
+	z.core.StopTheWorld = config.resizemode == ResizeStopTheWorld
+	z.core.ZeroSuppressed = true
+	z.core.Init(config.nodesize, z.varnum)
+	z.nodefinalizer = func(n *int) {
+		z.core.Nodes[*n].Refcou--
+	}
+	size := 10000
+	if config.cachesize != 0 {
+		size = config.cachesize
+	}
+	z.cache.Init(size, config.cacheratio)
+	return z, nil
+}
+
+// Varnum returns the number of defined variables.
+func (z *ZDD) Varnum() int {
+	return int(z.varnum)
+}
+
+// Error returns the error status of the diagram.
+func (z *ZDD) Error() string {
+	if z.error == nil {
+		return ""
+	}
+	return z.error.Error()
+}
+
+// Errored returns true if there was an error during a computation.
+func (z *ZDD) Errored() bool {
+	return z.error != nil
+}
+
+func (z *ZDD) seterror(format string, a ...interface{}) Node {
+	if z.error != nil {
+		format = format + "; " + z.Error()
+	}
+	z.error = fmt.Errorf(format, a...)
+	if _DEBUG {
+		log.Println(z.error)
+	}
+	return nil
+}
+
+func (z *ZDD) makenode(level int32, low, high int) int {
+	if low < 0 || high < 0 {
+		return -1
+	}
+	res, err := z.core.Makenode(level, low, high, z.refstack)
+	switch err {
+	case dd.ErrReset:
+		z.cache.Reset()
+	case dd.ErrResize:
+		z.cache.Resize(len(z.core.Nodes))
+	case dd.ErrMemory:
+		z.seterror("%s", err)
+	}
+	return res
+}
+
+func (z *ZDD) pushref(n int) int {
+	z.refstack = append(z.refstack, n)
+	return n
+}
+
+func (z *ZDD) retnode(n int) Node {
+	if n < 0 {
+		return nil
+	}
+	x := n
+	if z.core.Nodes[n].Refcou < _MAXREFCOUNT {
+		z.core.Nodes[n].Refcou++
+		runtime.SetFinalizer(&x, z.nodefinalizer)
+	}
+	return &x
+}
+
+func (z *ZDD) checkptr(n Node) error {
+	if n == nil || *n < 0 || *n >= len(z.core.Nodes) || (*n > 1 && z.core.Nodes[*n].Low == -1) {
+		z.seterror("Illegal acces to node")
+		return z.error
+	}
+	return nil
+}
+
+func (z *ZDD) checkvar(v int, op string) error {
+	if (v < 0) || (int32(v) >= z.varnum) {
+		z.seterror("Unknown variable used (%d) in call to %s", v, op)
+		return z.error
+	}
+	return nil
+}
+
+func (z *ZDD) level(n int) int32 {
+	return z.core.Nodes[n].Level
+}
+
+func (z *ZDD) low(n int) int {
+	return z.core.Nodes[n].Low
+}
+
+func (z *ZDD) high(n int) int {
+	return z.core.Nodes[n].High
+}
+
+// Empty returns the empty family, the constant 0.
+func (z *ZDD) Empty() Node {
+	return z.retnode(0)
+}
+
+// Base returns the family containing only the empty set, the constant 1.
+func (z *ZDD) Base() Node {
+	return z.retnode(1)
+}
+
+// Set returns the family containing only the set of variables vars. The
+// variables must be in the range [0..Varnum) and may be given in any order.
+func (z *ZDD) Set(vars []int) Node {
+	in := make([]bool, z.varnum)
+	for _, v := range vars {
+		if z.checkvar(v, "Set") != nil {
+			return nil
+		}
+		in[v] = true
+	}
+	res := 1
+	for v := int(z.varnum) - 1; v >= 0; v-- {
+		if in[v] {
+			z.refstack = append(z.refstack[:0], res)
+			res = z.makenode(int32(v), 0, res)
+		}
+	}
+	return z.retnode(res)
+}
+
+// Subset0 returns the sets of n that do not contain variable v.
+func (z *ZDD) Subset0(n Node, v int) Node {
+	return z.unary(n, v, zddsubset0, "Subset0")
+}
+
+// Subset1 returns the sets of n that contain variable v, from which we remove
+// v.
+func (z *ZDD) Subset1(n Node, v int) Node {
+	return z.unary(n, v, zddsubset1, "Subset1")
+}
+
+// Change returns the family obtained by toggling variable v in every set of n:
+// we add v to the sets that do not contain it, and remove it from the others.
+func (z *ZDD) Change(n Node, v int) Node {
+	return z.unary(n, v, zddchange, "Change")
+}
+
+func (z *ZDD) unary(n Node, v int, op zddop, name string) Node {
+	if z.checkptr(n) != nil {
+		return z.seterror("Wrong operand in call to %s", name)
+	}
+	if z.checkvar(v, name) != nil {
+		return nil
+	}
+	z.refstack = z.refstack[:0]
+	z.pushref(*n)
+	return z.retnode(z.subset(*n, int32(v), op))
+}
+
+// subset implements Subset0, Subset1 and Change.
+func (z *ZDD) subset(n int, v int32, op zddop) int {
+	if n < 0 {
+		return -1
+	}
+	level := z.level(n)
+	if level > v {
+		switch op {
+		case zddsubset0:
+			return n
+		case zddsubset1:
+			return 0
+		}
+		return z.makenode(v, 0, n)
+	}
+	if level == v {
+		switch op {
+		case zddsubset0:
+			return z.low(n)
+		case zddsubset1:
+			return z.high(n)
+		}
+		return z.makenode(v, z.high(n), z.low(n))
+	}
+	if e := z.cache.Table[dd.Triple(n, int(v), int(op), len(z.cache.Table))]; e.A == n && e.B == int(v) && e.C == int(op) {
+		return e.Res
+	}
+	initial := len(z.refstack)
+	low := z.pushref(z.subset(z.low(n), v, op))
+	high := z.pushref(z.subset(z.high(n), v, op))
+	res := z.makenode(level, low, high)
+	z.refstack = z.refstack[:initial]
+	if res >= 0 {
+		// the cache may have been resized during the recursive calls
+		z.cache.Table[dd.Triple(n, int(v), int(op), len(z.cache.Table))] = dd.Entry4{A: n, B: int(v), C: int(op), Res: res}
+	}
+	return res
+}
+
+// Union returns the sets that are in n1 or in n2.
+func (z *ZDD) Union(n1, n2 Node) Node {
+	return z.binary(n1, n2, zddunion, "Union")
+}
+
+// Intersection returns the sets that are both in n1 and in n2.
+func (z *ZDD) Intersection(n1, n2 Node) Node {
+	return z.binary(n1, n2, zddintersection, "Intersection")
+}
+
+// Difference returns the sets of n1 that are not in n2.
+func (z *ZDD) Difference(n1, n2 Node) Node {
+	return z.binary(n1, n2, zdddifference, "Difference")
+}
+
+// Product returns the family of all the unions of a set of n1 with a set of n2,
+// also called the join or the unate product of the two families.
+func (z *ZDD) Product(n1, n2 Node) Node {
+	return z.binary(n1, n2, zddproduct, "Product")
+}
+
+func (z *ZDD) binary(n1, n2 Node, op zddop, name string) Node {
+	if z.checkptr(n1) != nil || z.checkptr(n2) != nil {
+		return z.seterror("Wrong operand in call to %s", name)
+	}
+	z.refstack = z.refstack[:0]
+	z.pushref(*n1)
+	z.pushref(*n2)
+	return z.retnode(z.apply(*n1, *n2, op))
+}
+
+// apply implements the binary operations on families.
+func (z *ZDD) apply(p, q int, op zddop) int {
+	if p < 0 || q < 0 {
+		return -1
+	}
+	switch op {
+	case zddunion:
+		switch {
+		case p == 0 || p == q:
+			return q
+		case q == 0:
+			return p
+		}
+	case zddintersection:
+		switch {
+		case p == 0 || q == 0:
+			return 0
+		case p == q:
+			return p
+		}
+	case zdddifference:
+		switch {
+		case p == 0 || p == q:
+			return 0
+		case q == 0:
+			return p
+		}
+	case zddproduct:
+		switch {
+		case p == 0 || q == 0:
+			return 0
+		case p == 1:
+			return q
+		case q == 1:
+			return p
+		}
+	}
+	if op != zdddifference && p > q {
+		// the other operations are commutative
+		p, q = q, p
+	}
+	if e := z.cache.Table[dd.Triple(p, q, int(op), len(z.cache.Table))]; e.A == p && e.B == q && e.C == int(op) {
+		return e.Res
+	}
+	level := z.level(p)
+	if l := z.level(q); l < level {
+		level = l
+	}
+	p0, p1 := z.cofactors(p, level)
+	q0, q1 := z.cofactors(q, level)
+	initial := len(z.refstack)
+	var res int
+	switch op {
+	case zddproduct:
+		// (v.p1 + p0)(v.q1 + q0) = v.(p1.q1 + p1.q0 + p0.q1) + p0.q0
+		low := z.pushref(z.apply(p0, q0, op))
+		a := z.pushref(z.apply(p1, q1, op))
+		b := z.pushref(z.apply(p1, q0, op))
+		c := z.pushref(z.apply(p0, q1, op))
+		high := z.pushref(z.apply(z.pushref(z.apply(a, b, zddunion)), c, zddunion))
+		res = z.makenode(level, low, high)
+	default:
+		low := z.pushref(z.apply(p0, q0, op))
+		high := z.pushref(z.apply(p1, q1, op))
+		res = z.makenode(level, low, high)
+	}
+	z.refstack = z.refstack[:initial]
+	if res >= 0 {
+		z.cache.Table[dd.Triple(p, q, int(op), len(z.cache.Table))] = dd.Entry4{A: p, B: q, C: int(op), Res: res}
+	}
+	return res
+}
+
+// cofactors returns the sets of n without and with the variable at the given
+// level, that should be less or equal to the level of n.
+func (z *ZDD) cofactors(n int, level int32) (int, int) {
+	if z.level(n) != level {
+		return n, 0
+	}
+	return z.low(n), z.high(n)
+}
+
+// Count returns the number of sets in n.
+func (z *ZDD) Count(n Node) *big.Int {
+	if z.checkptr(n) != nil {
+		return big.NewInt(0)
+	}
+	memo := make(map[int]*big.Int)
+	var count func(k int) *big.Int
+	count = func(k int) *big.Int {
+		if k < 2 {
+			return big.NewInt(int64(k))
+		}
+		if res, ok := memo[k]; ok {
+			return res
+		}
+		res := new(big.Int).Add(count(z.low(k)), count(z.high(k)))
+		memo[k] = res
+		return res
+	}
+	return count(*n)
+}
+
+// Allsets iterates through all the sets in n and calls f on each one of them,
+// given as a list of variables in increasing order. We stop the iteration at
+// the first call to f that returns an error, and return this error; otherwise
+// we return nil. The slice given to f is reused between calls.
+func (z *ZDD) Allsets(f func([]int) error, n Node) error {
+	if z.checkptr(n) != nil {
+		return fmt.Errorf("wrong node in call to Allsets (%v)", n)
+	}
+	set := make([]int, 0, z.varnum)
+	var visit func(k int) error
+	visit = func(k int) error {
+		switch k {
+		case 0:
+			return nil
+		case 1:
+			return f(set)
+		}
+		if err := visit(z.low(k)); err != nil {
+			return err
+		}
+		set = append(set, int(z.level(k)))
+		err := visit(z.high(k))
+		set = set[:len(set)-1]
+		return err
+	}
+	return visit(*n)
+}
+
+// NodeCount returns the number of nodes in n, not counting the constants.
+func (z *ZDD) NodeCount(n Node) int {
+	if z.checkptr(n) != nil {
+		return 0
+	}
+	seen := make(map[int]bool)
+	var visit func(k int)
+	visit = func(k int) {
+		if k < 2 || seen[k] {
+			return
+		}
+		seen[k] = true
+		visit(z.low(k))
+		visit(z.high(k))
+	}
+	visit(*n)
+	return len(seen)
+}
+
+// ToZDD returns the family of sets of variables whose characteristic function
+// is n: each satisfying assignment of n corresponds to the set of variables
+// that are true in this assignment. The two diagrams must have the same number
+// of variables, that are matched by index; the order of variables in b can be
+// different from the identity. This can be used to enumerate, or count, the
+// models of a sparse function more efficiently. We return nil, and set the
+// error flag of z, if there is an error.
+func (b *BDD) ToZDD(z *ZDD, n Node) Node {
+	if b.checkptr(n) != nil {
+		return z.seterror("Wrong operand in call to ToZDD (%s)", b.Error())
+	}
+	if z.varnum != b.varnum {
+		return z.seterror("Different number of variables (%d and %d) in call to ToZDD", b.varnum, z.varnum)
+	}
+	// expand adds the variables at levels [from..to) of b, that do not occur on
+	// the paths of a node, to the sets in f.
+	expand := func(f Node, from, to int32) Node {
+		for l := to - 1; l >= from && f != nil; l-- {
+			f = z.Union(f, z.Change(f, int(b.level2var[l])))
+		}
+		return f
+	}
+	memo := make(map[int]Node)
+	var rec func(k int) Node
+	rec = func(k int) Node {
+		if k < 2 {
+			return z.retnode(k)
+		}
+		if res, ok := memo[k]; ok {
+			return res
+		}
+		level, low, high := b.level(k), b.low(k), b.high(k)
+		l := expand(rec(low), level+1, b.level(low))
+		h := expand(rec(high), level+1, b.level(high))
+		if l == nil || h == nil {
+			return nil
+		}
+		res := z.Union(l, z.Change(h, int(b.level2var[level])))
+		memo[k] = res
+		return res
+	}
+	return expand(rec(*n), 0, b.level(*n))
+}
+
+// FromZDD returns the characteristic function of the family of sets n, that
+// is the function true for the assignments where the variables in one of the
+// sets of n are true, and all the others are false. The two diagrams must have
+// the same number of variables, that are matched by index. This is the inverse
+// of ToZDD. We return nil, and set the error flag of b, if there is an error.
+func (b *BDD) FromZDD(z *ZDD, n Node) Node {
+	if b.sealed != nil {
+		return b.sealerror("FromZDD")
+	}
+	if z.checkptr(n) != nil {
+		return b.seterror("Wrong operand in call to FromZDD (%s)", z.Error())
+	}
+	if z.varnum != b.varnum {
+		return b.seterror("Different number of variables (%d and %d) in call to FromZDD", b.varnum, z.varnum)
+	}
+	// none returns the conjunction of the negations of the variables in
+	// [from..to), that are absent from the sets when they are skipped.
+	none := func(from, to int32) Node {
+		res := b.True()
+		for v := from; v < to; v++ {
+			res = b.And(res, b.NIthvar(int(v)))
+		}
+		return res
+	}
+	memo := make(map[int]Node)
+	var rec func(k int) Node
+	rec = func(k int) Node {
+		switch k {
+		case 0:
+			return b.False()
+		case 1:
+			return b.True()
+		}
+		if res, ok := memo[k]; ok {
+			return res
+		}
+		v, low, high := z.level(k), z.low(k), z.high(k)
+		res := b.Ite(b.Ithvar(int(v)), b.And(none(v+1, z.level(high)), rec(high)), b.And(none(v+1, z.level(low)), rec(low)))
+		memo[k] = res
+		return res
+	}
+	return b.And(none(0, z.level(*n)), rec(*n))
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Silvano DAL ZILIO
+//
+// MIT License
+
+package rudd
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// family is a family of sets over a few variables, where each set is encoded
+// as a bitmask.
+type family map[int]bool
+
+func (f family) keys() []int {
+	res := []int{}
+	for s := range f {
+		res = append(res, s)
+	}
+	sort.Ints(res)
+	return res
+}
+
+// zddfamily returns the sets of n as bitmasks.
+func zddfamily(t *testing.T, z *ZDD, n Node) []int {
+	res := []int{}
+	err := z.Allsets(func(set []int) error {
+		mask := 0
+		for _, v := range set {
+			mask |= 1 << v
+		}
+		res = append(res, mask)
+		return nil
+	}, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(res)
+	return res
+}
+
+func (f family) build(z *ZDD) Node {
+	res := z.Empty()
+	for s := range f {
+		set := []int{}
+		for v := 0; v < z.Varnum(); v++ {
+			if s&(1<<v) != 0 {
+				set = append(set, v)
+			}
+		}
+		res = z.Union(res, z.Set(set))
+	}
+	return res
+}
+
+func TestZDD(t *testing.T) {
+	const varnum = 5
+	z, err := NewZDD(varnum, Nodesize(20), Cachesize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := func() family {
+		f := family{}
+		for k := rand.Intn(8); k > 0; k-- {
+			f[rand.Intn(1<<varnum)] = true
+		}
+		return f
+	}
+	equal := func(op string, expected family, n Node) {
+		t.Helper()
+		actual := zddfamily(t, z, n)
+		if keys := expected.keys(); len(keys) != len(actual) || z.Count(n).Int64() != int64(len(keys)) {
+			t.Fatalf("%s: expected %v, actual %v", op, keys, actual)
+		} else {
+			for k := range keys {
+				if keys[k] != actual[k] {
+					t.Fatalf("%s: expected %v, actual %v", op, keys, actual)
+				}
+			}
+		}
+	}
+	for round := 0; round < 200; round++ {
+		f, g := random(), random()
+		nf, ng := f.build(z), g.build(z)
+		equal("build", f, nf)
+		union, inter, diff, prod := family{}, family{}, family{}, family{}
+		for s := range f {
+			union[s] = true
+			if g[s] {
+				inter[s] = true
+			} else {
+				diff[s] = true
+			}
+			for r := range g {
+				prod[s|r] = true
+			}
+		}
+		for s := range g {
+			union[s] = true
+		}
+		equal("Union", union, z.Union(nf, ng))
+		equal("Intersection", inter, z.Intersection(nf, ng))
+		equal("Difference", diff, z.Difference(nf, ng))
+		equal("Product", prod, z.Product(nf, ng))
+		v := rand.Intn(varnum)
+		sub0, sub1, change := family{}, family{}, family{}
+		for s := range f {
+			if s&(1<<v) == 0 {
+				sub0[s] = true
+			} else {
+				sub1[s&^(1<<v)] = true
+			}
+			change[s^(1<<v)] = true
+		}
+		equal("Subset0", sub0, z.Subset0(nf, v))
+		equal("Subset1", sub1, z.Subset1(nf, v))
+		equal("Change", change, z.Change(nf, v))
+	}
+	if z.Errored() {
+		t.Fatal(z.Error())
+	}
+	// nodes are unique
+	if n := z.Union(z.Set([]int{1, 3}), z.Set([]int{3, 1})); *n != *z.Set([]int{1, 3}) {
+		t.Errorf("expected the same node for the same family")
+	}
+	if z.Subset0(z.Base(), varnum) != nil || !z.Errored() {
+		t.Errorf("expected an error with an unknown variable")
+	}
+}
+
+// TestZDDSparse checks that the family of all the sets of size 1 (the
+// singletons) has a number of nodes linear in the number of variables.
+func TestZDDSparse(t *testing.T) {
+	const varnum = 100
+	z, _ := NewZDD(varnum)
+	n := z.Empty()
+	for v := 0; v < varnum; v++ {
+		n = z.Union(n, z.Set([]int{v}))
+	}
+	if size := z.NodeCount(n); size != varnum {
+		t.Errorf("expected %d nodes, actual %d", varnum, size)
+	}
+	// the pairs of singletons are the sets of size 2
+	if count := z.Count(z.Difference(z.Product(n, n), n)).Int64(); count != varnum*(varnum-1)/2 {
+		t.Errorf("expected %d pairs, actual %d", varnum*(varnum-1)/2, count)
+	}
+}
+
+func TestZDDConversion(t *testing.T) {
+	const varnum = 6
+	bdd, _ := New(varnum, Nodesize(1000))
+	z, _ := NewZDD(varnum, Nodesize(20))
+	// we use a different order for the BDD, to check that variables are
+	// matched by index
+	if err := bdd.SetVarOrder([]int{3, 0, 5, 1, 4, 2}); err != nil {
+		t.Fatal(err)
+	}
+	for round := 0; round < 50; round++ {
+		f := bdd.False()
+		for k := rand.Intn(4); k >= 0; k-- {
+			cube := bdd.True()
+			for v := 0; v < varnum; v++ {
+				switch rand.Intn(3) {
+				case 0:
+					cube = bdd.And(cube, bdd.Ithvar(v))
+				case 1:
+					cube = bdd.And(cube, bdd.NIthvar(v))
+				}
+			}
+			f = bdd.Or(f, cube)
+		}
+		n := bdd.ToZDD(z, f)
+		if z.Count(n).Cmp(bdd.Satcount(f)) != 0 {
+			t.Fatalf("expected %s sets, actual %s", bdd.Satcount(f), z.Count(n))
+		}
+		// every set is a model of f
+		err := z.Allsets(func(set []int) error {
+			assignment := make([]bool, varnum)
+			for _, v := range set {
+				assignment[v] = true
+			}
+			if *bdd.And(f, bdd.Makecube(nil, assignment)) == 0 {
+				t.Fatalf("set %v is not a model of f", set)
+			}
+			return nil
+		}, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g := bdd.FromZDD(z, n); !bdd.Equal(f, g) {
+			t.Fatalf("FromZDD(ToZDD(f)) is not equal to f")
+		}
+	}
+	if bdd.Errored() || z.Errored() {
+		t.Fatal(bdd.Error(), z.Error())
+	}
+	// the characteristic function of the family {{0, 2}} has a single model
+	if f := bdd.FromZDD(z, z.Set([]int{0, 2})); bdd.Satcount(f).Int64() != 1 {
+		t.Errorf("expected a single model, actual %s", bdd.Satcount(f))
+	}
+	other, _ := NewZDD(varnum + 1)
+	if bdd.ToZDD(other, bdd.True()) != nil || !other.Errored() {
+		t.Errorf("expected an error with a different number of variables")
+	}
+}